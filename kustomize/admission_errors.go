@@ -0,0 +1,77 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// admissionDenialRegexp matches the message of requests
+// denied by validating or mutating admission webhooks
+var admissionDenialRegexp = regexp.MustCompile(`^admission webhook "([^"]+)" denied the request`)
+
+// admissionDenialError is an API error of a request denied by an
+// admission webhook, it keeps the API status so errors can still
+// be checked using the k8serrors functions
+type admissionDenialError struct {
+	*k8serrors.StatusError
+	webhook string
+	message string
+}
+
+func (e *admissionDenialError) Error() string {
+	s := e.Status()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "denied by admission webhook %q", e.webhook)
+	if s.Code != 0 {
+		fmt.Fprintf(&b, " (%d %s)", s.Code, http.StatusText(int(s.Code)))
+	}
+	if e.message != "" {
+		fmt.Fprintf(&b, ": %s", e.message)
+	}
+
+	if s.Details != nil {
+		for _, c := range s.Details.Causes {
+			b.WriteString("\n  - ")
+			if c.Field != "" {
+				fmt.Fprintf(&b, "%s: ", c.Field)
+			}
+			if c.Type != "" {
+				fmt.Fprintf(&b, "%s: ", c.Type)
+			}
+			b.WriteString(c.Message)
+		}
+	}
+
+	return b.String()
+}
+
+// describeAdmissionDenial returns err with the webhook name, status
+// code and causes in the message, if an admission webhook denied
+// the request, and err unchanged otherwise
+func describeAdmissionDenial(err error) error {
+	var se *k8serrors.StatusError
+	if !errors.As(err, &se) {
+		return err
+	}
+
+	match := admissionDenialRegexp.FindStringSubmatch(se.Status().Message)
+	if match == nil {
+		return err
+	}
+
+	message := strings.TrimSpace(strings.TrimPrefix(se.Status().Message[len(match[0]):], ":"))
+
+	return &admissionDenialError{StatusError: se, webhook: match[1], message: message}
+}
+
+// isAdmissionDenial returns true if an admission webhook denied the request
+func isAdmissionDenial(err error) bool {
+	var ae *admissionDenialError
+	return errors.As(err, &ae)
+}
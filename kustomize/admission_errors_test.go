@@ -0,0 +1,43 @@
+package kustomize
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDescribeAdmissionDenial(t *testing.T) {
+	denied := &k8serrors.StatusError{ErrStatus: k8smetav1.Status{
+		Status:  k8smetav1.StatusFailure,
+		Code:    403,
+		Reason:  k8smetav1.StatusReasonForbidden,
+		Message: `admission webhook "validate.kyverno.svc" denied the request: resource Deployment/default/test was blocked due to the following policies`,
+		Details: &k8smetav1.StatusDetails{Causes: []k8smetav1.StatusCause{
+			{Field: "spec.template.spec.containers[0].image", Message: "image tag latest is not allowed"},
+		}},
+	}}
+
+	err := describeAdmissionDenial(denied)
+	assert.Equal(t, true, isAdmissionDenial(err), nil)
+	assert.Equal(t, true, k8serrors.IsForbidden(err), nil)
+	assert.Equal(t,
+		"denied by admission webhook \"validate.kyverno.svc\" (403 Forbidden): resource Deployment/default/test was blocked due to the following policies\n"+
+			"  - spec.template.spec.containers[0].image: image tag latest is not allowed",
+		err.Error(), nil)
+
+	noExplanation := k8serrors.NewBadRequest(`admission webhook "example.com" denied the request without explanation`)
+	err = describeAdmissionDenial(noExplanation)
+	assert.Equal(t, `denied by admission webhook "example.com" (400 Bad Request): without explanation`, err.Error(), nil)
+
+	// other errors are returned unchanged
+	notFound := k8serrors.NewNotFound(k8smetav1.SchemeGroupVersion.WithResource("configmaps").GroupResource(), "test")
+	assert.Equal(t, error(notFound), describeAdmissionDenial(notFound), nil)
+	assert.Equal(t, false, isAdmissionDenial(notFound), nil)
+
+	other := fmt.Errorf("other error")
+	assert.Equal(t, other, describeAdmissionDenial(other), nil)
+	assert.Equal(t, nil, describeAdmissionDenial(nil), nil)
+}
@@ -0,0 +1,265 @@
+package kustomize
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// buildCacheVersion changes when cached builds become incompatible
+const buildCacheVersion = "1"
+
+// buildCache stores the results of builds by the
+// fingerprint of their inputs in a directory
+type buildCache struct {
+	path string
+}
+
+// getBuildCache returns the cache for the build_cache_path
+// provider configuration, or nil if caching is disabled
+func getBuildCache(path string) *buildCache {
+	if path == "" {
+		return nil
+	}
+
+	return &buildCache{path: path}
+}
+
+func (c *buildCache) fileName(fingerprint string) string {
+	return filepath.Join(c.path, fingerprint+".yaml")
+}
+
+// get returns the cached build for fingerprint, if any
+func (c *buildCache) get(fingerprint string) (resmap.ResMap, bool) {
+	if c == nil || fingerprint == "" {
+		return nil, false
+	}
+
+	content, err := ioutil.ReadFile(c.fileName(fingerprint))
+	if err != nil {
+		return nil, false
+	}
+
+	rf := provider.NewDefaultDepProvider().GetResourceFactory()
+	rm, err := resmap.NewFactory(rf).NewResMapFromBytes(content)
+	if err != nil {
+		log.Printf("[WARN] kustomization build cache: ignoring %q: %s", c.fileName(fingerprint), err)
+		return nil, false
+	}
+
+	return rm, true
+}
+
+// put stores the build for fingerprint, replacing the cached file
+// atomically to not expose partial builds to concurrent readers,
+// builds can contain secrets, only the user can read the cache
+func (c *buildCache) put(fingerprint string, rm resmap.ResMap) error {
+	if c == nil || fingerprint == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.path, 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(c.path, ".build-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := writeResourcesYAML(f, rm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), c.fileName(fingerprint))
+}
+
+// buildFingerprint hashes all inputs of the build of the kustomization
+// at path, the files of the kustomization and of all local bases and
+// components and the files they reference, the kustomize options
+// including the plugin_env, and the OpenAPI schema of the cluster
+//
+// Builds with inputs that can not be hashed, remote bases and
+// plugins like helm charts or KRM functions, have no fingerprint
+// and are always built. So have builds with SOPS encrypted generator
+// sources, their plain text must not be written to the cache.
+func buildFingerprint(fSys filesys.FileSystem, path string, opts *krusty.Options, kOpts []interface{}, openAPISchema []byte) (string, bool) {
+	if opts.PluginConfig.PluginRestrictions != types.PluginRestrictionsBuiltinsOnly {
+		return "", false
+	}
+
+	root, _, err := fSys.CleanedAbs(path)
+	if err != nil {
+		return "", false
+	}
+
+	files := make(map[string][]byte)
+	if !fingerprintKustomization(fSys, root.String(), files, false) {
+		return "", false
+	}
+
+	if hasSOPSEncryptedSources(fSys, getKustomizationGeneratorSources(fSys, root.String())) {
+		return "", false
+	}
+
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	options, err := json.Marshal(kOpts)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha512.New()
+	h.Write([]byte(buildCacheVersion))
+	h.Write([]byte(getModuleVersions(kustomizeAPIModule)[kustomizeAPIModule]))
+	h.Write(options)
+	h.Write(openAPISchema)
+	for _, n := range names {
+		rel, err := filepath.Rel(root.String(), n)
+		if err != nil {
+			return "", false
+		}
+
+		h.Write([]byte(rel))
+		h.Write(files[n])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// fingerprintKustomization adds the hashes of the kustomization in
+// dir and of all files it references to files, referenced directories
+// are added as kustomizations, returns false for remote bases unless
+// allowRemote, remote bases are then part of the hash by their URL
+func fingerprintKustomization(fSys filesys.FileSystem, dir string, files map[string][]byte, allowRemote bool) bool {
+	// directories are marked visited, for cyclic references
+	files[dir] = nil
+
+	var content []byte
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		p := filepath.Join(dir, n)
+		if c, err := fSys.ReadFile(p); err == nil {
+			content = c
+			files[p] = hashFileContentBytes(c)
+			break
+		}
+	}
+	if content == nil {
+		return false
+	}
+
+	var k map[string]interface{}
+	if err := yaml.Unmarshal(content, &k); err != nil {
+		return false
+	}
+
+	// bases not on disk are remote
+	for _, field := range []string{"resources", "bases", "components"} {
+		refs, _ := k[field].([]interface{})
+		for _, r := range refs {
+			s, ok := r.(string)
+			if !ok || (!allowRemote && !fSys.Exists(resolveReference(dir, s))) {
+				return false
+			}
+		}
+	}
+
+	for _, s := range collectStrings(k) {
+		candidates := []string{s}
+		// generator files and envs may be key=path
+		if i := strings.Index(s, "="); i >= 0 {
+			candidates = append(candidates, s[i+1:])
+		}
+
+		for _, c := range candidates {
+			p := resolveReference(dir, c)
+			if _, seen := files[p]; seen {
+				continue
+			}
+
+			if fSys.IsDir(p) {
+				if isKustomizationDirFS(fSys, p) && !fingerprintKustomization(fSys, p, files, allowRemote) {
+					return false
+				}
+				continue
+			}
+
+			if c, err := fSys.ReadFile(p); err == nil {
+				files[p] = hashFileContentBytes(c)
+			}
+		}
+	}
+
+	return true
+}
+
+func isKustomizationDirFS(fSys filesys.FileSystem, dir string) bool {
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		if fSys.Exists(filepath.Join(dir, n)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolveReference(dir string, ref string) string {
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref)
+	}
+
+	return filepath.Join(dir, ref)
+}
+
+// collectStrings returns all string values of the decoded YAML v
+func collectStrings(v interface{}) []string {
+	switch o := v.(type) {
+	case string:
+		if o == "" || strings.ContainsAny(o, "\n") {
+			return nil
+		}
+		return []string{o}
+	case []interface{}:
+		var out []string
+		for _, c := range o {
+			out = append(out, collectStrings(c)...)
+		}
+		return out
+	case map[string]interface{}:
+		var out []string
+		for _, c := range o {
+			out = append(out, collectStrings(c)...)
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func hashFileContentBytes(content []byte) []byte {
+	h := sha512.Sum512(content)
+	return h[:]
+}
@@ -0,0 +1,191 @@
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for n, c := range files {
+		p := filepath.Join(dir, n)
+		assert.Equal(t, nil, os.MkdirAll(filepath.Dir(p), 0755), nil)
+		assert.Equal(t, nil, ioutil.WriteFile(p, []byte(c), 0644), nil)
+	}
+}
+
+const testCacheConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  key: value
+`
+
+func testFingerprint(t *testing.T, path string, opts *krusty.Options) string {
+	fp, ok := buildFingerprint(filesys.MakeFsOnDisk(), path, opts, nil, nil)
+	assert.Equal(t, true, ok, nil)
+	assert.NotEqual(t, "", fp, nil)
+
+	return fp
+}
+
+func TestBuildFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml":    "resources:\n- cm.yaml\n",
+		"base/cm.yaml":               testCacheConfigMap,
+		"overlay/kustomization.yaml": "resources:\n- ../base\nconfigMapGenerator:\n- name: gen\n  files:\n  - key=file.txt\n",
+		"overlay/file.txt":           "a",
+	})
+	overlay := filepath.Join(dir, "overlay")
+	opts := krusty.MakeDefaultOptions()
+
+	initial := testFingerprint(t, overlay, opts)
+	assert.Equal(t, initial, testFingerprint(t, overlay, opts), nil)
+
+	// files of bases
+	writeTestFiles(t, dir, map[string]string{"base/cm.yaml": strings.Replace(testCacheConfigMap, "value", "changed", 1)})
+	changedBase := testFingerprint(t, overlay, opts)
+	assert.NotEqual(t, initial, changedBase, nil)
+
+	// files of generators
+	writeTestFiles(t, dir, map[string]string{"overlay/file.txt": "b"})
+	changedGenerator := testFingerprint(t, overlay, opts)
+	assert.NotEqual(t, changedBase, changedGenerator, nil)
+
+	// unrelated files
+	writeTestFiles(t, dir, map[string]string{"overlay/README.md": "docs"})
+	assert.Equal(t, changedGenerator, testFingerprint(t, overlay, opts), nil)
+}
+
+func TestBuildFingerprintRelocated(t *testing.T) {
+	files := map[string]string{
+		"kustomization.yaml": "resources:\n- cm.yaml\n",
+		"cm.yaml":            testCacheConfigMap,
+	}
+
+	a := t.TempDir()
+	b := t.TempDir()
+	writeTestFiles(t, a, files)
+	writeTestFiles(t, b, files)
+
+	opts := krusty.MakeDefaultOptions()
+	assert.Equal(t, testFingerprint(t, a, opts), testFingerprint(t, b, opts), nil)
+}
+
+func TestBuildFingerprintNone(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"remote/kustomization.yaml": "resources:\n- github.com/kbst/example?ref=v1\n",
+		"local/kustomization.yaml":  "resources:\n- cm.yaml\n",
+		"local/cm.yaml":             testCacheConfigMap,
+	})
+
+	_, ok := buildFingerprint(filesys.MakeFsOnDisk(), filepath.Join(dir, "remote"), krusty.MakeDefaultOptions(), nil, nil)
+	assert.Equal(t, false, ok, nil)
+
+	plugins := krusty.MakeDefaultOptions()
+	plugins.PluginConfig = types.EnabledPluginConfig(types.BploUseStaticallyLinked)
+	_, ok = buildFingerprint(filesys.MakeFsOnDisk(), filepath.Join(dir, "local"), plugins, nil, nil)
+	assert.Equal(t, false, ok, nil)
+
+	// decrypted generator sources are not cached
+	writeTestFiles(t, dir, map[string]string{
+		"sops/kustomization.yaml": "secretGenerator:\n- name: test\n  envs:\n  - secret.env\n",
+		"sops/secret.env":         testSOPSEnv,
+	})
+	_, ok = buildFingerprint(filesys.MakeFsOnDisk(), filepath.Join(dir, "sops"), krusty.MakeDefaultOptions(), nil, nil)
+	assert.Equal(t, false, ok, nil)
+}
+
+func TestBuildFingerprintOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"kustomization.yaml": "resources:\n- cm.yaml\n",
+		"cm.yaml":            testCacheConfigMap,
+	})
+
+	fSys := filesys.MakeFsOnDisk()
+	opts := krusty.MakeDefaultOptions()
+
+	a, _ := buildFingerprint(fSys, dir, opts, nil, nil)
+	b, _ := buildFingerprint(fSys, dir, opts, []interface{}{map[string]interface{}{"load_restrictor": "none"}}, nil)
+	c, _ := buildFingerprint(fSys, dir, opts, nil, []byte("{}"))
+	e, _ := buildFingerprint(fSys, dir, opts, []interface{}{map[string]interface{}{"plugin_env": map[string]interface{}{"KEY": "value"}}}, nil)
+	assert.NotEqual(t, a, b, nil)
+	assert.NotEqual(t, a, c, nil)
+	assert.NotEqual(t, a, e, nil)
+}
+
+func TestKustomizationBuildCache(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"kustomization.yaml": "resources:\n- cm.yaml\n",
+		"cm.yaml":            testCacheConfigMap,
+	})
+	config := &Config{
+		BuildLock:  newBuildLock(),
+		BuildCache: getBuildCache(filepath.Join(dir, ".cache")),
+	}
+
+	build := func() *schema.ResourceData {
+		d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+			"path": dir,
+		})
+		diags := kustomizationBuild(context.TODO(), d, config)
+		assert.Equal(t, false, diags.HasError(), nil)
+
+		return d
+	}
+
+	initial := build()
+	fingerprint := initial.Get("fingerprint").(string)
+	assert.NotEqual(t, "", fingerprint, nil)
+
+	cached, err := ioutil.ReadFile(config.BuildCache.fileName(fingerprint))
+	assert.Equal(t, nil, err, nil)
+	assert.Contains(t, string(cached), "key: value", nil)
+
+	// only the user can read cached builds
+	info, err := os.Stat(config.BuildCache.path)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm(), nil)
+	info, err = os.Stat(config.BuildCache.fileName(fingerprint))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), nil)
+
+	// unchanged inputs are read from the cache
+	modified := strings.Replace(testCacheConfigMap, "value", "from-cache", 1)
+	assert.Equal(t, nil, ioutil.WriteFile(config.BuildCache.fileName(fingerprint), []byte(modified), 0644), nil)
+
+	d := build()
+	assert.Equal(t, fingerprint, d.Get("fingerprint"), nil)
+	assert.Contains(t, d.Get("manifests").(map[string]interface{})["_/ConfigMap/_/test"], "from-cache", nil)
+	assert.Equal(t, initial.Get("ids").(*schema.Set).List(), d.Get("ids").(*schema.Set).List(), nil)
+
+	// changed inputs are built
+	writeTestFiles(t, dir, map[string]string{"cm.yaml": strings.Replace(testCacheConfigMap, "value", "changed", 1)})
+
+	d = build()
+	assert.NotEqual(t, fingerprint, d.Get("fingerprint"), nil)
+	assert.Contains(t, d.Get("manifests").(map[string]interface{})["_/ConfigMap/_/test"], "changed", nil)
+}
+
+func TestKustomizationBuildCacheDisabled(t *testing.T) {
+	var c *buildCache
+
+	_, ok := c.get("fingerprint")
+	assert.Equal(t, false, ok, nil)
+	assert.Equal(t, nil, c.put("fingerprint", nil), nil)
+	assert.Equal(t, (*buildCache)(nil), getBuildCache(""), nil)
+}
@@ -0,0 +1,39 @@
+package kustomize
+
+import (
+	"fmt"
+)
+
+// buildLimits fail builds that are too large to hold in memory
+// and in state, zero values are unlimited
+type buildLimits struct {
+	maxResources     int
+	maxManifestBytes int
+}
+
+func getBuildLimits(maxResources int, maxManifestBytes int) buildLimits {
+	return buildLimits{
+		maxResources:     maxResources,
+		maxManifestBytes: maxManifestBytes,
+	}
+}
+
+// checkResources fails if a build has more than the maximum number
+// of resources, checked before serializing any of them
+func (l buildLimits) checkResources(n int) error {
+	if l.maxResources > 0 && n > l.maxResources {
+		return fmt.Errorf("build has %d resources, more than max_build_resources of %d, split the kustomization or raise the limit", n, l.maxResources)
+	}
+
+	return nil
+}
+
+// checkManifestBytes fails if the manifests serialized so far are larger
+// than the maximum, checked after every resource to fail early
+func (l buildLimits) checkManifestBytes(n int) error {
+	if l.maxManifestBytes > 0 && n > l.maxManifestBytes {
+		return fmt.Errorf("manifests of the build exceed max_build_manifest_bytes of %d bytes, split the kustomization or raise the limit", l.maxManifestBytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package kustomize
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/yaml"
+)
+
+// buildLock lets builds run in parallel, except builds changing global
+// state of kustomize, see https://github.com/kubernetes-sigs/kustomize/issues/3659
+//
+// The OpenAPI schema of kustomize is global and parsed on first use.
+// Builds using the built-in schema only read it and share the lock,
+// builds setting a custom schema hold the lock exclusively and restore
+// the built-in schema before releasing it.
+type buildLock struct {
+	mu sync.RWMutex
+
+	// the built-in schema is parsed, before builds share it
+	parsed bool
+}
+
+func newBuildLock() *buildLock {
+	return &buildLock{}
+}
+
+// kustomizeBuildLock is the lock of all builds, the global state of
+// kustomize is shared by all provider configurations and functions
+var kustomizeBuildLock = newBuildLock()
+
+// parseBuiltinSchema resets a custom schema and parses
+// the built-in schema, must hold the lock exclusively
+func (l *buildLock) parseBuiltinSchema() {
+	resetCustomOpenAPISchema()
+	openapi.Schema()
+	l.parsed = true
+}
+
+// lock acquires the lock for a build, exclusive for builds changing
+// global state, and returns the function releasing it
+func (l *buildLock) lock(ctx context.Context, exclusive bool) (func(), error) {
+	if exclusive {
+		l.mu.Lock()
+
+		// the build may have waited for the lock, skip it if cancelled
+		if err := ctx.Err(); err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+
+		return func() {
+			l.parseBuiltinSchema()
+			l.mu.Unlock()
+		}, nil
+	}
+
+	l.mu.RLock()
+	for !l.parsed {
+		l.mu.RUnlock()
+		l.mu.Lock()
+		if !l.parsed {
+			l.parseBuiltinSchema()
+		}
+		l.mu.Unlock()
+		l.mu.RLock()
+	}
+
+	if err := ctx.Err(); err != nil {
+		l.mu.RUnlock()
+		return nil, err
+	}
+
+	return l.mu.RUnlock, nil
+}
+
+// isExclusiveBuild returns true if the build of the kustomization at
+// path changes global state of kustomize, because it or any of its
+// local bases and components sets a custom OpenAPI schema, or it
+// inflates helm charts into the shared chart home
+func isExclusiveBuild(fSys filesys.FileSystem, path string, opts *krusty.Options) bool {
+	if opts.PluginConfig.HelmConfig.Enabled {
+		return true
+	}
+
+	visited := make(map[string]bool)
+
+	var walk func(dir string) bool
+	walk = func(dir string) bool {
+		if visited[dir] {
+			return false
+		}
+		visited[dir] = true
+
+		for _, n := range konfig.RecognizedKustomizationFileNames() {
+			content, err := fSys.ReadFile(filepath.Join(dir, n))
+			if err != nil {
+				continue
+			}
+
+			var k types.Kustomization
+			if err := yaml.Unmarshal(content, &k); err != nil {
+				// left for kustomize to report, build exclusively to be safe
+				return true
+			}
+
+			if len(k.OpenAPI) > 0 {
+				return true
+			}
+
+			bases := append(append(k.Resources, k.Components...), k.Bases...)
+			for _, b := range bases {
+				p := b
+				if !filepath.IsAbs(p) {
+					p = filepath.Join(dir, p)
+				}
+
+				if fSys.IsDir(p) && walk(p) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		return false
+	}
+
+	return walk(path)
+}
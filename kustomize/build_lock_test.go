@@ -0,0 +1,84 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+)
+
+func TestBuildLockShared(t *testing.T) {
+	l := newBuildLock()
+
+	unlock1, err := l.lock(context.TODO(), false)
+	assert.Equal(t, nil, err, nil)
+
+	// shared builds do not wait for each other
+	done := make(chan bool)
+	go func() {
+		unlock2, err := l.lock(context.TODO(), false)
+		assert.Equal(t, nil, err, nil)
+		unlock2()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("shared lock blocked")
+	}
+
+	unlock1()
+}
+
+func TestBuildLockExclusive(t *testing.T) {
+	l := newBuildLock()
+
+	unlock, err := l.lock(context.TODO(), true)
+	assert.Equal(t, nil, err, nil)
+
+	err = openapi.SetSchema(nil, []byte(`{"definitions": {}}`), true)
+	assert.Equal(t, nil, err, nil)
+	openapi.Schema()
+	assert.Equal(t, customOpenAPISchemaVersion, openapi.GetSchemaVersion(), nil)
+
+	unlock()
+
+	// the built-in schema is restored
+	assert.NotEqual(t, customOpenAPISchemaVersion, openapi.GetSchemaVersion(), nil)
+}
+
+func TestBuildLockCancelled(t *testing.T) {
+	l := newBuildLock()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	_, err := l.lock(ctx, true)
+	assert.Equal(t, context.Canceled, err, nil)
+
+	_, err = l.lock(ctx, false)
+	assert.Equal(t, context.Canceled, err, nil)
+}
+
+func TestIsExclusiveBuild(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("/base/kustomization.yaml", []byte("resources:\n- cm.yaml\n"))
+	fSys.WriteFile("/base/cm.yaml", []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"))
+	fSys.WriteFile("/openapi/kustomization.yaml", []byte("openapi:\n  path: schema.json\n"))
+	fSys.WriteFile("/shared/kustomization.yaml", []byte("resources:\n- ../base\n- https://github.com/example/example\n"))
+	fSys.WriteFile("/exclusive/kustomization.yaml", []byte("resources:\n- ../base\ncomponents:\n- ../openapi\n"))
+
+	opts := krusty.MakeDefaultOptions()
+	assert.Equal(t, false, isExclusiveBuild(fSys, "/base", opts), nil)
+	assert.Equal(t, false, isExclusiveBuild(fSys, "/shared", opts), nil)
+	assert.Equal(t, true, isExclusiveBuild(fSys, "/openapi", opts), nil)
+	assert.Equal(t, true, isExclusiveBuild(fSys, "/exclusive", opts), nil)
+
+	opts.PluginConfig.HelmConfig.Enabled = true
+	assert.Equal(t, true, isExclusiveBuild(fSys, "/base", opts), nil)
+}
@@ -0,0 +1,432 @@
+package kustomize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+)
+
+// names of the virtual files with the converted CRDs
+const crdFileNameFormat = "terraform-provider-kustomization-crds-%d.json"
+
+// crdSchema is the OpenAPI v3 schema of one served version of a CRD
+type crdSchema struct {
+	gvk        resid.Gvk
+	plural     string
+	namespaced bool
+	schema     map[string]interface{}
+}
+
+// definitionName returns the name of the OpenAPI definition,
+// e.g. "com.example.v1.Backend" for example.com/v1 Backend
+func (c crdSchema) definitionName() string {
+	parts := strings.Split(c.gvk.Group, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+
+	return fmt.Sprintf("%s.%s.%s", strings.Join(parts, "."), c.gvk.Version, c.gvk.Kind)
+}
+
+func getMap(m map[string]interface{}, path ...string) map[string]interface{} {
+	for _, p := range path {
+		m, _ = m[p].(map[string]interface{})
+	}
+
+	return m
+}
+
+// parseCRDs returns the schemas of all CRDs in content, both
+// apiextensions.k8s.io/v1 and v1beta1, other documents are ignored
+func parseCRDs(content []byte) ([]crdSchema, error) {
+	var crds []crdSchema
+
+	d := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		var doc map[string]interface{}
+		err := d.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		apiVersion, _ := doc["apiVersion"].(string)
+		if doc["kind"] != "CustomResourceDefinition" || !strings.HasPrefix(apiVersion, "apiextensions.k8s.io/") {
+			continue
+		}
+
+		s := getMap(doc, "spec")
+		group, _ := s["group"].(string)
+		kind, _ := getMap(s, "names")["kind"].(string)
+		plural, _ := getMap(s, "names")["plural"].(string)
+		namespaced := s["scope"] != "Cluster"
+
+		// v1beta1 CRDs can have one schema for all versions
+		commonSchema := getMap(s, "validation", "openAPIV3Schema")
+
+		versions, _ := s["versions"].([]interface{})
+		if v, ok := s["version"].(string); ok && len(versions) == 0 {
+			versions = []interface{}{map[string]interface{}{"name": v, "served": true}}
+		}
+
+		for _, v := range versions {
+			version, _ := v.(map[string]interface{})
+			if served, ok := version["served"].(bool); ok && !served {
+				continue
+			}
+
+			schema := getMap(version, "schema", "openAPIV3Schema")
+			if schema == nil {
+				schema = commonSchema
+			}
+			if schema == nil {
+				continue
+			}
+
+			name, _ := version["name"].(string)
+			crds = append(crds, crdSchema{
+				gvk:        resid.Gvk{Group: group, Version: name, Kind: kind},
+				plural:     plural,
+				namespaced: namespaced,
+				schema:     schema,
+			})
+		}
+	}
+
+	return crds, nil
+}
+
+// kustomizeDefinitions converts the schemas to the OpenAPI definitions
+// kustomize expects for crds, e.g. to find name references
+func kustomizeDefinitions(crds []crdSchema) ([]byte, error) {
+	definitions := make(map[string]interface{})
+
+	for _, c := range crds {
+		schema := make(map[string]interface{})
+		for k, v := range c.schema {
+			schema[k] = v
+		}
+
+		// kustomize ignores definitions that do not look like a resource
+		properties := make(map[string]interface{})
+		for k, v := range getMap(c.schema, "properties") {
+			properties[k] = v
+		}
+		for _, p := range []string{"apiVersion", "kind"} {
+			if _, ok := properties[p]; !ok {
+				properties[p] = map[string]interface{}{"type": "string"}
+			}
+		}
+		if _, ok := properties["metadata"]; !ok {
+			properties["metadata"] = map[string]interface{}{"type": "object"}
+		}
+		schema["properties"] = properties
+
+		definitions[c.definitionName()] = map[string]interface{}{"Schema": schema}
+	}
+
+	return json.Marshal(definitions)
+}
+
+// openAPISchema converts the schemas to an OpenAPI v2 document,
+// to merge patches of the custom resources by their list keys
+func openAPISchema(crds []crdSchema) ([]byte, error) {
+	definitions := make(map[string]interface{})
+	paths := make(map[string]interface{})
+
+	for _, c := range crds {
+		gvk := []interface{}{
+			map[string]interface{}{"group": c.gvk.Group, "version": c.gvk.Version, "kind": c.gvk.Kind},
+		}
+
+		schema := make(map[string]interface{})
+		for k, v := range c.schema {
+			schema[k] = v
+		}
+		schema["x-kubernetes-group-version-kind"] = gvk
+		definitions[c.definitionName()] = schema
+
+		// kustomize determines the scope from the API paths
+		path := fmt.Sprintf("/apis/%s/%s/%s/{name}", c.gvk.Group, c.gvk.Version, c.plural)
+		if c.namespaced {
+			path = fmt.Sprintf("/apis/%s/%s/namespaces/{namespace}/%s/{name}", c.gvk.Group, c.gvk.Version, c.plural)
+		}
+		paths[path] = map[string]interface{}{
+			"get": map[string]interface{}{
+				"x-kubernetes-group-version-kind": gvk[0],
+			},
+		}
+	}
+
+	s, err := json.Marshal(map[string]interface{}{
+		"swagger":     "2.0",
+		"info":        map[string]interface{}{"title": "CustomResourceDefinitions", "version": "v1"},
+		"paths":       paths,
+		"definitions": definitions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addListMapPatchStrategy(s)
+}
+
+// loadCRDs reads the CRDs of k, and replaces the paths of files
+// with CRDs by virtual files, with the CRDs converted for kustomize,
+// files without CRDs are expected to be converted already
+func loadCRDs(fs filesys.FileSystem, k *types.Kustomization) (map[string][]byte, []crdSchema, error) {
+	files := make(map[string][]byte)
+	var crds []crdSchema
+
+	for i, p := range k.Crds {
+		content, err := fs.ReadFile(p)
+		if err != nil {
+			// left for kustomize to report
+			continue
+		}
+
+		c, err := parseCRDs(content)
+		if err != nil || len(c) == 0 {
+			continue
+		}
+
+		name := fmt.Sprintf(crdFileNameFormat, i)
+		files[name], err = kustomizeDefinitions(c)
+		if err != nil {
+			return nil, nil, fmt.Errorf("converting CRDs in %q failed: %s", p, err)
+		}
+		k.Crds[i] = name
+
+		crds = append(crds, c...)
+	}
+
+	return files, crds, nil
+}
+
+// addCRDOpenAPISchema adds the schemas to the OpenAPI schema
+// kustomize uses, until the next resetCRDOpenAPISchema
+func addCRDOpenAPISchema(crds []crdSchema) error {
+	if len(crds) == 0 {
+		return nil
+	}
+
+	// a custom schema of a previous build would reset the CRD schemas
+	resetCustomOpenAPISchema()
+
+	s, err := openAPISchema(crds)
+	if err != nil {
+		return err
+	}
+
+	return openapi.AddSchema(s)
+}
+
+func resetCRDOpenAPISchema(crds []crdSchema) {
+	if len(crds) > 0 {
+		openapi.ResetOpenAPI()
+	}
+}
+
+// validateSchema validates v against the structural schema s, for
+// the types, required fields and enums of objects, arrays and values,
+// references are resolved against the OpenAPI definitions defs
+func validateSchema(path string, v interface{}, s map[string]interface{}, defs map[string]interface{}) (errs []string) {
+	quantity := false
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		// quantities are strings in the schema but numbers are accepted
+		quantity = name == "io.k8s.apimachinery.pkg.api.resource.Quantity"
+		s, _ = defs[name].(map[string]interface{})
+	}
+
+	if s == nil || v == nil {
+		return nil
+	}
+
+	// OpenAPI v2 schemas of the API server use a format instead
+	if s["x-kubernetes-int-or-string"] == true || s["format"] == "int-or-string" || quantity {
+		switch v.(type) {
+		case string, int, int64, float64:
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: must be an integer or string", path)}
+	}
+
+	switch s["type"] {
+	case "object":
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: must be an object", path)}
+		}
+
+		required, _ := s["required"].([]interface{})
+		for _, r := range required {
+			if _, ok := o[r.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s: is required", path, r))
+			}
+		}
+
+		properties := getMap(s, "properties")
+		additional := getMap(s, "additionalProperties")
+		for k, c := range o {
+			if p, ok := properties[k].(map[string]interface{}); ok {
+				errs = append(errs, validateSchema(path+"."+k, c, p, defs)...)
+			} else if additional != nil {
+				errs = append(errs, validateSchema(path+"."+k, c, additional, defs)...)
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: must be an array", path)}
+		}
+
+		items := getMap(s, "items")
+		for i, c := range a {
+			errs = append(errs, validateSchema(fmt.Sprintf("%s[%d]", path, i), c, items, defs)...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%s: must be a string", path))
+		}
+	case "integer":
+		switch n := v.(type) {
+		case int, int64:
+		case float64:
+			if n != float64(int64(n)) {
+				errs = append(errs, fmt.Sprintf("%s: must be an integer", path))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s: must be an integer", path))
+		}
+	case "number":
+		switch v.(type) {
+		case int, int64, float64:
+		default:
+			errs = append(errs, fmt.Sprintf("%s: must be a number", path))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%s: must be a boolean", path))
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				return errs
+			}
+		}
+		errs = append(errs, fmt.Sprintf("%s: must be one of %v", path, enum))
+	}
+
+	return errs
+}
+
+// validateCustomResources validates the custom resources
+// in rm against the schema of their CRD and version
+func validateCustomResources(rm resmap.ResMap, crds []crdSchema) error {
+	schemas := make(map[resid.Gvk]map[string]interface{})
+	for _, c := range crds {
+		schemas[c.gvk] = c.schema
+	}
+
+	for _, r := range rm.Resources() {
+		s, ok := schemas[r.GetGvk()]
+		if !ok {
+			continue
+		}
+
+		obj, err := r.Map()
+		if err != nil {
+			return err
+		}
+
+		errs := validateSchema(r.GetKind(), obj, s, nil)
+		if len(errs) == 0 {
+			continue
+		}
+		sort.Strings(errs)
+
+		id := r.CurId()
+		kr := &kManifestId{
+			group:     id.Group,
+			kind:      id.Kind,
+			namespace: id.Namespace,
+			name:      id.Name,
+		}
+
+		return fmt.Errorf("%q is invalid: %s", kr.string(), strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// crdFileSystem serves the CRDs converted for kustomize
+// as virtual files next to the root kustomization
+type crdFileSystem struct {
+	filesys.FileSystem
+	root  filesys.ConfirmedDir
+	files map[string][]byte
+}
+
+func makeCRDFS(fs filesys.FileSystem, path string, files map[string][]byte) (filesys.FileSystem, error) {
+	if len(files) == 0 {
+		return fs, nil
+	}
+
+	root, _, err := fs.CleanedAbs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return crdFileSystem{
+		FileSystem: fs,
+		root:       root,
+		files:      files,
+	}, nil
+}
+
+func (cfs crdFileSystem) file(name string) (string, bool) {
+	if filepath.Dir(name) != string(cfs.root) {
+		return "", false
+	}
+
+	n := filepath.Base(name)
+	_, ok := cfs.files[n]
+	return n, ok
+}
+
+func (cfs crdFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	if n, ok := cfs.file(path); ok {
+		return cfs.root, n, nil
+	}
+
+	return cfs.FileSystem.CleanedAbs(path)
+}
+
+func (cfs crdFileSystem) Exists(name string) bool {
+	_, ok := cfs.file(name)
+	return ok || cfs.FileSystem.Exists(name)
+}
+
+func (cfs crdFileSystem) ReadFile(name string) ([]byte, error) {
+	if n, ok := cfs.file(name); ok {
+		return cfs.files[n], nil
+	}
+
+	return cfs.FileSystem.ReadFile(name)
+}
@@ -0,0 +1,226 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+)
+
+const testBackendCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: backends.example.com
+spec:
+  group: example.com
+  names:
+    kind: Backend
+    plural: backends
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required:
+            - routes
+            properties:
+              routes:
+                type: array
+                x-kubernetes-list-type: map
+                x-kubernetes-list-map-keys:
+                - name
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                    weight:
+                      type: integer
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gateways.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gateway
+    plural: gateways
+  scope: Cluster
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+  - name: v1alpha1
+    served: false
+    storage: false
+    schema:
+      openAPIV3Schema:
+        type: object
+`
+
+func TestParseCRDs(t *testing.T) {
+	crds, err := parseCRDs([]byte(testBackendCRD))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 2, len(crds), nil)
+
+	assert.Equal(t, resid.Gvk{Group: "example.com", Version: "v1", Kind: "Backend"}, crds[0].gvk, nil)
+	assert.Equal(t, true, crds[0].namespaced, nil)
+	assert.Equal(t, "com.example.v1.Backend", crds[0].definitionName(), nil)
+
+	// versions that are not served are ignored
+	assert.Equal(t, resid.Gvk{Group: "example.com", Version: "v1", Kind: "Gateway"}, crds[1].gvk, nil)
+	assert.Equal(t, false, crds[1].namespaced, nil)
+
+	crds, err = parseCRDs([]byte(`{"com.example.v1.Backend": {"Schema": {}}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(crds), nil)
+}
+
+func TestValidateSchema(t *testing.T) {
+	crds, _ := parseCRDs([]byte(testBackendCRD))
+	s := crds[0].schema
+
+	valid := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"routes": []interface{}{map[string]interface{}{"name": "a", "weight": 50}},
+		},
+	}
+	assert.Equal(t, []string(nil), validateSchema("Backend", valid, s, nil), nil)
+
+	invalid := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"routes": []interface{}{map[string]interface{}{"name": "a", "weight": "50"}},
+		},
+	}
+	assert.Equal(t, []string{"Backend.spec.routes[0].weight: must be an integer"}, validateSchema("Backend", invalid, s, nil), nil)
+
+	missing := map[string]interface{}{"spec": map[string]interface{}{}}
+	assert.Equal(t, []string{"Backend.spec.routes: is required"}, validateSchema("Backend", missing, s, nil), nil)
+}
+
+func testCRDKustomization(t *testing.T, dir string, resources string) (filesys.FileSystem, []crdSchema) {
+	err := ioutil.WriteFile(filepath.Join(dir, "crd.yaml"), []byte(testBackendCRD), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "resources.yaml"), []byte(resources), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "patch.yaml"), []byte(`apiVersion: example.com/v1
+kind: Backend
+metadata:
+  name: test
+spec:
+  routes:
+  - name: b
+    weight: 100
+`), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	k := types.Kustomization{
+		Namespace:             "test-crds",
+		Resources:             []string{"resources.yaml"},
+		PatchesStrategicMerge: []types.PatchStrategicMerge{"patch.yaml"},
+		Crds:                  []string{filepath.Join(dir, "crd.yaml")},
+	}
+
+	files, crds, err := loadCRDs(filesys.MakeFsOnDisk(), &k)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{"terraform-provider-kustomization-crds-0.json"}, k.Crds, nil)
+
+	data, err := yaml.Marshal(k)
+	assert.Equal(t, nil, err, nil)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), data, 0644)
+	assert.Equal(t, nil, err, nil)
+
+	fSys, err := makeCRDFS(filesys.MakeFsOnDisk(), dir, files)
+	assert.Equal(t, nil, err, nil)
+
+	return fSys, crds
+}
+
+func TestCRDSchemaMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crds-*")
+	assert.Equal(t, nil, err, nil)
+	defer os.RemoveAll(dir)
+
+	fSys, crds := testCRDKustomization(t, dir, `apiVersion: example.com/v1
+kind: Backend
+metadata:
+  name: test
+spec:
+  routes:
+  - name: a
+    weight: 50
+  - name: b
+    weight: 50
+---
+apiVersion: example.com/v1
+kind: Gateway
+metadata:
+  name: test
+`)
+
+	err = addCRDOpenAPISchema(crds)
+	assert.Equal(t, nil, err, nil)
+	defer resetCRDOpenAPISchema(crds)
+
+	rm, err := runKustomizeBuild(fSys, dir, testKustomizeOptions(t))
+	assert.Equal(t, nil, err, nil)
+
+	ids, _, err := flattenKustomizationIDs(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []string{"example.com/Backend/test-crds/test", "example.com/Gateway/_/test"}, ids, nil)
+
+	routes, err := rm.Resources()[0].GetFieldValue("spec.routes")
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"name": "a", "weight": 50},
+		map[string]interface{}{"name": "b", "weight": 100},
+	}, routes, nil)
+
+	err = validateCustomResources(rm, crds)
+	assert.Equal(t, nil, err, nil)
+}
+
+func TestCRDSchemaValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crds-*")
+	assert.Equal(t, nil, err, nil)
+	defer os.RemoveAll(dir)
+
+	fSys, crds := testCRDKustomization(t, dir, `apiVersion: example.com/v1
+kind: Backend
+metadata:
+  name: test
+spec:
+  routes:
+  - name: a
+    weight: fifty
+`)
+
+	err = addCRDOpenAPISchema(crds)
+	assert.Equal(t, nil, err, nil)
+	defer resetCRDOpenAPISchema(crds)
+
+	rm, err := runKustomizeBuild(fSys, dir, testKustomizeOptions(t))
+	assert.Equal(t, nil, err, nil)
+
+	err = validateCustomResources(rm, crds)
+	assert.EqualError(t, err, "\"example.com/Backend/test-crds/test\" is invalid: Backend.spec.routes[1].weight: must be an integer", nil)
+}
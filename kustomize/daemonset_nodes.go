@@ -0,0 +1,118 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	k8scorev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// waitDaemonSetRefresh waits until the rollout of the daemonset is done,
+// without waiting for the pods on unavailable nodes, if enabled
+func waitDaemonSetRefresh(km *kManifest) (interface{}, string, error) {
+	status := daemonSetRolloutStatus
+	if km.skipUnavailableNodes {
+		status = func(u *k8sunstructured.Unstructured) (rolloutStatus, error) {
+			skipped, err := km.unavailableNodePods(u)
+			if err != nil {
+				// e.g. without permission to list nodes
+				log.Printf("[WARN] %q: waiting for the pods on all nodes: %s", km.id().string(), err)
+				skipped = 0
+			}
+
+			return daemonSetRolloutStatusSkipping(u, skipped)
+		}
+	}
+
+	return waitRolloutRefresh(status)(km)
+}
+
+// unavailableNode returns true for nodes that are cordoned or not
+// ready, daemonset pods are scheduled on them, but may never start
+func unavailableNode(u k8sunstructured.Unstructured) (bool, error) {
+	if unschedulable, _, _ := k8sunstructured.NestedBool(u.Object, "spec", "unschedulable"); unschedulable {
+		return true, nil
+	}
+
+	ready, err := nodeReady(u)
+	return !ready, err
+}
+
+// updatedPodReady returns true if the pod of the daemonset
+// of generation is updated and ready
+func updatedPodReady(u k8sunstructured.Unstructured, generation int64) (bool, error) {
+	if u.GetLabels()["pod-template-generation"] != fmt.Sprintf("%d", generation) {
+		return false, nil
+	}
+
+	var pod k8scorev1.Pod
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &pod); err != nil {
+		return false, err
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == k8scorev1.PodReady {
+			return c.Status == k8scorev1.ConditionTrue, nil
+		}
+	}
+
+	return false, nil
+}
+
+// unavailableNodePods returns the number of pods of the daemonset u on
+// unavailable nodes that are not updated or not ready
+func (km *kManifest) unavailableNodePods(u *k8sunstructured.Unstructured) (int32, error) {
+	nodes, err := km.client.Resource(nodesGVR).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	unavailable := make(map[string]bool)
+	for _, n := range nodes.Items {
+		ok, err := unavailableNode(n)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			unavailable[n.GetName()] = true
+		}
+	}
+	if len(unavailable) == 0 {
+		return 0, nil
+	}
+
+	selector, err := podSelector(u)
+	if err != nil {
+		return 0, err
+	}
+
+	pods, err := km.client.
+		Resource(podsGVR).
+		Namespace(u.GetNamespace()).
+		List(context.TODO(), k8smetav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return 0, err
+	}
+
+	var skipped int32
+	for _, p := range pods.Items {
+		node, _, _ := k8sunstructured.NestedString(p.Object, "spec", "nodeName")
+		if !isOwnedBy(p, u) || !unavailable[node] {
+			continue
+		}
+
+		ready, err := updatedPodReady(p, u.GetGeneration())
+		if err != nil {
+			return 0, err
+		}
+		if !ready {
+			skipped++
+		}
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,96 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const testDaemonSet = `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: test
+  namespace: default
+  uid: daemonset-uid
+  generation: 2
+spec:
+  selector:
+    matchLabels: {app: test}
+  updateStrategy: {type: RollingUpdate}
+status: {observedGeneration: 2, desiredNumberScheduled: 4, updatedNumberScheduled: 3, numberAvailable: 2}
+`
+
+func testNode(name string, spec string, ready string) string {
+	return `
+apiVersion: v1
+kind: Node
+metadata:
+  name: ` + name + `
+spec: ` + spec + `
+status:
+  conditions:
+  - {type: Ready, status: "` + ready + `"}
+`
+}
+
+func testDaemonSetPod(name string, node string, generation string, ready string) string {
+	return `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: ` + name + `
+  namespace: default
+  labels: {app: test, pod-template-generation: "` + generation + `"}
+  ownerReferences:
+  - {apiVersion: apps/v1, kind: DaemonSet, name: test, uid: daemonset-uid}
+spec: {nodeName: ` + node + `}
+status:
+  conditions:
+  - {type: Ready, status: "` + ready + `"}
+`
+}
+
+func TestUnavailableNodePods(t *testing.T) {
+	objects := []string{
+		testNode("ready", "{}", "True"),
+		testNode("cordoned", "{unschedulable: true}", "True"),
+		testNode("not-ready", "{}", "False"),
+		testNode("not-ready-updated", "{}", "False"),
+		testDaemonSetPod("test-ready", "ready", "2", "True"),
+		testDaemonSetPod("test-cordoned", "cordoned", "1", "True"),
+		testDaemonSetPod("test-not-ready", "not-ready", "2", "False"),
+		testDaemonSetPod("test-not-ready-updated", "not-ready-updated", "2", "True"),
+	}
+
+	var objs []k8sruntime.Object
+	for _, o := range objects {
+		objs = append(objs, testRolloutObject(t, o))
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[k8sschema.GroupVersionResource]string{
+			podsGVR:  "PodList",
+			nodesGVR: "NodeList",
+		},
+		objs...,
+	)
+	km := newKManifest(nil, client)
+
+	daemonset := testRolloutObject(t, testDaemonSet)
+	skipped, err := km.unavailableNodePods(daemonset)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, int32(2), skipped, nil)
+
+	status, err := daemonSetRolloutStatusSkipping(daemonset, skipped)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, rolloutStatus{done: true}, status, nil)
+
+	status, err = daemonSetRolloutStatus(daemonset)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, rolloutStatus{progress: "3 out of 4 new pods have been updated"}, status, nil)
+}
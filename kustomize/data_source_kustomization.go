@@ -1,58 +1,29 @@
 package kustomize
 
 import (
-	"crypto/sha512"
-	"encoding/hex"
 	"fmt"
 	"hash/crc32"
 	"log"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/kbst/terraform-provider-kustomize/manifest"
 	"sigs.k8s.io/kustomize/api/krusty"
 	"sigs.k8s.io/kustomize/api/resmap"
 	"sigs.k8s.io/kustomize/api/types"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
 )
 
-func getIDFromResources(rm resmap.ResMap) (s string, err error) {
-	h := sha512.New()
-
-	yaml, err := rm.AsYaml()
-	if err != nil {
-		return "", fmt.Errorf("ResMap AsYaml failed: %s", err)
-	}
-	h.Write(yaml)
-
-	s = hex.EncodeToString(h.Sum(nil))
-
-	return s, nil
+// hash prefixes sort ids by their priority
+var priorityPrefixes = map[manifest.Priority]uint32{
+	manifest.PriorityFirst:   1,
+	manifest.PriorityDefault: 5,
+	manifest.PriorityLast:    9,
 }
 
 func determinePrefix(kr *kManifestId) (p uint32) {
-	// Default prefix to 5
-	p = 5
-
-	for _, k := range []string{
-		"Namespace",
-		"CustomResourceDefinition",
-	} {
-		if strings.HasPrefix(kr.kind, k) {
-			p = 1
-		}
-	}
-
-	for _, k := range []string{
-		"MutatingWebhookConfiguration",
-		"ValidatingWebhookConfiguration",
-	} {
-		if strings.HasPrefix(kr.kind, k) {
-			p = 9
-		}
-	}
-
-	return p
+	return priorityPrefixes[kr.id().Priority()]
 }
 
 func prefixHash(p uint32, h uint32) int {
@@ -85,6 +56,10 @@ func runKustomizeBuild(fSys filesys.FileSystem, path string, kOpts *schema.Resou
 
 	k := krusty.MakeKustomizer(opts)
 
+	fSys = makeNameSuffixHashFS(fSys, getDisableNameSuffixHash(kOpts))
+
+	resetCustomOpenAPISchema()
+
 	rm, err = k.Run(fSys, path)
 	if err != nil {
 		return nil, fmt.Errorf("Kustomizer Run for path '%s' failed: %s", path, err)
@@ -93,7 +68,46 @@ func runKustomizeBuild(fSys filesys.FileSystem, path string, kOpts *schema.Resou
 	return rm, nil
 }
 
-func setGeneratedAttributes(d *schema.ResourceData, rm resmap.ResMap) error {
+func setGeneratedAttributes(d *schema.ResourceData, rm resmap.ResMap, limits buildLimits) error {
+	stripAnnotations, err := compileIgnorePatterns(convertListInterfaceToListString(d.Get("strip_annotations").([]interface{})))
+	if err != nil {
+		return fmt.Errorf("strip_annotations: %s", err)
+	}
+
+	stripLabels, err := compileIgnorePatterns(convertListInterfaceToListString(d.Get("strip_labels").([]interface{})))
+	if err != nil {
+		return fmt.Errorf("strip_labels: %s", err)
+	}
+
+	// parsed before stripping, to allow stripping the origin annotations
+	origins, err := getOrigins(rm)
+	if err != nil {
+		return fmt.Errorf("couldn't parse origin annotations: %s", err)
+	}
+	d.Set("origins", flattenOrigins(origins))
+
+	if err := stripMetadata(rm, stripAnnotations, stripLabels); err != nil {
+		return fmt.Errorf("couldn't strip annotations and labels: %s", err)
+	}
+
+	if d.Get("create_namespaces").(bool) {
+		if err := addMissingNamespaces(rm); err != nil {
+			return fmt.Errorf("couldn't add namespaces: %s", err)
+		}
+	}
+
+	if nf := d.Get("namespace_filter").([]interface{}); len(nf) > 0 {
+		if err := filterNamespaces(rm, convertListInterfaceToListString(nf)); err != nil {
+			return fmt.Errorf("namespace_filter: %s", err)
+		}
+	}
+	d.Set("namespaces", getReferencedNamespaces(rm))
+
+	resources, id, err := flattenBuild(rm, limits)
+	if err != nil {
+		return fmt.Errorf("couldn't flatten resources: %s", err)
+	}
+
 	ids, idsPrio, err := flattenKustomizationIDs(rm)
 	if err != nil {
 		return fmt.Errorf("couldn't flatten kustomization IDs: %s", err)
@@ -101,21 +115,45 @@ func setGeneratedAttributes(d *schema.ResourceData, rm resmap.ResMap) error {
 	d.Set("ids", ids)
 	d.Set("ids_prio", idsPrio)
 
-	resources, err := flattenKustomizationResources(rm)
-	if err != nil {
-		return fmt.Errorf("couldn't flatten resources: %s", err)
+	generatedIDs, staticIDs := flattenGeneratedIDs(rm)
+	d.Set("generated_ids", generatedIDs)
+	d.Set("static_ids", staticIDs)
+	d.SetId(id)
+
+	// only the hashes are stored in state, if requested, the manifests
+	// are rebuilt on demand, e.g. with the build function
+	if hash, _ := d.Get("hash_manifests").(bool); hash {
+		d.Set("manifest_hashes", hashManifests(resources))
+		resources = nil
 	}
 	d.Set("manifests", resources)
 
-	id, err := getIDFromResources(rm)
+	byKind, err := groupKustomizationResources(ids, resources, "kind", func(id manifest.ID) string {
+		return id.Kind
+	})
 	if err != nil {
-		return fmt.Errorf("couldn't get ID from resources: %s", err)
+		return fmt.Errorf("couldn't group resources by kind: %s", err)
 	}
-	d.SetId(id)
+	d.Set("by_kind", byKind)
+
+	byNamespace, err := groupKustomizationResources(ids, resources, "namespace", func(id manifest.ID) string {
+		return id.Namespace
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't group resources by namespace: %s", err)
+	}
+	d.Set("by_namespace", byNamespace)
 
 	return nil
 }
 
+// setBuildMetrics sets the duration of the data source read since
+// start and the number of resources of the build rm
+func setBuildMetrics(d *schema.ResourceData, rm resmap.ResMap, start time.Time) {
+	d.Set("build_duration_ms", int(time.Since(start).Milliseconds()))
+	d.Set("resource_count", rm.Size())
+}
+
 func getKustomizeOptions(d *schema.ResourceData) (opts *krusty.Options) {
 
 	opts = krusty.MakeDefaultOptions()
@@ -1,54 +1,37 @@
 package kustomize
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 
+	"sigs.k8s.io/kustomize/api/resmap"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
 )
 
 func dataSourceKustomization() *schema.Resource {
 	return &schema.Resource{
-		Read: kustomizationBuild,
+		ReadContext: kustomizationBuild,
 
 		Schema: map[string]*schema.Schema{
 			"path": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
 			},
-			"kustomize_options": &schema.Schema{
-				Type:     schema.TypeList,
+			"kustomize_options": kustomizeOptionsSchema(),
+			"helm_values":       helmValuesSchema(),
+			"validate":          validateModeSchema(),
+			"validate_cluster": &schema.Schema{
+				Type:     schema.TypeString,
 				Optional: true,
-				MaxItems: 1,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"load_restrictor": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"enable_alpha_plugins": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_exec": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_helm": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"enable_star": {
-							Type:     schema.TypeBool,
-							Optional: true,
-						},
-						"helm_path": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-					},
-				},
+			},
+			"fingerprint": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
 			},
 			"ids": &schema.Schema{
 				Type:     schema.TypeSet,
@@ -64,29 +47,197 @@ func dataSourceKustomization() *schema.Resource {
 					Set:  idSetHash,
 				},
 			},
+			"generated_ids": idsSchema(),
+			"static_ids":    idsSchema(),
+			"build_duration_ms": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"resource_count": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
 			"manifests": &schema.Schema{
 				Type:     schema.TypeMap,
 				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
+			"manifest_hashes": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"hash_manifests": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"by_kind":      groupedResourcesSchema("kind"),
+			"by_namespace": groupedResourcesSchema("namespace"),
+			"origins":      originsSchema(),
+			"namespaces": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"create_namespaces": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"strip_annotations": stripPatternsSchema(),
+			"strip_labels":      stripPatternsSchema(),
+			"namespace_filter":  namespaceFilterSchema(),
 		},
 	}
 }
 
-func kustomizationBuild(d *schema.ResourceData, m interface{}) error {
+func kustomizationBuild(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	readStart := time.Now()
+
 	path := d.Get("path").(string)
 
+	rm, fingerprint, err := buildKustomizationPath(ctx, d, m, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationBuild: %s", err))
+	}
+	d.Set("fingerprint", fingerprint)
+
+	err = setGeneratedAttributes(d, rm, m.(*Config).BuildLimits)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	setBuildMetrics(d, rm, readStart)
+
+	err = serverValidate(d, m, rm)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationBuild: %s", err))
+	}
+
+	return patchTargetWarnings(filesys.MakeFsOnDisk(), path, rm)
+}
+
+// buildKustomizationPath runs kustomize build for path, with the
+// kustomize_options of d, and returns the build and the fingerprint
+// of its inputs, unchanged builds are read from the build cache
+func buildKustomizationPath(ctx context.Context, d *schema.ResourceData, m interface{}, path string) (resmap.ResMap, string, error) {
 	fSys := filesys.MakeFsOnDisk()
+	opts := getKustomizeOptions(d)
+
+	// merge patches using the OpenAPI schema of the cluster
+	openAPISchema, err := getClusterOpenAPISchema(ctx, d, m)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fingerprint, _ := buildFingerprint(fSys, path, opts, d.Get("kustomize_options").([]interface{}), openAPISchema)
+
+	cache := m.(*Config).BuildCache
+	if rm, ok := cache.get(fingerprint); ok {
+		return rm, fingerprint, nil
+	}
+
+	// decrypt SOPS encrypted generator sources in memory
+	fSys = makeSOPSFS(fSys, getKustomizationGeneratorSources(fSys, path), m.(*Config).Sops)
+
+	// only run allowed exec KRM functions
+	fSys = makeExecFunctionsFS(fSys, m.(*Config).ExecFunctions, opts)
+
+	// expose the plugin_env to KRM functions
+	pluginEnv := getPluginEnv(d)
+	fSys = makePluginEnvFS(fSys, pluginEnv)
+
+	// set the helm_values of the data source, if any
+	hvs, err := getHelmValues(getHelmValuesConfig(d))
+	if err != nil {
+		return nil, "", err
+	}
+	fSys = makeHelmValuesFS(fSys, hvs)
 
-	// mutex as tmp workaround for upstream bug
-	// https://github.com/kubernetes-sigs/kustomize/issues/3659
-	mu := m.(*Config).Mutex
-	mu.Lock()
+	fSys, err = makeOpenAPIFS(fSys, path, openAPISchema)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exclusive := openAPISchema != nil || len(pluginEnv) > 0 || isExclusiveBuild(fSys, path, opts)
+	unlock, err := m.(*Config).BuildLock.lock(ctx, exclusive)
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+
+	defer setPluginEnv(pluginEnv)()
+
+	start := time.Now()
 	rm, err := runKustomizeBuild(fSys, path, d)
-	mu.Unlock()
+	m.(*Config).Metrics.addBuild(path, time.Since(start))
 	if err != nil {
-		return fmt.Errorf("kustomizationBuild: %s", err)
+		return nil, "", err
+	}
+
+	if err := unmatchedHelmValues(hvs); err != nil {
+		return nil, "", err
+	}
+
+	if err := cache.put(fingerprint, rm); err != nil {
+		log.Printf("[WARN] kustomization build cache: %q: %s", path, err)
 	}
 
-	return setGeneratedAttributes(d, rm)
+	return rm, fingerprint, nil
+}
+
+// kustomizeOptionsSchema returns the schema of the kustomize_options
+// shared by the build data sources
+func kustomizeOptionsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"load_restrictor": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"enable_alpha_plugins": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"enable_exec": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"enable_helm": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"enable_star": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"helm_path": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"openapi_from_cluster": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"openapi_cluster": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"disable_name_suffix_hash": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"plugin_env": {
+					Type:      schema.TypeMap,
+					Optional:  true,
+					Sensitive: true,
+					Elem:      &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
 }
@@ -1,10 +1,15 @@
 package kustomize
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAccDataSourceKustomization_basic(t *testing.T) {
@@ -36,6 +41,79 @@ data "kustomization_build" "test" {
 `, path)
 }
 
+func TestKustomizationBuildGrouped(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path": "test_kustomizations/basic/initial",
+	})
+	diags := kustomizationBuild(context.TODO(), d, &Config{BuildLock: newBuildLock()})
+	assert.Equal(t, false, diags.HasError(), nil)
+
+	assert.Equal(t, 4, len(d.Get("manifests").(map[string]interface{})), nil)
+	assert.Equal(t, 4, d.Get("resource_count"), nil)
+
+	assert.Equal(t, 4, d.Get("by_kind.#"), nil)
+	assert.Equal(t, "Deployment", d.Get("by_kind.0.kind"), nil)
+	assert.Equal(t, 1, len(d.Get("by_kind.0.manifests").(map[string]interface{})), nil)
+	assert.Equal(t, 2, d.Get("by_namespace.#"), nil)
+	assert.Equal(t, "test-basic", d.Get("by_namespace.1.namespace"), nil)
+
+	assert.Equal(t, []interface{}{"test-basic"}, d.Get("namespaces").(*schema.Set).List(), nil)
+}
+
+func TestKustomizationBuildHashManifests(t *testing.T) {
+	build := func(hash bool) *schema.ResourceData {
+		d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+			"path":           "test_kustomizations/basic/initial",
+			"hash_manifests": hash,
+		})
+		diags := kustomizationBuild(context.TODO(), d, &Config{BuildLock: newBuildLock()})
+		assert.Equal(t, false, diags.HasError(), nil)
+
+		return d
+	}
+
+	full := build(false)
+	hashed := build(true)
+
+	manifests := full.Get("manifests").(map[string]interface{})
+	hashes := hashed.Get("manifest_hashes").(map[string]interface{})
+	assert.Equal(t, 4, len(hashes), nil)
+	assert.Equal(t, 0, len(hashed.Get("manifests").(map[string]interface{})), nil)
+	assert.Equal(t, 0, len(hashed.Get("by_kind.0.manifests").(map[string]interface{})), nil)
+	assert.Equal(t, 0, len(full.Get("manifest_hashes").(map[string]interface{})), nil)
+
+	for id, m := range manifests {
+		h := sha256.Sum256([]byte(m.(string)))
+		assert.Equal(t, hex.EncodeToString(h[:]), hashes[id], nil)
+	}
+
+	assert.Equal(t, full.Id(), hashed.Id(), nil)
+	assert.Equal(t, full.Get("ids").(*schema.Set).List(), hashed.Get("ids").(*schema.Set).List(), nil)
+}
+
+func TestAccDataSourceKustomization_hashManifests(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "kustomization_build" "test" {
+	path           = "test_kustomizations/basic/initial"
+	hash_manifests = true
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "ids.#", "4"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "manifest_hashes.%", "4"),
+					resource.TestCheckResourceAttr("data.kustomization_build.test", "manifests.%", "0"),
+				),
+			},
+		},
+	})
+}
+
 func TestAccDataSourceKustomization_legacyName(t *testing.T) {
 
 	resource.Test(t, resource.TestCase{
@@ -0,0 +1,179 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/api/konfig"
+)
+
+func dataSourceKustomizationBuilds() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationBuilds,
+
+		Schema: map[string]*schema.Schema{
+			"paths": &schema.Schema{
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				ExactlyOneOf: []string{"paths", "glob"},
+			},
+			"glob": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"paths", "glob"},
+			},
+			"kustomize_options": kustomizeOptionsSchema(),
+			"hash_manifests": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"builds": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"path": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"fingerprint": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ids": &schema.Schema{
+							Type:     schema.TypeSet,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Set:      idSetHash,
+						},
+						"ids_prio": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeSet,
+								Set:  idSetHash,
+							},
+						},
+						"manifests": &schema.Schema{
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"manifest_hashes": &schema.Schema{
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func isKustomizationDir(path string) bool {
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		if fi, err := os.Stat(filepath.Join(path, n)); err == nil && !fi.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getBuildPaths returns the paths to build by name, either the paths
+// map or the directories with a kustomization matching the glob,
+// named by their path
+func getBuildPaths(d *schema.ResourceData) (map[string]string, error) {
+	paths := make(map[string]string)
+
+	if glob := d.Get("glob").(string); glob != "" {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range matches {
+			if isKustomizationDir(p) {
+				paths[p] = p
+			}
+		}
+
+		return paths, nil
+	}
+
+	for n, p := range d.Get("paths").(map[string]interface{}) {
+		paths[n] = p.(string)
+	}
+
+	return paths, nil
+}
+
+func kustomizationBuilds(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	paths, err := getBuildPaths(d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationBuilds: %s", err))
+	}
+
+	names := make([]string, 0, len(paths))
+	for n := range paths {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	h := sha512.New()
+	var builds []interface{}
+	for _, n := range names {
+		path := paths[n]
+
+		rm, fingerprint, err := buildKustomizationPath(ctx, d, m, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationBuilds: %q: %s", n, err))
+		}
+
+		resources, id, err := flattenBuild(rm, m.(*Config).BuildLimits)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationBuilds: %q: couldn't flatten resources: %s", n, err))
+		}
+
+		ids, idsPrio, err := flattenKustomizationIDs(rm)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationBuilds: %q: couldn't flatten kustomization IDs: %s", n, err))
+		}
+
+		h.Write([]byte(n))
+		h.Write([]byte(id))
+
+		build := map[string]interface{}{
+			"name":        n,
+			"path":        path,
+			"fingerprint": fingerprint,
+			"ids":         ids,
+			"ids_prio":    idsPrio,
+		}
+		if d.Get("hash_manifests").(bool) {
+			build["manifest_hashes"] = hashManifests(resources)
+		} else {
+			build["manifests"] = resources
+		}
+		builds = append(builds, build)
+	}
+
+	d.Set("builds", builds)
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBuildPathsGlob(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationBuilds().Schema, map[string]interface{}{
+		"glob": "test_kustomizations/basic/*",
+	})
+
+	paths, err := getBuildPaths(d)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[string]string{
+		"test_kustomizations/basic/initial":  "test_kustomizations/basic/initial",
+		"test_kustomizations/basic/modified": "test_kustomizations/basic/modified",
+	}, paths, nil)
+}
+
+func TestKustomizationBuilds(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationBuilds().Schema, map[string]interface{}{
+		"paths": map[string]interface{}{
+			"a": "test_kustomizations/basic/initial",
+			"b": "test_kustomizations/basic/modified",
+		},
+	})
+
+	diags := kustomizationBuilds(context.TODO(), d, &Config{BuildLock: newBuildLock()})
+	assert.Equal(t, false, diags.HasError(), nil)
+
+	assert.Equal(t, 2, d.Get("builds.#"), nil)
+	assert.Equal(t, "a", d.Get("builds.0.name"), nil)
+	assert.Equal(t, "test_kustomizations/basic/initial", d.Get("builds.0.path"), nil)
+	assert.Equal(t, 4, d.Get("builds.0.ids.#"), nil)
+	assert.Equal(t, 3, d.Get("builds.0.ids_prio.#"), nil)
+	assert.Equal(t, 4, len(d.Get("builds.0.manifests").(map[string]interface{})), nil)
+	assert.Equal(t, "b", d.Get("builds.1.name"), nil)
+	assert.NotEqual(t, "", d.Id(), nil)
+}
+
+func TestKustomizationBuildsHashManifests(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationBuilds().Schema, map[string]interface{}{
+		"paths": map[string]interface{}{
+			"a": "test_kustomizations/basic/initial",
+		},
+		"hash_manifests": true,
+	})
+
+	diags := kustomizationBuilds(context.TODO(), d, &Config{BuildLock: newBuildLock()})
+	assert.Equal(t, false, diags.HasError(), nil)
+
+	assert.Equal(t, 4, len(d.Get("builds.0.manifest_hashes").(map[string]interface{})), nil)
+	assert.Equal(t, 0, len(d.Get("builds.0.manifests").(map[string]interface{})), nil)
+}
+
+func TestAccDataSourceKustomizationBuilds_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "kustomization_builds" "test" {
+	glob = "test_kustomizations/basic/*"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_builds.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_builds.test", "builds.#", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_builds.test", "builds.0.name", "test_kustomizations/basic/initial"),
+					resource.TestCheckResourceAttr("data.kustomization_builds.test", "builds.0.manifests.%", "4"),
+					resource.TestCheckResourceAttr("data.kustomization_builds.test", "builds.1.name", "test_kustomizations/basic/modified"),
+				),
+			},
+		},
+	})
+}
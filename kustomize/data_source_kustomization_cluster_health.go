@@ -0,0 +1,188 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8scorev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	nodesGVR       = k8sschema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	deploymentsGVR = k8sschema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+)
+
+func dataSourceKustomizationClusterHealth() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationClusterHealth,
+
+		Schema: map[string]*schema.Schema{
+			"cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"required_deployments": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateNamespacedName,
+				},
+			},
+			"reachable": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"nodes_ready": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"node_count": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"ready_node_count": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"deployments_ready": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeBool},
+			},
+			"ready": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// splitNamespacedName splits e.g. "kube-system/coredns"
+// into the namespace and the name
+func splitNamespacedName(s string) (namespace string, name string, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid deployment: %q, valid deployments look like: \"kube-system/coredns\"", s)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+func validateNamespacedName(v interface{}, k string) (ws []string, es []error) {
+	if _, _, err := splitNamespacedName(v.(string)); err != nil {
+		es = append(es, err)
+	}
+
+	return ws, es
+}
+
+func nodeReady(u k8sunstructured.Unstructured) (bool, error) {
+	var node k8scorev1.Node
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &node); err != nil {
+		return false, err
+	}
+
+	for _, c := range node.Status.Conditions {
+		if c.Type == k8scorev1.NodeReady {
+			return c.Status == k8scorev1.ConditionTrue, nil
+		}
+	}
+
+	return false, nil
+}
+
+func kustomizationClusterHealth(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	name := d.Get("cluster").(string)
+
+	kc, err := m.(*Config).getCluster(name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(emptyToUnderscore(name))
+
+	deployments := convertListInterfaceToListString(d.Get("required_deployments").([]interface{}))
+	deploymentsReady := make(map[string]interface{})
+	for _, dep := range deployments {
+		deploymentsReady[dep] = false
+	}
+
+	// an unreachable cluster is not an error, but not ready
+	err = checkClusterReachable(kc.restConfig)
+	if err != nil {
+		log.Printf("[WARN] kustomizationClusterHealth: cluster unreachable: %s", err)
+
+		d.Set("reachable", false)
+		d.Set("nodes_ready", false)
+		d.Set("node_count", 0)
+		d.Set("ready_node_count", 0)
+		d.Set("deployments_ready", deploymentsReady)
+		d.Set("ready", false)
+		return nil
+	}
+
+	client, _, err := kc.get()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	nodes, err := client.Resource(nodesGVR).List(ctx, k8smetav1.ListOptions{})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationClusterHealth: listing nodes failed: %s", err))
+	}
+
+	readyNodes := 0
+	for _, n := range nodes.Items {
+		ok, err := nodeReady(n)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationClusterHealth: %q: %s", n.GetName(), err))
+		}
+
+		if ok {
+			readyNodes++
+		}
+	}
+	nodesReady := len(nodes.Items) > 0 && readyNodes == len(nodes.Items)
+
+	ready := nodesReady
+	for _, dep := range deployments {
+		namespace, name, _ := splitNamespacedName(dep)
+
+		u, err := client.Resource(deploymentsGVR).Namespace(namespace).Get(ctx, name, k8smetav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			ready = false
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationClusterHealth: %q: %s", dep, err))
+		}
+
+		ok, err := deploymentReady(u)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationClusterHealth: %q: %s", dep, err))
+		}
+
+		deploymentsReady[dep] = ok
+		ready = ready && ok
+	}
+
+	d.Set("reachable", true)
+	d.Set("nodes_ready", nodesReady)
+	d.Set("node_count", len(nodes.Items))
+	d.Set("ready_node_count", readyNodes)
+	d.Set("deployments_ready", deploymentsReady)
+	d.Set("ready", ready)
+
+	return nil
+}
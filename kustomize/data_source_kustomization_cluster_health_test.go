@@ -0,0 +1,44 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSplitNamespacedName(t *testing.T) {
+	namespace, name, err := splitNamespacedName("kube-system/coredns")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "kube-system", namespace, nil)
+	assert.Equal(t, "coredns", name, nil)
+
+	for _, s := range []string{"coredns", "/coredns", "kube-system/", "a/b/c"} {
+		_, _, err = splitNamespacedName(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNodeReady(t *testing.T) {
+	node := func(status string) k8sunstructured.Unstructured {
+		return k8sunstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Node",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "MemoryPressure", "status": "False"},
+					map[string]interface{}{"type": "Ready", "status": status},
+				},
+			},
+		}}
+	}
+
+	ok, err := nodeReady(node("True"))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, true, ok, nil)
+
+	ok, err = nodeReady(node("Unknown"))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, false, ok, nil)
+}
@@ -0,0 +1,208 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// defaultCascadeKinds are the kinds of the objects that are commonly
+// garbage collected with their owners, e.g. the pods of deployments
+// or the persistent volume claims of statefulsets
+var defaultCascadeKinds = []string{
+	"apps/v1/ReplicaSet",
+	"v1/Pod",
+	"v1/PersistentVolumeClaim",
+	"batch/v1/Job",
+}
+
+func dataSourceKustomizationDestroyPreview() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationDestroyPreview,
+
+		Schema: map[string]*schema.Schema{
+			"ids": &schema.Schema{
+				Type:     schema.TypeList,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"kinds": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateAPIVersionKind,
+				},
+			},
+			"cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"dependents": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"owner_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type cascadeDependent struct {
+	id      string
+	ownerID string
+}
+
+// cascadeDependents returns the objects that are garbage collected when
+// the owners are deleted, following owner references transitively, with
+// the ID of the owner they are deleted with, sorted by ID
+func cascadeDependents(owners map[k8stypes.UID]string, objects []k8sunstructured.Unstructured) []cascadeDependent {
+	deleted := make(map[k8stypes.UID]string, len(owners))
+	for uid, id := range owners {
+		deleted[uid] = id
+	}
+
+	var dependents []cascadeDependent
+	for changed := true; changed; {
+		changed = false
+		for _, u := range objects {
+			if _, ok := deleted[u.GetUID()]; ok {
+				continue
+			}
+
+			for _, ref := range u.GetOwnerReferences() {
+				ownerID, ok := deleted[ref.UID]
+				if !ok {
+					continue
+				}
+
+				k := kManifestId{
+					group:     u.GroupVersionKind().Group,
+					kind:      u.GetKind(),
+					namespace: u.GetNamespace(),
+					name:      u.GetName(),
+				}
+				dependents = append(dependents, cascadeDependent{id: k.string(), ownerID: ownerID})
+				deleted[u.GetUID()] = ownerID
+				changed = true
+				break
+			}
+		}
+	}
+
+	sort.Slice(dependents, func(i, j int) bool {
+		return dependents[i].id < dependents[j].id
+	})
+
+	return dependents
+}
+
+func kustomizationDestroyPreview(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	ids := convertListInterfaceToListString(d.Get("ids").([]interface{}))
+
+	owners := make(map[k8stypes.UID]string)
+	namespaces := make(map[string]bool)
+	for _, id := range ids {
+		k, err := parseProviderId(id)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationDestroyPreview: %s", err))
+		}
+
+		mapping, err := mapper.RESTMapping(k8sschema.GroupKind{Group: k.group, Kind: k.kind})
+		if k8smeta.IsNoMatchError(err) {
+			// the kind is not available, nothing to delete
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationDestroyPreview: api error %q: %s", id, err))
+		}
+
+		u, err := client.Resource(mapping.Resource).Namespace(k.namespace).Get(ctx, k.name, k8smetav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationDestroyPreview: %q: %s", id, err))
+		}
+
+		owners[u.GetUID()] = id
+		// dependents of cluster scoped owners can be in any namespace
+		namespaces[k.namespace] = true
+	}
+
+	kinds := convertListInterfaceToListString(d.Get("kinds").([]interface{}))
+	if len(kinds) == 0 {
+		kinds = defaultCascadeKinds
+	}
+
+	resources, err := getKindResources(mapper, kinds)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationDestroyPreview: %s", err))
+	}
+
+	var objects []k8sunstructured.Unstructured
+	if len(owners) > 0 {
+		listNamespaces := make([]string, 0, len(namespaces))
+		for ns := range namespaces {
+			listNamespaces = append(listNamespaces, ns)
+		}
+		if namespaces[""] {
+			listNamespaces = []string{""}
+		}
+
+		for _, r := range resources {
+			for _, ns := range listNamespaces {
+				resp, err := client.Resource(r.gvr).Namespace(ns).List(ctx, k8smetav1.ListOptions{})
+				if err != nil {
+					return diag.FromErr(fmt.Errorf("kustomizationDestroyPreview: listing %q: %s", r.gvr.String(), err))
+				}
+				objects = append(objects, resp.Items...)
+			}
+		}
+	}
+
+	var dependents []interface{}
+	for _, dep := range cascadeDependents(owners, objects) {
+		dependents = append(dependents, map[string]interface{}{
+			"id":       dep.id,
+			"owner_id": dep.ownerID,
+		})
+	}
+
+	h := sha512.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+	}
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	d.Set("dependents", dependents)
+
+	return nil
+}
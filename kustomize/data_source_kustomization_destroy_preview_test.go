@@ -0,0 +1,51 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+func testCascadeObject(apiVersion, kind, name string, uid, owner k8stypes.UID) k8sunstructured.Unstructured {
+	u := k8sunstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace("test-ns")
+	u.SetName(name)
+	u.SetUID(uid)
+	if owner != "" {
+		u.SetOwnerReferences([]k8smetav1.OwnerReference{{UID: owner}})
+	}
+
+	return u
+}
+
+func TestCascadeDependents(t *testing.T) {
+	owners := map[k8stypes.UID]string{
+		"deployment":  "apps/Deployment/test-ns/test",
+		"statefulset": "apps/StatefulSet/test-ns/test-db",
+	}
+
+	objects := []k8sunstructured.Unstructured{
+		// listed before its owner
+		testCascadeObject("v1", "Pod", "test-abc-1", "pod-1", "replicaset"),
+		testCascadeObject("apps/v1", "ReplicaSet", "test-abc", "replicaset", "deployment"),
+		testCascadeObject("v1", "Pod", "test-db-0", "pod-2", "statefulset"),
+		testCascadeObject("v1", "PersistentVolumeClaim", "data-test-db-0", "pvc-1", "statefulset"),
+		// retained on delete, without owner reference
+		testCascadeObject("v1", "PersistentVolumeClaim", "data-test-db-1", "pvc-2", ""),
+		testCascadeObject("v1", "Pod", "other-0", "pod-3", "other"),
+	}
+
+	assert.Equal(t, []cascadeDependent{
+		{id: "_/PersistentVolumeClaim/test-ns/data-test-db-0", ownerID: "apps/StatefulSet/test-ns/test-db"},
+		{id: "_/Pod/test-ns/test-abc-1", ownerID: "apps/Deployment/test-ns/test"},
+		{id: "_/Pod/test-ns/test-db-0", ownerID: "apps/StatefulSet/test-ns/test-db"},
+		{id: "apps/ReplicaSet/test-ns/test-abc", ownerID: "apps/Deployment/test-ns/test"},
+	}, cascadeDependents(owners, objects), nil)
+
+	assert.Equal(t, 0, len(cascadeDependents(map[k8stypes.UID]string{}, objects)), nil)
+}
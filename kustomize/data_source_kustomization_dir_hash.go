@@ -0,0 +1,95 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func dataSourceKustomizationDirHash() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationDirHash,
+
+		Schema: map[string]*schema.Schema{
+			"path": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"hash": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"files": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func kustomizationDirHash(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path := d.Get("path").(string)
+
+	hash, files, err := dirHash(filesys.MakeFsOnDisk(), path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomization_dir_hash: %q: %s", path, err))
+	}
+
+	d.SetId(hash)
+	d.Set("hash", hash)
+	d.Set("files", files)
+
+	return nil
+}
+
+// dirHash returns the SHA256 hash of the kustomization at path and
+// of all local bases, components and files it references, and the
+// paths of the hashed files relative to path
+//
+// Unlike the build fingerprint, the kustomization is not built and
+// remote bases are hashed by their URL only, the hash does not change
+// if the content behind an URL without a pinned ref changes.
+func dirHash(fSys filesys.FileSystem, path string) (string, []string, error) {
+	root, _, err := fSys.CleanedAbs(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hashes := make(map[string][]byte)
+	if !fingerprintKustomization(fSys, root.String(), hashes, true) {
+		return "", nil, fmt.Errorf("no kustomization found")
+	}
+
+	var files []string
+	for p, c := range hashes {
+		// directories are only marked visited
+		if c == nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(root.String(), p)
+		if err != nil {
+			return "", nil, err
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, f := range files {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+		h.Write(hashes[filepath.Join(root.String(), filepath.FromSlash(f))])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), files, nil
+}
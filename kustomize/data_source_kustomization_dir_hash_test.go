@@ -0,0 +1,44 @@
+package kustomize
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func TestDirHash(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml":      "resources:\n- configmap.yaml\n",
+		"base/configmap.yaml":          testCacheConfigMap,
+		"overlay/kustomization.yaml":   "resources:\n- ../base\n- https://github.com/example/repo//deploy?ref=v1.0.0\nconfigMapGenerator:\n- name: env\n  envs:\n  - app.env\n",
+		"overlay/app.env":              "KEY=value\n",
+		"overlay/unreferenced.yaml":    "ignored",
+		"unrelated/kustomization.yaml": "resources: []\n",
+	})
+	fSys := filesys.MakeFsOnDisk()
+	overlay := filepath.Join(dir, "overlay")
+
+	hash, files, err := dirHash(fSys, overlay)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{"../base/configmap.yaml", "../base/kustomization.yaml", "app.env", "kustomization.yaml"}, files, nil)
+
+	// stable
+	again, _, _ := dirHash(fSys, overlay)
+	assert.Equal(t, hash, again, nil)
+
+	// unreferenced files do not change the hash
+	writeTestFiles(t, dir, map[string]string{"overlay/unreferenced.yaml": "changed"})
+	again, _, _ = dirHash(fSys, overlay)
+	assert.Equal(t, hash, again, nil)
+
+	// files of bases do
+	writeTestFiles(t, dir, map[string]string{"base/configmap.yaml": testCacheConfigMap + "  other: value\n"})
+	again, _, _ = dirHash(fSys, overlay)
+	assert.NotEqual(t, hash, again, nil)
+
+	_, _, err = dirHash(fSys, filepath.Join(dir, "missing"))
+	assert.NotEqual(t, nil, err, nil)
+}
@@ -0,0 +1,146 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+)
+
+func dataSourceKustomizationDiscovery() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationDiscovery,
+
+		Schema: map[string]*schema.Schema{
+			"cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kinds": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateAPIVersionKind,
+				},
+			},
+			"server_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"api_versions": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"kinds_available": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeBool},
+			},
+		},
+	}
+}
+
+// splitAPIVersionKind splits e.g. "policy/v1/PodDisruptionBudget"
+// or "v1/ConfigMap" into the API version and the kind
+func splitAPIVersionKind(s string) (apiVersion string, kind string, err error) {
+	i := strings.LastIndex(s, "/")
+	if i <= 0 || i == len(s)-1 {
+		return "", "", fmt.Errorf("invalid kind: %q, valid kinds look like: \"policy/v1/PodDisruptionBudget\" or \"v1/ConfigMap\"", s)
+	}
+
+	return s[:i], s[i+1:], nil
+}
+
+func validateAPIVersionKind(v interface{}, k string) (ws []string, es []error) {
+	if _, _, err := splitAPIVersionKind(v.(string)); err != nil {
+		es = append(es, err)
+	}
+
+	return ws, es
+}
+
+func isKindAvailable(dc discovery.DiscoveryInterface, apiVersion string, kind string) (bool, error) {
+	rl, err := dc.ServerResourcesForGroupVersion(apiVersion)
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return hasKind(rl, kind), nil
+}
+
+func hasKind(rl *k8smetav1.APIResourceList, kind string) bool {
+	for _, r := range rl.APIResources {
+		// skip subresources, e.g. deployments/scale
+		if strings.Contains(r.Name, "/") {
+			continue
+		}
+
+		if r.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func kustomizationDiscovery(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	kc, err := m.(*Config).getCluster(d.Get("cluster").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	dc, err := kc.discovery()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationDiscovery: %s", err))
+	}
+
+	sv, err := dc.ServerVersion()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationDiscovery: %s", err))
+	}
+
+	groups, err := dc.ServerGroups()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationDiscovery: %s", err))
+	}
+
+	apiVersions := []string{}
+	for _, g := range groups.Groups {
+		for _, v := range g.Versions {
+			apiVersions = append(apiVersions, v.GroupVersion)
+		}
+	}
+
+	available := make(map[string]interface{})
+	for _, k := range convertListInterfaceToListString(d.Get("kinds").([]interface{})) {
+		apiVersion, kind, err := splitAPIVersionKind(k)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		ok, err := isKindAvailable(dc, apiVersion, kind)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationDiscovery: %q: %s", k, err))
+		}
+
+		available[k] = ok
+	}
+
+	d.SetId(sv.GitVersion)
+	d.Set("server_version", sv.GitVersion)
+	d.Set("api_versions", apiVersions)
+	d.Set("kinds_available", available)
+
+	return nil
+}
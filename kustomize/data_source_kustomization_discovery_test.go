@@ -0,0 +1,57 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSplitAPIVersionKind(t *testing.T) {
+	apiVersion, kind, err := splitAPIVersionKind("policy/v1/PodDisruptionBudget")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "policy/v1", apiVersion, nil)
+	assert.Equal(t, "PodDisruptionBudget", kind, nil)
+
+	apiVersion, kind, err = splitAPIVersionKind("v1/ConfigMap")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "v1", apiVersion, nil)
+	assert.Equal(t, "ConfigMap", kind, nil)
+
+	for _, s := range []string{"ConfigMap", "/ConfigMap", "v1/"} {
+		_, _, err = splitAPIVersionKind(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestIsKindAvailable(t *testing.T) {
+	dc := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	dc.Resources = []*k8smetav1.APIResourceList{
+		{
+			GroupVersion: "policy/v1beta1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget"},
+				{Name: "poddisruptionbudgets/status", Kind: "PodDisruptionBudget"},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "deployments/scale", Kind: "Scale"},
+			},
+		},
+	}
+
+	for k, expected := range map[string]bool{
+		"policy/v1beta1/PodDisruptionBudget": true,
+		"policy/v1/PodDisruptionBudget":      false,
+		"apps/v1/Scale":                      false,
+	} {
+		apiVersion, kind, _ := splitAPIVersionKind(k)
+		ok, err := isKindAvailable(dc, apiVersion, kind)
+		assert.Equal(t, nil, err, k)
+		assert.Equal(t, expected, ok, k)
+	}
+}
@@ -0,0 +1,160 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceKustomizationDuplicates() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationDuplicates,
+
+		Schema: map[string]*schema.Schema{
+			"source": &schema.Schema{
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"ids": &schema.Schema{
+							Type:     schema.TypeSet,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"fail_on_duplicates": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"duplicates": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"sources": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// idSources are the ids of a named source, e.g. a data source
+type idSources struct {
+	name string
+	ids  []string
+}
+
+type duplicateID struct {
+	id      string
+	sources []string
+}
+
+// findDuplicateIDs returns the ids of more than one source,
+// sorted by id, with the names of the sources in order
+func findDuplicateIDs(sources []idSources) ([]duplicateID, error) {
+	seen := make(map[string]bool)
+	byID := make(map[string][]string)
+	for _, s := range sources {
+		if seen[s.name] {
+			return nil, fmt.Errorf("duplicate source name %q", s.name)
+		}
+		seen[s.name] = true
+
+		for _, id := range s.ids {
+			byID[id] = append(byID[id], s.name)
+		}
+	}
+
+	var duplicates []duplicateID
+	for id, names := range byID {
+		if len(names) > 1 {
+			duplicates = append(duplicates, duplicateID{id: id, sources: names})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].id < duplicates[j].id
+	})
+
+	return duplicates, nil
+}
+
+func getIDSources(in []interface{}) []idSources {
+	var sources []idSources
+	for _, v := range in {
+		s := v.(map[string]interface{})
+
+		ids := convertListInterfaceToListString(s["ids"].(*schema.Set).List())
+		sort.Strings(ids)
+
+		sources = append(sources, idSources{
+			name: s["name"].(string),
+			ids:  ids,
+		})
+	}
+
+	return sources
+}
+
+func kustomizationDuplicates(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	sources := getIDSources(d.Get("source").([]interface{}))
+
+	duplicates, err := findDuplicateIDs(sources)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationDuplicates: %s", err))
+	}
+
+	if d.Get("fail_on_duplicates").(bool) && len(duplicates) > 0 {
+		var msgs []string
+		for _, dup := range duplicates {
+			var names []string
+			for _, n := range dup.sources {
+				names = append(names, fmt.Sprintf("%q", n))
+			}
+			msgs = append(msgs, fmt.Sprintf("%q is rendered by %s", dup.id, strings.Join(names, " and ")))
+		}
+		return diag.FromErr(fmt.Errorf("kustomizationDuplicates: %s", strings.Join(msgs, "; ")))
+	}
+
+	var out []interface{}
+	for _, dup := range duplicates {
+		out = append(out, map[string]interface{}{
+			"id":      dup.id,
+			"sources": dup.sources,
+		})
+	}
+	d.Set("duplicates", out)
+
+	h := sha512.New()
+	for _, s := range sources {
+		h.Write([]byte(s.name))
+		for _, id := range s.ids {
+			h.Write([]byte(id))
+		}
+	}
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package kustomize
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDuplicateIDs(t *testing.T) {
+	duplicates, err := findDuplicateIDs([]idSources{
+		{name: "a", ids: []string{"_/Namespace/_/test", "apps/Deployment/test/a"}},
+		{name: "b", ids: []string{"_/Namespace/_/test", "apps/Deployment/test/b"}},
+		{name: "c", ids: []string{"_/Namespace/_/test", "apps/Deployment/test/b"}},
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []duplicateID{
+		{id: "_/Namespace/_/test", sources: []string{"a", "b", "c"}},
+		{id: "apps/Deployment/test/b", sources: []string{"b", "c"}},
+	}, duplicates, nil)
+
+	duplicates, err = findDuplicateIDs([]idSources{
+		{name: "a", ids: []string{"_/Namespace/_/a"}},
+		{name: "b", ids: []string{"_/Namespace/_/b"}},
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(duplicates), nil)
+
+	_, err = findDuplicateIDs([]idSources{{name: "a"}, {name: "a"}})
+	assert.EqualError(t, err, `duplicate source name "a"`, nil)
+}
+
+func TestDataSourceKustomizationDuplicates_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationDuplicatesConfig_basic("false"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_duplicates.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_duplicates.test", "duplicates.#", "4"),
+					resource.TestCheckResourceAttr("data.kustomization_duplicates.test", "duplicates.0.id", "_/Namespace/_/test-basic"),
+					resource.TestCheckResourceAttr("data.kustomization_duplicates.test", "duplicates.0.sources.#", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_duplicates.test", "duplicates.0.sources.0", "initial"),
+					resource.TestCheckResourceAttr("data.kustomization_duplicates.test", "duplicates.0.sources.1", "modified"),
+				),
+			},
+			{
+				Config:      testDataSourceKustomizationDuplicatesConfig_basic("true"),
+				ExpectError: regexp.MustCompile(`"_/Namespace/_/test-basic" is rendered by "initial" and "modified"`),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationDuplicatesConfig_basic(fail string) string {
+	return `
+data "kustomization_build" "initial" {
+	path = "test_kustomizations/basic/initial"
+}
+
+data "kustomization_build" "modified" {
+	path = "test_kustomizations/basic/modified"
+}
+
+data "kustomization_duplicates" "test" {
+	source {
+		name = "initial"
+		ids  = data.kustomization_build.initial.ids
+	}
+
+	source {
+		name = "modified"
+		ids  = data.kustomization_build.modified.ids
+	}
+
+	fail_on_duplicates = ` + fail + `
+}
+`
+}
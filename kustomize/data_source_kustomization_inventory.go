@@ -0,0 +1,232 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+func dataSourceKustomizationInventory() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationInventory,
+
+		Schema: map[string]*schema.Schema{
+			"label_selector": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateLabelSelector,
+			},
+			"kinds": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateAPIVersionKind,
+				},
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func validateLabelSelector(v interface{}, k string) (ws []string, es []error) {
+	if _, err := labels.Parse(v.(string)); err != nil {
+		es = append(es, fmt.Errorf("invalid %s: %s", k, err))
+	}
+
+	return ws, es
+}
+
+// inventoryResource is a listable resource of the cluster
+type inventoryResource struct {
+	gvr        k8sschema.GroupVersionResource
+	namespaced bool
+}
+
+// getListableResources returns the resources that support list
+func getListableResources(lists []*k8smetav1.APIResourceList) ([]inventoryResource, error) {
+	var resources []inventoryResource
+	for _, rl := range lists {
+		gv, err := k8sschema.ParseGroupVersion(rl.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range rl.APIResources {
+			// skip subresources, e.g. deployments/scale
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+
+			if !hasVerb(r.Verbs, "list") {
+				continue
+			}
+
+			resources = append(resources, inventoryResource{
+				gvr:        gv.WithResource(r.Name),
+				namespaced: r.Namespaced,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func hasVerb(verbs k8smetav1.Verbs, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getKindResources returns the resources of kinds, e.g. "apps/v1/Deployment",
+// kinds not available in the cluster are skipped
+func getKindResources(mapper k8smeta.RESTMapper, kinds []string) ([]inventoryResource, error) {
+	var resources []inventoryResource
+	for _, k := range kinds {
+		apiVersion, kind, err := splitAPIVersionKind(k)
+		if err != nil {
+			return nil, err
+		}
+
+		gv, err := k8sschema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		mapping, err := mapper.RESTMapping(gv.WithKind(kind).GroupKind(), gv.Version)
+		if k8smeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", k, err)
+		}
+
+		resources = append(resources, inventoryResource{
+			gvr:        mapping.Resource,
+			namespaced: mapping.Scope.Name() == k8smeta.RESTScopeNameNamespace,
+		})
+	}
+
+	return resources, nil
+}
+
+// getPreferredListableResources returns the preferred version
+// of all resources of the cluster that support list
+func getPreferredListableResources(kc *kubeClients) ([]inventoryResource, error) {
+	dc, err := kc.discovery()
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := dc.ServerPreferredResources()
+	// groups failing discovery, e.g. unavailable aggregated APIs, are skipped
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	return getListableResources(lists)
+}
+
+func kustomizationInventory(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	kc, err := m.(*Config).getCluster(d.Get("cluster").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	client, mapper, err := kc.get()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var resources []inventoryResource
+	if kinds := convertListInterfaceToListString(d.Get("kinds").([]interface{})); len(kinds) > 0 {
+		resources, err = getKindResources(mapper, kinds)
+	} else {
+		resources, err = getPreferredListableResources(kc)
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationInventory: %s", err))
+	}
+
+	selector := d.Get("label_selector").(string)
+	namespace := d.Get("namespace").(string)
+
+	var ids []string
+	manifests := make(map[string]string)
+	for _, r := range resources {
+		// cluster scoped resources are not in any namespace
+		if namespace != "" && !r.namespaced {
+			continue
+		}
+
+		api := client.Resource(r.gvr)
+		resp, err := api.Namespace(namespace).List(ctx, k8smetav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationInventory: listing %q: %s", r.gvr.String(), err))
+		}
+
+		for _, u := range resp.Items {
+			kr := &kManifestId{
+				group:     u.GroupVersionKind().Group,
+				kind:      u.GetKind(),
+				namespace: u.GetNamespace(),
+				name:      u.GetName(),
+			}
+
+			manifest, err := u.MarshalJSON()
+			if err != nil {
+				return diag.FromErr(fmt.Errorf("kustomizationInventory: %q: %s", kr.string(), err))
+			}
+
+			ids = append(ids, kr.string())
+			manifests[kr.string()] = string(manifest)
+		}
+	}
+	sort.Strings(ids)
+
+	h := sha512.New()
+	h.Write([]byte(selector))
+	for _, id := range ids {
+		h.Write([]byte(id))
+	}
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	d.Set("ids", ids)
+	d.Set("manifests", manifests)
+
+	return nil
+}
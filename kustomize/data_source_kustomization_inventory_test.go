@@ -0,0 +1,109 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestValidateLabelSelector(t *testing.T) {
+	_, es := validateLabelSelector("app.kubernetes.io/managed-by=terraform,env in (a,b)", "label_selector")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateLabelSelector("app=", "label_selector")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateLabelSelector("app in a", "label_selector")
+	assert.Equal(t, 1, len(es), nil)
+}
+
+func TestGetListableResources(t *testing.T) {
+	resources, err := getListableResources([]*k8smetav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Verbs: k8smetav1.Verbs{"get", "list"}},
+				{Name: "namespaces/status", Kind: "Namespace", Verbs: k8smetav1.Verbs{"get", "list"}},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: k8smetav1.Verbs{"get", "list"}},
+				{Name: "bindings", Kind: "Binding", Namespaced: true, Verbs: k8smetav1.Verbs{"create"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []k8smetav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: k8smetav1.Verbs{"list"}},
+			},
+		},
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []inventoryResource{
+		{gvr: k8sschema.GroupVersionResource{Version: "v1", Resource: "namespaces"}},
+		{gvr: k8sschema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, namespaced: true},
+		{gvr: k8sschema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, namespaced: true},
+	}, resources, nil)
+}
+
+func TestGetKindResources(t *testing.T) {
+	mapper := k8smeta.NewDefaultRESTMapper(nil)
+	mapper.Add(k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, k8smeta.RESTScopeNamespace)
+	mapper.Add(k8sschema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, k8smeta.RESTScopeRoot)
+
+	resources, err := getKindResources(mapper, []string{"apps/v1/Deployment", "v1/Namespace", "example.com/v1/Backend"})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []inventoryResource{
+		{gvr: k8sschema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, namespaced: true},
+		{gvr: k8sschema.GroupVersionResource{Version: "v1", Resource: "namespaces"}},
+	}, resources, nil)
+}
+
+func TestAccDataSourceKustomizationInventory_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationInventoryConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_inventory.ns", "ids.#", "1"),
+					resource.TestCheckResourceAttrSet("data.kustomization_inventory.ns", "manifests._/Namespace/_/test-inventory"),
+					resource.TestCheckResourceAttr("data.kustomization_inventory.all", "manifests.%", "1"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationInventoryConfig_basic() string {
+	return `
+resource "kustomization_resource" "ns" {
+	manifest = jsonencode({
+		apiVersion = "v1"
+		kind       = "Namespace"
+		metadata = {
+			name = "test-inventory"
+			labels = {
+				"test-inventory" = "true"
+			}
+		}
+	})
+}
+
+data "kustomization_inventory" "ns" {
+	label_selector = "test-inventory=true"
+	kinds          = ["v1/Namespace", "example.com/v1/DoesNotExist"]
+
+	depends_on = [kustomization_resource.ns]
+}
+
+data "kustomization_inventory" "all" {
+	label_selector = "test-inventory=true"
+
+	depends_on = [kustomization_resource.ns]
+}
+`
+}
@@ -0,0 +1,70 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceKustomizationManifests() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationManifests,
+
+		Schema: map[string]*schema.Schema{
+			"content": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"ids_prio": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeSet,
+					Set:  idSetHash,
+				},
+			},
+			"generated_ids": idsSchema(),
+			"static_ids":    idsSchema(),
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"by_kind":      groupedResourcesSchema("kind"),
+			"by_namespace": groupedResourcesSchema("namespace"),
+			"origins":      originsSchema(),
+			"namespaces": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"create_namespaces": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"strip_annotations": stripPatternsSchema(),
+			"strip_labels":      stripPatternsSchema(),
+			"namespace_filter":  namespaceFilterSchema(),
+		},
+	}
+}
+
+func kustomizationManifests(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	content := d.Get("content").(string)
+
+	rm, err := runKustomizeInMemory(ctx, m, []string{content}, nil)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationManifests: %s", err))
+	}
+
+	return diag.FromErr(setGeneratedAttributes(d, rm, m.(*Config).BuildLimits))
+}
@@ -0,0 +1,94 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// Basic acceptance test
+func TestDataSourceKustomizationManifests_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationManifestsConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_manifests.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids_prio.#", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids_prio.0.#", "1"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids_prio.1.#", "1"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "manifests.%", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "manifests._/Namespace/_/test-manifests", "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"name\":\"test-manifests\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationManifestsConfig_basic() string {
+	return `
+data "kustomization_manifests" "test" {
+	content = <<-EOF
+		---
+		apiVersion: v1
+		kind: Namespace
+		metadata:
+		  name: test-manifests
+		---
+		# Source: example/templates/configmap.yaml
+		apiVersion: v1
+		kind: ConfigMap
+		metadata:
+		  name: test
+		  namespace: test-manifests
+		data:
+		  key: value
+	EOF
+}
+`
+}
+
+func TestDataSourceKustomizationManifests_list(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationManifestsConfig_list(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_manifests.test", "manifests._/Namespace/_/test-manifests", "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"name\":\"test-manifests\"}}"),
+					resource.TestCheckResourceAttrSet("data.kustomization_manifests.test", "manifests._/ConfigMap/test-manifests/test"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationManifestsConfig_list() string {
+	return `
+data "kustomization_manifests" "test" {
+	content = <<-EOF
+		apiVersion: v1
+		kind: List
+		items:
+		- apiVersion: v1
+		  kind: Namespace
+		  metadata:
+		    name: test-manifests
+		- apiVersion: v1
+		  kind: ConfigMap
+		  metadata:
+		    name: test
+		    namespace: test-manifests
+		  data:
+		    key: value
+	EOF
+}
+`
+}
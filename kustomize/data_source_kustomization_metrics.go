@@ -0,0 +1,60 @@
+package kustomize
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceKustomizationMetrics() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationMetrics,
+
+		Schema: map[string]*schema.Schema{
+			"build_durations": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"apply_durations": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"wait_durations": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"api_calls": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+			},
+			"api_calls_total": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"summary": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func kustomizationMetrics(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	metrics := m.(*Config).Metrics
+	apiCalls, total := metrics.apiCallCounts()
+
+	d.SetId("metrics")
+	d.Set("build_durations", metrics.durations(metrics.builds))
+	d.Set("apply_durations", metrics.durations(metrics.applies))
+	d.Set("wait_durations", metrics.durations(metrics.waits))
+	d.Set("api_calls", apiCalls)
+	d.Set("api_calls_total", total)
+	d.Set("summary", metrics.summary())
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package kustomize
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	dsschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/kbst/terraform-provider-kustomize/manifest"
+)
+
+// objectsDataSource decodes manifests into objects
+//
+// Implemented with the plugin framework, plugin SDK attributes
+// require a fixed type, decoded manifests have different types.
+type objectsDataSource struct{}
+
+func newObjectsDataSource() datasource.DataSource {
+	return &objectsDataSource{}
+}
+
+type objectsDataSourceModel struct {
+	Manifests types.Map     `tfsdk:"manifests"`
+	Objects   types.Dynamic `tfsdk:"objects"`
+}
+
+func (d *objectsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_objects"
+}
+
+func (d *objectsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = dsschema.Schema{
+		Description: "Decodes manifests, e.g. of the build data sources, into objects by ID.",
+		Attributes: map[string]dsschema.Attribute{
+			"manifests": dsschema.MapAttribute{
+				Description: "Map of JSON or YAML encoded manifests by ID.",
+				ElementType: types.StringType,
+				Required:    true,
+			},
+			"objects": dsschema.DynamicAttribute{
+				Description: "Object of the decoded manifests by ID.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *objectsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data objectsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var manifests map[string]string
+	resp.Diagnostics.Append(data.Manifests.ElementsAs(ctx, &manifests, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	attrTypes := make(map[string]attr.Type, len(ids))
+	attrs := make(map[string]attr.Value, len(ids))
+	for _, id := range ids {
+		u, err := manifest.Parse([]byte(manifests[id]))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("manifests").AtMapKey(id), "Invalid manifest", err.Error())
+			continue
+		}
+
+		v, err := manifestValue(u.Object)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("manifests").AtMapKey(id), "Invalid manifest", err.Error())
+			continue
+		}
+
+		attrTypes[id] = v.Type(ctx)
+		attrs[id] = v
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	objects, diags := types.ObjectValue(attrTypes, attrs)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Objects = types.DynamicValue(objects)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,71 @@
+package kustomize
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func readObjectsDataSource(manifests map[string]string) *datasource.ReadResponse {
+	ctx := context.Background()
+	ds := newObjectsDataSource()
+
+	var schemaResp datasource.SchemaResponse
+	ds.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	s := schemaResp.Schema
+	typ := s.Type().TerraformType(ctx)
+
+	values := make(map[string]tftypes.Value, len(manifests))
+	for id, m := range manifests {
+		values[id] = tftypes.NewValue(tftypes.String, m)
+	}
+	config := tfsdk.Config{
+		Schema: s,
+		Raw: tftypes.NewValue(typ, map[string]tftypes.Value{
+			"manifests": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, values),
+			"objects":   tftypes.NewValue(tftypes.DynamicPseudoType, nil),
+		}),
+	}
+
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{Schema: s, Raw: tftypes.NewValue(typ, nil)},
+	}
+	ds.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+
+	return resp
+}
+
+func TestObjectsDataSource(t *testing.T) {
+	resp := readObjectsDataSource(map[string]string{
+		"_/ConfigMap/_/test": testFunctionManifest,
+		"_/Service/_/test":   "apiVersion: v1\nkind: Service\nmetadata:\n  name: test\nspec:\n  ports:\n  - port: 80\n",
+	})
+	assert.Equal(t, false, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var data objectsDataSourceModel
+	resp.State.Get(context.Background(), &data)
+
+	objects := data.Objects.UnderlyingValue().(types.Object).Attributes()
+	assert.Equal(t, 2, len(objects), nil)
+
+	cm := objects["_/ConfigMap/_/test"].(types.Object)
+	assert.Equal(t, types.StringValue("ConfigMap"), cm.Attributes()["kind"], nil)
+
+	// objects of different kinds have different types
+	svc := objects["_/Service/_/test"].(types.Object)
+	ports := svc.Attributes()["spec"].(types.Object).Attributes()["ports"].(types.Tuple)
+	port := ports.Elements()[0].(types.Object).Attributes()["port"]
+	assert.Equal(t, attr.Value(types.NumberValue(new(big.Float).SetInt64(80))), port, nil)
+
+	resp = readObjectsDataSource(map[string]string{
+		"_/ConfigMap/_/test": "kind: ConfigMap\n",
+	})
+	assert.Equal(t, true, resp.Diagnostics.HasError(), nil)
+}
@@ -2,11 +2,14 @@ package kustomize
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
@@ -76,6 +79,126 @@ func getPatchOptionsSchema() *schema.Resource {
 	}
 }
 
+func getPatchTargetSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"group": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"version": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kind": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"label_selector": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"annotation_selector": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func getImagesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"new_name": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"new_tag": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"digest": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+			},
+		},
+	}
+}
+
+func getPatchesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"options": {
+					Type:     schema.TypeList,
+					MaxItems: 1,
+					Optional: true,
+					Elem:     getPatchOptionsSchema(),
+				},
+				"path": {
+					Type:     schema.TypeString,
+					Optional: true,
+					//ConflictsWith: []string{"patch"},
+				},
+				"patch": {
+					Type:     schema.TypeString,
+					Optional: true,
+					//ConflictsWith: []string{"path"},
+				},
+				"vars": {
+					Type:         schema.TypeMap,
+					Optional:     true,
+					Elem:         &schema.Schema{Type: schema.TypeString},
+					ValidateFunc: validatePatchVars,
+				},
+				"target": {
+					Type:     schema.TypeList,
+					Optional: true,
+					MaxItems: 1,
+					Elem:     getPatchTargetSchema(),
+				},
+			},
+		},
+	}
+}
+
+func getReplicasSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"count": {
+					Type:     schema.TypeInt,
+					Optional: true,
+				},
+			},
+		},
+	}
+}
+
 func getReplacementSelectorSchema() *schema.Resource {
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
@@ -130,7 +253,7 @@ func getPatchOptions(o map[string]interface{}) map[string]bool {
 
 func dataSourceKustomizationOverlay() *schema.Resource {
 	return &schema.Resource{
-		Read: kustomizationOverlay,
+		ReadContext: kustomizationOverlay,
 
 		// support almost all attributes available in a Kustomization
 		//
@@ -166,6 +289,14 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 					},
 				},
 			},
+			"build_metadata": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice(types.BuildMetadataOptions, false),
+				},
+			},
 			"components": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
@@ -209,6 +340,18 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 							Optional: true,
 							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
+						"data_json": {
+							Type:         schema.TypeMap,
+							Optional:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateStructuredData,
+						},
+						"data_yaml": {
+							Type:         schema.TypeMap,
+							Optional:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateStructuredData,
+						},
 						"options": {
 							Type:     schema.TypeList,
 							MaxItems: 1,
@@ -238,30 +381,7 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 				Optional: true,
 				Elem:     getGeneratorOptionsSchema(),
 			},
-			"images": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"name": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"new_name": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"new_tag": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"digest": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-					},
-				},
-			},
+			"images": getImagesSchema(),
 			"name_prefix": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
@@ -274,67 +394,8 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
-			"patches": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"options": {
-							Type:     schema.TypeList,
-							MaxItems: 1,
-							Optional: true,
-							Elem:     getPatchOptionsSchema(),
-						},
-						"path": {
-							Type:     schema.TypeString,
-							Optional: true,
-							//ConflictsWith: []string{"patch"},
-						},
-						"patch": {
-							Type:     schema.TypeString,
-							Optional: true,
-							//ConflictsWith: []string{"path"},
-						},
-						"target": {
-							Type:     schema.TypeList,
-							Optional: true,
-							MaxItems: 1,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"group": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"version": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"kind": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"name": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"namespace": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"label_selector": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-									"annotation_selector": {
-										Type:     schema.TypeString,
-										Optional: true,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+			"overlays": overlaysSchema(),
+			"patches":  getPatchesSchema(),
 			"replacements": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
@@ -416,22 +477,7 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 					},
 				},
 			},
-			"replicas": &schema.Schema{
-				Type:     schema.TypeList,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"name": {
-							Type:     schema.TypeString,
-							Optional: true,
-						},
-						"count": {
-							Type:     schema.TypeInt,
-							Optional: true,
-						},
-					},
-				},
-			},
+			"replicas": getReplicasSchema(),
 			"resources": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
@@ -504,6 +550,25 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 					},
 				},
 			},
+			"sealed_secrets": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"certificate": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"scope": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      sealedSecretsScopeStrict,
+							ValidateFunc: validation.StringInSlice([]string{sealedSecretsScopeStrict, sealedSecretsScopeNamespaceWide, sealedSecretsScopeClusterWide}, false),
+						},
+					},
+				},
+			},
 			"secret_generator": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
@@ -628,11 +693,42 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 					Set:  idSetHash,
 				},
 			},
+			"generated_ids": idsSchema(),
+			"static_ids":    idsSchema(),
+			"build_duration_ms": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"resource_count": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
 			"manifests": &schema.Schema{
 				Type:     schema.TypeMap,
 				Computed: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
+			"by_kind":      groupedResourcesSchema("kind"),
+			"by_namespace": groupedResourcesSchema("namespace"),
+			"origins":      originsSchema(),
+			"namespaces": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"create_namespaces": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"strip_annotations": stripPatternsSchema(),
+			"strip_labels":      stripPatternsSchema(),
+			"namespace_filter":  namespaceFilterSchema(),
+			"validate":          validateModeSchema(),
+			"validate_cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 			"kustomize_options": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
@@ -663,6 +759,24 @@ func dataSourceKustomizationOverlay() *schema.Resource {
 							Type:     schema.TypeString,
 							Optional: true,
 						},
+						"openapi_from_cluster": {
+							Type:     schema.TypeBool,
+							Optional: true,
+						},
+						"openapi_cluster": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"disable_name_suffix_hash": {
+							Type:     schema.TypeBool,
+							Optional: true,
+						},
+						"plugin_env": {
+							Type:      schema.TypeMap,
+							Optional:  true,
+							Sensitive: true,
+							Elem:      &schema.Schema{Type: schema.TypeString},
+						},
 					},
 				},
 			},
@@ -709,6 +823,87 @@ func convertListInterfaceFirstItemToReplacementOptions(in []interface{}) (out *t
 	return out
 }
 
+func getPatches(ps []interface{}) (patches []types.Patch) {
+	for i := range ps {
+		if ps[i] == nil {
+			continue
+		}
+
+		p := ps[i].(map[string]interface{})
+		kp := types.Patch{}
+
+		kp.Path, _ = p["path"].(string)
+		kp.Patch = p["patch"].(string)
+
+		if vars, ok := p["vars"].(map[string]interface{}); ok {
+			kp.Patch = substitutePatchVars(kp.Patch, convertMapStringInterfaceToMapStringString(vars))
+		}
+
+		t := convertMapStringInterfaceToMapStringString(
+			convertListInterfaceFirstItemToMapStringInterface(
+				p["target"].([]interface{}),
+			),
+		)
+
+		if len(t) > 0 {
+			kp.Target = &types.Selector{}
+			kp.Target.Group = t["group"]
+			kp.Target.Version = t["version"]
+			kp.Target.Kind = t["kind"]
+			kp.Target.Name = t["name"]
+			kp.Target.Namespace = t["namespace"]
+			kp.Target.AnnotationSelector = t["annotation_selector"]
+			kp.Target.LabelSelector = t["label_selector"]
+		}
+		o := p["options"].([]interface{})
+		if len(o) == 1 && o[0] != nil {
+			kp.Options = getPatchOptions(o[0].(map[string]interface{}))
+		}
+
+		patches = append(patches, kp)
+	}
+
+	return patches
+}
+
+func getImages(imgs []interface{}) (images []types.Image) {
+	for i := range imgs {
+		if imgs[i] == nil {
+			continue
+		}
+
+		img := imgs[i].(map[string]interface{})
+		kimg := types.Image{}
+
+		kimg.Name = img["name"].(string)
+		kimg.NewName = img["new_name"].(string)
+		kimg.NewTag = img["new_tag"].(string)
+		kimg.Digest = img["digest"].(string)
+
+		images = append(images, kimg)
+	}
+
+	return images
+}
+
+func getReplicas(rs []interface{}) (replicas []types.Replica) {
+	for i := range rs {
+		if rs[i] == nil {
+			continue
+		}
+
+		img := rs[i].(map[string]interface{})
+		r := types.Replica{}
+
+		r.Name = img["name"].(string)
+		r.Count = int64(img["count"].(int))
+
+		replicas = append(replicas, r)
+	}
+
+	return replicas
+}
+
 func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 	k.TypeMeta = types.TypeMeta{
 		APIVersion: "kustomize.config.k8s.io/v1beta1",
@@ -745,6 +940,12 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 		}
 	}
 
+	if d.Get("build_metadata") != nil {
+		k.BuildMetadata = convertListInterfaceToListString(
+			d.Get("build_metadata").([]interface{}),
+		)
+	}
+
 	if d.Get("components") != nil {
 		k.Components = convertListInterfaceToListString(
 			d.Get("components").([]interface{}),
@@ -774,6 +975,12 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 				cmg["literals"].([]interface{}),
 			)
 
+			// validated by the schema, errors are not possible here
+			dataJSON, _ := getStructuredDataLiterals(cmg["data_json"].(map[string]interface{}), false)
+			cma.LiteralSources = append(cma.LiteralSources, dataJSON...)
+			dataYAML, _ := getStructuredDataLiterals(cmg["data_yaml"].(map[string]interface{}), true)
+			cma.LiteralSources = append(cma.LiteralSources, dataYAML...)
+
 			cma.FileSources = convertListInterfaceToListString(
 				cmg["files"].([]interface{}),
 			)
@@ -815,60 +1022,11 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 	}
 
 	if d.Get("images") != nil {
-		imgs := d.Get("images").([]interface{})
-		for i := range imgs {
-			if imgs[i] == nil {
-				continue
-			}
-
-			img := imgs[i].(map[string]interface{})
-			kimg := types.Image{}
-
-			kimg.Name = img["name"].(string)
-			kimg.NewName = img["new_name"].(string)
-			kimg.NewTag = img["new_tag"].(string)
-			kimg.Digest = img["digest"].(string)
-
-			k.Images = append(k.Images, kimg)
-		}
+		k.Images = getImages(d.Get("images").([]interface{}))
 	}
 
 	if d.Get("patches") != nil {
-		ps := d.Get("patches").([]interface{})
-		for i := range ps {
-			if ps[i] == nil {
-				continue
-			}
-
-			p := ps[i].(map[string]interface{})
-			kp := types.Patch{}
-
-			kp.Path = p["path"].(string)
-			kp.Patch = p["patch"].(string)
-
-			t := convertMapStringInterfaceToMapStringString(
-				convertListInterfaceFirstItemToMapStringInterface(
-					p["target"].([]interface{}),
-				),
-			)
-
-			if len(t) > 0 {
-				kp.Target = &types.Selector{}
-				kp.Target.Group = t["group"]
-				kp.Target.Version = t["version"]
-				kp.Target.Kind = t["kind"]
-				kp.Target.Name = t["name"]
-				kp.Target.Namespace = t["namespace"]
-				kp.Target.AnnotationSelector = t["annotation_selector"]
-				kp.Target.LabelSelector = t["label_selector"]
-			}
-			o := p["options"].([]interface{})
-			if len(o) == 1 && o[0] != nil {
-				kp.Options = getPatchOptions(o[0].(map[string]interface{}))
-			}
-
-			k.Patches = append(k.Patches, kp)
-		}
+		k.Patches = getPatches(d.Get("patches").([]interface{}))
 	}
 
 	if d.Get("replacements") != nil {
@@ -944,20 +1102,7 @@ func getKustomization(d *schema.ResourceData) (k types.Kustomization) {
 	}
 
 	if d.Get("replicas") != nil {
-		rs := d.Get("replicas").([]interface{})
-		for i := range rs {
-			if rs[i] == nil {
-				continue
-			}
-
-			img := rs[i].(map[string]interface{})
-			r := types.Replica{}
-
-			r.Name = img["name"].(string)
-			r.Count = int64(img["count"].(int))
-
-			k.Replicas = append(k.Replicas, r)
-		}
+		k.Replicas = getReplicas(d.Get("replicas").([]interface{}))
 	}
 
 	if d.Get("name_prefix") != nil {
@@ -1104,9 +1249,28 @@ func refuseExistingKustomization(fSys filesys.FileSystem) error {
 	return nil
 }
 
-func kustomizationOverlay(d *schema.ResourceData, m interface{}) error {
+func kustomizationOverlay(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	readStart := time.Now()
+
 	k := getKustomization(d)
 
+	// add the images, patches and replicas of the selected environment
+	err := addOverlayEnvironment(&k, d.Get("overlays").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = resolveVaultLiterals(&k, m.(*Config).Vault)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// use the schemas of CRDs to merge and validate custom resources
+	crdFiles, crds, err := loadCRDs(filesys.MakeFsOnDisk(), &k)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+	}
+
 	var b bytes.Buffer
 	ye := yaml.NewEncoder(io.Writer(&b))
 	ye.Encode(k)
@@ -1116,27 +1280,94 @@ func kustomizationOverlay(d *schema.ResourceData, m interface{}) error {
 	fSys, tmp, err := makeOverlayFS(filesys.MakeFsOnDisk())
 	defer os.RemoveAll(tmp)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	// error if the current working directory is already a Kustomization
 	err = refuseExistingKustomization(fSys)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
+	}
+
+	// decrypt SOPS encrypted generator sources in memory
+	fSys = makeSOPSFS(fSys, getGeneratorSources(k), m.(*Config).Sops)
+
+	// only run allowed exec KRM functions
+	fSys = makeExecFunctionsFS(fSys, m.(*Config).ExecFunctions, getKustomizeOptions(d))
+
+	// expose the plugin_env to KRM functions
+	pluginEnv := getPluginEnv(d)
+	fSys = makePluginEnvFS(fSys, pluginEnv)
+
+	fSys, err = makeCRDFS(fSys, ".", crdFiles)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+	}
+
+	// merge patches using the OpenAPI schema of the cluster
+	openAPISchema, err := getClusterOpenAPISchema(ctx, d, m)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+	}
+
+	fSys, err = makeOpenAPIFS(fSys, ".", openAPISchema)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
 	}
 
 	fSys.WriteFile(KFILENAME, data)
 	defer fSys.RemoveAll(KFILENAME)
 
-	// mutex as tmp workaround for upstream bug
-	// https://github.com/kubernetes-sigs/kustomize/issues/3659
-	mu := m.(*Config).Mutex
-	mu.Lock()
+	// the CRD schemas are added to the global OpenAPI schema
+	exclusive := len(crds) > 0 || openAPISchema != nil || len(pluginEnv) > 0 || isExclusiveBuild(fSys, ".", getKustomizeOptions(d))
+	unlock, err := m.(*Config).BuildLock.lock(ctx, exclusive)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer unlock()
+
+	defer setPluginEnv(pluginEnv)()
+
+	err = addCRDOpenAPISchema(crds)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+	}
+	defer resetCRDOpenAPISchema(crds)
+
+	start := time.Now()
 	rm, err := runKustomizeBuild(fSys, ".", d)
-	mu.Unlock()
+	m.(*Config).Metrics.addBuild("kustomization_overlay", time.Since(start))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+	}
+
+	err = validateCustomResources(rm, crds)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+	}
+
+	sc, err := getSealedSecretsConfig(d.Get("sealed_secrets").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if sc != nil {
+		err = sealSecrets(rm, sc)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
+		}
+	}
+
+	err = setGeneratedAttributes(d, rm, m.(*Config).BuildLimits)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	setBuildMetrics(d, rm, readStart)
+
+	err = serverValidate(d, m, rm)
 	if err != nil {
-		return fmt.Errorf("buildKustomizeOverlay: %s", err)
+		return diag.FromErr(fmt.Errorf("buildKustomizeOverlay: %s", err))
 	}
 
-	return setGeneratedAttributes(d, rm)
+	return patchTargetWarnings(fSys, ".", rm)
 }
@@ -298,6 +298,47 @@ output "check_cm2" {
 `
 }
 
+// Test config_map_generator data_json and data_yaml attrs
+func TestDataSourceKustomizationOverlay_configMapGeneratorStructuredData(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testKustomizationConfigMapGeneratorStructuredDataConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckOutput("check_cm", "{\"apiVersion\":\"v1\",\"data\":{\"config.json\":\"{\\n  \\\"enabled\\\": true\\n}\\n\",\"config.yaml\":\"name: example\\nports:\\n- 80\\n- 443\\n\"},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test-configmap\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testKustomizationConfigMapGeneratorStructuredDataConfig() string {
+	return `
+data "kustomization_overlay" "test" {
+	config_map_generator {
+		name = "test-configmap"
+		data_json = {
+			"config.json" = jsonencode({ enabled = true })
+		}
+		data_yaml = {
+			"config.yaml" = jsonencode({ name = "example", ports = [80, 443] })
+		}
+
+		options {
+			disable_name_suffix_hash = true
+		}
+	}
+}
+
+output "check_cm" {
+	value = data.kustomization_overlay.test.manifests["_/ConfigMap/_/test-configmap"]
+}
+`
+}
+
 // Test namespace attr
 func TestDataSourceKustomizationOverlay_namespace(t *testing.T) {
 
@@ -441,10 +482,13 @@ func TestDataSourceKustomizationOverlay_crds(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config: testDataSourceKustomizationOverlayConfig_crds(),
-				// we only need to validate we pass the value to Kustomize
-				// this test verifies that the path by providing an invalid OpenAPI spec
-				// the Kustomize error proves the path was passed correctly
-				ExpectError: regexp.MustCompile("json: cannot unmarshal string into Go value of type accumulator.OpenAPIDefinition"),
+				// the scope of the CRDs determines which
+				// custom resources get the namespace set
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_overlay.test", "ids.#", "2"),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_overlay.test", "ids.*", "test.example.com/Namespacedcrd/test-overlay-crds/namespacedco"),
+					resource.TestCheckTypeSetElemAttr("data.kustomization_overlay.test", "ids.*", "test.example.com/Clusteredcrd/_/clusteredco"),
+				),
 			},
 		},
 	})
@@ -452,10 +496,56 @@ func TestDataSourceKustomizationOverlay_crds(t *testing.T) {
 
 func testDataSourceKustomizationOverlayConfig_crds() string {
 	return `
+data "kustomization_overlay" "test" {
+	namespace = "test-overlay-crds"
+
+	crds = [
+		"test_kustomizations/crd/initial/crd.yaml",
+	]
+
+	resources = [
+		"test_kustomizations/crd/initial/co.yaml",
+	]
+}
+`
+}
+
+// Test crds attr validates custom resources
+func TestDataSourceKustomizationOverlay_crdsInvalid(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testDataSourceKustomizationOverlayConfig_crdsInvalid(),
+				ExpectError: regexp.MustCompile(`"test.example.com/Namespacedcrd/test-crd/namespacedco" is invalid: Namespacedcrd.spec.test-key: must be a string`),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationOverlayConfig_crdsInvalid() string {
+	return `
 data "kustomization_overlay" "test" {
 	crds = [
 		"test_kustomizations/crd/initial/crd.yaml",
 	]
+
+	resources = [
+		"test_kustomizations/crd/initial/co.yaml",
+	]
+
+	patches {
+		patch = <<-EOF
+			- op: replace
+			  path: /spec/test-key
+			  value: 1
+		EOF
+		target {
+			kind = "Namespacedcrd"
+		}
+	}
 }
 `
 }
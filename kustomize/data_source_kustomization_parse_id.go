@@ -0,0 +1,54 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceKustomizationParseID() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationParseID,
+
+		Schema: map[string]*schema.Schema{
+			"resource_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"group": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"kind": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func kustomizationParseID(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	kr, err := parseProviderId(d.Get("resource_id").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationParseID: %s", err))
+	}
+
+	d.Set("group", kr.group)
+	d.Set("kind", kr.kind)
+	d.Set("namespace", kr.namespace)
+	d.Set("name", kr.name)
+
+	d.SetId(kr.string())
+
+	return nil
+}
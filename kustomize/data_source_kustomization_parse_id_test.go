@@ -0,0 +1,62 @@
+package kustomize
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// Basic acceptance test
+func TestDataSourceKustomizationParseID_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationParseIDConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.deployment", "group", "apps"),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.deployment", "kind", "Deployment"),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.deployment", "namespace", "test-ns"),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.deployment", "name", "test"),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.namespace", "group", ""),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.namespace", "kind", "Namespace"),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.namespace", "namespace", ""),
+					resource.TestCheckResourceAttr("data.kustomization_parse_id.namespace", "name", "test-ns"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationParseIDConfig_basic() string {
+	return `
+data "kustomization_parse_id" "deployment" {
+	resource_id = "apps/Deployment/test-ns/test"
+}
+
+data "kustomization_parse_id" "namespace" {
+	resource_id = "_/Namespace/_/test-ns"
+}
+`
+}
+
+func TestDataSourceKustomizationParseID_invalid(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "kustomization_parse_id" "test" {
+	resource_id = "apps_v1_Deployment|test-ns|test"
+}
+`,
+				ExpectError: regexp.MustCompile("invalid ID"),
+			},
+		},
+	})
+}
@@ -0,0 +1,165 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func dataSourceKustomizationPatch() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationPatch,
+
+		Schema: map[string]*schema.Schema{
+			"resources": &schema.Schema{
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"patches": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"options": {
+							Type:     schema.TypeList,
+							MaxItems: 1,
+							Optional: true,
+							Elem:     getPatchOptionsSchema(),
+						},
+						"patch": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"vars": {
+							Type:         schema.TypeMap,
+							Optional:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validatePatchVars,
+						},
+						"target": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem:     getPatchTargetSchema(),
+						},
+					},
+				},
+			},
+			"ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      idSetHash,
+			},
+			"ids_prio": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeSet,
+					Set:  idSetHash,
+				},
+			},
+			"generated_ids": idsSchema(),
+			"static_ids":    idsSchema(),
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"by_kind":      groupedResourcesSchema("kind"),
+			"by_namespace": groupedResourcesSchema("namespace"),
+			"origins":      originsSchema(),
+			"namespaces": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"create_namespaces": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"strip_annotations": stripPatternsSchema(),
+			"strip_labels":      stripPatternsSchema(),
+			"namespace_filter":  namespaceFilterSchema(),
+		},
+	}
+}
+
+// makePatchFS writes the resources and a Kustomization patching them
+// to an in memory file system, patches can not reference files on disk
+func makePatchFS(resources []string, patches []types.Patch) (filesys.FileSystem, error) {
+	fSys := filesys.MakeFsInMemory()
+
+	k := types.Kustomization{
+		Patches: patches,
+	}
+	k.FixKustomizationPostUnmarshalling()
+
+	for i, r := range resources {
+		name := fmt.Sprintf("resources_%d.yaml", i)
+
+		err := fSys.WriteFile(filepath.Join(filesys.Separator, name), []byte(r))
+		if err != nil {
+			return nil, err
+		}
+
+		k.Resources = append(k.Resources, name)
+	}
+
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fSys.WriteFile(filepath.Join(filesys.Separator, konfig.DefaultKustomizationFileName()), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return fSys, nil
+}
+
+// runKustomizeInMemory patches the resources without
+// reading from or writing to the file system
+func runKustomizeInMemory(ctx context.Context, m interface{}, resources []string, patches []types.Patch) (resmap.ResMap, error) {
+	fSys, err := makePatchFS(resources, patches)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := m.(*Config).BuildLock.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	resetCustomOpenAPISchema()
+
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	return k.Run(fSys, filesys.Separator)
+}
+
+func kustomizationPatch(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	resources := convertListInterfaceToListString(d.Get("resources").([]interface{}))
+	patches := getPatches(d.Get("patches").([]interface{}))
+
+	rm, err := runKustomizeInMemory(ctx, m, resources, patches)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationPatch: %s", err))
+	}
+
+	return diag.FromErr(setGeneratedAttributes(d, rm, m.(*Config).BuildLimits))
+}
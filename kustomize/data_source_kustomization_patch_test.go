@@ -0,0 +1,106 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const testPatchResources = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: test-patch
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: test-patch
+data:
+  key: value
+`
+
+func TestMakePatchFS(t *testing.T) {
+	patches := getPatches([]interface{}{
+		map[string]interface{}{
+			"patch": `[{"op": "replace", "path": "/data/key", "value": "patched"}]`,
+			"target": []interface{}{
+				map[string]interface{}{
+					"kind": "ConfigMap",
+					"name": "test",
+				},
+			},
+			"options": []interface{}{},
+		},
+	})
+
+	fSys, err := makePatchFS([]string{testPatchResources}, patches)
+	assert.Equal(t, nil, err, nil)
+
+	rm, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, filesys.Separator)
+	assert.Equal(t, nil, err, nil)
+
+	res, _, err := flattenBuild(rm, buildLimits{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 2, len(res), nil)
+	assert.Contains(t, res["_/ConfigMap/test-patch/test"], `"key":"patched"`, nil)
+}
+
+// Basic acceptance test
+func TestDataSourceKustomizationPatch_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationPatchConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_patch.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_patch.test", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_patch.test", "ids_prio.#", "3"),
+					resource.TestCheckResourceAttr("data.kustomization_patch.test", "manifests.%", "2"),
+					resource.TestCheckResourceAttr("data.kustomization_patch.test", "manifests._/ConfigMap/test-patch/test", "{\"apiVersion\":\"v1\",\"data\":{\"key\":\"patched\"},\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test\",\"namespace\":\"test-patch\"}}"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationPatchConfig_basic() string {
+	return `
+data "kustomization_patch" "test" {
+	resources = [
+		<<-EOF
+		apiVersion: v1
+		kind: Namespace
+		metadata:
+		  name: test-patch
+		---
+		apiVersion: v1
+		kind: ConfigMap
+		metadata:
+		  name: test
+		  namespace: test-patch
+		data:
+		  key: value
+		EOF
+	]
+
+	patches {
+		patch = <<-EOF
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  name: test
+			  namespace: test-patch
+			data:
+			  key: patched
+		EOF
+	}
+}
+`
+}
@@ -0,0 +1,127 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func policyResultSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"id": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"policy": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"message": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceKustomizationPolicy() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationPolicy,
+
+		Schema: map[string]*schema.Schema{
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"policies": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"namespaces": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"fail_on_violations": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"valid": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"violations": policyResultSchema(),
+			"warnings":   policyResultSchema(),
+		},
+	}
+}
+
+func kustomizationPolicy(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	sources := d.Get("policies").(map[string]interface{})
+	namespaces := convertListInterfaceToListString(d.Get("namespaces").([]interface{}))
+	policies, err := loadPolicies(ctx, sources, namespaces)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationPolicy: %s", err))
+	}
+
+	manifests := d.Get("manifests").(map[string]interface{})
+	results, err := evaluatePolicies(ctx, policies, manifests)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationPolicy: %s", err))
+	}
+
+	violations := results[policyRuleDeny]
+	if d.Get("fail_on_violations").(bool) && len(violations) > 0 {
+		var msgs []string
+		for _, v := range violations {
+			msgs = append(msgs, fmt.Sprintf("%q violates policy %q: %s", v.id, v.policy, v.message))
+		}
+		return diag.FromErr(fmt.Errorf("kustomizationPolicy: %s", strings.Join(msgs, "; ")))
+	}
+
+	d.Set("valid", len(violations) == 0)
+	d.Set("violations", flattenPolicyResults(violations))
+	d.Set("warnings", flattenPolicyResults(results[policyRuleWarn]))
+
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	names := make([]string, 0, len(sources))
+	for n := range sources {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	h := sha512.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte(manifests[id].(string)))
+	}
+	for _, n := range names {
+		h.Write([]byte(n))
+		h.Write([]byte(sources[n].(string)))
+	}
+	for _, ns := range namespaces {
+		h.Write([]byte(ns))
+	}
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
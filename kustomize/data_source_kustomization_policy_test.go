@@ -0,0 +1,65 @@
+package kustomize
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// Basic acceptance test
+func TestDataSourceKustomizationPolicy_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationPolicyConfig_basic(false),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_policy.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_policy.test", "valid", "false"),
+					resource.TestCheckResourceAttr("data.kustomization_policy.test", "violations.#", "1"),
+					resource.TestCheckResourceAttr("data.kustomization_policy.test", "violations.0.id", "_/Namespace/_/test-basic"),
+					resource.TestCheckResourceAttr("data.kustomization_policy.test", "violations.0.policy", "main.deny"),
+					resource.TestCheckResourceAttr("data.kustomization_policy.test", "violations.0.message", "namespace test-basic has no owner label"),
+				),
+			},
+			{
+				Config:      testDataSourceKustomizationPolicyConfig_basic(true),
+				ExpectError: regexp.MustCompile("\"_/Namespace/_/test-basic\" violates policy \"main.deny\""),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationPolicyConfig_basic(fail bool) string {
+	failOnViolations := "false"
+	if fail {
+		failOnViolations = "true"
+	}
+
+	return `
+data "kustomization_build" "test" {
+	path = "test_kustomizations/basic/initial"
+}
+
+data "kustomization_policy" "test" {
+	manifests = data.kustomization_build.test.manifests
+
+	policies = {
+		"namespaces.rego" = <<EOT
+package main
+
+deny[msg] {
+	input.kind == "Namespace"
+	not input.metadata.labels.owner
+	msg := sprintf("namespace %s has no owner label", [input.metadata.name])
+}
+EOT
+	}
+
+	fail_on_violations = ` + failOnViolations + `
+}
+`
+}
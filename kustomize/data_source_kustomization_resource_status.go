@@ -0,0 +1,161 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func dataSourceKustomizationResourceStatus() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationResourceStatus,
+
+		Schema: map[string]*schema.Schema{
+			"resource_id": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ExactlyOneOf:  []string{"resource_id", "kind"},
+				ConflictsWith: []string{"group", "version", "namespace", "name"},
+			},
+			"group": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"version": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kind": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"name"},
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"exists": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"uid": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"manifest": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"status": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func getStatusResourceId(d *schema.ResourceData) (*kManifestId, error) {
+	if id := d.Get("resource_id").(string); id != "" {
+		return parseProviderId(id)
+	}
+
+	return &kManifestId{
+		group:     d.Get("group").(string),
+		kind:      d.Get("kind").(string),
+		namespace: d.Get("namespace").(string),
+		name:      d.Get("name").(string),
+	}, nil
+}
+
+func kustomizationResourceStatus(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	k, err := getStatusResourceId(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(k.string())
+
+	gk := k8sschema.GroupKind{Group: k.group, Kind: k.kind}
+
+	var versions []string
+	if v := d.Get("version").(string); v != "" {
+		versions = append(versions, v)
+	}
+
+	mapping, err := mapper.RESTMapping(gk, versions...)
+	if k8smeta.IsNoMatchError(err) {
+		// the kind is not available, e.g. the CRD is not installed
+		return diag.FromErr(setResourceStatus(d, nil))
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("api error %q: %s", k.string(), err))
+	}
+
+	api := client.Resource(mapping.Resource)
+
+	var resp *k8sunstructured.Unstructured
+	if mapping.Scope.Name() == k8smeta.RESTScopeNameNamespace {
+		resp, err = api.Namespace(k.namespace).Get(ctx, k.name, k8smetav1.GetOptions{})
+	} else {
+		resp, err = api.Get(ctx, k.name, k8smetav1.GetOptions{})
+	}
+	if k8serrors.IsNotFound(err) {
+		return diag.FromErr(setResourceStatus(d, nil))
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("%q: %s", k.string(), err))
+	}
+
+	return diag.FromErr(setResourceStatus(d, resp))
+}
+
+func setResourceStatus(d *schema.ResourceData, u *k8sunstructured.Unstructured) error {
+	if u == nil {
+		d.Set("exists", false)
+		d.Set("uid", "")
+		d.Set("manifest", "")
+		d.Set("status", "")
+		return nil
+	}
+
+	manifest, err := u.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var status []byte
+	if s, ok := u.Object["status"]; ok {
+		status, err = json.Marshal(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.Set("exists", true)
+	d.Set("uid", string(u.GetUID()))
+	d.Set("manifest", string(manifest))
+	d.Set("status", string(status))
+
+	return nil
+}
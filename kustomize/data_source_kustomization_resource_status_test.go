@@ -0,0 +1,49 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceKustomizationResourceStatus_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceKustomizationResourceStatusConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_resource_status.ns", "exists", "true"),
+					resource.TestCheckResourceAttrSet("data.kustomization_resource_status.ns", "uid"),
+					resource.TestCheckResourceAttrSet("data.kustomization_resource_status.ns", "manifest"),
+					resource.TestCheckResourceAttr("data.kustomization_resource_status.ns", "status", "{\"phase\":\"Active\"}"),
+					resource.TestCheckResourceAttr("data.kustomization_resource_status.missing", "exists", "false"),
+					resource.TestCheckResourceAttr("data.kustomization_resource_status.missing", "manifest", ""),
+					resource.TestCheckResourceAttr("data.kustomization_resource_status.crd", "exists", "false"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceKustomizationResourceStatusConfig_basic() string {
+	return `
+data "kustomization_resource_status" "ns" {
+	resource_id = "_/Namespace/_/default"
+}
+
+data "kustomization_resource_status" "missing" {
+	kind      = "ConfigMap"
+	namespace = "default"
+	name      = "does-not-exist"
+}
+
+data "kustomization_resource_status" "crd" {
+	group = "example.com"
+	kind  = "DoesNotExist"
+	name  = "test"
+}
+`
+}
@@ -0,0 +1,150 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"sigs.k8s.io/kustomize/kyaml/openapi/kubernetesapi"
+)
+
+func dataSourceKustomizationValidate() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationValidate,
+
+		Schema: map[string]*schema.Schema{
+			"manifests": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"kubernetes_version": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validation.StringInSlice(builtinSchemaVersions(), false),
+				ConflictsWith: []string{"openapi_schema"},
+			},
+			"openapi_schema": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"kubernetes_version"},
+			},
+			"crds": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ignore_missing_schemas": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"fail_on_invalid": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"valid": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"violations": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"errors": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func kustomizationValidate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	s := []byte(d.Get("openapi_schema").(string))
+	if len(s) == 0 {
+		version := d.Get("kubernetes_version").(string)
+		if version == "" {
+			version = kubernetesapi.DefaultOpenAPI
+		}
+
+		var err error
+		s, err = getBuiltinSchema(version)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationValidate: %s", err))
+		}
+	}
+
+	var crds []crdSchema
+	for _, c := range convertListInterfaceToListString(d.Get("crds").([]interface{})) {
+		cs, err := parseCRDs([]byte(c))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationValidate: %s", err))
+		}
+		crds = append(crds, cs...)
+	}
+
+	ms, err := makeManifestSchemas(s, crds)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationValidate: %s", err))
+	}
+
+	manifests := d.Get("manifests").(map[string]interface{})
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ignoreMissingSchemas := d.Get("ignore_missing_schemas").(bool)
+
+	var violations []interface{}
+	var invalid []string
+	for _, id := range ids {
+		errs, err := ms.validate(manifests[id].(string), ignoreMissingSchemas)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationValidate: %q: %s", id, err))
+		}
+
+		if len(errs) == 0 {
+			continue
+		}
+
+		violations = append(violations, map[string]interface{}{
+			"id":     id,
+			"errors": errs,
+		})
+		invalid = append(invalid, fmt.Sprintf("%q is invalid: %s", id, strings.Join(errs, ", ")))
+	}
+
+	if d.Get("fail_on_invalid").(bool) && len(invalid) > 0 {
+		return diag.FromErr(fmt.Errorf("kustomizationValidate: %s", strings.Join(invalid, "; ")))
+	}
+
+	d.Set("valid", len(violations) == 0)
+	d.Set("violations", violations)
+
+	h := sha512.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte(manifests[id].(string)))
+	}
+	d.SetId(hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
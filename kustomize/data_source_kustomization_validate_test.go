@@ -0,0 +1,146 @@
+package kustomize
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// Basic acceptance test
+func TestDataSourceKustomizationValidate_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationValidateConfig_basic(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.kustomization_validate.test", "id"),
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "valid", "true"),
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "violations.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationValidateConfig_basic() string {
+	return `
+data "kustomization_build" "test" {
+	path = "test_kustomizations/basic/initial"
+}
+
+data "kustomization_validate" "test" {
+	manifests = data.kustomization_build.test.manifests
+}
+`
+}
+
+func TestDataSourceKustomizationValidate_invalid(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceKustomizationValidateConfig_invalid(false),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "valid", "false"),
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "violations.#", "1"),
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "violations.0.id", "_/Service/test/test"),
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "violations.0.errors.0", "Service.spec.ports[0].port: must be an integer"),
+				),
+			},
+			{
+				Config:      testDataSourceKustomizationValidateConfig_invalid(true),
+				ExpectError: regexp.MustCompile("\"_/Service/test/test\" is invalid"),
+			},
+		},
+	})
+}
+
+func testDataSourceKustomizationValidateConfig_invalid(fail bool) string {
+	failOnInvalid := "false"
+	if fail {
+		failOnInvalid = "true"
+	}
+
+	return `
+data "kustomization_validate" "test" {
+	manifests = {
+		"_/Service/test/test" = jsonencode({
+			apiVersion = "v1"
+			kind       = "Service"
+			metadata = {
+				name      = "test"
+				namespace = "test"
+			}
+			spec = {
+				ports = [{ port = "http" }]
+			}
+		})
+	}
+
+	fail_on_invalid = ` + failOnInvalid + `
+}
+`
+}
+
+func TestDataSourceKustomizationValidate_crds(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "kustomization_validate" "test" {
+	manifests = {
+		"example.com/Backend/_/test" = jsonencode({
+			apiVersion = "example.com/v1"
+			kind       = "Backend"
+			metadata = {
+				name = "test"
+			}
+			spec = {
+				routes = [{ name = "a", weight = 100 }]
+			}
+		})
+	}
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "valid", "false"),
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "violations.0.errors.0", "no schema for example.com/v1 Backend"),
+				),
+			},
+			{
+				Config: `
+data "kustomization_validate" "test" {
+	manifests = {
+		"example.com/Backend/_/test" = jsonencode({
+			apiVersion = "example.com/v1"
+			kind       = "Backend"
+			metadata = {
+				name = "test"
+			}
+			spec = {
+				routes = [{ name = "a", weight = 100 }]
+			}
+		})
+	}
+
+	crds = [<<EOT
+` + testBackendCRD + `EOT
+	]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_validate.test", "valid", "true"),
+				),
+			},
+		},
+	})
+}
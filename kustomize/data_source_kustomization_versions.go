@@ -0,0 +1,91 @@
+package kustomize
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+const (
+	kustomizeAPIModule = "sigs.k8s.io/kustomize/api"
+	kyamlModule        = "sigs.k8s.io/kustomize/kyaml"
+	clientGoModule     = "k8s.io/client-go"
+)
+
+func dataSourceKustomizationVersions() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: kustomizationVersions,
+
+		Schema: map[string]*schema.Schema{
+			"kustomize_api_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"kyaml_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"client_go_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"kustomization_api_versions": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"kubernetes_schema_versions": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// getModuleVersions returns the versions of the modules compiled
+// into the provider, "unknown" if the build has no module information
+func getModuleVersions(modules ...string) map[string]string {
+	versions := make(map[string]string)
+	for _, m := range modules {
+		versions[m] = "unknown"
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+
+	for _, dep := range bi.Deps {
+		if _, ok := versions[dep.Path]; !ok {
+			continue
+		}
+
+		versions[dep.Path] = dep.Version
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			versions[dep.Path] = dep.Replace.Version
+		}
+	}
+
+	return versions
+}
+
+func kustomizationVersions(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	versions := getModuleVersions(kustomizeAPIModule, kyamlModule, clientGoModule)
+
+	d.SetId(versions[kustomizeAPIModule])
+	d.Set("kustomize_api_version", versions[kustomizeAPIModule])
+	d.Set("kyaml_version", versions[kyamlModule])
+	d.Set("client_go_version", versions[clientGoModule])
+	d.Set("kustomization_api_versions", map[string]interface{}{
+		types.KustomizationKind: types.KustomizationVersion,
+		types.ComponentKind:     types.ComponentVersion,
+	})
+	d.Set("kubernetes_schema_versions", builtinSchemaVersions())
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetModuleVersions(t *testing.T) {
+	versions := getModuleVersions(kustomizeAPIModule, "example.com/does-not-exist")
+	assert.Equal(t, "v0.11.5", versions[kustomizeAPIModule], nil)
+	assert.Equal(t, "unknown", versions["example.com/does-not-exist"], nil)
+}
+
+func TestDataSourceKustomizationVersions_basic(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest: true,
+		Providers:  testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "kustomization_versions" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.kustomization_versions.test", "kustomize_api_version", "v0.11.5"),
+					resource.TestCheckResourceAttr("data.kustomization_versions.test", "kyaml_version", "v0.13.7"),
+					resource.TestCheckResourceAttr("data.kustomization_versions.test", "client_go_version", "v0.24.1"),
+					resource.TestCheckResourceAttr("data.kustomization_versions.test", "kustomization_api_versions.Kustomization", "kustomize.config.k8s.io/v1beta1"),
+					resource.TestCheckResourceAttr("data.kustomization_versions.test", "kustomization_api_versions.Component", "kustomize.config.k8s.io/v1alpha1"),
+					resource.TestCheckResourceAttr("data.kustomization_versions.test", "kubernetes_schema_versions.0", "v1.21.2"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// finalizerManagers returns the field managers that added each of
+// the finalizers of u, usually the controllers that remove them
+func finalizerManagers(u *k8sunstructured.Unstructured) map[string][]string {
+	managers := make(map[string][]string)
+	for _, mf := range u.GetManagedFields() {
+		if mf.FieldsV1 == nil {
+			continue
+		}
+
+		var fields struct {
+			Metadata struct {
+				Finalizers map[string]interface{} `json:"f:finalizers"`
+			} `json:"f:metadata"`
+		}
+		if err := json.Unmarshal(mf.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+
+		// set elements are keyed by their JSON value, e.g. v:"example.com/finalizer"
+		for k := range fields.Metadata.Finalizers {
+			if !strings.HasPrefix(k, "v:") {
+				continue
+			}
+
+			f, err := strconv.Unquote(strings.TrimPrefix(k, "v:"))
+			if err != nil {
+				continue
+			}
+			managers[f] = append(managers[f], mf.Manager)
+		}
+	}
+
+	return managers
+}
+
+// describeFinalizers returns the remaining finalizers of u and the
+// managers that added them, or an empty string if there are none
+func describeFinalizers(u *k8sunstructured.Unstructured) string {
+	finalizers := u.GetFinalizers()
+	if len(finalizers) == 0 {
+		return ""
+	}
+
+	managers := finalizerManagers(u)
+
+	var out []string
+	for _, f := range finalizers {
+		if m := managers[f]; len(m) > 0 {
+			sort.Strings(m)
+			f = fmt.Sprintf("%s (added by %s)", f, strings.Join(m, ", "))
+		}
+		out = append(out, f)
+	}
+
+	return fmt.Sprintf("finalizers remaining: %s", strings.Join(out, ", "))
+}
+
+// deletionProgress returns the progress of deleting u
+func deletionProgress(u *k8sunstructured.Unstructured) string {
+	if u.GetDeletionTimestamp() == nil {
+		return "not deleted yet"
+	}
+
+	if f := describeFinalizers(u); f != "" {
+		return fmt.Sprintf("deletion blocked, %s", f)
+	}
+
+	return "deletion in progress"
+}
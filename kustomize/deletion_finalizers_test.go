@@ -0,0 +1,36 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeFinalizers(t *testing.T) {
+	u := testRolloutObject(t, `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: test
+  namespace: test
+  deletionTimestamp: "2024-01-01T00:00:00Z"
+  finalizers:
+  - kubernetes.io/pvc-protection
+  - example.com/backup
+  managedFields:
+  - manager: kube-controller-manager
+    operation: Update
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:metadata:
+        f:finalizers:
+          .: {}
+          'v:"kubernetes.io/pvc-protection"': {}
+`)
+
+	assert.Equal(t, "deletion blocked, finalizers remaining: kubernetes.io/pvc-protection (added by kube-controller-manager), example.com/backup", deletionProgress(u), nil)
+
+	u.SetFinalizers(nil)
+	assert.Equal(t, "", describeFinalizers(u), nil)
+	assert.Equal(t, "deletion in progress", deletionProgress(u), nil)
+}
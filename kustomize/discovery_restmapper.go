@@ -0,0 +1,199 @@
+package kustomize
+
+import (
+	"log"
+	"sync"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// groupInvalidator is implemented by mappers that can invalidate
+// the discovery information of single API groups
+type groupInvalidator interface {
+	invalidateGroups(groups ...string)
+}
+
+// invalidateGroups invalidates the discovery information of the groups,
+// mappers that can not invalidate single groups are reset entirely
+func invalidateGroups(mapper k8smeta.ResettableRESTMapper, groups ...string) {
+	if gi, ok := mapper.(groupInvalidator); ok {
+		gi.invalidateGroups(groups...)
+		return
+	}
+
+	mapper.Reset()
+}
+
+// discoveryRESTMapper maps kinds using discovery information cached
+// by group version, shared by all resources of the provider
+//
+// Applying a CRD or APIService only invalidates the API group it serves,
+// the next mapping rediscovers the list of groups and the resources of
+// that group, resources of all other group versions stay cached.
+type discoveryRESTMapper struct {
+	dc discovery.DiscoveryInterface
+
+	mu        sync.Mutex
+	groups    *k8smetav1.APIGroupList
+	resources map[k8sschema.GroupVersion]*k8smetav1.APIResourceList
+	delegate  k8smeta.RESTMapper
+}
+
+func newDiscoveryRESTMapper(dc discovery.DiscoveryInterface) *discoveryRESTMapper {
+	return &discoveryRESTMapper{
+		dc:        dc,
+		resources: make(map[k8sschema.GroupVersion]*k8smetav1.APIResourceList),
+	}
+}
+
+// Reset invalidates the discovery information of all groups
+func (m *discoveryRESTMapper) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.groups = nil
+	m.resources = make(map[k8sschema.GroupVersion]*k8smetav1.APIResourceList)
+	m.delegate = nil
+}
+
+func (m *discoveryRESTMapper) invalidateGroups(groups ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invalid := make(map[string]bool)
+	for _, g := range groups {
+		invalid[g] = true
+	}
+
+	for gv := range m.resources {
+		if invalid[gv.Group] {
+			delete(m.resources, gv)
+		}
+	}
+
+	// groups or versions may have been added or removed
+	m.groups = nil
+	m.delegate = nil
+}
+
+// getDelegate returns the mapper for the cached discovery
+// information, discovering invalidated group versions first
+func (m *discoveryRESTMapper) getDelegate() (k8smeta.RESTMapper, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.delegate != nil {
+		return m.delegate, nil
+	}
+
+	if m.groups == nil {
+		groups, err := m.dc.ServerGroups()
+		if err != nil {
+			return nil, err
+		}
+		m.groups = groups
+	}
+
+	var grs []*restmapper.APIGroupResources
+	for _, g := range m.groups.Groups {
+		gr := &restmapper.APIGroupResources{
+			Group:              g,
+			VersionedResources: make(map[string][]k8smetav1.APIResource),
+		}
+
+		for _, v := range g.Versions {
+			gv := k8sschema.GroupVersion{Group: g.Name, Version: v.Version}
+
+			rl, ok := m.resources[gv]
+			if !ok {
+				var err error
+				rl, err = m.dc.ServerResourcesForGroupVersion(v.GroupVersion)
+				if k8serrors.IsNotFound(err) {
+					// removed since the groups were discovered
+					rl = &k8smetav1.APIResourceList{GroupVersion: v.GroupVersion}
+				} else if err != nil {
+					// e.g. unavailable aggregated APIs, retried with the next rediscovery
+					log.Printf("[WARN] provider kustomization: discovery of %q failed: %s", v.GroupVersion, err)
+					continue
+				}
+				m.resources[gv] = rl
+			}
+
+			gr.VersionedResources[v.Version] = rl.APIResources
+		}
+
+		grs = append(grs, gr)
+	}
+
+	m.delegate = restmapper.NewDiscoveryRESTMapper(grs)
+
+	return m.delegate, nil
+}
+
+func (m *discoveryRESTMapper) KindFor(resource k8sschema.GroupVersionResource) (k8sschema.GroupVersionKind, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return k8sschema.GroupVersionKind{}, err
+	}
+
+	return d.KindFor(resource)
+}
+
+func (m *discoveryRESTMapper) KindsFor(resource k8sschema.GroupVersionResource) ([]k8sschema.GroupVersionKind, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.KindsFor(resource)
+}
+
+func (m *discoveryRESTMapper) ResourceFor(input k8sschema.GroupVersionResource) (k8sschema.GroupVersionResource, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return k8sschema.GroupVersionResource{}, err
+	}
+
+	return d.ResourceFor(input)
+}
+
+func (m *discoveryRESTMapper) ResourcesFor(input k8sschema.GroupVersionResource) ([]k8sschema.GroupVersionResource, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.ResourcesFor(input)
+}
+
+func (m *discoveryRESTMapper) RESTMapping(gk k8sschema.GroupKind, versions ...string) (*k8smeta.RESTMapping, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.RESTMapping(gk, versions...)
+}
+
+func (m *discoveryRESTMapper) RESTMappings(gk k8sschema.GroupKind, versions ...string) ([]*k8smeta.RESTMapping, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.RESTMappings(gk, versions...)
+}
+
+func (m *discoveryRESTMapper) ResourceSingularizer(resource string) (string, error) {
+	d, err := m.getDelegate()
+	if err != nil {
+		return "", err
+	}
+
+	return d.ResourceSingularizer(resource)
+}
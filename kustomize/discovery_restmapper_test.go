@@ -0,0 +1,143 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// discoveryRequests counts the requests for groups and resources
+func discoveryRequests(fake *k8stesting.Fake) (groups int, resources int) {
+	for _, a := range fake.Actions() {
+		switch a.GetResource().Resource {
+		case "group":
+			groups++
+		case "resource":
+			resources++
+		}
+	}
+	fake.ClearActions()
+
+	return groups, resources
+}
+
+func TestDiscoveryRESTMapperInvalidateGroups(t *testing.T) {
+	fake := &k8stesting.Fake{
+		Resources: []*k8smetav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []k8smetav1.APIResource{
+					{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+				},
+			},
+			{
+				GroupVersion: "apps/v1",
+				APIResources: []k8smetav1.APIResource{
+					{Name: "deployments", Kind: "Deployment", Namespaced: true},
+				},
+			},
+		},
+	}
+	mapper := newDiscoveryRESTMapper(&fakediscovery.FakeDiscovery{Fake: fake})
+
+	mapping, err := mapper.RESTMapping(k8sschema.GroupKind{Group: "apps", Kind: "Deployment"}, "v1")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "deployments", mapping.Resource.Resource, nil)
+
+	groups, resources := discoveryRequests(fake)
+	assert.Equal(t, 1, groups, nil)
+	assert.Equal(t, 2, resources, nil)
+
+	// cached
+	_, err = mapper.RESTMapping(k8sschema.GroupKind{Kind: "ConfigMap"}, "v1")
+	assert.Equal(t, nil, err, nil)
+
+	groups, resources = discoveryRequests(fake)
+	assert.Equal(t, 0, groups, nil)
+	assert.Equal(t, 0, resources, nil)
+
+	_, err = mapper.RESTMapping(k8sschema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+	assert.Equal(t, true, k8smeta.IsNoMatchError(err), nil)
+
+	// a CRD adds a new group
+	fake.Resources = append(fake.Resources, &k8smetav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []k8smetav1.APIResource{
+			{Name: "widgets", Kind: "Widget", Namespaced: true},
+		},
+	})
+	invalidateGroups(mapper, "example.com")
+
+	mapping, err = mapper.RESTMapping(k8sschema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "widgets", mapping.Resource.Resource, nil)
+
+	// only the invalidated group is rediscovered
+	groups, resources = discoveryRequests(fake)
+	assert.Equal(t, 1, groups, nil)
+	assert.Equal(t, 1, resources, nil)
+
+	mapper.Reset()
+
+	_, err = mapper.RESTMapping(k8sschema.GroupKind{Group: "apps", Kind: "Deployment"}, "v1")
+	assert.Equal(t, nil, err, nil)
+
+	groups, resources = discoveryRequests(fake)
+	assert.Equal(t, 1, groups, nil)
+	assert.Equal(t, 3, resources, nil)
+}
+
+func TestDiscoveryRESTMapperRemovedGroupVersion(t *testing.T) {
+	fake := &k8stesting.Fake{
+		Resources: []*k8smetav1.APIResourceList{
+			{
+				GroupVersion: "example.com/v1",
+				APIResources: []k8smetav1.APIResource{
+					{Name: "widgets", Kind: "Widget", Namespaced: true},
+				},
+			},
+		},
+	}
+	mapper := newDiscoveryRESTMapper(&fakediscovery.FakeDiscovery{Fake: fake})
+
+	_, err := mapper.RESTMapping(k8sschema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+	assert.Equal(t, nil, err, nil)
+
+	// the CRD is deleted
+	fake.Resources = nil
+	invalidateGroups(mapper, "example.com")
+
+	_, err = mapper.RESTMapping(k8sschema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+	assert.Equal(t, true, k8smeta.IsNoMatchError(err), nil)
+}
+
+func TestStaticRESTMapperInvalidateGroups(t *testing.T) {
+	fake := &k8stesting.Fake{}
+	discovery := newDiscoveryRESTMapper(&fakediscovery.FakeDiscovery{Fake: fake})
+	mapper := newStaticRESTMapper(getStaticRESTMapper([]interface{}{
+		map[string]interface{}{
+			"group":      "apps",
+			"version":    "v1",
+			"kind":       "Deployment",
+			"resource":   "deployments",
+			"namespaced": true,
+		},
+	}), discovery)
+
+	_, err := mapper.RESTMapping(k8sschema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+	assert.Equal(t, true, k8smeta.IsNoMatchError(err), nil)
+	discoveryRequests(fake)
+
+	invalidateGroups(mapper, "example.com")
+
+	_, err = mapper.RESTMapping(k8sschema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+	assert.Equal(t, true, k8smeta.IsNoMatchError(err), nil)
+
+	groups, _ := discoveryRequests(fake)
+	assert.Equal(t, 1, groups, nil)
+}
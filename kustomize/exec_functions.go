@@ -0,0 +1,196 @@
+package kustomize
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// function configs specify the function in one of these
+// annotations or the legacy configFn metadata field
+var execFunctionMarkers = [][]byte{
+	[]byte("config.kubernetes.io/function"),
+	[]byte("config.k8s.io/function"),
+	[]byte("configFn"),
+}
+
+// execAllowlist restricts the exec KRM functions kustomize
+// runs to the allowed binaries, by path or SHA256 hash
+type execAllowlist struct {
+	paths  map[string]bool
+	hashes map[string]bool
+}
+
+func getExecAllowlist(in []interface{}) *execAllowlist {
+	if len(in) == 0 || in[0] == nil {
+		return nil
+	}
+
+	e := in[0].(map[string]interface{})
+
+	a := &execAllowlist{
+		paths:  make(map[string]bool),
+		hashes: make(map[string]bool),
+	}
+
+	for _, p := range convertListInterfaceToListString(e["allowed_paths"].([]interface{})) {
+		a.paths[resolvePath(p)] = true
+	}
+
+	for _, h := range convertListInterfaceToListString(e["allowed_sha256"].([]interface{})) {
+		a.hashes[strings.ToLower(strings.TrimPrefix(h, "sha256:"))] = true
+	}
+
+	return a
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// resolveExecPath returns the binary exec runs for path, names
+// are looked up in PATH, relative paths are relative to dir
+func resolveExecPath(path string, dir string) (string, error) {
+	if !strings.ContainsRune(path, filepath.Separator) {
+		p, err := exec.LookPath(path)
+		if err != nil {
+			return "", err
+		}
+		return resolvePath(p), nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	return resolvePath(path), nil
+}
+
+func (a *execAllowlist) check(path string, dir string) error {
+	resolved, err := resolveExecPath(path, dir)
+	if err != nil {
+		return fmt.Errorf("exec function %q: %s", path, err)
+	}
+
+	if a.paths[resolved] {
+		return nil
+	}
+
+	hash, err := fileSHA256(resolved)
+	if err != nil {
+		return fmt.Errorf("exec function %q: %s", path, err)
+	}
+
+	if a.hashes[hash] {
+		return nil
+	}
+
+	return fmt.Errorf("exec function %q is not allowed, add %q to allowed_paths or %q to allowed_sha256 of the provider exec_functions", path, resolved, hash)
+}
+
+func hasExecFunctionMarker(content []byte) bool {
+	for _, m := range execFunctionMarkers {
+		if bytes.Contains(content, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findExecFunctions returns the paths of all exec functions
+// of the function configs in content, including configs
+// inlined in a kustomization
+func findExecFunctions(content []byte) (paths []string) {
+	if !hasExecFunctionMarker(content) {
+		return nil
+	}
+
+	var walk func(n *kyaml.Node)
+	walk = func(n *kyaml.Node) {
+		switch n.Kind {
+		case kyaml.MappingNode:
+			if fn := runtimeutil.GetFunctionSpec(kyaml.NewRNode(n)); fn != nil && fn.Exec.Path != "" {
+				paths = append(paths, fn.Exec.Path)
+			}
+		case kyaml.ScalarNode:
+			// inline function configs are YAML strings
+			if strings.Contains(n.Value, "\n") && len(n.Value) < len(content) {
+				paths = append(paths, findExecFunctions([]byte(n.Value))...)
+			}
+		}
+
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+
+	// content that is not valid YAML is left for kustomize to report
+	d := kyaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc kyaml.Node
+		if err := d.Decode(&doc); err != nil {
+			break
+		}
+		walk(&doc)
+	}
+
+	return paths
+}
+
+// execFunctionsFileSystem checks the exec functions of function
+// configs against the allowlist when kustomize reads them,
+// before kustomize runs any of the functions
+type execFunctionsFileSystem struct {
+	filesys.FileSystem
+	allowlist *execAllowlist
+}
+
+func makeExecFunctionsFS(fs filesys.FileSystem, a *execAllowlist, opts *krusty.Options) filesys.FileSystem {
+	if a == nil || !opts.PluginConfig.FnpLoadingOptions.EnableExec {
+		return fs
+	}
+
+	return execFunctionsFileSystem{
+		FileSystem: fs,
+		allowlist:  a,
+	}
+}
+
+func (efs execFunctionsFileSystem) ReadFile(name string) ([]byte, error) {
+	content, err := efs.FileSystem.ReadFile(name)
+	if err != nil {
+		return content, err
+	}
+
+	dir := filepath.Dir(resolvePath(name))
+	for _, p := range findExecFunctions(content) {
+		if err := efs.allowlist.check(p, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return content, nil
+}
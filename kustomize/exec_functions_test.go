@@ -0,0 +1,121 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const testExecFunctionConfig = `apiVersion: example.com/v1
+kind: Generator
+metadata:
+  name: test
+  annotations:
+    config.kubernetes.io/function: |
+      exec:
+        path: ./generator.sh
+`
+
+func TestFindExecFunctions(t *testing.T) {
+	assert.Equal(t, []string{"./generator.sh"}, findExecFunctions([]byte(testExecFunctionConfig)), nil)
+
+	// function configs inlined in a kustomization
+	k := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+generators:
+- |-
+  apiVersion: example.com/v1
+  kind: Generator
+  metadata:
+    name: test
+    configFn:
+      exec:
+        path: /usr/local/bin/generator
+`
+	assert.Equal(t, []string{"/usr/local/bin/generator"}, findExecFunctions([]byte(k)), nil)
+
+	assert.Equal(t, []string(nil), findExecFunctions([]byte("KEY=VALUE")), nil)
+}
+
+func testExecFunctionsDir(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "exec-functions-*")
+	assert.Equal(t, nil, err, nil)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	script := []byte("#!/bin/sh\ncat\n")
+	err = ioutil.WriteFile(filepath.Join(dir, "generator.sh"), script, 0755)
+	assert.Equal(t, nil, err, nil)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "generator.yaml"), []byte(testExecFunctionConfig), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	h := sha256.Sum256(script)
+	return dir, hex.EncodeToString(h[:])
+}
+
+func TestExecFunctionsFS(t *testing.T) {
+	dir, hash := testExecFunctionsDir(t)
+	config := filepath.Join(dir, "generator.yaml")
+
+	opts := krusty.MakeDefaultOptions()
+	opts.PluginConfig.FnpLoadingOptions.EnableExec = true
+
+	testCases := []struct {
+		name    string
+		paths   []interface{}
+		hashes  []interface{}
+		allowed bool
+	}{
+		{"none", []interface{}{}, []interface{}{}, false},
+		{"path", []interface{}{filepath.Join(dir, "generator.sh")}, []interface{}{}, true},
+		{"other path", []interface{}{"/usr/local/bin/generator"}, []interface{}{}, false},
+		{"sha256", []interface{}{}, []interface{}{"sha256:" + hash}, true},
+		{"other sha256", []interface{}{}, []interface{}{"0000"}, false},
+	}
+
+	for _, tc := range testCases {
+		a := getExecAllowlist([]interface{}{
+			map[string]interface{}{
+				"allowed_paths":  tc.paths,
+				"allowed_sha256": tc.hashes,
+			},
+		})
+
+		fSys := makeExecFunctionsFS(filesys.MakeFsOnDisk(), a, opts)
+		content, err := fSys.ReadFile(config)
+		if tc.allowed {
+			assert.Equal(t, nil, err, tc.name)
+			assert.Equal(t, testExecFunctionConfig, string(content), tc.name)
+		} else {
+			assert.Error(t, err, tc.name)
+		}
+	}
+}
+
+func TestExecFunctionsFSNotConfigured(t *testing.T) {
+	dir, _ := testExecFunctionsDir(t)
+	config := filepath.Join(dir, "generator.yaml")
+
+	opts := krusty.MakeDefaultOptions()
+	opts.PluginConfig.FnpLoadingOptions.EnableExec = true
+
+	// without an allowlist all exec functions are allowed
+	fSys := makeExecFunctionsFS(filesys.MakeFsOnDisk(), nil, opts)
+	_, err := fSys.ReadFile(config)
+	assert.Equal(t, nil, err, nil)
+
+	// without exec enabled, functions do not run
+	a := getExecAllowlist([]interface{}{
+		map[string]interface{}{"allowed_paths": []interface{}{}, "allowed_sha256": []interface{}{}},
+	})
+	fSys = makeExecFunctionsFS(filesys.MakeFsOnDisk(), a, krusty.MakeDefaultOptions())
+	_, err = fSys.ReadFile(config)
+	assert.Equal(t, nil, err, nil)
+}
@@ -0,0 +1,330 @@
+package kustomize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// splitOwnershipPath splits a path like 'spec.containers[*].resources'
+// into its segments, bracket segments keep their brackets
+func splitOwnershipPath(path string) (segments []string, err error) {
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end, err := closingBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, path[i:end+1])
+			i = end
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	return segments, nil
+}
+
+// closingBracket returns the index of the bracket closing the one
+// at start, brackets in quoted strings are ignored
+func closingBracket(path string, start int) (int, error) {
+	var quote byte
+	for i := start + 1; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%q: unclosed bracket", path)
+}
+
+// compileOwnershipPattern compiles a take_ownership_of path to match
+// server-side apply field paths like '.spec.containers[name="app"].image',
+// '[*]' matches any list element and quoted keys, e.g.
+// 'metadata.annotations["example.com/key"]', map keys containing dots.
+// Paths match the field itself and all fields below it
+func compileOwnershipPattern(path string) (*regexp.Regexp, error) {
+	segments, err := splitOwnershipPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	for _, s := range segments {
+		switch {
+		case s == "[*]":
+			re.WriteString(`\[[^\]]*\]`)
+		case strings.HasPrefix(s, `["`) || strings.HasPrefix(s, "['"):
+			re.WriteString(`\.` + regexp.QuoteMeta(s[2:len(s)-2]))
+		case strings.HasPrefix(s, "["):
+			re.WriteString(regexp.QuoteMeta(s))
+		default:
+			re.WriteString(`\.` + regexp.QuoteMeta(s))
+		}
+	}
+	re.WriteString(`(?:[.\[]|$)`)
+
+	return regexp.Compile(re.String())
+}
+
+func compileOwnershipPatterns(paths []string) (patterns []*regexp.Regexp, err error) {
+	for _, p := range paths {
+		re, err := compileOwnershipPattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("take_ownership_of: %s", err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	return patterns, nil
+}
+
+func validateOwnershipPath(v interface{}, k string) (ws []string, es []error) {
+	if _, err := compileOwnershipPattern(v.(string)); err != nil {
+		es = append(es, fmt.Errorf("invalid %s: %s", k, err))
+	}
+
+	return ws, es
+}
+
+// applyConflicts returns the field paths of a server-side
+// apply conflict error, nil for other errors
+func applyConflicts(err error) (fields []string) {
+	if !k8serrors.IsConflict(err) {
+		return nil
+	}
+
+	status, ok := err.(k8serrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+
+	for _, c := range status.Status().Details.Causes {
+		if c.Type == k8smetav1.CauseTypeFieldManagerConflict && c.Field != "" {
+			fields = append(fields, c.Field)
+		}
+	}
+
+	return fields
+}
+
+// removeManagedField removes the field at the server-side apply field
+// path from obj and returns the updated obj, false if the field does not
+// exist. Map keys are matched against obj, keys of field paths are not
+// escaped and may contain dots
+func removeManagedField(obj interface{}, path string) (interface{}, bool) {
+	switch {
+	case strings.HasPrefix(path, "."):
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return obj, false
+		}
+
+		// prefer the longest matching key
+		key := ""
+		found := false
+		for k := range m {
+			if !strings.HasPrefix(path[1:], k) {
+				continue
+			}
+			if rest := path[1+len(k):]; rest != "" && rest[0] != '.' && rest[0] != '[' {
+				continue
+			}
+			if !found || len(k) > len(key) {
+				key, found = k, true
+			}
+		}
+		if !found {
+			return obj, false
+		}
+
+		rest := path[1+len(key):]
+		if rest == "" {
+			delete(m, key)
+			return m, true
+		}
+
+		v, ok := removeManagedField(m[key], rest)
+		m[key] = v
+		return m, ok
+	case strings.HasPrefix(path, "["):
+		l, ok := obj.([]interface{})
+		if !ok {
+			return obj, false
+		}
+
+		end, err := closingBracket(path, 0)
+		if err != nil {
+			return obj, false
+		}
+
+		for i, item := range l {
+			if !matchesListSelector(item, i, path[1:end]) {
+				continue
+			}
+
+			rest := path[end+1:]
+			if rest == "" {
+				return append(l[:i:i], l[i+1:]...), true
+			}
+
+			v, ok := removeManagedField(item, rest)
+			l[i] = v
+			return l, ok
+		}
+	}
+
+	return obj, false
+}
+
+// matchesListSelector returns true if the list item at index i is
+// selected by a field path selector, an index like '0', a value of
+// a set like '="value"' or keys like 'name="app",protocol="TCP"'
+func matchesListSelector(item interface{}, i int, selector string) bool {
+	if idx, err := strconv.Atoi(selector); err == nil {
+		return idx == i
+	}
+
+	if strings.HasPrefix(selector, "=") {
+		return jsonEqual(item, selector[1:])
+	}
+
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for selector != "" {
+		eq := strings.Index(selector, "=")
+		if eq < 0 {
+			return false
+		}
+		key := selector[:eq]
+
+		dec := json.NewDecoder(strings.NewReader(selector[eq+1:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return false
+		}
+
+		if !jsonEqual(m[key], string(raw)) {
+			return false
+		}
+
+		selector = strings.TrimPrefix(selector[eq+1+int(dec.InputOffset()):], ",")
+	}
+
+	return true
+}
+
+// jsonEqual returns true if v encodes to the same JSON as value
+func jsonEqual(v interface{}, value string) bool {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return false
+	}
+
+	a, errA := json.Marshal(v)
+	b, errB := json.Marshal(parsed)
+
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+// withoutManagedFields returns a copy of km without the fields
+func (km *kManifest) withoutManagedFields(fields []string) (*kManifest, error) {
+	u := km.resource.DeepCopy()
+	for _, f := range fields {
+		removeManagedField(u.Object, f)
+	}
+
+	j, err := u.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	kmc := newKManifest(km.mapper, km.client)
+	kmc.resource = u
+	kmc.json = j
+	kmc.secrets = km.secrets
+
+	return kmc, nil
+}
+
+// apiApplyTakingOwnership applies km without forcing conflicts. Fields
+// conflicting with other field managers are taken over, if they match
+// any of the patterns, and otherwise left to the other managers
+func (km *kManifest) apiApplyTakingOwnership(opts k8smetav1.PatchOptions, takeOwnershipOf []string) (resp *k8sunstructured.Unstructured, err error) {
+	if len(takeOwnershipOf) == 0 {
+		return km.apiApply(opts)
+	}
+
+	patterns, err := compileOwnershipPatterns(takeOwnershipOf)
+	if err != nil {
+		return resp, km.fmtErr(err)
+	}
+
+	noForce := false
+	opts.Force = &noForce
+	resp, err = km.apiApply(opts)
+
+	conflicts := applyConflicts(err)
+	if len(conflicts) == 0 {
+		return resp, err
+	}
+
+	var leave []string
+	for _, c := range conflicts {
+		if !matchesAny(c, patterns) {
+			leave = append(leave, c)
+		}
+	}
+
+	if len(leave) > 0 {
+		log.Printf("[WARN] %q: leaving fields conflicting with other field managers to them: %s", km.id().string(), strings.Join(leave, ", "))
+	}
+	km.leftFields = leave
+
+	kmc, err := km.withoutManagedFields(leave)
+	if err != nil {
+		return resp, km.fmtErr(err)
+	}
+
+	force := true
+	opts.Force = &force
+	return kmc.apiApply(opts)
+}
@@ -0,0 +1,164 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCompileOwnershipPattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		field   string
+		matches bool
+	}{
+		{"spec.replicas", ".spec.replicas", true},
+		{"spec.replicas", ".spec.replicasets", false},
+		{"spec.template.spec.containers[*].resources", `.spec.template.spec.containers[name="app"].resources.limits.cpu`, true},
+		{"spec.template.spec.containers[*].resources", `.spec.template.spec.containers[name="app"].image`, false},
+		{`spec.template.spec.containers[name="app"]`, `.spec.template.spec.containers[name="app"].image`, true},
+		{`spec.template.spec.containers[name="app"]`, `.spec.template.spec.containers[name="sidecar"].image`, false},
+		{`metadata.annotations["example.com/key"]`, ".metadata.annotations.example.com/key", true},
+		{"metadata.labels", ".metadata.labels.app", true},
+	}
+
+	for _, tc := range testCases {
+		re, err := compileOwnershipPattern(tc.pattern)
+		assert.Equal(t, nil, err, tc.pattern)
+		assert.Equal(t, tc.matches, re.MatchString(tc.field), tc.pattern+" "+tc.field)
+	}
+
+	for _, invalid := range []string{"", "spec.containers[*"} {
+		_, err := compileOwnershipPattern(invalid)
+		assert.NotEqual(t, nil, err, invalid)
+	}
+}
+
+const testOwnershipDeployment = `{
+	"apiVersion": "apps/v1",
+	"kind": "Deployment",
+	"metadata": {"name": "test", "namespace": "default", "annotations": {"example.com/key": "value"}},
+	"spec": {
+		"replicas": 3,
+		"template": {"spec": {"containers": [
+			{"name": "app", "image": "app:1", "ports": [{"containerPort": 80, "protocol": "TCP"}]},
+			{"name": "sidecar", "image": "sidecar:1"}
+		]}}
+	}
+}`
+
+func TestRemoveManagedField(t *testing.T) {
+	testCases := []struct {
+		field    string
+		expected string
+	}{
+		{".spec.replicas", `{"replicas":null}`},
+		{".metadata.annotations.example.com/key", `{"annotations":{}}`},
+		{`.spec.template.spec.containers[name="sidecar"]`, `{"containers":[{"image":"app:1","name":"app","ports":[{"containerPort":80,"protocol":"TCP"}]}]}`},
+		{`.spec.template.spec.containers[name="app"].image`, `{"containers":[{"name":"app","ports":[{"containerPort":80,"protocol":"TCP"}]},{"image":"sidecar:1","name":"sidecar"}]}`},
+		{`.spec.template.spec.containers[name="app"].ports[containerPort=80,protocol="TCP"]`, `{"containers":[{"image":"app:1","name":"app","ports":[]},{"image":"sidecar:1","name":"sidecar"}]}`},
+		{`.spec.template.spec.containers[1].image`, `{"containers":[{"image":"app:1","name":"app","ports":[{"containerPort":80,"protocol":"TCP"}]},{"name":"sidecar"}]}`},
+	}
+
+	for _, tc := range testCases {
+		km := &kManifest{}
+		assert.Equal(t, nil, km.load([]byte(testOwnershipDeployment)), nil)
+
+		kmc, err := km.withoutManagedFields([]string{tc.field})
+		assert.Equal(t, nil, err, tc.field)
+
+		var actual map[string]interface{}
+		json.Unmarshal(kmc.json, &actual)
+
+		var check interface{}
+		switch tc.field[:9] {
+		case ".metadata":
+			check = map[string]interface{}{"annotations": actual["metadata"].(map[string]interface{})["annotations"]}
+		case ".spec.rep":
+			check = map[string]interface{}{"replicas": actual["spec"].(map[string]interface{})["replicas"]}
+		default:
+			check = actual["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"]
+		}
+		j, _ := json.Marshal(check)
+		assert.Equal(t, tc.expected, string(j), tc.field)
+	}
+
+	// the original manifest is not modified
+	km := &kManifest{}
+	km.load([]byte(testOwnershipDeployment))
+	km.withoutManagedFields([]string{".spec.replicas"})
+	assert.Equal(t, int64(3), km.resource.Object["spec"].(map[string]interface{})["replicas"], nil)
+
+	_, ok := removeManagedField(km.resource.Object, ".spec.missing")
+	assert.Equal(t, false, ok, nil)
+}
+
+func testApplyConflict(fields ...string) error {
+	var causes []k8smetav1.StatusCause
+	for _, f := range fields {
+		causes = append(causes, k8smetav1.StatusCause{
+			Type:    k8smetav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "other"`,
+			Field:   f,
+		})
+	}
+
+	return &k8serrors.StatusError{ErrStatus: k8smetav1.Status{
+		Status:  k8smetav1.StatusFailure,
+		Code:    409,
+		Reason:  k8smetav1.StatusReasonConflict,
+		Details: &k8smetav1.StatusDetails{Causes: causes},
+	}}
+}
+
+func TestApplyTakingOwnership(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "apps", "version": "v1", "kind": "Deployment", "resource": "deployments", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[k8sschema.GroupVersionResource]string{},
+	)
+
+	var forced []string
+	client.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pa := action.(k8stesting.PatchAction)
+		if pa.GetPatchType() != "application/apply-patch+yaml" {
+			return true, nil, assert.AnError
+		}
+
+		var obj map[string]interface{}
+		json.Unmarshal(pa.GetPatch(), &obj)
+		spec := obj["spec"].(map[string]interface{})
+		if _, ok := spec["replicas"]; ok && forced == nil {
+			return true, nil, testApplyConflict(".spec.replicas", `.spec.template.spec.containers[name="app"].image`)
+		}
+
+		containers := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+		forced = append(forced, containers[0].(map[string]interface{})["image"].(string))
+		if _, ok := spec["replicas"]; ok {
+			forced = append(forced, "replicas")
+		}
+
+		return true, nil, nil
+	})
+
+	km := newKManifest(mapper, client)
+	assert.Equal(t, nil, km.load([]byte(testOwnershipDeployment)), nil)
+
+	_, err := km.apiApplyTakingOwnership(k8smetav1.PatchOptions{}, []string{"spec.template.spec.containers[*].image"})
+	assert.Equal(t, nil, err, nil)
+
+	// the image is taken over, the replicas are left to the other manager
+	assert.Equal(t, []string{"app:1"}, forced, nil)
+	assert.Equal(t, 1, len(km.leftFields), nil)
+}
@@ -0,0 +1,150 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// buildFunctionOptions are the kustomize_options the build function
+// supports, options requiring a cluster or secrets are not available
+//
+// Functions have no provider configuration, exec KRM functions and
+// plugins are not available, they require the exec_functions allowlist.
+var buildFunctionOptions = map[string]bool{
+	"load_restrictor":          true,
+	"enable_helm":              true,
+	"enable_star":              true,
+	"helm_path":                true,
+	"disable_name_suffix_hash": true,
+}
+
+type buildFunction struct{}
+
+func newBuildFunction() function.Function {
+	return &buildFunction{}
+}
+
+func (f *buildFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "build"
+}
+
+func (f *buildFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	keys := make([]string, 0, len(buildFunctionOptions))
+	for k := range buildFunctionOptions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	resp.Definition = function.Definition{
+		Summary:     "Build a kustomization",
+		Description: "Runs kustomize build for the kustomization at path and returns a map of JSON encoded manifests by resource ID, like the manifests of the kustomization_build data source, for read-only uses in expressions.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "path",
+				Description: "Path to the kustomization, relative to the working directory, e.g. using path.module.",
+			},
+			function.DynamicParameter{
+				Name:        "options",
+				Description: fmt.Sprintf("Object of kustomize options, like the kustomize_options of the kustomization_build data source, or {} for the defaults. Supported options: %s.", strings.Join(keys, ", ")),
+			},
+		},
+		Return: function.MapReturn{
+			ElementType: types.StringType,
+		},
+	}
+}
+
+func (f *buildFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var path string
+	var o types.Dynamic
+	resp.Error = req.Arguments.Get(ctx, &path, &o)
+	if resp.Error != nil {
+		return
+	}
+
+	opts, err := buildFunctionKustomizeOptions(ctx, o)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+
+	manifests, err := runBuildFunction(ctx, path, opts)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	result, diags := types.MapValueFrom(ctx, types.StringType, manifests)
+	resp.Error = function.FuncErrorFromDiags(ctx, diags)
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, result)
+}
+
+// buildFunctionKustomizeOptions converts the options object o
+// into the kustomize_options of the build data sources
+func buildFunctionKustomizeOptions(ctx context.Context, o types.Dynamic) (map[string]interface{}, error) {
+	if o.IsNull() || o.IsUnderlyingValueNull() {
+		return map[string]interface{}{}, nil
+	}
+
+	tv, err := o.ToTerraformValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := terraformValueInterface(tv)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("options must be an object")
+	}
+
+	for k := range opts {
+		if !buildFunctionOptions[k] {
+			return nil, fmt.Errorf("unsupported option %q", k)
+		}
+	}
+
+	return opts, nil
+}
+
+// runBuildFunction builds the kustomization at path with the
+// kustomize options opts and returns the manifests by ID
+func runBuildFunction(ctx context.Context, path string, opts map[string]interface{}) (map[string]string, error) {
+	d := dataSourceKustomization().Data(nil)
+	if err := d.Set("kustomize_options", []interface{}{opts}); err != nil {
+		return nil, fmt.Errorf("options: %s", err)
+	}
+
+	fSys := filesys.MakeFsOnDisk()
+
+	unlock, err := kustomizeBuildLock.lock(ctx, isExclusiveBuild(fSys, path, getKustomizeOptions(d)))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	rm, err := runKustomizeBuild(fSys, path, d)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, _, err := flattenBuild(rm, buildLimits{})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifests, nil
+}
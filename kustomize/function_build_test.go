@@ -0,0 +1,44 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func runBuildFunctionRequest(path string, options attr.Value) function.RunResponse {
+	resp := function.RunResponse{
+		Result: function.NewResultData(types.MapUnknown(types.StringType)),
+	}
+	newBuildFunction().Run(context.Background(), function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(path), types.DynamicValue(options)}),
+	}, &resp)
+
+	return resp
+}
+
+func TestBuildFunction(t *testing.T) {
+	empty, _ := types.ObjectValue(map[string]attr.Type{}, map[string]attr.Value{})
+	resp := runBuildFunctionRequest("test_kustomizations/basic/initial", empty)
+	assert.Equal(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	manifests := resp.Result.Value().(types.Map).Elements()
+	assert.Equal(t, 4, len(manifests), nil)
+	assert.Contains(t, manifests, "_/Namespace/_/test-basic", nil)
+
+	unsupported, _ := types.ObjectValue(map[string]attr.Type{"plugin_env": types.StringType}, map[string]attr.Value{"plugin_env": types.StringValue("test")})
+	resp = runBuildFunctionRequest("test_kustomizations/basic/initial", unsupported)
+	assert.NotEqual(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	// exec functions require the provider's exec_functions allowlist
+	exec, _ := types.ObjectValue(map[string]attr.Type{"enable_exec": types.BoolType}, map[string]attr.Value{"enable_exec": types.BoolValue(true)})
+	resp = runBuildFunctionRequest("test_kustomizations/basic/initial", exec)
+	assert.NotEqual(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	resp = runBuildFunctionRequest("test_kustomizations/does-not-exist", empty)
+	assert.NotEqual(t, (*function.FuncError)(nil), resp.Error, nil)
+}
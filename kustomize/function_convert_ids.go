@@ -0,0 +1,116 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	idFormatProvider = "provider"
+	idFormatLegacy   = "legacy"
+)
+
+// convertResourceID converts the ID s, of the provider or in the legacy
+// format, to format, the legacy format requires the version, taken from
+// s or from versions by group and kind, e.g. "apps/Deployment"
+func convertResourceID(s string, format string, versions map[string]string) (string, error) {
+	id, err := parseResourceID(s)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case idFormatProvider:
+		return kManifestId{group: id.group, kind: id.kind, namespace: id.namespace, name: id.name}.string(), nil
+	case idFormatLegacy:
+		version := id.version
+		if version == "" {
+			gk := fmt.Sprintf("%s/%s", emptyToUnderscore(id.group), id.kind)
+			v, ok := versions[gk]
+			if !ok {
+				return "", fmt.Errorf("%q: no version for %q in versions", s, gk)
+			}
+			version = v
+		}
+
+		group := id.group
+		if group == "" {
+			group = "~G"
+		}
+		namespace := id.namespace
+		if namespace == "" {
+			namespace = "~X"
+		}
+
+		return fmt.Sprintf("%s_%s_%s|%s|%s", group, version, id.kind, namespace, id.name), nil
+	}
+
+	return "", fmt.Errorf("invalid format %q, must be %q or %q", format, idFormatProvider, idFormatLegacy)
+}
+
+type convertIDsFunction struct{}
+
+func newConvertIDsFunction() function.Function {
+	return &convertIDsFunction{}
+}
+
+func (f *convertIDsFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "convert_ids"
+}
+
+func (f *convertIDsFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Convert resource IDs between the legacy and the current format",
+		Description: "Converts a list of resource IDs, in the current format of the provider, e.g. apps/Deployment/example/example, or the legacy format, e.g. apps_v1_Deployment|example|example, to the given format. Returns a map from each ID to the converted ID, to migrate for_each keys and generate moved blocks.",
+		Parameters: []function.Parameter{
+			function.ListParameter{
+				Name:        "ids",
+				Description: "The resource IDs to convert.",
+				ElementType: types.StringType,
+			},
+			function.StringParameter{
+				Name:        "format",
+				Description: "The format to convert to, provider or legacy.",
+			},
+			function.MapParameter{
+				Name:        "versions",
+				Description: "The API version by group and kind, e.g. apps/Deployment or _/Service, for converting IDs without a version to the legacy format.",
+				ElementType: types.StringType,
+			},
+		},
+		Return: function.MapReturn{
+			ElementType: types.StringType,
+		},
+	}
+}
+
+func (f *convertIDsFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var ids []string
+	var format string
+	var versions map[string]string
+	resp.Error = req.Arguments.Get(ctx, &ids, &format, &versions)
+	if resp.Error != nil {
+		return
+	}
+
+	converted := make(map[string]string, len(ids))
+	for _, id := range ids {
+		c, err := convertResourceID(id, format, versions)
+		if err != nil {
+			resp.Error = function.NewArgumentFuncError(0, err.Error())
+			return
+		}
+		converted[id] = c
+	}
+
+	result, diags := types.MapValueFrom(ctx, types.StringType, converted)
+	resp.Error = function.FuncErrorFromDiags(ctx, diags)
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, result)
+}
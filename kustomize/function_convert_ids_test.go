@@ -0,0 +1,58 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertResourceID(t *testing.T) {
+	versions := map[string]string{"apps/Deployment": "v1", "_/Namespace": "v1"}
+
+	testCases := []struct {
+		id       string
+		format   string
+		expected string
+	}{
+		{"apps_v1_Deployment|test-ns|test", idFormatProvider, "apps/Deployment/test-ns/test"},
+		{"~G_v1_Namespace|~X|test-ns", idFormatProvider, "_/Namespace/_/test-ns"},
+		{"apps/Deployment/test-ns/test", idFormatProvider, "apps/Deployment/test-ns/test"},
+		{"apps/Deployment/test-ns/test", idFormatLegacy, "apps_v1_Deployment|test-ns|test"},
+		{"_/Namespace/_/test-ns", idFormatLegacy, "~G_v1_Namespace|~X|test-ns"},
+		{"apps_v1beta1_Deployment|test-ns|test", idFormatLegacy, "apps_v1beta1_Deployment|test-ns|test"},
+	}
+
+	for _, tc := range testCases {
+		c, err := convertResourceID(tc.id, tc.format, versions)
+		assert.Equal(t, nil, err, tc.id)
+		assert.Equal(t, tc.expected, c, tc.id)
+	}
+
+	_, err := convertResourceID("_/Service/test-ns/test", idFormatLegacy, versions)
+	assert.NotEqual(t, nil, err, nil)
+
+	_, err = convertResourceID("apps/Deployment/test-ns/test", "v2", versions)
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestConvertIDsFunction(t *testing.T) {
+	ids, _ := types.ListValueFrom(context.Background(), types.StringType, []string{"apps_v1_Deployment|test-ns|test"})
+	versions, _ := types.MapValueFrom(context.Background(), types.StringType, map[string]string{})
+
+	resp := function.RunResponse{
+		Result: function.NewResultData(types.MapUnknown(types.StringType)),
+	}
+	newConvertIDsFunction().Run(context.Background(), function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{ids, types.StringValue(idFormatProvider), versions}),
+	}, &resp)
+	assert.Equal(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	want, _ := types.MapValueFrom(context.Background(), types.StringType, map[string]string{
+		"apps_v1_Deployment|test-ns|test": "apps/Deployment/test-ns/test",
+	})
+	assert.Equal(t, want, resp.Result.Value(), nil)
+}
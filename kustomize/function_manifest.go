@@ -0,0 +1,233 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/kbst/terraform-provider-kustomize/manifest"
+)
+
+type manifestDecodeFunction struct{}
+
+func newManifestDecodeFunction() function.Function {
+	return &manifestDecodeFunction{}
+}
+
+func (f *manifestDecodeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "manifest_decode"
+}
+
+func (f *manifestDecodeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Decode a manifest into an object",
+		Description: "Decodes a JSON or YAML manifest of a single Kubernetes object, e.g. of the manifests of the build data sources, into an object.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "manifest",
+				Description: "The JSON or YAML manifest to decode.",
+			},
+		},
+		Return: function.DynamicReturn{},
+	}
+}
+
+func (f *manifestDecodeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var m string
+	resp.Error = req.Arguments.Get(ctx, &m)
+	if resp.Error != nil {
+		return
+	}
+
+	u, err := manifest.Parse([]byte(m))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	v, err := manifestValue(u.Object)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, types.DynamicValue(v))
+}
+
+// manifestValue converts the decoded manifest v into a Terraform
+// value, objects are objects and lists are tuples, like the values
+// returned by Terraform's jsondecode
+func manifestValue(v interface{}) (attr.Value, error) {
+	switch o := v.(type) {
+	case nil:
+		return types.DynamicNull(), nil
+	case string:
+		return types.StringValue(o), nil
+	case bool:
+		return types.BoolValue(o), nil
+	case int64:
+		return types.NumberValue(new(big.Float).SetInt64(o)), nil
+	case float64:
+		return types.NumberValue(big.NewFloat(o)), nil
+	case []interface{}:
+		elemTypes := make([]attr.Type, len(o))
+		elems := make([]attr.Value, len(o))
+		for i, e := range o {
+			ev, err := manifestValue(e)
+			if err != nil {
+				return nil, err
+			}
+			elemTypes[i] = ev.Type(context.Background())
+			elems[i] = ev
+		}
+
+		tv, diags := types.TupleValue(elemTypes, elems)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s", diags[0].Detail())
+		}
+		return tv, nil
+	case map[string]interface{}:
+		attrTypes := make(map[string]attr.Type, len(o))
+		attrs := make(map[string]attr.Value, len(o))
+		for k, e := range o {
+			ev, err := manifestValue(e)
+			if err != nil {
+				return nil, err
+			}
+			attrTypes[k] = ev.Type(context.Background())
+			attrs[k] = ev
+		}
+
+		ov, diags := types.ObjectValue(attrTypes, attrs)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s", diags[0].Detail())
+		}
+		return ov, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+type manifestEncodeFunction struct{}
+
+func newManifestEncodeFunction() function.Function {
+	return &manifestEncodeFunction{}
+}
+
+func (f *manifestEncodeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "manifest_encode"
+}
+
+func (f *manifestEncodeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Encode an object into a manifest",
+		Description: "Encodes a Kubernetes object into a JSON manifest, compact and with sorted keys like the manifests of the build data sources, so it can be applied using kustomization_resource or compared to other manifests.",
+		Parameters: []function.Parameter{
+			function.DynamicParameter{
+				Name:        "object",
+				Description: "The object to encode, it requires apiVersion and kind.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *manifestEncodeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var d types.Dynamic
+	resp.Error = req.Arguments.Get(ctx, &d)
+	if resp.Error != nil {
+		return
+	}
+
+	tv, err := d.ToTerraformValue(ctx)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	v, err := terraformValueInterface(tv)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	m, err := manifest.Normalize(body)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, m)
+}
+
+// terraformValueInterface converts the Terraform value v
+// into the values encoding/json encodes
+func terraformValueInterface(v tftypes.Value) (interface{}, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+
+	if !v.IsKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
+
+	switch {
+	case v.Type().Is(tftypes.String):
+		var s string
+		err := v.As(&s)
+		return s, err
+	case v.Type().Is(tftypes.Bool):
+		var b bool
+		err := v.As(&b)
+		return b, err
+	case v.Type().Is(tftypes.Number):
+		var n big.Float
+		if err := v.As(&n); err != nil {
+			return nil, err
+		}
+		return json.Number(n.Text('g', -1)), nil
+	case v.Type().Is(tftypes.Object{}), v.Type().Is(tftypes.Map{}):
+		var m map[string]tftypes.Value
+		if err := v.As(&m); err != nil {
+			return nil, err
+		}
+
+		out := make(map[string]interface{}, len(m))
+		for k, mv := range m {
+			e, err := terraformValueInterface(mv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k, err)
+			}
+			out[k] = e
+		}
+		return out, nil
+	case v.Type().Is(tftypes.List{}), v.Type().Is(tftypes.Set{}), v.Type().Is(tftypes.Tuple{}):
+		var l []tftypes.Value
+		if err := v.As(&l); err != nil {
+			return nil, err
+		}
+
+		out := make([]interface{}, len(l))
+		for i, e := range l {
+			ev, err := terraformValueInterface(e)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			out[i] = ev
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value of type %s", v.Type())
+}
@@ -0,0 +1,66 @@
+package kustomize
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const testFunctionManifest = `{"apiVersion":"v1","data":{"key":"value"},"kind":"ConfigMap","metadata":{"labels":null,"name":"test"},"spec":{"ports":[80,443]}}`
+
+func runManifestDecodeFunction(m string) function.RunResponse {
+	resp := function.RunResponse{
+		Result: function.NewResultData(types.DynamicUnknown()),
+	}
+	newManifestDecodeFunction().Run(context.Background(), function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(m)}),
+	}, &resp)
+
+	return resp
+}
+
+func runManifestEncodeFunction(v attr.Value) function.RunResponse {
+	resp := function.RunResponse{
+		Result: function.NewResultData(types.StringUnknown()),
+	}
+	newManifestEncodeFunction().Run(context.Background(), function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.DynamicValue(v)}),
+	}, &resp)
+
+	return resp
+}
+
+func TestManifestDecodeFunction(t *testing.T) {
+	resp := runManifestDecodeFunction("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\nspec:\n  ports:\n  - 80\n")
+	assert.Equal(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	d := resp.Result.Value().(types.Dynamic)
+	o := d.UnderlyingValue().(types.Object)
+	assert.Equal(t, types.StringValue("ConfigMap"), o.Attributes()["kind"], nil)
+
+	spec := o.Attributes()["spec"].(types.Object)
+	ports := spec.Attributes()["ports"].(types.Tuple)
+	assert.Equal(t, []attr.Value{types.NumberValue(new(big.Float).SetInt64(80))}, ports.Elements(), nil)
+
+	resp = runManifestDecodeFunction("kind: ConfigMap\n")
+	assert.NotEqual(t, (*function.FuncError)(nil), resp.Error, nil)
+}
+
+func TestManifestEncodeFunction(t *testing.T) {
+	resp := runManifestDecodeFunction(testFunctionManifest)
+	assert.Equal(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	// encoding a decoded manifest returns the manifest
+	resp = runManifestEncodeFunction(resp.Result.Value().(types.Dynamic).UnderlyingValue())
+	assert.Equal(t, (*function.FuncError)(nil), resp.Error, nil)
+	assert.Equal(t, types.StringValue(testFunctionManifest), resp.Result.Value(), nil)
+
+	o, _ := types.ObjectValue(map[string]attr.Type{"kind": types.StringType}, map[string]attr.Value{"kind": types.StringValue("ConfigMap")})
+	resp = runManifestEncodeFunction(o)
+	assert.NotEqual(t, (*function.FuncError)(nil), resp.Error, nil)
+}
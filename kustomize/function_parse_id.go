@@ -0,0 +1,125 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var parseIDAttrTypes = map[string]attr.Type{
+	"group":     types.StringType,
+	"version":   types.StringType,
+	"kind":      types.StringType,
+	"namespace": types.StringType,
+	"name":      types.StringType,
+}
+
+// resourceID is a parsed ID, the IDs of the provider have no version
+type resourceID struct {
+	group     string
+	version   string
+	kind      string
+	namespace string
+	name      string
+}
+
+// kustomize marks empty fields of its resource IDs with these values
+var kustomizeIDEmpty = map[string]bool{"~G": true, "~V": true, "~K": true, "~X": true, "~N": true}
+
+func kustomizeIDField(v string) string {
+	if kustomizeIDEmpty[v] {
+		return ""
+	}
+	return v
+}
+
+// parseResourceID parses the IDs of the provider, e.g.
+// "apps/Deployment/test-ns/test", and the resource IDs kustomize uses
+// in its output and error messages, e.g. "apps_v1_Deployment|test-ns|test"
+func parseResourceID(s string) (resourceID, error) {
+	if !strings.Contains(s, "|") {
+		kr, err := parseProviderId(s)
+		if err != nil {
+			return resourceID{}, err
+		}
+
+		return resourceID{
+			group:     kr.group,
+			kind:      kr.kind,
+			namespace: kr.namespace,
+			name:      kr.name,
+		}, nil
+	}
+
+	parts := strings.Split(s, "|")
+	gvk := strings.Split(parts[0], "_")
+	if len(parts) != 3 || len(gvk) != 3 {
+		return resourceID{}, fmt.Errorf("invalid ID: %q, valid IDs look like: \"apps/Deployment/example/example\" or \"apps_v1_Deployment|example|example\"", s)
+	}
+
+	return resourceID{
+		group:     kustomizeIDField(gvk[0]),
+		version:   kustomizeIDField(gvk[1]),
+		kind:      kustomizeIDField(gvk[2]),
+		namespace: kustomizeIDField(parts[1]),
+		name:      kustomizeIDField(parts[2]),
+	}, nil
+}
+
+type parseIDFunction struct{}
+
+func newParseIDFunction() function.Function {
+	return &parseIDFunction{}
+}
+
+func (f *parseIDFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_id"
+}
+
+func (f *parseIDFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Split a resource ID into its parts",
+		Description: "Splits a resource ID of the provider, e.g. apps/Deployment/example/example, or of kustomize, e.g. apps_v1_Deployment|example|example, into an object with group, version, kind, namespace and name. Empty parts are empty strings, the IDs of the provider have no version.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "id",
+				Description: "The resource ID to parse.",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttributeTypes: parseIDAttrTypes,
+		},
+	}
+}
+
+func (f *parseIDFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var id string
+	resp.Error = req.Arguments.Get(ctx, &id)
+	if resp.Error != nil {
+		return
+	}
+
+	rid, err := parseResourceID(id)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	result, diags := types.ObjectValue(parseIDAttrTypes, map[string]attr.Value{
+		"group":     types.StringValue(rid.group),
+		"version":   types.StringValue(rid.version),
+		"kind":      types.StringValue(rid.kind),
+		"namespace": types.StringValue(rid.namespace),
+		"name":      types.StringValue(rid.name),
+	})
+	resp.Error = function.FuncErrorFromDiags(ctx, diags)
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, result)
+}
@@ -0,0 +1,59 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResourceID(t *testing.T) {
+	id, err := parseResourceID("apps/Deployment/test-ns/test")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, resourceID{group: "apps", kind: "Deployment", namespace: "test-ns", name: "test"}, id, nil)
+
+	id, err = parseResourceID("apps_v1_Deployment|test-ns|test")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, resourceID{group: "apps", version: "v1", kind: "Deployment", namespace: "test-ns", name: "test"}, id, nil)
+
+	id, err = parseResourceID("~G_v1_Namespace|~X|test-ns")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, resourceID{version: "v1", kind: "Namespace", name: "test-ns"}, id, nil)
+
+	_, err = parseResourceID("apps_v1_Deployment|test")
+	assert.NotEqual(t, nil, err, nil)
+
+	_, err = parseResourceID("apps/Deployment/test")
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func runParseIDFunction(id string) function.RunResponse {
+	resp := function.RunResponse{
+		Result: function.NewResultData(types.ObjectUnknown(parseIDAttrTypes)),
+	}
+	newParseIDFunction().Run(context.Background(), function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(id)}),
+	}, &resp)
+
+	return resp
+}
+
+func TestParseIDFunction(t *testing.T) {
+	resp := runParseIDFunction("apps_v1_Deployment|test-ns|test")
+	assert.Equal(t, (*function.FuncError)(nil), resp.Error, nil)
+
+	want, _ := types.ObjectValue(parseIDAttrTypes, map[string]attr.Value{
+		"group":     types.StringValue("apps"),
+		"version":   types.StringValue("v1"),
+		"kind":      types.StringValue("Deployment"),
+		"namespace": types.StringValue("test-ns"),
+		"name":      types.StringValue("test"),
+	})
+	assert.Equal(t, want, resp.Result.Value(), nil)
+
+	resp = runParseIDFunction("invalid")
+	assert.NotEqual(t, (*function.FuncError)(nil), resp.Error, nil)
+}
@@ -0,0 +1,233 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+func helmValuesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"chart": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"release_name": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"values_inline": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validateHelmValues,
+				},
+				"values_merge": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "merge",
+					ValidateFunc: validation.StringInSlice(
+						[]string{"merge", "replace"},
+						false,
+					),
+				},
+			},
+		},
+	}
+}
+
+func validateHelmValues(v interface{}, k string) (ws []string, es []error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(v.(string)), &values); err != nil {
+		es = append(es, fmt.Errorf("invalid %s: %s", k, err))
+	}
+
+	return ws, es
+}
+
+// helmValues are the values_inline to set for the helmCharts of
+// the kustomizations of a build, matched by chart and release name
+type helmValues struct {
+	chart       string
+	releaseName string
+	values      map[string]interface{}
+	replace     bool
+
+	// set when a helmCharts entry of the build matches
+	matched bool
+}
+
+func getHelmValues(in []interface{}) ([]*helmValues, error) {
+	var hvs []*helmValues
+	for _, v := range in {
+		hv := v.(map[string]interface{})
+
+		var values map[string]interface{}
+		if err := yaml.Unmarshal([]byte(hv["values_inline"].(string)), &values); err != nil {
+			return nil, fmt.Errorf("helm_values %q: %s", hv["chart"], err)
+		}
+
+		hvs = append(hvs, &helmValues{
+			chart:       hv["chart"].(string),
+			releaseName: hv["release_name"].(string),
+			values:      values,
+			replace:     hv["values_merge"].(string) == "replace",
+		})
+	}
+
+	return hvs, nil
+}
+
+func (hv *helmValues) matches(chart map[string]interface{}) bool {
+	name, _ := chart["name"].(string)
+	if name != hv.chart {
+		return false
+	}
+
+	if hv.releaseName == "" {
+		return true
+	}
+
+	releaseName, _ := chart["releaseName"].(string)
+	return releaseName == hv.releaseName
+}
+
+// unmatchedHelmValues fails for helm_values that did not match any
+// helmCharts entry of the build, e.g. because of a typo
+func unmatchedHelmValues(hvs []*helmValues) error {
+	var unmatched []string
+	for _, hv := range hvs {
+		if hv.matched {
+			continue
+		}
+
+		name := fmt.Sprintf("%q", hv.chart)
+		if hv.releaseName != "" {
+			name = fmt.Sprintf("%q with release name %q", hv.chart, hv.releaseName)
+		}
+		unmatched = append(unmatched, name)
+	}
+
+	if len(unmatched) > 0 {
+		return fmt.Errorf("helm_values for chart %s match no helmCharts of the kustomization", strings.Join(unmatched, ", "))
+	}
+
+	return nil
+}
+
+// mergeHelmValues deep merges src into dst, values of src win
+func mergeHelmValues(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+
+	for k, v := range src {
+		sm, srcIsMap := v.(map[string]interface{})
+		dm, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeHelmValues(dm, sm)
+			continue
+		}
+
+		dst[k] = copyHelmValue(v)
+	}
+
+	return dst
+}
+
+// copyHelmValue deep copies v, values are shared by all
+// kustomizations and must not be modified by merges
+func copyHelmValue(v interface{}) interface{} {
+	switch o := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(o))
+		for k, c := range o {
+			out[k] = copyHelmValue(c)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(o))
+		for i, c := range o {
+			out[i] = copyHelmValue(c)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// helmValuesFileSystem sets the valuesInline of the matching helmCharts
+// of all kustomizations kustomize reads, including bases and components
+type helmValuesFileSystem struct {
+	filesys.FileSystem
+	values []*helmValues
+}
+
+func makeHelmValuesFS(fs filesys.FileSystem, hvs []*helmValues) filesys.FileSystem {
+	if len(hvs) == 0 {
+		return fs
+	}
+
+	return helmValuesFileSystem{
+		FileSystem: fs,
+		values:     hvs,
+	}
+}
+
+func (hfs helmValuesFileSystem) ReadFile(name string) ([]byte, error) {
+	content, err := hfs.FileSystem.ReadFile(name)
+	if err != nil || !isKustomizationFileName(name) {
+		return content, err
+	}
+
+	var k map[string]interface{}
+	if err := yaml.Unmarshal(content, &k); err != nil || k == nil {
+		// left for kustomize to report
+		return content, nil
+	}
+
+	charts, _ := k["helmCharts"].([]interface{})
+	changed := false
+	for _, c := range charts {
+		chart, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, hv := range hfs.values {
+			if !hv.matches(chart) {
+				continue
+			}
+			hv.matched = true
+			changed = true
+
+			if hv.replace {
+				chart["valuesInline"] = copyHelmValue(hv.values)
+				continue
+			}
+
+			existing, _ := chart["valuesInline"].(map[string]interface{})
+			chart["valuesInline"] = mergeHelmValues(existing, hv.values)
+		}
+	}
+
+	if !changed {
+		return content, nil
+	}
+
+	return yaml.Marshal(k)
+}
+
+// getHelmValuesConfig returns the helm_values of d, data sources
+// sharing the build without the argument have none
+func getHelmValuesConfig(d *schema.ResourceData) []interface{} {
+	hvs, _ := d.Get("helm_values").([]interface{})
+	return hvs
+}
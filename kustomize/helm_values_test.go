@@ -0,0 +1,101 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+const testHelmChartsKustomization = `helmCharts:
+- name: minecraft
+  releaseName: moria
+  valuesInline:
+    minecraftServer:
+      eula: true
+      difficulty: easy
+- name: minecraft
+  releaseName: erebor
+  valuesInline:
+    minecraftServer:
+      difficulty: easy
+`
+
+func readTestHelmCharts(t *testing.T, hvs []*helmValues) []interface{} {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("kustomization.yaml", []byte(testHelmChartsKustomization))
+
+	content, err := makeHelmValuesFS(fSys, hvs).ReadFile("kustomization.yaml")
+	assert.Equal(t, nil, err, nil)
+
+	var k map[string]interface{}
+	assert.Equal(t, nil, yaml.Unmarshal(content, &k), nil)
+
+	return k["helmCharts"].([]interface{})
+}
+
+func TestHelmValuesFS(t *testing.T) {
+	hvs, err := getHelmValues([]interface{}{
+		map[string]interface{}{
+			"chart":         "minecraft",
+			"release_name":  "moria",
+			"values_inline": "minecraftServer:\n  difficulty: hard\n",
+			"values_merge":  "merge",
+		},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	charts := readTestHelmCharts(t, hvs)
+	assert.Equal(t, map[string]interface{}{
+		"minecraftServer": map[string]interface{}{"eula": true, "difficulty": "hard"},
+	}, charts[0].(map[string]interface{})["valuesInline"], nil)
+	assert.Equal(t, map[string]interface{}{
+		"minecraftServer": map[string]interface{}{"difficulty": "easy"},
+	}, charts[1].(map[string]interface{})["valuesInline"], nil)
+	assert.Equal(t, nil, unmatchedHelmValues(hvs), nil)
+}
+
+func TestHelmValuesFSReplace(t *testing.T) {
+	hvs, err := getHelmValues([]interface{}{
+		map[string]interface{}{
+			"chart":         "minecraft",
+			"release_name":  "",
+			"values_inline": "minecraftServer:\n  difficulty: hard\n",
+			"values_merge":  "replace",
+		},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	// without release_name all releases of the chart match
+	for _, c := range readTestHelmCharts(t, hvs) {
+		assert.Equal(t, map[string]interface{}{
+			"minecraftServer": map[string]interface{}{"difficulty": "hard"},
+		}, c.(map[string]interface{})["valuesInline"], nil)
+	}
+}
+
+func TestUnmatchedHelmValues(t *testing.T) {
+	hvs, err := getHelmValues([]interface{}{
+		map[string]interface{}{
+			"chart":         "minecraft",
+			"release_name":  "rivendell",
+			"values_inline": "{}",
+			"values_merge":  "merge",
+		},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	readTestHelmCharts(t, hvs)
+	assert.EqualError(t, unmatchedHelmValues(hvs), `helm_values for chart "minecraft" with release name "rivendell" match no helmCharts of the kustomization`, nil)
+}
+
+func TestMergeHelmValues(t *testing.T) {
+	src := map[string]interface{}{"a": map[string]interface{}{"b": 1}}
+	dst := mergeHelmValues(map[string]interface{}{"a": "scalar", "c": true}, src)
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": true}, dst, nil)
+
+	// src is not modified by later merges
+	mergeHelmValues(dst, map[string]interface{}{"a": map[string]interface{}{"d": 2}})
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": 1}}, src, nil)
+}
@@ -0,0 +1,29 @@
+package kustomize
+
+import (
+	"net/http"
+)
+
+// limitTransport bounds the number of concurrent requests
+// to the Kubernetes API, sem is shared by all clusters
+type limitTransport struct {
+	sem chan struct{}
+	rt  http.RoundTripper
+}
+
+func newLimitTransport(sem chan struct{}) func(rt http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &limitTransport{sem: sem, rt: rt}
+	}
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-t.sem }()
+
+	return t.rt.RoundTrip(req)
+}
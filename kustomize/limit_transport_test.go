@@ -0,0 +1,49 @@
+package kustomize
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingTransport struct {
+	current int32
+	max     int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := atomic.AddInt32(&t.current, 1)
+	for {
+		m := atomic.LoadInt32(&t.max)
+		if c <= m || atomic.CompareAndSwapInt32(&t.max, m, c) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	atomic.AddInt32(&t.current, -1)
+
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func TestLimitTransport(t *testing.T) {
+	ct := &countingTransport{}
+	rt := newLimitTransport(make(chan struct{}, 2))(ct)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+			rt.RoundTrip(req)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), ct.max, nil)
+}
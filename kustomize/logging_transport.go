@@ -0,0 +1,73 @@
+package kustomize
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+)
+
+var redactHeadersRegexp = regexp.MustCompile(`(?im)^(Authorization|Proxy-Authorization|Cookie|Set-Cookie):[^\r\n]*`)
+
+// lastAppliedConfig annotations hold the complete manifest of the
+// object, of any kind, as a JSON string value
+var redactLastAppliedRegexp = regexp.MustCompile(`("(?:` + regexp.QuoteMeta(lastAppliedConfigAnnotation) + `|` + regexp.QuoteMeta(gzipLastAppliedConfigAnnotation) + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// loggingTransport logs requests to and responses from the
+// Kubernetes API, with credentials and secret data redacted
+type loggingTransport struct {
+	rt http.RoundTripper
+}
+
+func newLoggingTransport(rt http.RoundTripper) http.RoundTripper {
+	return &loggingTransport{rt: rt}
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		log.Printf("[DEBUG] Kubernetes API request %s %s: dump failed: %s", req.Method, req.URL, err)
+	} else {
+		log.Printf("[DEBUG] Kubernetes API request:\n%s", redactHTTPDump(req.URL.Path, dump))
+	}
+
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		log.Printf("[DEBUG] Kubernetes API request %s %s failed: %s", req.Method, req.URL, err)
+		return resp, err
+	}
+
+	// do not read the body of watch responses, they are streamed
+	withBody := req.URL.Query().Get("watch") != "true"
+
+	dump, err = httputil.DumpResponse(resp, withBody)
+	if err != nil {
+		log.Printf("[DEBUG] Kubernetes API response %s %s: dump failed: %s", req.Method, req.URL, err)
+	} else {
+		log.Printf("[DEBUG] Kubernetes API response:\n%s", redactHTTPDump(req.URL.Path, dump))
+	}
+
+	return resp, nil
+}
+
+// redactHTTPDump redacts credential headers, the values of
+// lastAppliedConfig annotations and the body of requests
+// and responses for secrets
+func redactHTTPDump(path string, dump []byte) string {
+	dump = redactHeadersRegexp.ReplaceAll(dump, []byte("$1: <redacted>"))
+	dump = redactLastAppliedRegexp.ReplaceAll(dump, []byte(`$1"<redacted>"`))
+
+	if !strings.Contains(path, "/secrets") {
+		return string(dump)
+	}
+
+	sep := []byte("\r\n\r\n")
+	i := bytes.Index(dump, sep)
+	if i < 0 || i+len(sep) == len(dump) {
+		return string(dump)
+	}
+
+	return string(dump[:i+len(sep)]) + "<redacted>"
+}
@@ -0,0 +1,19 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHTTPDump(t *testing.T) {
+	dump := "GET /api/v1/namespaces/test/configmaps/test HTTP/1.1\r\nHost: localhost\r\nAuthorization: Bearer secret-token\r\n\r\n{\"data\":{}}"
+	assert.Equal(t, "GET /api/v1/namespaces/test/configmaps/test HTTP/1.1\r\nHost: localhost\r\nAuthorization: <redacted>\r\n\r\n{\"data\":{}}", redactHTTPDump("/api/v1/namespaces/test/configmaps/test", []byte(dump)), nil)
+
+	dump = "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"data\":{\"password\":\"c2VjcmV0\"}}"
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n<redacted>", redactHTTPDump("/api/v1/namespaces/test/secrets/test", []byte(dump)), nil)
+
+	// last applied annotations of all kinds
+	dump = "PATCH /apis/apps/v1/namespaces/test/deployments/test HTTP/1.1\r\nHost: localhost\r\n\r\n{\"metadata\":{\"annotations\":{\"kubectl.kubernetes.io/last-applied-configuration\":\"{\\\"kind\\\":\\\"Deployment\\\",\\\"env\\\":\\\"secret\\\"}\",\"kustomization.kubestack.com/last-applied-config-gzip\": \"H4sIAAAA\",\"other\":\"kept\"}}}"
+	assert.Equal(t, "PATCH /apis/apps/v1/namespaces/test/deployments/test HTTP/1.1\r\nHost: localhost\r\n\r\n{\"metadata\":{\"annotations\":{\"kubectl.kubernetes.io/last-applied-configuration\":\"<redacted>\",\"kustomization.kubestack.com/last-applied-config-gzip\": \"<redacted>\",\"other\":\"kept\"}}}", redactHTTPDump("/apis/apps/v1/namespaces/test/deployments/test", []byte(dump)), nil)
+}
@@ -2,14 +2,15 @@ package kustomize
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/kbst/terraform-provider-kustomize/manifest"
 
-	k8sappsv1 "k8s.io/api/apps/v1"
+	k8scorev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	k8smeta "k8s.io/apimachinery/pkg/api/meta"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,12 +20,14 @@ import (
 	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	k8sdynamic "k8s.io/client-go/dynamic"
-	"k8s.io/client-go/restmapper"
 )
 
 var waitRefreshFunctions = map[string]waitRefreshFunction{
-	"apps/Deployment": waitDeploymentRefresh,
-	"apps/Daemonset":  waitDaemonsetRefresh,
+	"apps/Deployment":           waitRolloutRefresh(deploymentRolloutStatus),
+	"apps/DaemonSet":            waitDaemonSetRefresh,
+	"apps/StatefulSet":          waitRolloutRefresh(statefulSetRolloutStatus),
+	"/Service":                  waitLoadBalancerRefresh,
+	"networking.k8s.io/Ingress": waitLoadBalancerRefresh,
 }
 
 type kManifestId struct {
@@ -46,27 +49,30 @@ func mustParseProviderId(str string) *kManifestId {
 }
 
 func parseProviderId(str string) (*kManifestId, error) {
-	parts := strings.Split(str, "/")
-
-	if len(parts) != 4 {
-		return nil, fmt.Errorf("invalid ID: %q, valid IDs look like: \"_/Namespace/_/example\"", str)
+	id, err := manifest.ParseID(str)
+	if err != nil {
+		return nil, err
 	}
 
-	group := parts[0]
-	kind := parts[1]
-	namespace := parts[2]
-	name := parts[3]
-
 	return &kManifestId{
-		group:     underscoreToEmpty(group),
-		kind:      kind,
-		namespace: underscoreToEmpty(namespace),
-		name:      name,
+		group:     id.Group,
+		kind:      id.Kind,
+		namespace: id.Namespace,
+		name:      id.Name,
 	}, nil
 }
 
+func (k kManifestId) id() manifest.ID {
+	return manifest.ID{
+		Group:     k.group,
+		Kind:      k.kind,
+		Namespace: k.namespace,
+		Name:      k.name,
+	}
+}
+
 func (k kManifestId) string() string {
-	return fmt.Sprintf("%s/%s/%s/%s", emptyToUnderscore(k.group), k.kind, emptyToUnderscore(k.namespace), k.name)
+	return k.id().String()
 }
 
 func underscoreToEmpty(value string) string {
@@ -85,12 +91,40 @@ func emptyToUnderscore(value string) string {
 
 type kManifest struct {
 	resource *k8sunstructured.Unstructured
-	mapper   *restmapper.DeferredDiscoveryRESTMapper
+	mapper   k8smeta.ResettableRESTMapper
 	client   k8sdynamic.Interface
 	json     []byte
+
+	// podLogs, if set, adds logs of failed containers to wait errors
+	podLogs podLogsFunction
+
+	// secrets, if set, resolves secret placeholders in API requests
+	secrets *secretResolver
+
+	// auditAnnotations, if set, are added to the objects of API requests
+	auditAnnotations map[string]string
+
+	// retry, if set, retries failed API requests
+	retry *retryPolicy
+
+	// readinessChecks, if set, determine when objects of their
+	// kinds are ready, instead of the built-in checks
+	readinessChecks map[string]waitRefreshFunction
+
+	// skipUnavailableNodes, if set, does not wait for the
+	// pods of daemonsets on cordoned or not ready nodes
+	skipUnavailableNodes bool
+
+	// rawDiff, if set, patches using JSON merge patches for all
+	// kinds, without strategic merge assumptions
+	rawDiff bool
+
+	// leftFields are the conflicting fields the last server-side
+	// apply left to other field managers
+	leftFields []string
 }
 
-func newKManifest(mapper *restmapper.DeferredDiscoveryRESTMapper, client k8sdynamic.Interface) *kManifest {
+func newKManifest(mapper k8smeta.ResettableRESTMapper, client k8sdynamic.Interface) *kManifest {
 	return &kManifest{
 		mapper: mapper,
 		client: client,
@@ -98,17 +132,43 @@ func newKManifest(mapper *restmapper.DeferredDiscoveryRESTMapper, client k8sdyna
 }
 
 func (km *kManifest) load(body []byte) error {
-	obj, err := k8sruntime.Decode(k8sunstructured.UnstructuredJSONScheme, body)
+	u, err := manifest.Decode(body)
 	if err != nil {
-		return logError(fmt.Errorf("json error: %s", err))
+		return logError(err)
 	}
 
-	km.resource = obj.(*k8sunstructured.Unstructured)
+	km.resource = u
 	km.json = body
 
 	return nil
 }
 
+// requestObject returns the resource and its JSON to send to the API,
+// with secret placeholders resolved and audit annotations added
+func (km *kManifest) requestObject() (*k8sunstructured.Unstructured, []byte, error) {
+	u, j, err := km.withSecrets()
+	if err != nil || len(km.auditAnnotations) == 0 {
+		return u, j, err
+	}
+
+	u = u.DeepCopy()
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for k, v := range km.auditAnnotations {
+		annotations[k] = v
+	}
+	u.SetAnnotations(annotations)
+
+	j, err = u.MarshalJSON()
+	if err != nil {
+		return nil, nil, km.fmtErr(err)
+	}
+
+	return u, j, nil
+}
+
 func (km *kManifest) gvk() k8sschema.GroupVersionKind {
 	return km.resource.GroupVersionKind()
 }
@@ -182,7 +242,12 @@ func (km *kManifest) apiGet(opts k8smetav1.GetOptions) (resp *k8sunstructured.Un
 		return resp, km.fmtErr(fmt.Errorf("get failed: %s", err))
 	}
 
-	return api.Get(context.TODO(), km.name(), opts)
+	err = km.retry.do(km.id().string(), func() (err error) {
+		resp, err = api.Get(context.TODO(), km.name(), opts)
+		return err
+	})
+
+	return resp, err
 }
 
 func (km *kManifest) apiCreate(opts k8smetav1.CreateOptions) (resp *k8sunstructured.Unstructured, err error) {
@@ -191,7 +256,59 @@ func (km *kManifest) apiCreate(opts k8smetav1.CreateOptions) (resp *k8sunstructu
 		return resp, km.fmtErr(fmt.Errorf("create failed: %s", err))
 	}
 
-	return api.Create(context.TODO(), km.resource, opts)
+	u, _, err := km.requestObject()
+	if err != nil {
+		return resp, err
+	}
+
+	attempt := 0
+	var existsErr error
+	err = km.retry.do(km.id().string(), func() (err error) {
+		attempt++
+		resp, err = api.Create(context.TODO(), u, opts)
+
+		// a failed earlier attempt, e.g. timing out,
+		// can have created the object nevertheless
+		if attempt > 1 && len(opts.DryRun) == 0 && k8serrors.IsAlreadyExists(err) {
+			existsErr = err
+			return nil
+		}
+		return err
+	})
+	if err != nil || existsErr == nil {
+		return resp, describeAdmissionDenial(err)
+	}
+
+	// only adopt the object if the earlier attempt created it,
+	// not if someone else created an object of the same name
+	live, err := km.apiGet(k8smetav1.GetOptions{})
+	if err != nil || !isCreatedFrom(live, u, opts.FieldManager) {
+		return resp, existsErr
+	}
+
+	log.Printf("[WARN] %q: created by an earlier attempt", km.id().string())
+	return live, nil
+}
+
+// isCreatedFrom returns true if the live object has the lastAppliedConfig
+// of the request object u, and was created by the field manager, if set
+func isCreatedFrom(live *k8sunstructured.Unstructured, u *k8sunstructured.Unstructured, manager string) bool {
+	lac := getLastAppliedConfig(u, true)
+	if lac == "" || getLastAppliedConfig(live, true) != lac {
+		return false
+	}
+
+	if manager == "" {
+		return true
+	}
+
+	for _, mf := range live.GetManagedFields() {
+		if mf.Manager == manager && mf.Operation == k8smetav1.ManagedFieldsOperationUpdate && mf.Subresource == "" {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (km *kManifest) apiDelete(opts k8smetav1.DeleteOptions) (err error) {
@@ -200,12 +317,25 @@ func (km *kManifest) apiDelete(opts k8smetav1.DeleteOptions) (err error) {
 		return km.fmtErr(fmt.Errorf("delete failed: %s", err))
 	}
 
-	return api.Delete(context.TODO(), km.name(), opts)
+	err = km.retry.do(km.id().string(), func() error {
+		return api.Delete(context.TODO(), km.name(), opts)
+	})
+
+	return describeAdmissionDenial(err)
 }
 
 func (km *kManifest) apiPreparePatch(kmo *kManifest, currAllowNotFound bool) (pt k8stypes.PatchType, p []byte, err error) {
-	original := kmo.json
-	modified := km.json
+	// audit annotations are part of both, to remove those
+	// no longer set and not patch unchanged values
+	_, original, err := kmo.requestObject()
+	if err != nil {
+		return pt, p, err
+	}
+
+	_, modified, err := km.requestObject()
+	if err != nil {
+		return pt, p, err
+	}
 
 	resp, err := km.apiGet(k8smetav1.GetOptions{})
 	if err != nil {
@@ -219,7 +349,11 @@ func (km *kManifest) apiPreparePatch(kmo *kManifest, currAllowNotFound bool) (pt
 		return pt, p, km.fmtErr(fmt.Errorf("error preparing patch: %s", err))
 	}
 
-	pt, p, err = getPatch(km.gvk(), original, modified, current)
+	if km.rawDiff {
+		pt, p, err = getMergePatch(original, modified, current)
+	} else {
+		pt, p, err = getPatch(km.gvk(), original, modified, current)
+	}
 	if err != nil {
 		return pt, p, km.fmtErr(fmt.Errorf("error preparing patch: %s", err))
 	}
@@ -233,7 +367,117 @@ func (km *kManifest) apiPatch(pt k8stypes.PatchType, p []byte, opts k8smetav1.Pa
 		return resp, km.fmtErr(fmt.Errorf("patch failed: %s", err))
 	}
 
-	return api.Patch(context.TODO(), km.name(), pt, p, opts)
+	err = km.retry.do(km.id().string(), func() (err error) {
+		resp, err = api.Patch(context.TODO(), km.name(), pt, p, opts)
+		return err
+	})
+	return resp, describeAdmissionDenial(err)
+}
+
+func (km *kManifest) apiReplace(opts k8smetav1.UpdateOptions) (resp *k8sunstructured.Unstructured, err error) {
+	api, err := km.api()
+	if err != nil {
+		return resp, km.fmtErr(fmt.Errorf("replace failed: %s", err))
+	}
+
+	current, err := km.apiGet(k8smetav1.GetOptions{})
+	if err != nil {
+		return resp, err
+	}
+
+	u, _, err := km.requestObject()
+	if err != nil {
+		return resp, err
+	}
+
+	// replace requires the resourceVersion of the object being
+	// replaced, set on a copy to keep the manifest unmodified
+	u = u.DeepCopy()
+	u.SetResourceVersion(current.GetResourceVersion())
+
+	err = km.retry.do(km.id().string(), func() (err error) {
+		resp, err = api.Update(context.TODO(), u, opts)
+		return err
+	})
+	if err != nil && k8serrors.IsConflict(err) {
+		return resp, km.fmtErr(fmt.Errorf("replace failed, resource was modified after resourceVersion %q was read: %s", current.GetResourceVersion(), err))
+	}
+
+	return resp, describeAdmissionDenial(err)
+}
+
+func (km *kManifest) apiScale(replicas int64, opts k8smetav1.PatchOptions) (resp *k8sunstructured.Unstructured, err error) {
+	api, err := km.api()
+	if err != nil {
+		return resp, km.fmtErr(fmt.Errorf("scale failed: %s", err))
+	}
+
+	p := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+
+	err = km.retry.do(km.id().string(), func() (err error) {
+		resp, err = api.Patch(context.TODO(), km.name(), k8stypes.MergePatchType, p, opts, "scale")
+		return err
+	})
+
+	return resp, describeAdmissionDenial(err)
+}
+
+// hasStatus returns true if the manifest sets a status
+func (km *kManifest) hasStatus() bool {
+	_, ok := km.resource.Object["status"]
+	return ok
+}
+
+// apiPatchStatus sets the status of the manifest using the status
+// subresource, for custom resources using the status as input
+func (km *kManifest) apiPatchStatus(opts k8smetav1.PatchOptions) (resp *k8sunstructured.Unstructured, err error) {
+	api, err := km.api()
+	if err != nil {
+		return resp, km.fmtErr(fmt.Errorf("status patch failed: %s", err))
+	}
+
+	u, _, err := km.requestObject()
+	if err != nil {
+		return resp, err
+	}
+
+	p, err := json.Marshal(map[string]interface{}{"status": u.Object["status"]})
+	if err != nil {
+		return resp, km.fmtErr(fmt.Errorf("status patch failed: %s", err))
+	}
+
+	err = km.retry.do(km.id().string(), func() (err error) {
+		resp, err = api.Patch(context.TODO(), km.name(), k8stypes.MergePatchType, p, opts, "status")
+		return err
+	})
+
+	return resp, err
+}
+
+func (km *kManifest) apiApply(opts k8smetav1.PatchOptions) (resp *k8sunstructured.Unstructured, err error) {
+	api, err := km.api()
+	if err != nil {
+		return resp, km.fmtErr(fmt.Errorf("apply failed: %s", err))
+	}
+
+	if opts.FieldManager == "" {
+		opts.FieldManager = fieldManager
+	}
+	if opts.Force == nil {
+		force := true
+		opts.Force = &force
+	}
+
+	_, j, err := km.requestObject()
+	if err != nil {
+		return resp, err
+	}
+
+	err = km.retry.do(km.id().string(), func() (err error) {
+		resp, err = api.Patch(context.TODO(), km.name(), k8stypes.ApplyPatchType, j, opts)
+		return err
+	})
+	return resp, describeAdmissionDenial(err)
 }
 
 func parseResourceData(km *kManifest, d string) (err error) {
@@ -276,9 +520,9 @@ func (km *kManifest) waitKind(t time.Duration) error {
 			mapping, err := km.mapping()
 			if err != nil {
 				if k8smeta.IsNoMatchError(err) {
-					// if not found, reset mapper cache
+					// if not found, rediscover the group
 					// before trying again (required for CRDs)
-					km.mapper.Reset()
+					invalidateGroups(km.mapper, km.gvk().Group)
 					return nil, "pending", nil
 				}
 				return nil, "", err
@@ -338,6 +582,7 @@ func (km *kManifest) waitNamespace(t time.Duration) error {
 }
 
 func (km *kManifest) waitDeleted(t time.Duration) error {
+	var last *k8sunstructured.Unstructured
 	stateConf := &resource.StateChangeConf{
 		Target:  []string{},
 		Pending: []string{"deleting"},
@@ -351,34 +596,55 @@ func (km *kManifest) waitDeleted(t time.Duration) error {
 				return nil, "", err
 			}
 
+			last = resp
+			km.logProgress(deletionProgress(resp))
 			return resp, "deleting", nil
 		},
 	}
 
 	_, err := stateConf.WaitForState()
 	if err != nil {
+		// name the finalizers blocking the deletion, if any
+		if last != nil {
+			if f := describeFinalizers(last); f != "" {
+				err = fmt.Errorf("%s, %s", err, f)
+			}
+		}
 		return km.fmtErr(fmt.Errorf("timed out deleting: %s", err))
 	}
 
 	return nil
 }
 
-func daemonsetReady(u *k8sunstructured.Unstructured) (bool, error) {
-	var daemonset k8sappsv1.DaemonSet
-	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &daemonset); err != nil {
-		return false, err
+func (km *kManifest) hasLoadBalancerStatus() bool {
+	switch fmt.Sprintf("%s/%s", km.gvk().Group, km.gvk().Kind) {
+	case "/Service", "networking.k8s.io/Ingress":
+		return true
 	}
-	if daemonset.Generation == daemonset.Status.ObservedGeneration &&
-		daemonset.Status.UpdatedNumberScheduled == daemonset.Status.DesiredNumberScheduled &&
-		daemonset.Status.NumberReady == daemonset.Status.DesiredNumberScheduled &&
-		daemonset.Status.NumberUnavailable == 0 {
-		return true, nil
-	} else {
-		return false, nil
+	return false
+}
+
+func loadBalancerReady(u *k8sunstructured.Unstructured) (bool, error) {
+	if u.GetKind() == "Service" {
+		t, _, err := k8sunstructured.NestedString(u.UnstructuredContent(), "spec", "type")
+		if err != nil {
+			return false, err
+		}
+		if t != string(k8scorev1.ServiceTypeLoadBalancer) {
+			// only services of type LoadBalancer get an address assigned
+			return true, nil
+		}
+	}
+
+	ingress, _, err := k8sunstructured.NestedSlice(u.UnstructuredContent(), "status", "loadBalancer", "ingress")
+	if err != nil {
+		return false, err
 	}
+
+	return len(ingress) > 0, nil
 }
 
-func waitDaemonsetRefresh(km *kManifest) (interface{}, string, error) {
+func waitLoadBalancerRefresh(km *kManifest) (interface{}, string, error) {
 	resp, err := km.apiGet(k8smetav1.GetOptions{})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -386,52 +652,143 @@ func waitDaemonsetRefresh(km *kManifest) (interface{}, string, error) {
 		}
 		return nil, "error", err
 	}
-	ready, err := daemonsetReady(resp)
+	ready, err := loadBalancerReady(resp)
 	if err != nil {
 		return nil, "error", err
 	}
 	if ready {
 		return resp, "done", nil
 	}
+	km.logProgress("no load balancer address assigned yet")
 	return nil, "in progress", nil
 }
 
-func deploymentReady(u *k8sunstructured.Unstructured) (bool, error) {
-	var deployment k8sappsv1.Deployment
-	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &deployment); err != nil {
-		return false, err
-	}
-	if deployment.Generation == deployment.Status.ObservedGeneration &&
-		deployment.Status.AvailableReplicas == *deployment.Spec.Replicas &&
-		deployment.Status.AvailableReplicas == deployment.Status.Replicas &&
-		deployment.Status.UnavailableReplicas == 0 {
-		return true, nil
-	} else {
-		return false, nil
+// logProgress logs the progress of a wait including the
+// latest event of the resource, if any
+func (km *kManifest) logProgress(progress string) {
+	msg := fmt.Sprintf("[INFO] %q: waiting: %s", km.id().string(), progress)
+
+	if event := km.lastEvent(); event != "" {
+		msg = fmt.Sprintf("%s, last event: %s", msg, event)
 	}
+
+	log.Print(msg)
 }
 
-func waitDeploymentRefresh(km *kManifest) (interface{}, string, error) {
-	resp, err := km.apiGet(k8smetav1.GetOptions{})
+func (km *kManifest) events(selector string) (events []k8scorev1.Event, err error) {
+	gvr := k8sschema.GroupVersionResource{Group: "", Version: "v1", Resource: "events"}
+
+	resp, err := km.client.
+		Resource(gvr).
+		Namespace(km.namespace()).
+		List(context.TODO(), k8smetav1.ListOptions{FieldSelector: selector})
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return nil, "missing", nil
+		return events, err
+	}
+
+	for _, u := range resp.Items {
+		var e k8scorev1.Event
+		if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &e); err != nil {
+			return events, err
 		}
-		return nil, "error", err
+		events = append(events, e)
 	}
-	ready, err := deploymentReady(resp)
+
+	return events, nil
+}
+
+func (km *kManifest) lastEvent() string {
+	selector := fmt.Sprintf("involvedObject.kind=%s,involvedObject.name=%s", km.gvk().Kind, km.name())
+
+	events, err := km.events(selector)
 	if err != nil {
-		return nil, "error", err
+		return ""
 	}
-	if ready {
-		return resp, "done", nil
+
+	var last k8scorev1.Event
+	for _, e := range events {
+		if last.LastTimestamp.Before(&e.LastTimestamp) || last.Name == "" {
+			last = e
+		}
 	}
-	return nil, "in progress", nil
+
+	if last.Name == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s: %s", last.Reason, last.Message)
+}
+
+func (km *kManifest) isCRD() bool {
+	return km.gvk().Group == "apiextensions.k8s.io" && km.gvk().Kind == "CustomResourceDefinition"
+}
+
+func (km *kManifest) isAPIService() bool {
+	return km.gvk().Group == "apiregistration.k8s.io" && km.gvk().Kind == "APIService"
+}
+
+// servedGroup returns the API group served by a CRD or
+// an APIService, and false for all other kinds
+func (km *kManifest) servedGroup() (string, bool) {
+	if !km.isCRD() && !km.isAPIService() {
+		return "", false
+	}
+
+	g, _, _ := k8sunstructured.NestedString(km.resource.UnstructuredContent(), "spec", "group")
+
+	return g, true
+}
+
+func (km *kManifest) isLoadBalancerService() bool {
+	if km.gvk().Group != "" || km.gvk().Kind != "Service" {
+		return false
+	}
+
+	t, _, _ := k8sunstructured.NestedString(km.resource.UnstructuredContent(), "spec", "type")
+
+	return t == string(k8scorev1.ServiceTypeLoadBalancer)
+}
+
+// waitLoadBalancerDeleted waits for the service controller to report
+// the cloud load balancer of the deleted service with uid as deleted
+func (km *kManifest) waitLoadBalancerDeleted(uid string, t time.Duration) error {
+	selector := fmt.Sprintf("involvedObject.uid=%s,reason=DeletedLoadBalancer", uid)
+
+	stateConf := &resource.StateChangeConf{
+		Target:  []string{"deleted"},
+		Pending: []string{"deleting"},
+		Timeout: t,
+		Refresh: func() (interface{}, string, error) {
+			events, err := km.events(selector)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if len(events) > 0 {
+				return events, "deleted", nil
+			}
+
+			km.logProgress("load balancer not deleted yet")
+			return events, "deleting", nil
+		},
+	}
+
+	_, err := stateConf.WaitForState()
+	if err != nil {
+		return km.fmtErr(fmt.Errorf("timed out waiting for load balancer cleanup: %s", err))
+	}
+
+	return nil
 }
 
 func (km *kManifest) waitCreatedOrUpdated(t time.Duration) error {
 	gvk := km.gvk()
-	if refresh, ok := waitRefreshFunctions[fmt.Sprintf("%s/%s", gvk.Group, gvk.Kind)]; ok {
+	gk := fmt.Sprintf("%s/%s", gvk.Group, gvk.Kind)
+	refresh, ok := km.readinessChecks[gk]
+	if !ok {
+		refresh, ok = waitRefreshFunctions[gk]
+	}
+	if ok {
 		delay := 10 * time.Second
 		stateConf := &resource.StateChangeConf{
 			Target:         []string{"done"},
@@ -445,9 +802,12 @@ func (km *kManifest) waitCreatedOrUpdated(t time.Duration) error {
 		}
 
 		_, err := stateConf.WaitForState()
-		if err != nil {
+		if _, ok := err.(*resource.TimeoutError); ok {
 			return km.fmtErr(fmt.Errorf("timed out creating/updating %s %s/%s: %s", gvk.Kind, km.namespace(), km.name(), err))
 		}
+		if err != nil {
+			return km.fmtErr(fmt.Errorf("creating/updating %s %s/%s failed: %s", gvk.Kind, km.namespace(), km.name(), err))
+		}
 	}
 	return nil
 }
@@ -0,0 +1,86 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// getManifestHistory returns the plaintext of the previously
+// applied manifests in state, the most recent first
+func getManifestHistory(d *schema.ResourceData, m interface{}) ([]string, error) {
+	var history []string
+	for _, v := range d.Get("history").([]interface{}) {
+		manifest, err := m.(*Config).StateEncryption.decrypt(v.(string))
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, manifest)
+	}
+
+	return history, nil
+}
+
+// setManifestHistory stores the history, encrypted if enabled,
+// keeping at most the most recent history_limit manifests
+func setManifestHistory(d *schema.ResourceData, history []string, m interface{}) error {
+	limit := d.Get("history_limit").(int)
+	if len(history) > limit {
+		history = history[:limit]
+	}
+
+	se := m.(*Config).StateEncryption
+	out := make([]interface{}, 0, len(history))
+	for _, h := range history {
+		encrypted, err := se.encrypt(h)
+		if err != nil {
+			return fmt.Errorf("encrypting manifest history failed: %s", err)
+		}
+		out = append(out, encrypted)
+	}
+
+	d.Set("history", out)
+	return nil
+}
+
+// changeGetter is implemented by ResourceData and ResourceDiff
+type changeGetter interface {
+	Get(string) interface{}
+	HasChange(string) bool
+}
+
+// isRollback returns true if the rollback_to_previous trigger changed
+// to a non-empty value, to apply the most recent manifest of the history
+func isRollback(d changeGetter) bool {
+	return d.HasChange("rollback_to_previous") && d.Get("rollback_to_previous").(string) != ""
+}
+
+// diffManifestHistory validates rollbacks, they require a previous
+// manifest in the history and can not be combined with a manifest
+// change, and plans the history of updates to change
+func diffManifestHistory(d *schema.ResourceDiff) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	if isRollback(d) {
+		if d.HasChange("manifest") {
+			return fmt.Errorf("rollback_to_previous can not be changed together with the manifest")
+		}
+
+		if len(d.Get("history").([]interface{})) == 0 {
+			return fmt.Errorf("rollback_to_previous: no previous manifest in history, set history_limit to keep applied manifests")
+		}
+
+		for _, k := range []string{"history", "generation", "resource_version", "server_side_applied", "last_applied_at", "apply_duration"} {
+			d.SetNewComputed(k)
+		}
+		return nil
+	}
+
+	if d.HasChange("history_limit") || (d.HasChange("manifest") && d.Get("history_limit").(int) > 0) {
+		d.SetNewComputed("history")
+	}
+
+	return nil
+}
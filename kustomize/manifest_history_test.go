@@ -0,0 +1,36 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestHistory(t *testing.T) {
+	se, err := getStateEncryption("test-passphrase")
+	assert.Equal(t, nil, err, nil)
+	m := &Config{StateEncryption: se}
+
+	d := schema.TestResourceDataRaw(t, kustomizationResource().Schema, map[string]interface{}{
+		"history_limit": 2,
+	})
+
+	err = setManifestHistory(d, []string{"third", "second", "first"}, m)
+	assert.Equal(t, nil, err, nil)
+
+	for _, v := range d.Get("history").([]interface{}) {
+		assert.Equal(t, true, isEncryptedManifest(v.(string)), nil)
+	}
+
+	history, err := getManifestHistory(d, m)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{"third", "second"}, history, nil)
+
+	err = setManifestHistory(d, nil, m)
+	assert.Equal(t, nil, err, nil)
+
+	history, err = getManifestHistory(d, m)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string(nil), history, nil)
+}
@@ -2,8 +2,16 @@ package kustomize
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestKManifestLoad(t *testing.T) {
@@ -32,3 +40,198 @@ func TestKManifestLoadErr(t *testing.T) {
 
 	assert.NotEqual(t, nil, err)
 }
+
+func TestKManifestAPIPatchStatus(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "example.com", "version": "v1", "kind": "Config", "resource": "configs", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	var subresource, patch string
+	client.PrependReactor("patch", "configs", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pa := action.(k8stesting.PatchAction)
+		subresource = pa.GetSubresource()
+		patch = string(pa.GetPatch())
+		return true, nil, nil
+	})
+
+	km := newKManifest(mapper, client)
+	err := km.load([]byte(`{"apiVersion":"example.com/v1","kind":"Config","metadata":{"name":"test","namespace":"test"},"spec":{},"status":{"phase":"Initialized"}}`))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, km.hasStatus())
+
+	_, err = km.apiPatchStatus(k8smetav1.PatchOptions{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "status", subresource)
+	assert.Equal(t, `{"status":{"phase":"Initialized"}}`, patch)
+}
+
+func TestKManifestRequestObject(t *testing.T) {
+	km := kManifest{}
+	err := km.load([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","annotations":{"existing":"value"}}}`))
+	assert.Equal(t, nil, err)
+
+	u, j, err := km.requestObject()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, km.json, j)
+	assert.Equal(t, map[string]string{"existing": "value"}, u.GetAnnotations())
+
+	km.auditAnnotations = map[string]string{"example.com/commit": "abc123"}
+	u, j, err = km.requestObject()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]string{"existing": "value", "example.com/commit": "abc123"}, u.GetAnnotations())
+	assert.Contains(t, string(j), `"example.com/commit":"abc123"`)
+
+	// the manifest itself is not modified
+	assert.Equal(t, map[string]string{"existing": "value"}, km.resource.GetAnnotations())
+	assert.NotContains(t, string(km.json), "example.com/commit")
+}
+
+func TestKManifestAPIScaleRetried(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "apps", "version": "v1", "kind": "Deployment", "resource": "deployments", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	attempts := 0
+	client.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, k8serrors.NewServiceUnavailable("unavailable")
+		}
+		assert.Equal(t, "scale", action.(k8stesting.PatchAction).GetSubresource())
+		return true, nil, nil
+	})
+
+	km := newKManifest(mapper, client)
+	km.retry = &retryPolicy{maxAttempts: 2, statusCodes: map[int32]bool{503: true}, sleep: func(time.Duration) {}}
+	err := km.load([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":3}}`))
+	assert.Equal(t, nil, err)
+
+	_, err = km.apiScale(3, k8smetav1.PatchOptions{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestKManifestAPIReplace(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "", "version": "v1", "kind": "ConfigMap", "resource": "configmaps", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		current := &k8sunstructured.Unstructured{}
+		current.SetAPIVersion("v1")
+		current.SetKind("ConfigMap")
+		current.SetName("test")
+		current.SetNamespace("test")
+		current.SetResourceVersion("42")
+		return true, current, nil
+	})
+	var resourceVersion string
+	client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		u := action.(k8stesting.UpdateAction).GetObject().(*k8sunstructured.Unstructured)
+		resourceVersion = u.GetResourceVersion()
+		return true, u, nil
+	})
+
+	km := newKManifest(mapper, client)
+	err := km.load([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"},"data":{"key":"value"}}`))
+	assert.Equal(t, nil, err)
+
+	_, err = km.apiReplace(k8smetav1.UpdateOptions{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "42", resourceVersion)
+
+	// the manifest itself is not modified
+	assert.Equal(t, "", km.resource.GetResourceVersion())
+}
+
+func TestKManifestAPIPreparePatchAuditAnnotations(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "", "version": "v1", "kind": "ConfigMap", "resource": "configmaps", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	current := &k8sunstructured.Unstructured{}
+	err := current.UnmarshalJSON([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test","annotations":{"example.com/commit":"abc123","example.com/run":"1"}},"data":{"key":"value"}}`))
+	assert.Equal(t, nil, err)
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme(), current)
+
+	manifest := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"},"data":{"key":"value"}}`)
+
+	kmo := newKManifest(mapper, client)
+	assert.Equal(t, nil, kmo.load(manifest))
+	kmo.auditAnnotations = map[string]string{"example.com/commit": "abc123", "example.com/run": "1"}
+
+	kmm := newKManifest(mapper, client)
+	assert.Equal(t, nil, kmm.load(manifest))
+	kmm.auditAnnotations = map[string]string{"example.com/commit": "def456"}
+
+	// changed values are patched, removed annotations deleted
+	_, p, err := kmm.apiPreparePatch(kmo, false)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"metadata":{"annotations":{"example.com/commit":"def456","example.com/run":null}}}`, string(p))
+}
+
+func TestKManifestAPICreateRetriedAlreadyExists(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "", "version": "v1", "kind": "ConfigMap", "resource": "configmaps", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	creates := 0
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		creates++
+		if creates == 1 {
+			// the object is created, but the response times out
+			return true, nil, k8serrors.NewServiceUnavailable("unavailable")
+		}
+		return true, nil, k8serrors.NewAlreadyExists(k8sschema.GroupResource{Resource: "configmaps"}, "test")
+	})
+	var lac string
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		current := &k8sunstructured.Unstructured{}
+		current.SetAPIVersion("v1")
+		current.SetKind("ConfigMap")
+		current.SetName("test")
+		current.SetNamespace("test")
+		current.SetAnnotations(map[string]string{lastAppliedConfigAnnotation: lac})
+		current.SetManagedFields([]k8smetav1.ManagedFieldsEntry{{Manager: fieldManager, Operation: k8smetav1.ManagedFieldsOperationUpdate}})
+		return true, current, nil
+	})
+
+	km := newKManifest(mapper, client)
+	km.retry = &retryPolicy{maxAttempts: 3, statusCodes: map[int32]bool{503: true}, sleep: func(time.Duration) {}}
+	err := km.load([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"},"data":{"key":"value"}}`))
+	assert.Equal(t, nil, err)
+	applied := string(km.json)
+	assert.Equal(t, true, setLastAppliedConfig(km, false))
+	lac = applied
+
+	// adopted, created by the earlier attempt
+	resp, err := km.apiCreate(k8smetav1.CreateOptions{FieldManager: fieldManager})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, creates)
+	assert.Equal(t, "test", resp.GetName())
+
+	// created from another manifest, by someone else
+	creates, lac = 0, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"},"data":{"key":"other"}}`
+	_, err = km.apiCreate(k8smetav1.CreateOptions{FieldManager: fieldManager})
+	assert.Equal(t, true, k8serrors.IsAlreadyExists(err))
+
+	// created from the manifest, by another field manager
+	creates, lac = 0, applied
+	_, err = km.apiCreate(k8smetav1.CreateOptions{FieldManager: "other"})
+	assert.Equal(t, true, k8serrors.IsAlreadyExists(err))
+
+	// objects existing before the first attempt are not adopted
+	creates = 1
+	km.retry = nil
+	_, err = km.apiCreate(k8smetav1.CreateOptions{FieldManager: fieldManager})
+	assert.Equal(t, true, k8serrors.IsAlreadyExists(err))
+}
@@ -0,0 +1,69 @@
+package kustomize
+
+import (
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// getDisableNameSuffixHash returns if disable_name_suffix_hash
+// is set in the kustomize_options of d
+func getDisableNameSuffixHash(d *schema.ResourceData) bool {
+	kOptsList := d.Get("kustomize_options").([]interface{})
+	if len(kOptsList) == 0 || kOptsList[0] == nil {
+		return false
+	}
+
+	disable, _ := kOptsList[0].(map[string]interface{})["disable_name_suffix_hash"].(bool)
+	return disable
+}
+
+// nameSuffixHashFileSystem disables the name suffix hash in the global
+// generatorOptions of all kustomizations, including bases and components,
+// which kustomize merges into the options of every generator
+type nameSuffixHashFileSystem struct {
+	filesys.FileSystem
+}
+
+func makeNameSuffixHashFS(fs filesys.FileSystem, disable bool) filesys.FileSystem {
+	if !disable {
+		return fs
+	}
+
+	return nameSuffixHashFileSystem{FileSystem: fs}
+}
+
+func isKustomizationFileName(name string) bool {
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		if filepath.Base(name) == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (nfs nameSuffixHashFileSystem) ReadFile(name string) ([]byte, error) {
+	content, err := nfs.FileSystem.ReadFile(name)
+	if err != nil || !isKustomizationFileName(name) {
+		return content, err
+	}
+
+	var k map[string]interface{}
+	if err := yaml.Unmarshal(content, &k); err != nil || k == nil {
+		// left for kustomize to report
+		return content, nil
+	}
+
+	o, _ := k["generatorOptions"].(map[string]interface{})
+	if o == nil {
+		o = make(map[string]interface{})
+	}
+	o["disableNameSuffixHash"] = true
+	k["generatorOptions"] = o
+
+	return yaml.Marshal(k)
+}
@@ -0,0 +1,35 @@
+package kustomize
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKustomizationBuildDisableNameSuffixHash(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml":    "configMapGenerator:\n- name: base\n  literals:\n  - key=value\n",
+		"overlay/kustomization.yaml": "resources:\n- ../base\nsecretGenerator:\n- name: overlay\n  literals:\n  - key=value\n  options:\n    disableNameSuffixHash: false\n",
+	})
+	config := &Config{BuildLock: newBuildLock()}
+
+	build := func(disable bool) []interface{} {
+		d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+			"path": filepath.Join(dir, "overlay"),
+			"kustomize_options": []interface{}{
+				map[string]interface{}{"disable_name_suffix_hash": disable},
+			},
+		})
+		diags := kustomizationBuild(context.TODO(), d, config)
+		assert.Equal(t, false, diags.HasError(), nil)
+
+		return d.Get("ids").(*schema.Set).List()
+	}
+
+	assert.NotContains(t, build(false), "_/ConfigMap/_/base", nil)
+	assert.ElementsMatch(t, []interface{}{"_/ConfigMap/_/base", "_/Secret/_/overlay"}, build(true), nil)
+}
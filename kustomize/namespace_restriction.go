@@ -0,0 +1,121 @@
+package kustomize
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/rest"
+)
+
+// namespaceRestriction restricts all requests to the Kubernetes API
+// to the namespaced resources of the allowed namespaces, for
+// credentials with namespace scoped RBAC only
+//
+// Requests for cluster scoped resources, other namespaces or API
+// discovery fail before they are sent, kinds are mapped using the
+// rest_mapping of the provider only.
+type namespaceRestriction map[string]bool
+
+// getNamespaceRestriction returns the restriction of the
+// restrict_namespaces provider configuration, or nil if unset
+func getNamespaceRestriction(in []interface{}) namespaceRestriction {
+	if len(in) == 0 {
+		return nil
+	}
+
+	nr := make(namespaceRestriction)
+	for _, ns := range in {
+		nr[ns.(string)] = true
+	}
+
+	return nr
+}
+
+func (nr namespaceRestriction) String() string {
+	namespaces := make([]string, 0, len(nr))
+	for ns := range nr {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	return strings.Join(namespaces, ", ")
+}
+
+// allows returns true for the server version and for requests to
+// namespaced resources in the allowed namespaces, e.g.
+// /api/v1/namespaces/test/configmaps or
+// /apis/apps/v1/namespaces/test/deployments/test
+func (nr namespaceRestriction) allows(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	// the API may be served below a path, e.g. by proxies
+	for i, p := range parts {
+		var tail []string
+		switch p {
+		case "version":
+			return i == len(parts)-1
+		case "api":
+			tail = parts[i+1:]
+			if len(tail) > 0 {
+				tail = tail[1:]
+			}
+		case "apis":
+			tail = parts[i+1:]
+			if len(tail) > 1 {
+				tail = tail[2:]
+			}
+		default:
+			continue
+		}
+
+		// namespaces, the namespace and at least the resource
+		return len(tail) >= 3 && tail[0] == "namespaces" && nr[tail[1]]
+	}
+
+	return false
+}
+
+type namespaceRestrictionTransport struct {
+	nr namespaceRestriction
+	rt http.RoundTripper
+}
+
+func (t *namespaceRestrictionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.nr.allows(req.URL.Path) {
+		return nil, fmt.Errorf("provider kustomization: restrict_namespaces: %s %s is not allowed, only namespaced resources in the namespaces %s are", req.Method, req.URL.Path, t.nr)
+	}
+
+	return t.rt.RoundTrip(req)
+}
+
+// restConfig wraps the configs returned by restConfig to fail
+// requests outside of the allowed namespaces, if restricted
+func (nr namespaceRestriction) restConfig(restConfig func() (*rest.Config, error)) func() (*rest.Config, error) {
+	if nr == nil {
+		return restConfig
+	}
+
+	return func() (*rest.Config, error) {
+		config, err := restConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &namespaceRestrictionTransport{nr: nr, rt: rt}
+		})
+
+		return config, nil
+	}
+}
+
+// staticOnlyRESTMapper maps kinds using the static mappings only,
+// without falling back to discovery
+type staticOnlyRESTMapper struct {
+	*k8smeta.DefaultRESTMapper
+}
+
+func (m staticOnlyRESTMapper) Reset() {}
@@ -0,0 +1,31 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceRestrictionAllows(t *testing.T) {
+	nr := getNamespaceRestriction([]interface{}{"tenant-a", "tenant-b"})
+
+	for path, want := range map[string]bool{
+		"/version":                                          true,
+		"/api/v1/namespaces/tenant-a/configmaps":            true,
+		"/api/v1/namespaces/tenant-b/configmaps/test":       true,
+		"/apis/apps/v1/namespaces/tenant-a/deployments":     true,
+		"/k8s/clusters/c-1/api/v1/namespaces/tenant-a/pods": true,
+		"/api/v1/namespaces/tenant-c/configmaps":            false,
+		"/api/v1/namespaces/tenant-a":                       false,
+		"/api/v1/namespaces":                                false,
+		"/api/v1/nodes":                                     false,
+		"/apis/rbac.authorization.k8s.io/v1/clusterroles":   false,
+		"/api":        false,
+		"/apis":       false,
+		"/openapi/v2": false,
+	} {
+		assert.Equal(t, want, nr.allows(path), path)
+	}
+
+	assert.Equal(t, namespaceRestriction(nil), getNamespaceRestriction(nil), nil)
+}
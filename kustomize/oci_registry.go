@@ -0,0 +1,423 @@
+package kustomize
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+	// media types of Flux OCI artifacts
+	fluxConfigMediaType  = "application/vnd.cncf.flux.config.v1+json"
+	fluxContentMediaType = "application/vnd.cncf.flux.content.v1.tar+gzip"
+
+	ociSourceAnnotation   = "org.opencontainers.image.source"
+	ociRevisionAnnotation = "org.opencontainers.image.revision"
+)
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ociArtifact is an artifact in the Flux OCI format,
+// with the manifests as a single tar+gzip layer
+type ociArtifact struct {
+	config   []byte
+	layer    []byte
+	manifest []byte
+}
+
+func ociDigest(content []byte) string {
+	h := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// tarGzipFiles archives the files reproducibly, the
+// archive only changes if the file names or content change
+func tarGzipFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	dirs := make(map[string]bool)
+	for name := range files {
+		names = append(names, name)
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	for d := range dirs {
+		names = append(names, d+"/")
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	gw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gw)
+
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:    name,
+			ModTime: time.Unix(0, 0),
+			Format:  tar.FormatPAX,
+		}
+
+		content, isFile := files[name]
+		if isFile {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Mode = 0644
+			hdr.Size = int64(len(content))
+		} else {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0755
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+
+		if isFile {
+			if _, err := tw.Write(content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func newOCIArtifact(files map[string][]byte, annotations map[string]string) (*ociArtifact, error) {
+	layer, err := tarGzipFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &ociArtifact{
+		config: []byte("{}"),
+		layer:  layer,
+	}
+
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	a.manifest, err = json.Marshal(ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Config: ociDescriptor{
+			MediaType: fluxConfigMediaType,
+			Digest:    ociDigest(a.config),
+			Size:      int64(len(a.config)),
+		},
+		Layers: []ociDescriptor{
+			{
+				MediaType: fluxContentMediaType,
+				Digest:    ociDigest(a.layer),
+				Size:      int64(len(a.layer)),
+			},
+		},
+		Annotations: annotations,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *ociArtifact) digest() string {
+	return ociDigest(a.manifest)
+}
+
+// ociRegistry is a minimal client for the OCI distribution API,
+// supporting anonymous, basic and bearer token authentication
+type ociRegistry struct {
+	baseURL  string
+	name     string
+	username string
+	password string
+	client   *http.Client
+	token    string
+}
+
+// newOCIRegistry returns a client for repository, e.g. "ghcr.io/example/manifests"
+func newOCIRegistry(repository string, username string, password string, insecure bool) (*ociRegistry, error) {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repository: %q, valid repositories look like: \"ghcr.io/example/manifests\"", repository)
+	}
+
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+
+	return &ociRegistry{
+		baseURL:  fmt.Sprintf("%s://%s", scheme, parts[0]),
+		name:     parts[1],
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// do sends the request, and retries it once with credentials,
+// if the registry responds with an authentication challenge
+func (r *ociRegistry) do(ctx context.Context, method string, u string, header http.Header, body []byte) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		} else if r.username != "" {
+			req.SetBasicAuth(r.username, r.password)
+		}
+
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("%s %s: unauthorized", method, u)
+	}
+
+	r.token, err = r.fetchToken(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.Do(req)
+}
+
+// parseChallenge parses the parameters of a WWW-Authenticate header, e.g.
+// Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:example:pull"
+func parseChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+
+	i := strings.Index(challenge, " ")
+	if i < 0 {
+		return params
+	}
+
+	for _, p := range strings.Split(challenge[i+1:], ",") {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		params[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+
+	return params
+}
+
+func (r *ociRegistry) fetchToken(ctx context.Context, challenge string) (string, error) {
+	params := parseChallenge(challenge)
+
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("invalid authentication challenge: %q", challenge)
+	}
+
+	q := url.Values{}
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	// request push access, the challenge may only ask for pull
+	q.Set("scope", fmt.Sprintf("repository:%s:pull,push", r.name))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching token failed: %s", resp.Status)
+	}
+
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("fetching token failed: %s", err)
+	}
+
+	if t.Token != "" {
+		return t.Token, nil
+	}
+
+	return t.AccessToken, nil
+}
+
+func (r *ociRegistry) url(format string, a ...interface{}) string {
+	return fmt.Sprintf("%s/v2/%s/%s", r.baseURL, r.name, fmt.Sprintf(format, a...))
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL, resp.Status, strings.TrimSpace(string(body)))
+}
+
+func (r *ociRegistry) pushBlob(ctx context.Context, content []byte) error {
+	digest := ociDigest(content)
+
+	resp, err := r.do(ctx, http.MethodHead, r.url("blobs/%s", digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = r.do(ctx, http.MethodPost, r.url("blobs/uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError(resp)
+	}
+
+	// the upload location may be relative to the registry
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+
+	resp, err = r.do(ctx, http.MethodPut, loc.String(), header, content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+// push uploads the artifact and tags it, returning the digest
+func (r *ociRegistry) push(ctx context.Context, a *ociArtifact, tag string) (string, error) {
+	for _, blob := range [][]byte{a.config, a.layer} {
+		if err := r.pushBlob(ctx, blob); err != nil {
+			return "", err
+		}
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", ociManifestMediaType)
+
+	resp, err := r.do(ctx, http.MethodPut, r.url("manifests/%s", tag), header, a.manifest)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", responseError(resp)
+	}
+
+	return a.digest(), nil
+}
+
+// resolve returns the digest of the tag, or
+// an empty string if the tag does not exist
+func (r *ociRegistry) resolve(ctx context.Context, tag string) (string, error) {
+	header := http.Header{}
+	header.Set("Accept", ociManifestMediaType)
+
+	resp, err := r.do(ctx, http.MethodHead, r.url("manifests/%s", tag), header, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+
+	return resp.Header.Get("Docker-Content-Digest"), nil
+}
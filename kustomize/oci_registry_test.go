@@ -0,0 +1,164 @@
+package kustomize
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRegistry implements the parts of the OCI distribution
+// API used to push, requiring a bearer token
+type testRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	scopes    []string
+}
+
+func (tr *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		user, pass, _ := r.BasicAuth()
+		if user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		tr.scopes = append(tr.scopes, r.URL.Query().Get("scope"))
+		fmt.Fprint(w, `{"token":"test-token"}`)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test",scope="repository:example/manifests:pull"`, r.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const prefix = "/v2/example/manifests/"
+	p := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch {
+	case r.Method == http.MethodHead && strings.HasPrefix(p, "blobs/"):
+		if _, ok := tr.blobs[strings.TrimPrefix(p, "blobs/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPost && p == "blobs/uploads/":
+		w.Header().Set("Location", prefix+"blobs/uploads/test-upload?state=1")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && p == "blobs/uploads/test-upload":
+		body, _ := ioutil.ReadAll(r.Body)
+		digest := r.URL.Query().Get("digest")
+		if digest != ociDigest(body) || r.URL.Query().Get("state") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tr.blobs[digest] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(p, "manifests/"):
+		body, _ := ioutil.ReadAll(r.Body)
+		tr.manifests[strings.TrimPrefix(p, "manifests/")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead && strings.HasPrefix(p, "manifests/"):
+		body, ok := tr.manifests[strings.TrimPrefix(p, "manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", ociDigest(body))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestOCIRegistryPush(t *testing.T) {
+	tr := &testRegistry{
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+	}
+	srv := httptest.NewServer(tr)
+	defer srv.Close()
+
+	repository := strings.TrimPrefix(srv.URL, "http://") + "/example/manifests"
+	r, err := newOCIRegistry(repository, "user", "pass", true)
+	assert.Equal(t, nil, err, nil)
+
+	digest, err := r.resolve(context.TODO(), "latest")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "", digest, nil)
+
+	files, err := renderFiles(testRenderManifests, false)
+	assert.Equal(t, nil, err, nil)
+
+	a, err := newOCIArtifact(files, map[string]string{ociRevisionAnnotation: "main@sha1:abc"})
+	assert.Equal(t, nil, err, nil)
+
+	digest, err = r.push(context.TODO(), a, "latest")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, a.digest(), digest, nil)
+	assert.Equal(t, []string{"repository:example/manifests:pull,push"}, tr.scopes, nil)
+	assert.Equal(t, 2, len(tr.blobs), nil)
+
+	digest, err = r.resolve(context.TODO(), "latest")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, a.digest(), digest, nil)
+
+	var m ociManifest
+	err = json.Unmarshal(tr.manifests["latest"], &m)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, fluxContentMediaType, m.Layers[0].MediaType, nil)
+	assert.Equal(t, "main@sha1:abc", m.Annotations[ociRevisionAnnotation], nil)
+
+	// the layer contains the rendered files
+	gr, err := gzip.NewReader(bytes.NewReader(tr.blobs[m.Layers[0].Digest]))
+	assert.Equal(t, nil, err, nil)
+	tarReader := tar.NewReader(gr)
+	names := []string{}
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, nil, err, nil)
+		names = append(names, hdr.Name)
+	}
+	assert.Equal(t, []string{
+		"_cluster/",
+		"_cluster/namespace-test-render.yaml",
+		"test-render/",
+		"test-render/configmap-test-config.yaml",
+		"test-render/deployment.apps-test.yaml",
+	}, names, nil)
+}
+
+func TestOCIArtifactReproducible(t *testing.T) {
+	files, _ := renderFiles(testRenderManifests, false)
+
+	a, err := newOCIArtifact(files, nil)
+	assert.Equal(t, nil, err, nil)
+
+	b, err := newOCIArtifact(files, nil)
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, a.digest(), b.digest(), nil)
+}
+
+func TestNewOCIRegistryInvalid(t *testing.T) {
+	for _, repository := range []string{"", "ghcr.io", "ghcr.io/", "/example"} {
+		_, err := newOCIRegistry(repository, "", "", false)
+		assert.Error(t, err, repository)
+	}
+}
@@ -0,0 +1,194 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/yaml"
+)
+
+// name of the virtual file next to the root kustomization,
+// serving the OpenAPI schema of the cluster to kustomize
+const openAPIFileName = "terraform-provider-kustomization-openapi.json"
+
+// kustomize reports this version while a custom schema is used
+const customOpenAPISchemaVersion = "using custom schema from file provided"
+
+// resetCustomOpenAPISchema resets a custom schema of a previous build,
+// kustomize only resets the schema for builds using a builtin version
+func resetCustomOpenAPISchema() {
+	if openapi.GetSchemaVersion() == customOpenAPISchemaVersion {
+		openapi.ResetOpenAPI()
+	}
+}
+
+// getOpenAPISchema returns the OpenAPI v2 schema of the cluster as JSON,
+// it is fetched once and cached for the lifetime of the provider
+func (kc *kubeClients) getOpenAPISchema(ctx context.Context) ([]byte, error) {
+	kc.openAPIMu.Lock()
+	defer kc.openAPIMu.Unlock()
+
+	if kc.openAPISchema != nil {
+		return kc.openAPISchema, nil
+	}
+
+	dc, err := kc.discovery()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := dc.RESTClient().Get().
+		AbsPath("/openapi/v2").
+		SetHeader("Accept", "application/json").
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, fmt.Errorf("fetching OpenAPI schema failed: %s", err)
+	}
+
+	s, err := addListMapPatchStrategy(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI schema failed: %s", err)
+	}
+
+	kc.openAPISchema = s
+
+	return s, nil
+}
+
+// addListMapPatchStrategy adds the strategic merge patch extensions
+// to lists merged by key, for the list-map-keys of CRD schemas,
+// kustomize only merges lists that have a patch strategy
+func addListMapPatchStrategy(s []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(s, &doc); err != nil {
+		return nil, err
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch o := v.(type) {
+		case map[string]interface{}:
+			keys, hasKeys := o["x-kubernetes-list-map-keys"].([]interface{})
+			_, hasStrategy := o["x-kubernetes-patch-strategy"]
+			if o["x-kubernetes-list-type"] == "map" && hasKeys && len(keys) > 0 && !hasStrategy {
+				o["x-kubernetes-patch-strategy"] = "merge"
+				o["x-kubernetes-patch-merge-key"] = keys[0]
+			}
+
+			for _, c := range o {
+				walk(c)
+			}
+		case []interface{}:
+			for _, c := range o {
+				walk(c)
+			}
+		}
+	}
+	walk(doc)
+
+	return json.Marshal(doc)
+}
+
+// getClusterOpenAPISchema returns the OpenAPI schema of the cluster,
+// if openapi_from_cluster is set in the kustomize_options
+func getClusterOpenAPISchema(ctx context.Context, d *schema.ResourceData, m interface{}) ([]byte, error) {
+	kOptsList := d.Get("kustomize_options").([]interface{})
+	if len(kOptsList) == 0 || kOptsList[0] == nil {
+		return nil, nil
+	}
+
+	kOpts := kOptsList[0].(map[string]interface{})
+	if fromCluster, _ := kOpts["openapi_from_cluster"].(bool); !fromCluster {
+		return nil, nil
+	}
+
+	cluster, _ := kOpts["openapi_cluster"].(string)
+	kc, err := m.(*Config).getCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return kc.getOpenAPISchema(ctx)
+}
+
+// openAPIFileSystem sets the openapi field of the root kustomization,
+// unless already set, to the schema served from a virtual file
+type openAPIFileSystem struct {
+	filesys.FileSystem
+	root   filesys.ConfirmedDir
+	schema []byte
+}
+
+func makeOpenAPIFS(fs filesys.FileSystem, path string, schema []byte) (filesys.FileSystem, error) {
+	if schema == nil {
+		return fs, nil
+	}
+
+	root, _, err := fs.CleanedAbs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return openAPIFileSystem{
+		FileSystem: fs,
+		root:       root,
+		schema:     schema,
+	}, nil
+}
+
+func (ofs openAPIFileSystem) isSchema(name string) bool {
+	return name == ofs.root.Join(openAPIFileName)
+}
+
+func (ofs openAPIFileSystem) isRootKustomization(name string) bool {
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		if name == ofs.root.Join(n) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ofs openAPIFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	if ofs.isSchema(path) {
+		return ofs.root, openAPIFileName, nil
+	}
+
+	return ofs.FileSystem.CleanedAbs(path)
+}
+
+func (ofs openAPIFileSystem) Exists(name string) bool {
+	return ofs.isSchema(name) || ofs.FileSystem.Exists(name)
+}
+
+func (ofs openAPIFileSystem) ReadFile(name string) ([]byte, error) {
+	if ofs.isSchema(name) {
+		return ofs.schema, nil
+	}
+
+	content, err := ofs.FileSystem.ReadFile(name)
+	if err != nil || !ofs.isRootKustomization(name) {
+		return content, err
+	}
+
+	var k map[string]interface{}
+	if err := yaml.Unmarshal(content, &k); err != nil || k == nil {
+		// left for kustomize to report
+		return content, nil
+	}
+
+	if _, ok := k["openapi"]; ok {
+		return content, nil
+	}
+
+	k["openapi"] = map[string]interface{}{"path": openAPIFileName}
+
+	return yaml.Marshal(k)
+}
@@ -0,0 +1,130 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// OpenAPI schema as published by the API server for a CRD,
+// with a list merged by key but without patch extensions
+const testOpenAPISchema = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.24.1"},
+  "paths": {},
+  "definitions": {
+    "com.example.v1.Backend": {
+      "type": "object",
+      "x-kubernetes-group-version-kind": [{"group": "example.com", "kind": "Backend", "version": "v1"}],
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"type": "object"},
+        "spec": {
+          "type": "object",
+          "properties": {
+            "routes": {
+              "type": "array",
+              "x-kubernetes-list-type": "map",
+              "x-kubernetes-list-map-keys": ["name"],
+              "items": {
+                "type": "object",
+                "properties": {
+                  "name": {"type": "string"},
+                  "weight": {"type": "integer"}
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestAddListMapPatchStrategy(t *testing.T) {
+	s, err := addListMapPatchStrategy([]byte(testOpenAPISchema))
+	assert.Equal(t, nil, err, nil)
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(s, &doc)
+	assert.Equal(t, nil, err, nil)
+
+	routes := doc["definitions"].(map[string]interface{})["com.example.v1.Backend"].(map[string]interface{})["properties"].(map[string]interface{})["spec"].(map[string]interface{})["properties"].(map[string]interface{})["routes"].(map[string]interface{})
+	assert.Equal(t, "merge", routes["x-kubernetes-patch-strategy"], nil)
+	assert.Equal(t, "name", routes["x-kubernetes-patch-merge-key"], nil)
+}
+
+func TestOpenAPIFSMergesByKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openapi-*")
+	assert.Equal(t, nil, err, nil)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"kustomization.yaml": `resources:
+- backend.yaml
+patchesStrategicMerge:
+- patch.yaml
+`,
+		"backend.yaml": `apiVersion: example.com/v1
+kind: Backend
+metadata:
+  name: test
+spec:
+  routes:
+  - name: a
+    weight: 50
+  - name: b
+    weight: 50
+`,
+		"patch.yaml": `apiVersion: example.com/v1
+kind: Backend
+metadata:
+  name: test
+spec:
+  routes:
+  - name: b
+    weight: 100
+`,
+	}
+	for n, c := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, n), []byte(c), 0644)
+		assert.Equal(t, nil, err, nil)
+	}
+
+	s, err := addListMapPatchStrategy([]byte(testOpenAPISchema))
+	assert.Equal(t, nil, err, nil)
+
+	fSys, err := makeOpenAPIFS(filesys.MakeFsOnDisk(), dir, s)
+	assert.Equal(t, nil, err, nil)
+
+	rm, err := runKustomizeBuild(fSys, dir, testKustomizeOptions(t))
+	assert.Equal(t, nil, err, nil)
+
+	routes, err := rm.Resources()[0].GetFieldValue("spec.routes")
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"name": "a", "weight": 50},
+		map[string]interface{}{"name": "b", "weight": 100},
+	}, routes, nil)
+
+	// without the schema of the CRD the list is replaced
+	rm, err = runKustomizeBuild(filesys.MakeFsOnDisk(), dir, testKustomizeOptions(t))
+	assert.Equal(t, nil, err, nil)
+
+	routes, err = rm.Resources()[0].GetFieldValue("spec.routes")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "b", "weight": 100},
+	}, routes, nil)
+}
+
+func testKustomizeOptions(t *testing.T) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{})
+}
@@ -0,0 +1,150 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+// resourceOrigin is where kustomize loaded or generated a resource
+// from, parsed from the config.kubernetes.io/origin annotation added
+// by buildMetadata: [originAnnotations]
+type resourceOrigin struct {
+	file      string
+	repo      string
+	ref       string
+	generator string
+}
+
+func originsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"id": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"file": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"repo": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"ref": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"generator": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+// getOrigins returns the origins of the resources of rm by id,
+// resources without origin annotation are omitted
+func getOrigins(rm resmap.ResMap) (map[string]resourceOrigin, error) {
+	origins := make(map[string]resourceOrigin)
+	for _, r := range rm.Resources() {
+		o, err := r.GetOrigin()
+		if err != nil {
+			return nil, err
+		}
+		if o == nil {
+			continue
+		}
+
+		kr := &kManifestId{
+			group:     r.CurId().Group,
+			kind:      r.CurId().Kind,
+			namespace: r.GetNamespace(),
+			name:      r.GetName(),
+		}
+
+		ro := resourceOrigin{
+			file: o.Path,
+			repo: o.Repo,
+			ref:  o.Ref,
+		}
+
+		// generated resources have the generator config instead of a path
+		if o.ConfiguredBy.Kind != "" {
+			ro.file = o.ConfiguredIn
+			ro.generator = strings.Trim(strings.Join([]string{
+				o.ConfiguredBy.APIVersion,
+				o.ConfiguredBy.Kind,
+				o.ConfiguredBy.Name,
+			}, "/"), "/")
+		}
+
+		origins[kr.string()] = ro
+	}
+
+	return origins, nil
+}
+
+func flattenOrigins(origins map[string]resourceOrigin) []interface{} {
+	ids := make([]string, 0, len(origins))
+	for id := range origins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		o := origins[id]
+		out = append(out, map[string]interface{}{
+			"id":        id,
+			"file":      o.file,
+			"repo":      o.repo,
+			"ref":       o.ref,
+			"generator": o.generator,
+		})
+	}
+
+	return out
+}
+
+func expandOrigins(in []interface{}) map[string]resourceOrigin {
+	origins := make(map[string]resourceOrigin)
+	for _, v := range in {
+		o := v.(map[string]interface{})
+		origins[o["id"].(string)] = resourceOrigin{
+			file:      o["file"].(string),
+			repo:      o["repo"].(string),
+			ref:       o["ref"].(string),
+			generator: o["generator"].(string),
+		}
+	}
+
+	return origins
+}
+
+// String describes the origin for error messages
+func (o resourceOrigin) String() string {
+	var parts []string
+	if o.generator != "" {
+		parts = append(parts, fmt.Sprintf("generated by %s", o.generator))
+	}
+	if o.file != "" {
+		parts = append(parts, fmt.Sprintf("from %s", o.file))
+	}
+	if o.repo != "" {
+		repo := o.repo
+		if o.ref != "" {
+			repo = fmt.Sprintf("%s?ref=%s", o.repo, o.ref)
+		}
+		parts = append(parts, fmt.Sprintf("in %s", repo))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,49 @@
+package kustomize
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKustomizationBuildOrigins(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml":    "resources:\n- cm.yaml\n",
+		"base/cm.yaml":               testCacheConfigMap,
+		"overlay/kustomization.yaml": "buildMetadata:\n- originAnnotations\nresources:\n- ../base\nconfigMapGenerator:\n- name: gen\n  literals:\n  - key=value\n",
+	})
+	config := &Config{BuildLock: newBuildLock()}
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path":              filepath.Join(dir, "overlay"),
+		"strip_annotations": []interface{}{"config.kubernetes.io/origin"},
+	})
+	diags := kustomizationBuild(context.TODO(), d, config)
+	assert.Equal(t, false, diags.HasError(), nil)
+
+	origins := expandOrigins(d.Get("origins").([]interface{}))
+	assert.Equal(t, resourceOrigin{file: "../base/cm.yaml"}, origins["_/ConfigMap/_/test"], nil)
+	assert.Equal(t, resourceOrigin{file: "kustomization.yaml", generator: "builtin/ConfigMapGenerator"}, origins["_/ConfigMap/_/gen-t757gk2bmf"], nil)
+
+	// stripped origin annotations are still parsed
+	assert.NotContains(t, d.Get("manifests").(map[string]interface{})["_/ConfigMap/_/test"], "config.kubernetes.io/origin", nil)
+}
+
+func TestResourceOriginString(t *testing.T) {
+	assert.Equal(t, "from cm.yaml", resourceOrigin{file: "cm.yaml"}.String(), nil)
+	assert.Equal(t, "from base/cm.yaml in github.com/kbst/example?ref=v1", resourceOrigin{file: "base/cm.yaml", repo: "github.com/kbst/example", ref: "v1"}.String(), nil)
+	assert.Equal(t, "generated by builtin/ConfigMapGenerator/gen from kustomization.yaml", resourceOrigin{file: "kustomization.yaml", generator: "builtin/ConfigMapGenerator/gen"}.String(), nil)
+}
+
+func TestKustomizationOverlayOrigins(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, dataSourceKustomizationOverlay().Schema, map[string]interface{}{
+		"build_metadata": []interface{}{"originAnnotations"},
+	})
+
+	k := getKustomization(d)
+	assert.Equal(t, []string{"originAnnotations"}, k.BuildMetadata, nil)
+}
@@ -0,0 +1,84 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+// overlaysSchema returns the schema of the environments of an overlay,
+// each with the images, patches and replicas it adds to the overlay
+//
+// The environments of all workspaces are defined once, selected sets
+// the one to build, usually from a variable or terraform.workspace.
+func overlaysSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		MaxItems: 1,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"selected": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"environment": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"name": {
+								Type:     schema.TypeString,
+								Required: true,
+							},
+							"images":   getImagesSchema(),
+							"patches":  getPatchesSchema(),
+							"replicas": getReplicasSchema(),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// addOverlayEnvironment appends the images, patches and replicas of the
+// selected environment to the Kustomization k, nothing is added if no
+// environment is selected
+func addOverlayEnvironment(k *types.Kustomization, overlays []interface{}) error {
+	if len(overlays) == 0 || overlays[0] == nil {
+		return nil
+	}
+
+	o := overlays[0].(map[string]interface{})
+	selected := o["selected"].(string)
+	if selected == "" {
+		return nil
+	}
+
+	var names []string
+	for _, e := range o["environment"].([]interface{}) {
+		if e == nil {
+			continue
+		}
+
+		env := e.(map[string]interface{})
+		name := env["name"].(string)
+		if name != selected {
+			names = append(names, name)
+			continue
+		}
+
+		k.Images = append(k.Images, getImages(env["images"].([]interface{}))...)
+		k.Patches = append(k.Patches, getPatches(env["patches"].([]interface{}))...)
+		k.Replicas = append(k.Replicas, getReplicas(env["replicas"].([]interface{}))...)
+
+		return nil
+	}
+
+	sort.Strings(names)
+	return fmt.Errorf("overlays: selected environment %q is not defined, environments: %s", selected, strings.Join(names, ", "))
+}
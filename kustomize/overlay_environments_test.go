@@ -0,0 +1,63 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+func testOverlayEnvironments(t *testing.T, selected string) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, dataSourceKustomizationOverlay().Schema, map[string]interface{}{
+		"replicas": []interface{}{
+			map[string]interface{}{"name": "web", "count": 1},
+		},
+		"overlays": []interface{}{
+			map[string]interface{}{
+				"selected": selected,
+				"environment": []interface{}{
+					map[string]interface{}{
+						"name": "staging",
+						"images": []interface{}{
+							map[string]interface{}{"name": "nginx", "new_tag": "staging"},
+						},
+					},
+					map[string]interface{}{
+						"name": "prod",
+						"images": []interface{}{
+							map[string]interface{}{"name": "nginx", "new_tag": "1.25"},
+						},
+						"replicas": []interface{}{
+							map[string]interface{}{"name": "web", "count": 3},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestAddOverlayEnvironment(t *testing.T) {
+	d := testOverlayEnvironments(t, "prod")
+	k := getKustomization(d)
+
+	err := addOverlayEnvironment(&k, d.Get("overlays").([]interface{}))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []types.Image{{Name: "nginx", NewTag: "1.25"}}, k.Images, nil)
+	assert.Equal(t, []types.Replica{{Name: "web", Count: 1}, {Name: "web", Count: 3}}, k.Replicas, nil)
+	assert.Equal(t, 0, len(k.Patches), nil)
+
+	d = testOverlayEnvironments(t, "")
+	k = getKustomization(d)
+
+	err = addOverlayEnvironment(&k, d.Get("overlays").([]interface{}))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(k.Images), nil)
+
+	d = testOverlayEnvironments(t, "dev")
+	k = getKustomization(d)
+
+	err = addOverlayEnvironment(&k, d.Get("overlays").([]interface{}))
+	assert.EqualError(t, err, "overlays: selected environment \"dev\" is not defined, environments: prod, staging", nil)
+}
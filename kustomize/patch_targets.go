@@ -0,0 +1,157 @@
+package kustomize
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// patchTarget is the target selector of a patch or
+// replacement, named like in the kustomization
+type patchTarget struct {
+	name     string
+	selector types.Selector
+}
+
+// getPatchTargets returns the target selectors of the patches and of the
+// targets of the inline replacements of k, patches without a target
+// fail the build if they do not match a resource
+func getPatchTargets(k types.Kustomization) (targets []patchTarget) {
+	add := func(field string, patches []types.Patch) {
+		for i, p := range patches {
+			if p.Target == nil {
+				continue
+			}
+
+			name := fmt.Sprintf("%s[%d]", field, i)
+			if p.Path != "" {
+				name = fmt.Sprintf("%s (%s)", name, p.Path)
+			}
+			targets = append(targets, patchTarget{name: name, selector: *p.Target})
+		}
+	}
+	add("patches", k.Patches)
+	add("patchesJson6902", k.PatchesJson6902)
+
+	for i, r := range k.Replacements {
+		for j, t := range r.Targets {
+			if t == nil || t.Select == nil {
+				continue
+			}
+
+			name := fmt.Sprintf("replacements[%d].targets[%d]", i, j)
+			targets = append(targets, patchTarget{name: name, selector: *t.Select})
+		}
+	}
+
+	return targets
+}
+
+// patchTimeNames returns the names r may have had when the patches of
+// k were applied, before k's name prefix, suffix and hash suffix
+func patchTimeNames(k types.Kustomization, r *resource.Resource) []string {
+	name := r.GetName()
+	if isGenerated(r) {
+		name = name[:strings.LastIndex(name, "-")]
+	}
+
+	return []string{
+		r.GetName(),
+		name,
+		strings.TrimSuffix(strings.TrimPrefix(name, k.NamePrefix), k.NameSuffix),
+	}
+}
+
+// selectsAny returns true if s selects any resource of rm, the build of k
+//
+// The resources of rm are matched like kustomize matches them before
+// the namespace, names and hash suffixes of k are set. The namespace is
+// not matched if k sets it, the original namespace is not known.
+func selectsAny(k types.Kustomization, rm resmap.ResMap, s types.Selector) (bool, error) {
+	sr, err := types.NewSelectorRegex(&s)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range rm.Resources() {
+		if !sr.MatchGvk(r.GetGvk()) {
+			continue
+		}
+
+		if k.Namespace == "" && !sr.MatchNamespace(r.CurId().EffectiveNamespace()) {
+			continue
+		}
+
+		// the original names of resources of bases with a name prefix
+		// or suffix are not known, names containing the name match
+		matched := s.Name != "" && strings.Contains(r.GetName(), s.Name)
+		for _, n := range patchTimeNames(k, r) {
+			matched = matched || sr.MatchName(n)
+		}
+		if !matched {
+			continue
+		}
+
+		if ok, err := r.MatchesLabelSelector(s.LabelSelector); err != nil || !ok {
+			continue
+		}
+
+		if ok, err := r.MatchesAnnotationSelector(s.AnnotationSelector); err != nil || !ok {
+			continue
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// unmatchedPatchTargets returns the names of the patches and replacement
+// targets of the kustomization at path that select none of the resources
+// of rm, patches of bases and components are not checked
+func unmatchedPatchTargets(fSys filesys.FileSystem, path string, rm resmap.ResMap) []string {
+	for _, n := range konfig.RecognizedKustomizationFileNames() {
+		content, err := fSys.ReadFile(filepath.Join(path, n))
+		if err != nil {
+			continue
+		}
+
+		var k types.Kustomization
+		if err := yaml.Unmarshal(content, &k); err != nil {
+			return nil
+		}
+
+		var unmatched []string
+		for _, t := range getPatchTargets(k) {
+			if ok, err := selectsAny(k, rm, t.selector); err == nil && !ok {
+				unmatched = append(unmatched, t.name)
+			}
+		}
+
+		return unmatched
+	}
+
+	return nil
+}
+
+// patchTargetWarnings warns about patches and replacements of the
+// kustomization at path that match no resources, e.g. due to typos
+func patchTargetWarnings(fSys filesys.FileSystem, path string, rm resmap.ResMap) (diags diag.Diagnostics) {
+	for _, name := range unmatchedPatchTargets(fSys, path, rm) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("%s matches no resources", name),
+			Detail:   fmt.Sprintf("The target of %s of the kustomization selects none of the resources of the build, check its group, version, kind, name, namespace and selectors.", name),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,76 @@
+package kustomize
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func TestUnmatchedPatchTargets(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml": `
+namePrefix: base-
+resources:
+- deployment.yaml
+`,
+		"base/deployment.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {app: web}
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`,
+		"overlay/kustomization.yaml": `
+namePrefix: prod-
+namespace: prod
+resources:
+- ../base
+configMapGenerator:
+- name: config
+  literals: [key=value]
+patches:
+- target: {kind: Deployment, name: web}
+  patch: '[{"op": "add", "path": "/spec/replicas", "value": 2}]'
+- target: {kind: Deployment, labelSelector: app=web}
+  patch: '[{"op": "add", "path": "/spec/paused", "value": false}]'
+- target: {kind: ConfigMap, name: config}
+  patch: '[{"op": "add", "path": "/data/other", "value": "value"}]'
+- target: {kind: Deployment, name: wbe}
+  path: typo.yaml
+- target: {kind: StatefulSet}
+  patch: '[{"op": "add", "path": "/spec/replicas", "value": 2}]'
+replacements:
+- source: {kind: ConfigMap, name: config, fieldPath: data.key}
+  targets:
+  - select: {kind: Deployment, name: base-web}
+    fieldPaths: [metadata.annotations.key]
+    options: {create: true}
+  - select: {kind: Service}
+    fieldPaths: [metadata.annotations.key]
+`,
+		"overlay/typo.yaml": `[{"op": "add", "path": "/spec/replicas", "value": 2}]`,
+	})
+
+	fSys := filesys.MakeFsOnDisk()
+	overlay := filepath.Join(dir, "overlay")
+
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	rm, err := k.Run(fSys, overlay)
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, []string{"patches[3] (typo.yaml)", "patches[4]", "replacements[0].targets[1]"}, unmatchedPatchTargets(fSys, overlay, rm), nil)
+
+	diags := patchTargetWarnings(fSys, overlay, rm)
+	assert.Equal(t, 3, len(diags), nil)
+	assert.Equal(t, false, diags.HasError(), nil)
+}
@@ -0,0 +1,47 @@
+package kustomize
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	patchVarNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	patchVarRegexp     = regexp.MustCompile(`\$?\$\(([A-Za-z_][A-Za-z0-9_]*)\)`)
+)
+
+func validatePatchVars(v interface{}, k string) (ws []string, es []error) {
+	for name := range v.(map[string]interface{}) {
+		if !patchVarNameRegexp.MatchString(name) {
+			es = append(es, fmt.Errorf("%s: invalid name %q, must start with a letter or underscore followed by letters, digits or underscores", k, name))
+		}
+	}
+
+	return ws, es
+}
+
+// substitutePatchVars replaces the $(name) placeholders of the vars in
+// patch with their values, values are inserted verbatim
+//
+// $$(name) escapes a placeholder and results in a literal $(name).
+// Placeholders of names not in vars, e.g. of Kustomize vars, are kept
+// unchanged, including their escapes.
+func substitutePatchVars(patch string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return patch
+	}
+
+	return patchVarRegexp.ReplaceAllStringFunc(patch, func(m string) string {
+		name := patchVarRegexp.FindStringSubmatch(m)[1]
+		value, ok := vars[name]
+		if !ok {
+			return m
+		}
+
+		if m[1] == '$' {
+			return m[1:]
+		}
+
+		return value
+	})
+}
@@ -0,0 +1,58 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubstitutePatchVars(t *testing.T) {
+	vars := map[string]string{
+		"domain": "example.com",
+		"ARN":    "arn:aws:iam::123456789012:role/example",
+	}
+
+	patch := `metadata:
+  annotations:
+    eks.amazonaws.com/role-arn: "$(ARN)"
+spec:
+  rules:
+  - host: app.$(domain)
+  - host: $$(domain)
+  - host: $(SERVICE_NAME)
+  - host: $$(SERVICE_NAME)
+`
+	expected := `metadata:
+  annotations:
+    eks.amazonaws.com/role-arn: "arn:aws:iam::123456789012:role/example"
+spec:
+  rules:
+  - host: app.example.com
+  - host: $(domain)
+  - host: $(SERVICE_NAME)
+  - host: $$(SERVICE_NAME)
+`
+	assert.Equal(t, expected, substitutePatchVars(patch, vars), nil)
+	assert.Equal(t, patch, substitutePatchVars(patch, nil), nil)
+}
+
+func TestGetPatchesVars(t *testing.T) {
+	patches := getPatches([]interface{}{
+		map[string]interface{}{
+			"patch":   `[{"op": "replace", "path": "/data/key", "value": "$(value)"}]`,
+			"vars":    map[string]interface{}{"value": "patched"},
+			"target":  []interface{}{},
+			"options": []interface{}{},
+		},
+	})
+
+	assert.Equal(t, `[{"op": "replace", "path": "/data/key", "value": "patched"}]`, patches[0].Patch, nil)
+}
+
+func TestValidatePatchVars(t *testing.T) {
+	_, es := validatePatchVars(map[string]interface{}{"domain": "", "_ARN1": ""}, "vars")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validatePatchVars(map[string]interface{}{"1domain": "", "my-domain": ""}, "vars")
+	assert.Equal(t, 2, len(es), nil)
+}
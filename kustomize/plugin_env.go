@@ -0,0 +1,168 @@
+package kustomize
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/yaml"
+)
+
+// annotations of function configs kustomize reads the function spec from
+var functionAnnotations = []string{
+	"config.kubernetes.io/function",
+	"config.k8s.io/function",
+}
+
+// getPluginEnv returns the plugin_env of the kustomize_options of d
+func getPluginEnv(d *schema.ResourceData) map[string]string {
+	kOptsList := d.Get("kustomize_options").([]interface{})
+	if len(kOptsList) == 0 || kOptsList[0] == nil {
+		return nil
+	}
+
+	env, _ := kOptsList[0].(map[string]interface{})["plugin_env"].(map[string]interface{})
+	if len(env) == 0 {
+		return nil
+	}
+
+	return convertMapStringInterfaceToMapStringString(env)
+}
+
+// setPluginEnv sets env in the environment of the provider, which exec
+// functions and the container runtime inherit, and returns a function
+// to restore the previous environment
+//
+// The environment is global state, builds with plugin_env must hold
+// the exclusive build lock.
+func setPluginEnv(env map[string]string) (restore func()) {
+	previous := make(map[string]*string, len(env))
+	for k, v := range env {
+		if p, ok := os.LookupEnv(k); ok {
+			previous[k] = &p
+		} else {
+			previous[k] = nil
+		}
+		os.Setenv(k, v)
+	}
+
+	return func() {
+		for k, p := range previous {
+			if p == nil {
+				os.Unsetenv(k)
+				continue
+			}
+			os.Setenv(k, *p)
+		}
+	}
+}
+
+// pluginEnvFileSystem adds the names of the plugin_env to the envs of
+// container function configs, the container runtime then exposes them
+// from the environment, without values on its command line
+type pluginEnvFileSystem struct {
+	filesys.FileSystem
+	names []string
+}
+
+func makePluginEnvFS(fs filesys.FileSystem, env map[string]string) filesys.FileSystem {
+	if len(env) == 0 {
+		return fs
+	}
+
+	names := make([]string, 0, len(env))
+	for k := range env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return pluginEnvFileSystem{
+		FileSystem: fs,
+		names:      names,
+	}
+}
+
+func (pfs pluginEnvFileSystem) ReadFile(name string) ([]byte, error) {
+	content, err := pfs.FileSystem.ReadFile(name)
+	if err != nil || isKustomizationFileName(name) || !hasExecFunctionMarker(content) {
+		return content, err
+	}
+
+	nodes, err := kio.FromBytes(content)
+	if err != nil {
+		// left for kustomize to report
+		return content, nil
+	}
+
+	changed := false
+	for _, n := range nodes {
+		annotations := n.GetAnnotations()
+		for _, a := range functionAnnotations {
+			spec, ok := annotations[a]
+			if !ok {
+				continue
+			}
+
+			updated, ok := addContainerEnvs(spec, pfs.names)
+			if !ok {
+				continue
+			}
+
+			annotations[a] = updated
+			if err := n.SetAnnotations(annotations); err != nil {
+				return nil, err
+			}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return content, nil
+	}
+
+	out, err := kio.StringAll(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(out), nil
+}
+
+// addContainerEnvs adds names to the envs of the container of the
+// function spec, returns false if the spec has no container
+func addContainerEnvs(spec string, names []string) (string, bool) {
+	var fn map[string]interface{}
+	if err := yaml.Unmarshal([]byte(spec), &fn); err != nil {
+		return spec, false
+	}
+
+	container, ok := fn["container"].(map[string]interface{})
+	if !ok {
+		return spec, false
+	}
+
+	// envs are names or name=value, values of the config take precedence
+	envs, _ := container["envs"].([]interface{})
+	existing := make(map[string]bool)
+	for _, e := range envs {
+		if s, ok := e.(string); ok {
+			existing[strings.SplitN(s, "=", 2)[0]] = true
+		}
+	}
+	for _, n := range names {
+		if !existing[n] {
+			envs = append(envs, n)
+		}
+	}
+	container["envs"] = envs
+
+	out, err := yaml.Marshal(fn)
+	if err != nil {
+		return spec, false
+	}
+
+	return string(out), true
+}
@@ -0,0 +1,85 @@
+package kustomize
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func TestSetPluginEnv(t *testing.T) {
+	os.Setenv("TEST_PLUGIN_ENV_SET", "previous")
+	defer os.Unsetenv("TEST_PLUGIN_ENV_SET")
+
+	restore := setPluginEnv(map[string]string{
+		"TEST_PLUGIN_ENV_SET":   "plugin",
+		"TEST_PLUGIN_ENV_UNSET": "plugin",
+	})
+	assert.Equal(t, "plugin", os.Getenv("TEST_PLUGIN_ENV_SET"), nil)
+	assert.Equal(t, "plugin", os.Getenv("TEST_PLUGIN_ENV_UNSET"), nil)
+
+	restore()
+	assert.Equal(t, "previous", os.Getenv("TEST_PLUGIN_ENV_SET"), nil)
+	_, ok := os.LookupEnv("TEST_PLUGIN_ENV_UNSET")
+	assert.Equal(t, false, ok, nil)
+}
+
+func TestPluginEnvFS(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("container.yaml", []byte(`apiVersion: example.com/v1
+kind: Generator
+metadata:
+  name: test
+  annotations:
+    config.kubernetes.io/function: |
+      container:
+        image: example.com/generator:v1
+        envs:
+        - TOKEN=from-config
+`))
+	fSys.WriteFile("exec.yaml", []byte(testExecFunctionConfig))
+
+	pfs := makePluginEnvFS(fSys, map[string]string{"TOKEN": "secret", "ENDPOINT": "https://example.com"})
+
+	content, err := pfs.ReadFile("container.yaml")
+	assert.Equal(t, nil, err, nil)
+	assert.Contains(t, string(content), "- TOKEN=from-config\n        - ENDPOINT\n", nil)
+	assert.NotContains(t, string(content), "secret", nil)
+
+	// exec functions inherit the environment
+	content, err = pfs.ReadFile("exec.yaml")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testExecFunctionConfig, string(content), nil)
+
+	assert.Equal(t, fSys, makePluginEnvFS(fSys, nil), nil)
+}
+
+func TestKustomizationBuildPluginEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"kustomization.yaml": "generators:\n- generator.yaml\n",
+		"generator.yaml":     testExecFunctionConfig,
+		"generator.sh":       "#!/bin/sh\nprintf 'apiVersion: v1\\nkind: ConfigMap\\nmetadata:\\n  name: env\\ndata:\\n  endpoint: %s\\n' \"$TEST_PLUGIN_ENDPOINT\"\n",
+	})
+	assert.Equal(t, nil, os.Chmod(filepath.Join(dir, "generator.sh"), 0755), nil)
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path": dir,
+		"kustomize_options": []interface{}{
+			map[string]interface{}{
+				"enable_exec": true,
+				"plugin_env":  map[string]interface{}{"TEST_PLUGIN_ENDPOINT": "https://example.com"},
+			},
+		},
+	})
+	diags := kustomizationBuild(context.TODO(), d, &Config{BuildLock: newBuildLock()})
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	assert.Contains(t, d.Get("manifests").(map[string]interface{})["_/ConfigMap/_/env"], `"endpoint":"https://example.com"`, nil)
+	_, ok := os.LookupEnv("TEST_PLUGIN_ENDPOINT")
+	assert.Equal(t, false, ok, nil)
+}
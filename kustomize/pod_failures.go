@@ -0,0 +1,207 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8sappsv1 "k8s.io/api/apps/v1"
+	k8scorev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// number of log lines of failing containers added to wait errors
+const podLogLines int64 = 10
+
+// waiting reasons of containers that do not recover without
+// a change, waits for their workloads fail immediately
+var failedContainerReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+var (
+	podsGVR        = k8sschema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	replicaSetsGVR = k8sschema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+)
+
+// podLogsFunction returns the last lines of the logs of the container
+// of the pod, of the previous instance of the container if previous
+type podLogsFunction func(namespace string, pod string, container string, previous bool) (string, error)
+
+// podLogs returns a podLogsFunction for the cluster
+func (kc *kubeClients) podLogs() podLogsFunction {
+	return func(namespace string, pod string, container string, previous bool) (string, error) {
+		config, err := kc.restConfig()
+		if err != nil {
+			return "", err
+		}
+
+		if kc.sem != nil {
+			config.Wrap(newLimitTransport(kc.sem))
+		}
+
+		cc, err := corev1client.NewForConfig(config)
+		if err != nil {
+			return "", err
+		}
+
+		lines := podLogLines
+		logs, err := cc.Pods(namespace).GetLogs(pod, &k8scorev1.PodLogOptions{
+			Container: container,
+			Previous:  previous,
+			TailLines: &lines,
+		}).DoRaw(context.TODO())
+
+		return string(logs), err
+	}
+}
+
+// currentPodSelector returns the selector of the pods of the current
+// revision of the workload u, pods of previous revisions may have
+// failed before, false if the current revision is not known yet
+func (km *kManifest) currentPodSelector(u *k8sunstructured.Unstructured) (k8slabels.Selector, bool, error) {
+	if _, found, err := k8sunstructured.NestedMap(u.UnstructuredContent(), "spec", "selector"); err != nil || !found {
+		return nil, false, err
+	}
+
+	selector, err := podSelector(u)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var key, value string
+	switch u.GetKind() {
+	case "Deployment":
+		key = k8sappsv1.DefaultDeploymentUniqueLabelKey
+		value, err = km.currentPodTemplateHash(u, selector)
+		if err != nil {
+			return nil, false, err
+		}
+	case "DaemonSet":
+		key = "pod-template-generation"
+		value = fmt.Sprintf("%d", u.GetGeneration())
+	case "StatefulSet":
+		key = k8sappsv1.ControllerRevisionHashLabelKey
+		value, _, _ = k8sunstructured.NestedString(u.UnstructuredContent(), "status", "updateRevision")
+	}
+	if value == "" {
+		return nil, false, nil
+	}
+
+	r, err := k8slabels.NewRequirement(key, "=", []string{value})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return selector.Add(*r), true, nil
+}
+
+// podSelector returns the selector of the pods of all
+// revisions of the workload u
+func podSelector(u *k8sunstructured.Unstructured) (k8slabels.Selector, error) {
+	s, _, err := k8sunstructured.NestedMap(u.UnstructuredContent(), "spec", "selector")
+	if err != nil {
+		return nil, err
+	}
+
+	var ls k8smetav1.LabelSelector
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(s, &ls); err != nil {
+		return nil, err
+	}
+
+	return k8smetav1.LabelSelectorAsSelector(&ls)
+}
+
+// currentPodTemplateHash returns the pod-template-hash of the
+// replica set of the current revision of the deployment u
+func (km *kManifest) currentPodTemplateHash(u *k8sunstructured.Unstructured, selector k8slabels.Selector) (string, error) {
+	revision := u.GetAnnotations()["deployment.kubernetes.io/revision"]
+	if revision == "" {
+		return "", nil
+	}
+
+	rss, err := km.client.
+		Resource(replicaSetsGVR).
+		Namespace(u.GetNamespace()).
+		List(context.TODO(), k8smetav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+
+	for _, rs := range rss.Items {
+		if !isOwnedBy(rs, u) || rs.GetAnnotations()["deployment.kubernetes.io/revision"] != revision {
+			continue
+		}
+
+		return rs.GetLabels()[k8sappsv1.DefaultDeploymentUniqueLabelKey], nil
+	}
+
+	return "", nil
+}
+
+func isOwnedBy(o k8sunstructured.Unstructured, owner *k8sunstructured.Unstructured) bool {
+	for _, ref := range o.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// failedPod returns an error describing the first failed container of
+// the pods of the current revision of the workload u, with the last
+// lines of its logs, nil if no container failed
+func (km *kManifest) failedPod(u *k8sunstructured.Unstructured) error {
+	selector, ok, err := km.currentPodSelector(u)
+	if err != nil || !ok {
+		return err
+	}
+
+	pods, err := km.client.
+		Resource(podsGVR).
+		Namespace(u.GetNamespace()).
+		List(context.TODO(), k8smetav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pods.Items {
+		var pod k8scorev1.Pod
+		if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(p.UnstructuredContent(), &pod); err != nil {
+			return err
+		}
+
+		statuses := append(append([]k8scorev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			w := cs.State.Waiting
+			if w == nil || !failedContainerReasons[w.Reason] {
+				continue
+			}
+
+			msg := fmt.Sprintf("pod %q container %q: %s", pod.Name, cs.Name, w.Reason)
+			if w.Message != "" {
+				msg = fmt.Sprintf("%s: %s", msg, w.Message)
+			}
+
+			// only containers that ran have logs
+			if w.Reason == "CrashLoopBackOff" && km.podLogs != nil {
+				logs, err := km.podLogs(pod.Namespace, pod.Name, cs.Name, true)
+				if err == nil && strings.TrimSpace(logs) != "" {
+					msg = fmt.Sprintf("%s, last log lines:\n%s", msg, strings.TrimRight(logs, "\n"))
+				}
+			}
+
+			return fmt.Errorf("%s", msg)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const testFailedDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  namespace: default
+  uid: deployment-uid
+  generation: 2
+  annotations: {deployment.kubernetes.io/revision: "2"}
+spec:
+  selector:
+    matchLabels: {app: test}
+`
+
+func testPodFailuresManifest(t *testing.T, objects ...string) *kManifest {
+	var objs []k8sruntime.Object
+	for _, o := range objects {
+		objs = append(objs, testRolloutObject(t, o))
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[k8sschema.GroupVersionResource]string{
+			podsGVR:        "PodList",
+			replicaSetsGVR: "ReplicaSetList",
+		},
+		objs...,
+	)
+
+	return newKManifest(nil, client)
+}
+
+func testReplicaSet(name string, revision string, hash string) string {
+	return `
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: ` + name + `
+  namespace: default
+  labels: {app: test, pod-template-hash: "` + hash + `"}
+  annotations: {deployment.kubernetes.io/revision: "` + revision + `"}
+  ownerReferences:
+  - {apiVersion: apps/v1, kind: Deployment, name: test, uid: deployment-uid}
+`
+}
+
+func testPod(name string, hash string, state string) string {
+	return `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: ` + name + `
+  namespace: default
+  labels: {app: test, pod-template-hash: "` + hash + `"}
+status:
+  containerStatuses:
+  - name: app
+    state: ` + state + `
+`
+}
+
+func TestFailedPod(t *testing.T) {
+	deployment := testRolloutObject(t, testFailedDeployment)
+	old := testReplicaSet("test-old", "1", "old")
+	current := testReplicaSet("test-new", "2", "new")
+
+	testCases := []struct {
+		name     string
+		objects  []string
+		expected string
+	}{
+		{"no pods", []string{current}, ""},
+		{"running", []string{current, testPod("test-new-a", "new", "{running: {}}")}, ""},
+		{"creating", []string{current, testPod("test-new-a", "new", "{waiting: {reason: ContainerCreating}}")}, ""},
+		{"previous revision", []string{old, current, testPod("test-old-a", "old", "{waiting: {reason: CrashLoopBackOff}}")}, ""},
+		{"image pull", []string{current, testPod("test-new-a", "new", `{waiting: {reason: ImagePullBackOff, message: Back-off pulling image "missing"}}`)},
+			`pod "test-new-a" container "app": ImagePullBackOff: Back-off pulling image "missing"`},
+		{"crash loop", []string{current, testPod("test-new-a", "new", "{waiting: {reason: CrashLoopBackOff}}")},
+			"pod \"test-new-a\" container \"app\": CrashLoopBackOff, last log lines:\nstarting\nerror: missing config"},
+	}
+
+	for _, tc := range testCases {
+		km := testPodFailuresManifest(t, tc.objects...)
+		km.podLogs = func(namespace string, pod string, container string, previous bool) (string, error) {
+			assert.Equal(t, true, previous, tc.name)
+			return "starting\nerror: missing config\n", nil
+		}
+
+		err := km.failedPod(deployment)
+		if tc.expected == "" {
+			assert.Equal(t, nil, err, tc.name)
+			continue
+		}
+		if assert.NotEqual(t, nil, err, tc.name) {
+			assert.Equal(t, tc.expected, err.Error(), tc.name)
+		}
+	}
+}
+
+func TestCurrentPodSelector(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected string
+	}{
+		{"daemonset", `
+kind: DaemonSet
+spec: {selector: {matchLabels: {app: test}}}
+`, "app=test,pod-template-generation=3"},
+		{"statefulset", `
+kind: StatefulSet
+spec: {selector: {matchLabels: {app: test}}}
+status: {updateRevision: test-abc}
+`, "app=test,controller-revision-hash=test-abc"},
+		{"statefulset without revision", `
+kind: StatefulSet
+spec: {selector: {matchLabels: {app: test}}}
+`, ""},
+	}
+
+	km := testPodFailuresManifest(t)
+	for _, tc := range testCases {
+		u := testRolloutObject(t, tc.manifest)
+		// YAML numbers decode as floats, the API returns integers
+		u.SetGeneration(3)
+
+		selector, ok, err := km.currentPodSelector(u)
+		assert.Equal(t, nil, err, tc.name)
+		assert.Equal(t, tc.expected != "", ok, tc.name)
+		if ok {
+			assert.Equal(t, tc.expected, selector.String(), tc.name)
+		}
+	}
+}
+
+func TestFailedPodUnknownRevision(t *testing.T) {
+	u := &k8sunstructured.Unstructured{}
+	u.SetKind("Deployment")
+	assert.Equal(t, nil, testPodFailuresManifest(t).failedPod(u), nil)
+}
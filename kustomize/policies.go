@@ -0,0 +1,209 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// policy rules, like conftest, deny and violation rules are violations,
+// warn rules are reported but never fail, rule names can have a
+// suffix, e.g. deny_latest_tag
+const (
+	policyRuleDeny      = "deny"
+	policyRuleViolation = "violation"
+	policyRuleWarn      = "warn"
+)
+
+// defaultPolicyNamespace is the package conftest evaluates by default
+const defaultPolicyNamespace = "main"
+
+// policy is a deny, violation or warn rule of a Rego package,
+// evaluated with every manifest as input
+type policy struct {
+	namespace string
+	rule      string
+	kind      string
+	query     rego.PreparedEvalQuery
+}
+
+func (p policy) name() string {
+	return fmt.Sprintf("%s.%s", p.namespace, p.rule)
+}
+
+type policyResult struct {
+	id      string
+	policy  string
+	message string
+}
+
+// policyRuleKind returns the kind of the rule name, deny for deny and
+// violation rules, warn for warn rules and false for other rules
+func policyRuleKind(name string) (string, bool) {
+	for _, r := range []string{policyRuleDeny, policyRuleViolation, policyRuleWarn} {
+		if name == r || strings.HasPrefix(name, r+"_") {
+			if r == policyRuleViolation {
+				return policyRuleDeny, true
+			}
+			return r, true
+		}
+	}
+
+	return "", false
+}
+
+// loadPolicies compiles the Rego modules and prepares the queries of
+// the rules of the packages in namespaces, sorted by package and rule
+func loadPolicies(ctx context.Context, sources map[string]interface{}, namespaces []string) ([]policy, error) {
+	modules := make(map[string]string, len(sources))
+	for n, s := range sources {
+		modules[n] = s.(string)
+	}
+
+	compiler, err := ast.CompileModules(modules)
+	if err != nil {
+		return nil, fmt.Errorf("policies: %s", err)
+	}
+
+	if len(namespaces) == 0 {
+		namespaces = []string{defaultPolicyNamespace}
+	}
+	selected := make(map[string]bool)
+	for _, ns := range namespaces {
+		selected[ns] = true
+	}
+
+	// rules can be split across modules of the same package
+	rules := make(map[string]policy)
+	for _, m := range compiler.Modules {
+		ns := strings.TrimPrefix(m.Package.Path.String(), "data.")
+		if !selected[ns] {
+			continue
+		}
+
+		for _, r := range m.Rules {
+			name := r.Head.Ref()[0].Value.String()
+			kind, ok := policyRuleKind(name)
+			if !ok {
+				continue
+			}
+
+			p := policy{namespace: ns, rule: name, kind: kind}
+			rules[p.name()] = p
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("policies: no %s, %s or %s rules in the namespaces %s", policyRuleDeny, policyRuleViolation, policyRuleWarn, strings.Join(namespaces, ", "))
+	}
+
+	names := make([]string, 0, len(rules))
+	for n := range rules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var policies []policy
+	for _, n := range names {
+		p := rules[n]
+
+		p.query, err = rego.New(
+			rego.Query(fmt.Sprintf("data.%s", n)),
+			rego.Compiler(compiler),
+		).PrepareForEval(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %s", n, err)
+		}
+
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// ruleMessages returns the messages of the value of a rule, like
+// conftest, rules are sets of messages or of objects with a msg
+func ruleMessages(v interface{}) ([]string, error) {
+	set, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a set of messages, got %T", v)
+	}
+
+	var msgs []string
+	for _, e := range set {
+		switch o := e.(type) {
+		case string:
+			msgs = append(msgs, o)
+		case map[string]interface{}:
+			msg, ok := o["msg"].(string)
+			if !ok {
+				return nil, fmt.Errorf("objects must have a msg string")
+			}
+			msgs = append(msgs, msg)
+		default:
+			return nil, fmt.Errorf("messages must be strings or objects with a msg, got %T", e)
+		}
+	}
+	sort.Strings(msgs)
+
+	return msgs, nil
+}
+
+// evaluatePolicies evaluates the rules of all policies for every
+// manifest and returns the results by rule kind
+func evaluatePolicies(ctx context.Context, policies []policy, manifests map[string]interface{}) (map[string][]policyResult, error) {
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	results := make(map[string][]policyResult)
+	for _, id := range ids {
+		var input interface{}
+		if err := json.Unmarshal([]byte(manifests[id].(string)), &input); err != nil {
+			return nil, fmt.Errorf("%q: %s", id, err)
+		}
+
+		for _, p := range policies {
+			rs, err := p.query.Eval(ctx, rego.EvalInput(input))
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: %q: %s", p.name(), id, err)
+			}
+
+			// rules without any message are undefined
+			for _, r := range rs {
+				for _, e := range r.Expressions {
+					msgs, err := ruleMessages(e.Value)
+					if err != nil {
+						return nil, fmt.Errorf("policy %q: %s", p.name(), err)
+					}
+
+					for _, m := range msgs {
+						results[p.kind] = append(results[p.kind], policyResult{id: id, policy: p.name(), message: m})
+					}
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func flattenPolicyResults(results []policyResult) []interface{} {
+	var out []interface{}
+	for _, r := range results {
+		out = append(out, map[string]interface{}{
+			"id":      r.id,
+			"policy":  r.policy,
+			"message": r.message,
+		})
+	}
+
+	return out
+}
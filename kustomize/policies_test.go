@@ -0,0 +1,79 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+package main
+
+deny[msg] {
+	input.kind == "Deployment"
+	c := input.spec.template.spec.containers[_]
+	endswith(c.image, ":latest")
+	msg := sprintf("container %s uses the latest tag", [c.name])
+}
+
+warn_labels[msg] {
+	not input.metadata.labels
+	msg := sprintf("%s has no labels", [input.metadata.name])
+}
+`
+
+// rules of the same package can be split across modules
+const testPolicyViolation = `
+package main
+
+violation[{"msg": msg}] {
+	input.spec.replicas < 2
+	msg := sprintf("%s has less than 2 replicas", [input.metadata.name])
+}
+`
+
+const testPolicyDeployment = `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test"},"spec":{"replicas":1,"template":{"spec":{"containers":[{"name":"a","image":"a:latest"},{"name":"b","image":"b:1.0"}]}}}}`
+
+func TestLoadPolicies(t *testing.T) {
+	policies, err := loadPolicies(context.TODO(), map[string]interface{}{"test.rego": testPolicy, "violation.rego": testPolicyViolation}, nil)
+	assert.Equal(t, nil, err, nil)
+
+	var names []string
+	for _, p := range policies {
+		names = append(names, p.name())
+	}
+	assert.Equal(t, []string{"main.deny", "main.violation", "main.warn_labels"}, names, nil)
+
+	_, err = loadPolicies(context.TODO(), map[string]interface{}{"test.rego": testPolicy}, []string{"other"})
+	assert.EqualError(t, err, "policies: no deny, violation or warn rules in the namespaces other", nil)
+
+	_, err = loadPolicies(context.TODO(), map[string]interface{}{"syntax.rego": "package main\n\ndeny[msg] {"}, nil)
+	assert.Error(t, err, nil)
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	policies, err := loadPolicies(context.TODO(), map[string]interface{}{"test.rego": testPolicy, "violation.rego": testPolicyViolation}, nil)
+	assert.Equal(t, nil, err, nil)
+
+	results, err := evaluatePolicies(context.TODO(), policies, map[string]interface{}{
+		"apps/Deployment/_/test": testPolicyDeployment,
+		"_/Namespace/_/test":     `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test","labels":{"a":"b"}}}`,
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []policyResult{
+		{id: "apps/Deployment/_/test", policy: "main.deny", message: "container a uses the latest tag"},
+		{id: "apps/Deployment/_/test", policy: "main.violation", message: "test has less than 2 replicas"},
+	}, results[policyRuleDeny], nil)
+	assert.Equal(t, []policyResult{
+		{id: "apps/Deployment/_/test", policy: "main.warn_labels", message: "test has no labels"},
+	}, results[policyRuleWarn], nil)
+}
+
+func TestEvaluatePoliciesError(t *testing.T) {
+	policies, err := loadPolicies(context.TODO(), map[string]interface{}{"test.rego": "package main\n\ndeny[1] { true }\n"}, nil)
+	assert.Equal(t, nil, err, nil)
+
+	_, err = evaluatePolicies(context.TODO(), policies, map[string]interface{}{"apps/Deployment/_/test": testPolicyDeployment})
+	assert.EqualError(t, err, "policy \"main.deny\": messages must be strings or objects with a msg, got json.Number", nil)
+}
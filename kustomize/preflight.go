@@ -0,0 +1,230 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// preflightPaths are the API paths the provider requests independent
+// of the manifests, for API discovery and the OpenAPI schema
+var preflightPaths = []string{"/api", "/apis", "/openapi/v2"}
+
+var selfSubjectAccessReviewGVR = k8sschema.GroupVersionResource{
+	Group:    "authorization.k8s.io",
+	Version:  "v1",
+	Resource: "selfsubjectaccessreviews",
+}
+
+// preflightReport is the result of checking the connection
+// and credentials of a cluster when configuring the provider
+type preflightReport struct {
+	cluster     string
+	host        string
+	credentials string
+	version     string
+	missing     []string
+	err         error
+}
+
+func (r preflightReport) failed() bool {
+	return r.err != nil || len(r.missing) > 0
+}
+
+func (r preflightReport) String() string {
+	var b strings.Builder
+
+	name := "provider"
+	if r.cluster != "" {
+		name = fmt.Sprintf("cluster %q", r.cluster)
+	}
+	fmt.Fprintf(&b, "%s:\n", name)
+
+	if r.host != "" {
+		fmt.Fprintf(&b, "    server: %s\n", r.host)
+	}
+	if r.credentials != "" {
+		fmt.Fprintf(&b, "    credentials: %s\n", r.credentials)
+	}
+	if r.version != "" {
+		fmt.Fprintf(&b, "    server version: %s\n", r.version)
+	}
+	if len(r.missing) > 0 {
+		fmt.Fprintf(&b, "    missing permissions: %s\n", strings.Join(r.missing, ", "))
+	}
+	if r.err != nil {
+		fmt.Fprintf(&b, "    error: %s\n", r.err)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// credentialSource returns the setting of credentialSources that
+// overrides the credentials of the kubeconfig, if any is set
+func credentialSource(get func(string) interface{}) string {
+	for _, k := range credentialSources {
+		if isSet(get(k)) {
+			return k
+		}
+	}
+
+	return ""
+}
+
+// describeCredentials returns how requests using the rest config
+// authenticate, without any of the secrets, source is the setting
+// the credentials are configured with, if not from the kubeconfig
+func describeCredentials(config *rest.Config, source string) string {
+	var s string
+	switch {
+	case source != "" && source != "username" && source != "token_file":
+		s = fmt.Sprintf("%s settings", source)
+	case config.ExecProvider != nil:
+		s = fmt.Sprintf("exec plugin %q", config.ExecProvider.Command)
+	case config.AuthProvider != nil:
+		s = fmt.Sprintf("auth provider %q", config.AuthProvider.Name)
+	case config.BearerTokenFile != "":
+		s = fmt.Sprintf("token file %q", config.BearerTokenFile)
+	case config.BearerToken != "":
+		s = "bearer token"
+	case config.CertFile != "" || len(config.CertData) > 0:
+		s = "client certificate"
+	case config.Username != "":
+		s = fmt.Sprintf("basic auth as %q", config.Username)
+	default:
+		s = "none, anonymous"
+	}
+
+	if config.Impersonate.UserName != "" {
+		s = fmt.Sprintf("%s, impersonating %q", s, config.Impersonate.UserName)
+	}
+
+	return s
+}
+
+// preflight checks that the cluster is reachable, that the credentials
+// authenticate and that they are allowed the requests the provider
+// makes for every resource
+//
+// Restricted clients only request the server version, permissions
+// are not checked, they can not be reviewed in the namespaces.
+func (kc *kubeClients) preflight(name string, source string) (r preflightReport) {
+	r.cluster = name
+
+	config, err := kc.restConfig()
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	r.host = config.Host
+	r.credentials = describeCredentials(config, source)
+
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	v, err := dc.ServerVersion()
+	if k8serrors.IsUnauthorized(err) {
+		r.err = fmt.Errorf("authentication failed: %s", err)
+		return r
+	}
+	if err != nil {
+		r.err = fmt.Errorf("cluster unreachable: %s", err)
+		return r
+	}
+	r.version = v.GitVersion
+
+	if kc.staticOnly {
+		return r
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	for _, path := range preflightPaths {
+		review := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "authorization.k8s.io/v1",
+			"kind":       "SelfSubjectAccessReview",
+			"spec": map[string]interface{}{
+				"nonResourceAttributes": map[string]interface{}{
+					"path": path,
+					"verb": "get",
+				},
+			},
+		}}
+
+		resp, err := client.Resource(selfSubjectAccessReviewGVR).Create(context.TODO(), review, k8smetav1.CreateOptions{})
+		if k8serrors.IsUnauthorized(err) {
+			r.err = fmt.Errorf("authentication failed: %s", err)
+			return r
+		}
+		if err != nil {
+			r.err = fmt.Errorf("checking permissions failed: %s", err)
+			return r
+		}
+
+		allowed, _, _ := unstructured.NestedBool(resp.Object, "status", "allowed")
+		if !allowed {
+			r.missing = append(r.missing, fmt.Sprintf("get %s", path))
+		}
+	}
+
+	return r
+}
+
+// preflight checks the connection of the provider and of all clusters,
+// in the order of the configuration, returning an error with the
+// reports of all of them if any failed
+//
+// The connection of the provider is not checked if it is not
+// configured and all resources use the cluster blocks.
+func (c *Config) preflight(d *schema.ResourceData) error {
+	var reports []preflightReport
+
+	if config, err := c.clients.restConfig(); err != nil || config.Host != "" || len(c.clusters) == 0 {
+		reports = append(reports, c.clients.preflight("", credentialSource(d.Get)))
+	}
+
+	for _, cl := range d.Get("cluster").([]interface{}) {
+		cl := cl.(map[string]interface{})
+		name := cl["name"].(string)
+		get := func(key string) interface{} { return cl[key] }
+
+		reports = append(reports, c.clusters[name].preflight(name, credentialSource(get)))
+	}
+
+	failed := false
+	var out []string
+	for _, r := range reports {
+		log.Printf("[INFO] provider kustomization: preflight %s", r)
+		failed = failed || r.failed()
+		out = append(out, r.String())
+	}
+
+	if failed {
+		return fmt.Errorf("provider kustomization: preflight failed:\n%s", strings.Join(out, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func testPreflightServer(token string, denied string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","message":"Unauthorized","reason":"Unauthorized","code":401}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/version":
+			w.Write([]byte(`{"major":"1","minor":"29","gitVersion":"v1.29.2"}`))
+		case "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews":
+			body, _ := ioutil.ReadAll(r.Body)
+
+			var review map[string]interface{}
+			json.Unmarshal(body, &review)
+			path := review["spec"].(map[string]interface{})["nonResourceAttributes"].(map[string]interface{})["path"]
+
+			review["status"] = map[string]interface{}{"allowed": path != denied}
+			resp, _ := json.Marshal(review)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(resp)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func testPreflightClients(host string, token string) *kubeClients {
+	return &kubeClients{restConfig: func() (*rest.Config, error) {
+		return &rest.Config{Host: host, BearerToken: token}, nil
+	}}
+}
+
+func TestPreflight(t *testing.T) {
+	server := testPreflightServer("valid", "")
+	defer server.Close()
+
+	r := testPreflightClients(server.URL, "valid").preflight("", "")
+	assert.Equal(t, false, r.failed(), r.String())
+	assert.Equal(t, "v1.29.2", r.version, nil)
+	assert.Equal(t, "bearer token", r.credentials, nil)
+
+	r = testPreflightClients(server.URL, "expired").preflight("prod", "")
+	assert.Equal(t, true, r.failed(), nil)
+	assert.Contains(t, r.String(), "cluster \"prod\":\n    server: "+server.URL+"\n    credentials: bearer token\n    error: authentication failed", nil)
+}
+
+func TestPreflightMissingPermissions(t *testing.T) {
+	server := testPreflightServer("valid", "/openapi/v2")
+	defer server.Close()
+
+	r := testPreflightClients(server.URL, "valid").preflight("", "")
+	assert.Equal(t, true, r.failed(), nil)
+	assert.Equal(t, []string{"get /openapi/v2"}, r.missing, nil)
+
+	// permissions of restricted clients are not checked
+	kc := testPreflightClients(server.URL, "valid")
+	kc.staticOnly = true
+	r = kc.preflight("", "")
+	assert.Equal(t, false, r.failed(), r.String())
+}
+
+func TestDescribeCredentials(t *testing.T) {
+	assert.Equal(t, "none, anonymous", describeCredentials(&rest.Config{}, ""), nil)
+	assert.Equal(t, "eks settings", describeCredentials(&rest.Config{BearerToken: "t"}, "eks"), nil)
+	assert.Equal(t, "client certificate, impersonating \"admin\"", describeCredentials(&rest.Config{
+		CertData:    []byte("cert"),
+		Impersonate: rest.ImpersonationConfig{UserName: "admin"},
+	}, ""), nil)
+}
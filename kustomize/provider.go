@@ -3,34 +3,104 @@ package kustomize
 import (
 	"fmt"
 	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/flowcontrol"
 
 	"github.com/mitchellh/go-homedir"
+	"golang.org/x/oauth2"
 )
 
 // Config ...
 type Config struct {
-	Client                dynamic.Interface
-	Mapper                *restmapper.DeferredDiscoveryRESTMapper
-	Mutex                 *sync.Mutex
-	GzipLastAppliedConfig bool
+	BuildLock               *buildLock
+	GzipLastAppliedConfig   bool
+	ApplyDefaults           applyDefaults
+	IgnoreAnnotations       []*regexp.Regexp
+	IgnoreLabels            []*regexp.Regexp
+	AuditAnnotations        map[string]string
+	AllowUnreachableCluster bool
+	Sops                    *sopsConfig
+	Vault                   *vaultConfig
+	SecretPlaceholders      bool
+	StateEncryption         *stateEncryption
+	ExecFunctions           *execAllowlist
+	BuildCache              *buildCache
+	BuildLimits             buildLimits
+	Retry                   *retryPolicy
+	RefreshCache            *refreshCache
+	Metrics                 *runMetrics
+	ReadinessChecks         map[string]waitRefreshFunction
+	SkipUnavailableNodes    bool
+
+	clients  *kubeClients
+	clusters map[string]*kubeClients
+}
+
+// kubeClients are the clients for one cluster,
+// initialized on first use
+type kubeClients struct {
+	restConfig func() (*rest.Config, error)
+	once       sync.Once
+	client     dynamic.Interface
+	mapper     k8smeta.ResettableRESTMapper
+	err        error
+
+	// discovery cache settings, a zero cacheTTL
+	// caches for the lifetime of the provider
+	cacheTTL      time.Duration
+	cacheDisabled bool
+	cacheMu       sync.Mutex
+	cacheReset    time.Time
+
+	// limits concurrent requests, if not nil
+	sem chan struct{}
+
+	// mappings used before discovery, if not nil
+	staticMapper *k8smeta.DefaultRESTMapper
+	// never use discovery, only the static mappings
+	staticOnly bool
+
+	reachableOnce sync.Once
+	reachable     bool
+
+	// version of the API server, requested on first use
+	versionOnce sync.Once
+	version     *k8sversion.Version
+	versionErr  error
+
+	// OpenAPI schema of the cluster, cached on first use
+	openAPIMu     sync.Mutex
+	openAPISchema []byte
 }
 
 // Provider ...
 func Provider() *schema.Provider {
 	p := &schema.Provider{
 		ResourcesMap: map[string]*schema.Resource{
-			"kustomization_resource": kustomizationResource(),
+			"kustomization_resource":     kustomizationResource(),
+			"kustomization_render":       kustomizationRender(),
+			"kustomization_oci_artifact": kustomizationOCIArtifact(),
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
@@ -39,35 +109,359 @@ func Provider() *schema.Provider {
 			// new name for the data source
 			"kustomization_build": dataSourceKustomization(),
 
+			// build many kustomizations in one data source
+			"kustomization_builds": dataSourceKustomizationBuilds(),
+
 			// define overlay from TF
 			"kustomization_overlay": dataSourceKustomizationOverlay(),
+
+			// patch manifests from other data sources or providers
+			"kustomization_patch": dataSourceKustomizationPatch(),
+
+			// parse manifests from other data sources or providers
+			"kustomization_manifests": dataSourceKustomizationManifests(),
+
+			// split resource IDs into their parts
+			"kustomization_parse_id": dataSourceKustomizationParseID(),
+
+			// validate manifests against Kubernetes and CRD schemas
+			"kustomization_validate": dataSourceKustomizationValidate(),
+
+			// enforce policies on manifests
+			"kustomization_policy": dataSourceKustomizationPolicy(),
+
+			// per-phase timings and API call counts of the run
+			"kustomization_metrics": dataSourceKustomizationMetrics(),
+
+			// read live objects without managing them
+			"kustomization_resource_status": dataSourceKustomizationResourceStatus(),
+
+			// objects cascade deleted with destroyed resources
+			"kustomization_destroy_preview": dataSourceKustomizationDestroyPreview(),
+
+			// list live objects by label selector
+			"kustomization_inventory": dataSourceKustomizationInventory(),
+
+			// versions of the libraries embedded in the provider
+			"kustomization_versions": dataSourceKustomizationVersions(),
+
+			// API groups, versions and kinds available in the cluster
+			"kustomization_discovery": dataSourceKustomizationDiscovery(),
+
+			// gate applies on cluster readiness
+			"kustomization_cluster_health": dataSourceKustomizationClusterHealth(),
+
+			// detect ids rendered by more than one data source
+			"kustomization_duplicates": dataSourceKustomizationDuplicates(),
+
+			// hash kustomization directories without building them
+			"kustomization_dir_hash": dataSourceKustomizationDirHash(),
 		},
 
 		Schema: map[string]*schema.Schema{
-			"kubeconfig_path": {
+			"proxy_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("KUBE_PROXY_URL", nil),
+				Description: "URL of the HTTP or SOCKS5 proxy to use for requests to the Kubernetes API. Can be set using KUBE_PROXY_URL env var",
+			},
+			"user_agent": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("KUBE_USER_AGENT", nil),
+				Description: "User-Agent for requests to the Kubernetes API. Recorded in the cluster's audit log to attribute changes, e.g. to a Terraform workspace or CI pipeline run. Can be set using KUBE_USER_AGENT env var",
+			},
+			"debug_api_calls": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "When 'true' requests to and responses from the Kubernetes API are logged at DEBUG level, with credentials, lastAppliedConfig annotations and secret data redacted.",
+			},
+			"client_qps": {
+				Type:        schema.TypeFloat,
+				Optional:    true,
+				Default:     120,
+				Description: "Maximum queries per second to the Kubernetes API.",
+			},
+			"client_burst": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     240,
+				Description: "Maximum burst of queries to the Kubernetes API.",
+			},
+			"request_timeout": {
 				Type:         schema.TypeString,
 				Optional:     true,
-				DefaultFunc:  schema.EnvDefaultFunc("KUBECONFIG_PATH", nil),
-				ExactlyOneOf: []string{"kubeconfig_path", "kubeconfig_raw", "kubeconfig_incluster"},
-				Description:  "Path to a kubeconfig file. Can be set using KUBECONFIG_PATH env var",
+				ValidateFunc: validateDuration,
+				Description:  "Timeout for individual requests to the Kubernetes API, e.g. '30s'. Defaults to no timeout.",
 			},
-			"kubeconfig_raw": {
+			"apply_defaults": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Elem:        getApplyDefaultsSchema(),
+				Description: "Defaults for kustomization_resource attributes, used for resources that do not set the attribute.",
+			},
+			"ignore_annotations": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Annotations to ignore, as exact names or regular expressions. Ignored annotations are removed from manifests before applying and diffing, e.g. to coexist with mutating controllers.",
+			},
+			"ignore_labels": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Labels to ignore, as exact names or regular expressions. Ignored labels are removed from manifests before applying and diffing, e.g. to coexist with mutating controllers.",
+			},
+			"audit_annotations": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				ValidateFunc: validateAnnotationKeys,
+				Description:  "Annotations added to every object created or updated by kustomization_resources, e.g. the workspace and commit of the Terraform run. Not part of the manifest, changing them does not update resources.",
+			},
+			"cluster": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Named cluster connections, kustomization_resources select one using their cluster attribute. Each accepts the same connection and authentication settings as the provider.",
+				Elem:        getClusterSchema(),
+			},
+			"discovery_cache_ttl": {
 				Type:         schema.TypeString,
 				Optional:     true,
-				ExactlyOneOf: []string{"kubeconfig_path", "kubeconfig_raw", "kubeconfig_incluster"},
-				Description:  "Raw kube config. If kubeconfig_raw is set, KUBECONFIG_PATH is ignored.",
+				ValidateFunc: validateDuration,
+				Description:  "Duration after which cached API discovery information is refreshed, e.g. '5m'. Defaults to caching for the entire Terraform run.",
 			},
-			"kubeconfig_incluster": {
-				Type:         schema.TypeBool,
+			"disable_discovery_cache": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "When 'true' API discovery information is refreshed for every operation.",
+			},
+			"parallelism": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of concurrent requests to the Kubernetes API, across all clusters and independent of Terraform's -parallelism. Defaults to 0, unlimited.",
+			},
+			"refresh_label_selector": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateLabelSelector,
+				Description:  "Label selector matching the objects of kustomization_resources, e.g. a label set using commonLabels. When set, refreshes list the matching objects once per kind and namespace instead of a get request for each resource. Resources not matching the selector are refreshed using get requests.",
+			},
+			"retry": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Elem:        getRetrySchema(),
+				Description: "Retry failed requests of kustomization_resources to the Kubernetes API, e.g. for flaky clusters or slow admission webhooks.",
+			},
+			"rest_mapping": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Static mappings of kinds to API resources, used instead of discovery. Allows credentials without permissions for discovery, e.g. namespace scoped RBAC, to manage the mapped kinds.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"group": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"version": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"kind": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"resource": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"namespaced": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+					},
+				},
+			},
+			"restrict_namespaces": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Restrict all requests to the Kubernetes API to namespaced resources in these namespaces, for credentials with namespace scoped RBAC only. Kinds are mapped using rest_mapping only, without API discovery.",
+			},
+			"wait_skip_unavailable_nodes": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "When waiting for DaemonSets, do not wait for their pods on nodes that are cordoned or not ready. Set to 'false' to wait for the pods on all nodes.",
+			},
+			"readiness_check": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Readiness check of a kind, e.g. of an in-house CRD, used by wait instead of the built-in checks. Objects are ready when the condition is True, or the JSONPath expression returns the value.",
+				Elem:        getReadinessCheckSchema(),
+			},
+			"allow_unreachable_cluster": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "When 'true' plans proceed if the Kubernetes API is unreachable or the cluster does not exist yet. Reads are deferred and computed attributes are unknown until the cluster is reachable.",
+			},
+			"preflight": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"allow_unreachable_cluster"},
+				Description:   "When 'true' check the connection, credentials and the permissions for API discovery of the provider and all clusters when configuring the provider, and fail with a report of the server, credentials, server version and missing permissions.",
+			},
+			"sops": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Decrypt SOPS encrypted files and envs of config map and secret generators using the sops binary.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     defaultSOPSPath,
+							Description: "Path to the sops binary.",
+						},
+						"age_key": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "One or more age identities, one per line, to decrypt age encrypted files.",
+						},
+						"age_key_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Path to a file with age identities.",
+						},
+						"age_ssh_key_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Path to an SSH private key, ed25519 or RSA, to use as age identity.",
+						},
+						"aws_profile": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "AWS profile to decrypt using AWS KMS.",
+						},
+						"aws_region": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "AWS region to decrypt using AWS KMS.",
+						},
+						"gcp_credentials_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Path to a Google Cloud credentials file to decrypt using GCP KMS.",
+						},
+						"azure_tenant_id": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Azure AD tenant ID to decrypt using Azure Key Vault.",
+						},
+						"azure_client_id": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Azure AD client ID to decrypt using Azure Key Vault.",
+						},
+						"azure_client_secret": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Azure AD client secret to decrypt using Azure Key Vault.",
+						},
+					},
+				},
+			},
+			"vault": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Connection to HashiCorp Vault, to resolve secret generator literals referencing Vault secrets.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"address": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Address of the Vault server. Defaults to VAULT_ADDR.",
+						},
+						"token": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Vault token. Defaults to VAULT_TOKEN.",
+						},
+						"namespace": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Vault Enterprise namespace. Defaults to VAULT_NAMESPACE.",
+						},
+					},
+				},
+			},
+			"exec_functions": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Allowlist of exec KRM function binaries. When set, builds with enable_exec only run exec functions allowed by path or SHA256 hash.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"allowed_paths": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Paths of allowed exec function binaries.",
+						},
+						"allowed_sha256": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "SHA256 hashes of allowed exec function binaries.",
+						},
+					},
+				},
+			},
+			"build_cache_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Directory to cache builds in by the fingerprint of their inputs. Builds of unchanged kustomizations are read from the cache instead of running kustomize. Disabled by default.",
+			},
+			"max_build_resources": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of resources of a single build. Larger builds fail with an error instead of exhausting memory. Defaults to 0, unlimited.",
+			},
+			"max_build_manifest_bytes": {
+				Type:         schema.TypeInt,
 				Optional:     true,
-				ExactlyOneOf: []string{"kubeconfig_path", "kubeconfig_raw", "kubeconfig_incluster"},
-				Description:  "Set to true when running inside a kubernetes cluster. If kubeconfig_incluster is set, KUBECONFIG_PATH is ignored.",
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum size in bytes of the JSON manifests of a single build. Larger builds fail with an error instead of exhausting memory. Defaults to 0, unlimited.",
 			},
-			"context": {
+			"state_encryption_key": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				DefaultFunc: schema.EnvDefaultFunc("KUBECONFIG_CONTEXT", nil),
-				Description: "Context to use in kubeconfig with multiple contexts, if not specified the default context is to be used.",
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("KUSTOMIZATION_STATE_ENCRYPTION_KEY", ""),
+				Description: "Passphrase to encrypt the manifests of kustomization_resources stored in the state with. Can be set using the KUSTOMIZATION_STATE_ENCRYPTION_KEY environment variable.",
+			},
+			"secret_placeholders": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Resolve ${secret:vault:path#key} placeholders in the manifests of kustomization_resources when applying them, the secret values are neither stored in the state nor shown in plans.",
 			},
 			"gzip_last_applied_config": {
 				Type:        schema.TypeBool,
@@ -78,75 +472,955 @@ func Provider() *schema.Provider {
 		},
 	}
 
+	for k, v := range getConnectionSchema() {
+		p.Schema[k] = v
+	}
+
 	p.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
-		var config *rest.Config
-		var err error
+		gzipLastAppliedConfig := d.Get("gzip_last_applied_config").(bool)
+
+		ad, err := getApplyDefaults(d.Get("apply_defaults").([]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: apply_defaults: %s", err)
+		}
+
+		ignoreAnnotations, err := compileIgnorePatterns(convertListInterfaceToListString(d.Get("ignore_annotations").([]interface{})))
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: ignore_annotations: %s", err)
+		}
+
+		ignoreLabels, err := compileIgnorePatterns(convertListInterfaceToListString(d.Get("ignore_labels").([]interface{})))
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: ignore_labels: %s", err)
+		}
 
-		raw := d.Get("kubeconfig_raw").(string)
-		path := d.Get("kubeconfig_path").(string)
-		incluster := d.Get("kubeconfig_incluster").(bool)
-		context := d.Get("context").(string)
+		auditAnnotations := convertMapStringInterfaceToMapStringString(d.Get("audit_annotations").(map[string]interface{}))
 
-		if raw != "" {
-			config, err = getClientConfig([]byte(raw), context)
+		stateEncryption, err := getStateEncryption(d.Get("state_encryption_key").(string))
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: state_encryption_key: %s", err)
+		}
+
+		retry, err := getRetryPolicy(d.Get("retry").([]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: retry: %s", err)
+		}
+
+		readinessChecks, err := getReadinessChecks(d.Get("readiness_check").([]interface{}))
+		if err != nil {
+			return nil, fmt.Errorf("provider kustomization: readiness_check: %s", err)
+		}
+
+		var cacheTTL time.Duration
+		if ttl := d.Get("discovery_cache_ttl").(string); ttl != "" {
+			cacheTTL, err = time.ParseDuration(ttl)
 			if err != nil {
-				return nil, fmt.Errorf("provider kustomization: kubeconfig_raw: %s", err)
+				return nil, fmt.Errorf("provider kustomization: discovery_cache_ttl: %s", err)
 			}
 		}
+		cacheDisabled := d.Get("disable_discovery_cache").(bool)
 
-		if raw == "" && path != "" {
-			data, err := readKubeconfigFile(path)
-			if err != nil {
-				return nil, fmt.Errorf("provider kustomization: kubeconfig_path: %s", err)
+		var sem chan struct{}
+		if p := d.Get("parallelism").(int); p > 0 {
+			sem = make(chan struct{}, p)
+		}
+
+		staticMapper := getStaticRESTMapper(d.Get("rest_mapping").([]interface{}))
+
+		restriction := getNamespaceRestriction(d.Get("restrict_namespaces").([]interface{}))
+		if restriction != nil && staticMapper == nil {
+			return nil, fmt.Errorf("provider kustomization: restrict_namespaces: requires rest_mapping, API discovery is not allowed")
+		}
+
+		metrics := newRunMetrics()
+
+		clusters := make(map[string]*kubeClients)
+		for _, c := range d.Get("cluster").([]interface{}) {
+			c := c.(map[string]interface{})
+			name := c["name"].(string)
+			if _, ok := clusters[name]; ok {
+				return nil, fmt.Errorf("provider kustomization: cluster: duplicate name %q", name)
+			}
+
+			clusters[name] = &kubeClients{
+				restConfig: metrics.instrument(restriction.restConfig(func() (*rest.Config, error) {
+					return getClusterRestConfig(d, c)
+				})),
+				cacheTTL:      cacheTTL,
+				cacheDisabled: cacheDisabled,
+				sem:           sem,
+				staticMapper:  staticMapper,
+				staticOnly:    restriction != nil,
+			}
+		}
+
+		c := &Config{
+			// clients are initialized on first use,
+			// data sources do not require a reachable cluster
+			clients: &kubeClients{
+				restConfig: metrics.instrument(restriction.restConfig(func() (*rest.Config, error) {
+					return getRestConfig(d)
+				})),
+				cacheTTL:      cacheTTL,
+				cacheDisabled: cacheDisabled,
+				sem:           sem,
+				staticMapper:  staticMapper,
+				staticOnly:    restriction != nil,
+			},
+			clusters:                clusters,
+			BuildLock:               kustomizeBuildLock,
+			GzipLastAppliedConfig:   gzipLastAppliedConfig,
+			ApplyDefaults:           ad,
+			IgnoreAnnotations:       ignoreAnnotations,
+			IgnoreLabels:            ignoreLabels,
+			AuditAnnotations:        auditAnnotations,
+			AllowUnreachableCluster: d.Get("allow_unreachable_cluster").(bool),
+			Sops:                    getSOPSConfig(d.Get("sops").([]interface{})),
+			Vault:                   getVaultConfig(d.Get("vault").([]interface{})),
+			SecretPlaceholders:      d.Get("secret_placeholders").(bool),
+			StateEncryption:         stateEncryption,
+			ExecFunctions:           getExecAllowlist(d.Get("exec_functions").([]interface{})),
+			BuildCache:              getBuildCache(d.Get("build_cache_path").(string)),
+			BuildLimits:             getBuildLimits(d.Get("max_build_resources").(int), d.Get("max_build_manifest_bytes").(int)),
+			Retry:                   retry,
+			RefreshCache:            getRefreshCache(d.Get("refresh_label_selector").(string)),
+			Metrics:                 metrics,
+			ReadinessChecks:         readinessChecks,
+			SkipUnavailableNodes:    d.Get("wait_skip_unavailable_nodes").(bool) && restriction == nil,
+		}
+
+		if d.Get("preflight").(bool) {
+			if err := c.preflight(d); err != nil {
+				return nil, err
+			}
+		}
+
+		return c, nil
+	}
+
+	return p
+}
+
+// getConnectionSchema returns the settings to connect and
+// authenticate to a cluster, of the provider and of each cluster
+func getConnectionSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"kubeconfig_path": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Path to a kubeconfig file. Multiple paths separated like in the KUBECONFIG env var are merged. If no other kubeconfig source is set, defaults to the KUBECONFIG_PATH or KUBECONFIG env var",
+		},
+		"kubeconfig_paths": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+			Description: "List of paths to kubeconfig files, merged like kubectl merges files in the KUBECONFIG env var.",
+		},
+		"kubeconfig_raw": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Raw kube config. If kubeconfig_raw is set, KUBECONFIG_PATH is ignored.",
+		},
+		"kubeconfig_incluster": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Description: "Set to true when running inside a kubernetes cluster. If kubeconfig_incluster is set, KUBECONFIG_PATH is ignored.",
+		},
+		"cluster_connection": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			ConfigMode:  schema.SchemaConfigModeAttr,
+			Elem:        getClusterConnectionSchema(),
+			Description: "Connection to the cluster as a single object with host, cluster_ca_certificate and token, client certificate or exec credentials, e.g. from the outputs of a cluster module. Certificates can be PEM or base64 encoded PEM.",
+		},
+		"auth_preference": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice(append(append([]string{}, kubeconfigSources...), authPreferenceEnv), false),
+			},
+			Description: "Order to try the kubeconfig sources in, allowing more than one of them to be set, e.g. kubeconfig_incluster in CI and kubeconfig_path locally. The first set source that loads is used. env names the KUBECONFIG_PATH and KUBECONFIG env vars.",
+		},
+		"context": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("KUBECONFIG_CONTEXT", nil),
+			Description: "Context to use in kubeconfig with multiple contexts, if not specified the default context is to be used.",
+		},
+		"username": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			DefaultFunc:  schema.EnvDefaultFunc("KUBE_USER", nil),
+			RequiredWith: []string{"username", "password"},
+			Description:  "Username for basic authentication to the Kubernetes API. Overrides credentials from the kubeconfig. Can be set using KUBE_USER env var",
+		},
+		"password": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			DefaultFunc:  schema.EnvDefaultFunc("KUBE_PASSWORD", nil),
+			RequiredWith: []string{"username", "password"},
+			Description:  "Password for basic authentication to the Kubernetes API. Overrides credentials from the kubeconfig. Can be set using KUBE_PASSWORD env var",
+		},
+		"token_file": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			DefaultFunc:   schema.EnvDefaultFunc("KUBE_TOKEN_FILE", nil),
+			ConflictsWith: []string{"username", "password"},
+			Description:   "Path to a file containing a bearer token for the Kubernetes API. The file is re-read periodically to support short lived, rotated tokens. Overrides credentials from the kubeconfig. Can be set using KUBE_TOKEN_FILE env var",
+		},
+		"oidc": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			MaxItems:      1,
+			ConflictsWith: []string{"username", "password", "token_file"},
+			Description:   "OIDC authentication to the Kubernetes API. Overrides credentials from the kubeconfig.",
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"issuer_url": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"client_id": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"client_secret": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"refresh_token": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"id_token": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"issuer_ca_file": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"eks": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			MaxItems:      1,
+			ConflictsWith: []string{"username", "password", "token_file", "oidc", "gke", "aks"},
+			Description:   "Authenticate to EKS clusters using the AWS SDK, without requiring the aws CLI. Overrides credentials from the kubeconfig.",
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"cluster_name": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"region": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"profile": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"role_arn": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"gke": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			MaxItems:      1,
+			ConflictsWith: []string{"username", "password", "token_file", "oidc", "eks", "aks"},
+			Description:   "Authenticate to GKE clusters using Google application default credentials, without requiring the gke-gcloud-auth-plugin. Overrides credentials from the kubeconfig.",
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"credentials": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"scopes": {
+						Type:     schema.TypeList,
+						Optional: true,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+				},
+			},
+		},
+		"aks": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			MaxItems:      1,
+			ConflictsWith: []string{"username", "password", "token_file", "oidc", "eks", "gke"},
+			Description:   "Authenticate to AKS clusters using Azure AD, without requiring kubelogin. Overrides credentials from the kubeconfig.",
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"tenant_id": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"client_id": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"client_secret": {
+						Type:      schema.TypeString,
+						Optional:  true,
+						Sensitive: true,
+					},
+					"federated_token_file": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"use_managed_identity": {
+						Type:     schema.TypeBool,
+						Optional: true,
+					},
+					"server_id": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"as": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Username to impersonate for requests to the Kubernetes API.",
+		},
+		"as_groups": {
+			Type:         schema.TypeList,
+			Optional:     true,
+			Elem:         &schema.Schema{Type: schema.TypeString},
+			RequiredWith: []string{"as"},
+			Description:  "Groups to impersonate for requests to the Kubernetes API.",
+		},
+		"as_uid": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			RequiredWith: []string{"as"},
+			Description:  "UID to impersonate for requests to the Kubernetes API.",
+		},
+	}
+}
+
+// getClusterSchema returns the schema of the named clusters, with
+// the connection settings of the provider but without their env
+// defaults, and without conflicts, that can only be declared
+// between top level attributes and are checked when connecting
+func getClusterSchema() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"name": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+	}
+
+	for k, v := range getConnectionSchema() {
+		c := *v
+		c.DefaultFunc = nil
+		c.ConflictsWith = nil
+		c.RequiredWith = nil
+		c.ExactlyOneOf = nil
+		s[k] = &c
+	}
+
+	return &schema.Resource{Schema: s}
+}
+
+// getRestConfig returns the rest config for the provider configuration d
+func getRestConfig(d *schema.ResourceData) (*rest.Config, error) {
+	config, err := getConnectionRestConfig(d.Get, "")
+	if err != nil {
+		return nil, fmt.Errorf("provider kustomization: %s", err)
+	}
+
+	err = setClientOptions(config, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// getClusterRestConfig returns the rest config for the named cluster c
+func getClusterRestConfig(d *schema.ResourceData, c map[string]interface{}) (*rest.Config, error) {
+	get := func(key string) interface{} { return c[key] }
+
+	config, err := getConnectionRestConfig(get, c["name"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("provider kustomization: cluster %q: %s", c["name"].(string), err)
+	}
+
+	err = setClientOptions(config, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// kubeconfigSources are the mutually exclusive
+// settings that the connection is loaded from
+var kubeconfigSources = []string{"kubeconfig_path", "kubeconfig_paths", "kubeconfig_raw", "kubeconfig_incluster", "cluster_connection"}
+
+// credentialSources are the mutually exclusive settings
+// that override the credentials of the kubeconfig
+var credentialSources = []string{"username", "token_file", "oidc", "eks", "gke", "aks"}
+
+// authPreferenceEnv names the KUBECONFIG_PATH and KUBECONFIG
+// env vars in auth_preference
+const authPreferenceEnv = "env"
+
+// getConnectionRestConfig returns the rest config for the connection
+// settings returned by get, of the provider or of the named cluster
+// name, only the provider falls back to the KUBECONFIG env vars
+func getConnectionRestConfig(get func(string) interface{}, name string) (*rest.Config, error) {
+	envFallback := name == ""
+
+	var sources []string
+	for _, k := range kubeconfigSources {
+		if isSet(get(k)) {
+			sources = append(sources, k)
+		}
+	}
+
+	preference := convertListInterfaceToListString(get("auth_preference").([]interface{}))
+	if len(sources) > 1 && len(preference) == 0 {
+		return nil, fmt.Errorf("only one of %s can be set, got %s, or set auth_preference", strings.Join(kubeconfigSources, ", "), strings.Join(sources, " and "))
+	}
+
+	var creds []string
+	for _, k := range credentialSources {
+		if isSet(get(k)) {
+			creds = append(creds, k)
+		}
+	}
+	if len(creds) > 1 {
+		return nil, fmt.Errorf("only one of %s can be set, got %s", strings.Join(credentialSources, ", "), strings.Join(creds, " and "))
+	}
+
+	if get("as").(string) == "" && (isSet(get("as_groups")) || isSet(get("as_uid"))) {
+		return nil, fmt.Errorf("as_groups and as_uid require as")
+	}
+
+	config, source, reason, err := loadKubeconfigSources(get, sources, preference, envFallback)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := "provider kustomization"
+	if name != "" {
+		prefix = fmt.Sprintf("%s: cluster %q", prefix, name)
+	}
+	log.Printf("[INFO] %s: connecting using %s, %s", prefix, source, reason)
+
+	err = setCredentials(config, get)
+	if err != nil {
+		return nil, err
+	}
+
+	if as := get("as").(string); as != "" {
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: as,
+			UID:      get("as_uid").(string),
+			Groups:   convertListInterfaceToListString(get("as_groups").([]interface{})),
+		}
+	}
+
+	return config, nil
+}
+
+// loadKubeconfigSources returns the rest config of the kubeconfig
+// source to connect with, the source and the reason it was selected
+//
+// Without auth_preference, at most one source is set. With it, the
+// sources are tried in its order, the first set source that loads
+// is used. Without any source set, the provider falls back to the
+// KUBECONFIG env vars, or an empty config, like kubectl does.
+func loadKubeconfigSources(get func(string) interface{}, sources []string, preference []string, envFallback bool) (*rest.Config, string, string, error) {
+	context := get("context").(string)
+	envPath := ""
+	if envFallback {
+		envPath = getKubeconfigPathFromEnv()
+	}
+
+	if len(preference) > 0 {
+		preferred := make(map[string]bool)
+		for _, p := range preference {
+			preferred[p] = true
+		}
+		for _, s := range sources {
+			if !preferred[s] {
+				return nil, "", "", fmt.Errorf("%s is set, but not listed in auth_preference", s)
+			}
+		}
+
+		var skipped []string
+		for _, p := range preference {
+			if p == authPreferenceEnv && (!envFallback || envPath == "") {
+				skipped = append(skipped, fmt.Sprintf("%s: KUBECONFIG_PATH and KUBECONFIG are not set", p))
+				continue
+			}
+			if p != authPreferenceEnv && !isSet(get(p)) {
+				skipped = append(skipped, fmt.Sprintf("%s: not set", p))
+				continue
 			}
 
-			config, err = getClientConfig(data, context)
+			config, err := loadKubeconfigSource(p, get, envPath, context)
 			if err != nil {
-				return nil, fmt.Errorf("provider kustomization: kubeconfig_path: %s", err)
+				skipped = append(skipped, err.Error())
+				continue
 			}
+
+			reason := "the first of auth_preference that is set and loads"
+			if len(skipped) > 0 {
+				reason = fmt.Sprintf("%s, skipped %s", reason, strings.Join(skipped, "; "))
+			}
+			return config, p, reason, nil
+		}
+
+		if len(sources) > 0 || envPath != "" {
+			return nil, "", "", fmt.Errorf("no source of auth_preference could be loaded: %s", strings.Join(skipped, "; "))
+		}
+	}
+
+	if len(sources) == 1 {
+		config, err := loadKubeconfigSource(sources[0], get, envPath, context)
+		return config, sources[0], "the only kubeconfig source set", err
+	}
+
+	// like kubectl, fall back to the env vars only if
+	// the configuration does not set any other source
+	if !envFallback {
+		return nil, "", "", fmt.Errorf("one of %s is required", strings.Join(kubeconfigSources, ", "))
+	}
+
+	if envPath != "" {
+		config, err := loadKubeconfigSource(authPreferenceEnv, get, envPath, context)
+		return config, authPreferenceEnv, "no kubeconfig source is set, using the KUBECONFIG_PATH or KUBECONFIG env var", err
+	}
+
+	// empty default config required to support
+	// using a cluster resource or data source
+	// that may not exist yet, to configure the provider
+	return &rest.Config{}, "no kubeconfig", "no kubeconfig source is set and the KUBECONFIG_PATH and KUBECONFIG env vars are empty", nil
+}
+
+// loadKubeconfigSource returns the rest config of the kubeconfig
+// source, envPath is the path of the env source
+func loadKubeconfigSource(source string, get func(string) interface{}, envPath string, context string) (*rest.Config, error) {
+	switch source {
+	case "kubeconfig_raw":
+		config, err := getClientConfig([]byte(get(source).(string)), context)
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig_raw: %s", err)
+		}
+		return config, nil
+	case "kubeconfig_path", authPreferenceEnv:
+		path := envPath
+		if source == "kubeconfig_path" {
+			path = get(source).(string)
 		}
 
-		if incluster {
-			config, err = rest.InClusterConfig()
+		if paths := filepath.SplitList(path); len(paths) > 1 {
+			config, err := getMergedClientConfig(paths, context)
 			if err != nil {
-				return nil, fmt.Errorf("provider kustomization: couldn't load in cluster config: %s", err)
+				return nil, fmt.Errorf("%s: %s", source, err)
 			}
+			return config, nil
 		}
 
-		// empty default config required to support
-		// using a cluster resource or data source
-		// that may not exist yet, to configure the provider
-		if config == nil {
-			config = &rest.Config{}
+		data, err := readKubeconfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
 		}
 
-		// Increase QPS and Burst rate limits
-		config.QPS = 120
-		config.Burst = 240
+		config, err := getClientConfig(data, context)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+		return config, nil
+	case "kubeconfig_paths":
+		paths := convertListInterfaceToListString(get(source).([]interface{}))
+		config, err := getMergedClientConfig(paths, context)
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig_paths: %s", err)
+		}
+		return config, nil
+	case "cluster_connection":
+		conn := get(source).([]interface{})
+		if conn[0] == nil {
+			return &rest.Config{}, nil
+		}
 
-		client, err := dynamic.NewForConfig(config)
+		config, err := getClusterConnectionConfig(conn[0].(map[string]interface{}))
 		if err != nil {
-			return nil, fmt.Errorf("provider kustomization: %s", err)
+			return nil, fmt.Errorf("cluster_connection: %s", err)
 		}
+		return config, nil
+	case "kubeconfig_incluster":
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig_incluster: couldn't load in cluster config: %s", err)
+		}
+		return config, nil
+	}
+
+	return nil, fmt.Errorf("unknown kubeconfig source %q", source)
+}
+
+// setCredentials sets the credentials returned by get on config,
+// overriding the credentials of the kubeconfig
+func setCredentials(config *rest.Config, get func(string) interface{}) error {
+	username := get("username").(string)
+	password := get("password").(string)
+	if username != "" {
+		// basic auth can not be combined with other credentials
+		config.Username = username
+		config.Password = password
+		config.BearerToken = ""
+		config.BearerTokenFile = ""
+		config.AuthProvider = nil
+		config.ExecProvider = nil
+	}
 
-		dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if tf := get("token_file").(string); tf != "" {
+		tokenFile, err := homedir.Expand(tf)
 		if err != nil {
-			return nil, fmt.Errorf("provider kustomization: %s", err)
+			return fmt.Errorf("token_file: %s", err)
 		}
 
-		mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc))
+		// client-go caches the token and re-reads the file
+		// regularly, to pick up rotated tokens before they expire
+		config.BearerToken = ""
+		config.BearerTokenFile = tokenFile
+		config.Username = ""
+		config.Password = ""
+		config.AuthProvider = nil
+		config.ExecProvider = nil
+	}
 
-		// Mutex to prevent parallel Kustomizer runs
-		// temp workaround for upstream bug
-		// https://github.com/kubernetes-sigs/kustomize/issues/3659
-		mu := &sync.Mutex{}
+	if oidc := get("oidc").([]interface{}); len(oidc) > 0 && oidc[0] != nil {
+		config.AuthProvider = getOIDCAuthProvider(oidc[0].(map[string]interface{}))
+		config.BearerToken = ""
+		config.BearerTokenFile = ""
+		config.Username = ""
+		config.Password = ""
+		config.ExecProvider = nil
+	}
 
-		gzipLastAppliedConfig := d.Get("gzip_last_applied_config").(bool)
+	if eks := get("eks").([]interface{}); len(eks) > 0 && eks[0] != nil {
+		ts, err := getEKSTokenSource(eks[0].(map[string]interface{}))
+		if err != nil {
+			return fmt.Errorf("eks: %s", err)
+		}
 
-		return &Config{client, mapper, mu, gzipLastAppliedConfig}, nil
+		setTokenSource(config, ts)
 	}
 
-	return p
+	if gke := get("gke").([]interface{}); len(gke) > 0 {
+		// an empty gke block is a nil element
+		g := map[string]interface{}{"credentials": "", "scopes": []interface{}{}}
+		if gke[0] != nil {
+			g = gke[0].(map[string]interface{})
+		}
+
+		ts, err := getGKETokenSource(g)
+		if err != nil {
+			return fmt.Errorf("gke: %s", err)
+		}
+
+		setTokenSource(config, ts)
+	}
+
+	if aks := get("aks").([]interface{}); len(aks) > 0 {
+		a := map[string]interface{}{}
+		if aks[0] != nil {
+			a = aks[0].(map[string]interface{})
+		}
+
+		ts, err := getAKSTokenSource(a)
+		if err != nil {
+			return fmt.Errorf("aks: %s", err)
+		}
+
+		setTokenSource(config, ts)
+	}
+
+	return nil
+}
+
+// isSet returns true if v is not the zero value of its schema type
+func isSet(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v != ""
+	case bool:
+		return v
+	case []interface{}:
+		return len(v) > 0
+	}
+
+	return v != nil
+}
+
+// getKubeconfigPathFromEnv returns the kubeconfig path
+// set in the KUBECONFIG_PATH or KUBECONFIG env var
+func getKubeconfigPathFromEnv() string {
+	for _, k := range []string{"KUBECONFIG_PATH", "KUBECONFIG"} {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// setClientOptions sets the options shared by all cluster connections
+func setClientOptions(config *rest.Config, d *schema.ResourceData) (err error) {
+	proxyURL := d.Get("proxy_url").(string)
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("provider kustomization: proxy_url: %s", err)
+		}
+		config.Proxy = http.ProxyURL(u)
+	}
+
+	if ua := d.Get("user_agent").(string); ua != "" {
+		config.UserAgent = ua
+	}
+
+	if d.Get("debug_api_calls").(bool) {
+		config.Wrap(newLoggingTransport)
+	}
+
+	config.QPS = float32(d.Get("client_qps").(float64))
+	config.Burst = d.Get("client_burst").(int)
+	if config.QPS > 0 && config.Burst > 0 {
+		config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(config.QPS, config.Burst)
+	}
+
+	if rt := d.Get("request_timeout").(string); rt != "" {
+		config.Timeout, err = time.ParseDuration(rt)
+		if err != nil {
+			return fmt.Errorf("provider kustomization: request_timeout: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// getClients returns the clients of the default cluster
+func (c *Config) getClients() (dynamic.Interface, k8smeta.ResettableRESTMapper, error) {
+	return c.clients.get()
+}
+
+// getCluster returns the clients of the named cluster,
+// or of the default cluster if name is empty
+func (c *Config) getCluster(name string) (*kubeClients, error) {
+	if name == "" {
+		return c.clients, nil
+	}
+
+	kc, ok := c.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("provider kustomization: cluster %q is not configured", name)
+	}
+
+	return kc, nil
+}
+
+// get initializes the clients on first use
+func (kc *kubeClients) get() (dynamic.Interface, k8smeta.ResettableRESTMapper, error) {
+	kc.once.Do(func() {
+		kc.client, kc.mapper, kc.err = newClients(kc.restConfig, kc.sem, kc.staticMapper, kc.staticOnly)
+		kc.cacheReset = time.Now()
+	})
+
+	if kc.err == nil {
+		kc.expireDiscoveryCache()
+	}
+
+	return kc.client, kc.mapper, kc.err
+}
+
+// expireDiscoveryCache resets the cached discovery information,
+// if caching is disabled or the cache TTL expired
+func (kc *kubeClients) expireDiscoveryCache() {
+	if !kc.cacheDisabled && kc.cacheTTL == 0 {
+		return
+	}
+
+	kc.cacheMu.Lock()
+	defer kc.cacheMu.Unlock()
+
+	if kc.cacheDisabled || time.Since(kc.cacheReset) > kc.cacheTTL {
+		kc.mapper.Reset()
+		kc.cacheReset = time.Now()
+	}
+}
+
+// isReachable returns true if the Kubernetes API responds,
+// the result is cached for the lifetime of the provider
+func (kc *kubeClients) isReachable() bool {
+	kc.reachableOnce.Do(func() {
+		err := checkClusterReachable(kc.restConfig)
+		if err != nil {
+			log.Printf("[WARN] provider kustomization: cluster unreachable: %s", err)
+		}
+
+		kc.reachable = err == nil
+	})
+
+	return kc.reachable
+}
+
+// discovery returns an uncached discovery client for the cluster
+func (kc *kubeClients) discovery() (discovery.DiscoveryInterface, error) {
+	config, err := kc.restConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if kc.sem != nil {
+		config.Wrap(newLimitTransport(kc.sem))
+	}
+
+	return discovery.NewDiscoveryClientForConfig(config)
+}
+
+func checkClusterReachable(restConfig func() (*rest.Config, error)) error {
+	config, err := restConfig()
+	if err != nil {
+		return err
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = dc.ServerVersion()
+
+	return err
+}
+
+func newClients(restConfig func() (*rest.Config, error), sem chan struct{}, staticMapper *k8smeta.DefaultRESTMapper, staticOnly bool) (client dynamic.Interface, mapper k8smeta.ResettableRESTMapper, err error) {
+	config, err := restConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sem != nil {
+		config.Wrap(newLimitTransport(sem))
+	}
+
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("provider kustomization: %s", err)
+	}
+
+	// rebuild the transport from the current connection settings
+	// if the credentials expire while the provider is running
+	httpClient.Transport = newReauthTransport(httpClient.Transport, func() (http.RoundTripper, error) {
+		config, err := restConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		if sem != nil {
+			config.Wrap(newLimitTransport(sem))
+		}
+
+		return rest.TransportFor(config)
+	})
+
+	client, err = dynamic.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("provider kustomization: %s", err)
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("provider kustomization: %s", err)
+	}
+
+	if staticOnly {
+		return client, staticOnlyRESTMapper{staticMapper}, nil
+	}
+
+	mapper = newStaticRESTMapper(staticMapper, newDiscoveryRESTMapper(dc))
+
+	return client, mapper, nil
+}
+
+// setTokenSource authenticates requests using tokens from ts,
+// tokens are cached and refreshed when they expire
+func setTokenSource(config *rest.Config, ts oauth2.TokenSource) {
+	config.BearerToken = ""
+	config.BearerTokenFile = ""
+	config.Username = ""
+	config.Password = ""
+	config.AuthProvider = nil
+	config.ExecProvider = nil
+
+	rts := oauth2.ReuseTokenSource(nil, ts)
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &oauth2.Transport{Source: rts, Base: rt}
+	})
+}
+
+func getOIDCAuthProvider(o map[string]interface{}) *clientcmdapi.AuthProviderConfig {
+	// keys as expected by client-go's oidc auth provider plugin
+	keys := map[string]string{
+		"issuer_url":     "idp-issuer-url",
+		"client_id":      "client-id",
+		"client_secret":  "client-secret",
+		"refresh_token":  "refresh-token",
+		"id_token":       "id-token",
+		"issuer_ca_file": "idp-certificate-authority",
+	}
+
+	c := make(map[string]string)
+	for k, pk := range keys {
+		if v := o[k].(string); v != "" {
+			c[pk] = v
+		}
+	}
+
+	return &clientcmdapi.AuthProviderConfig{Name: "oidc", Config: c}
+}
+
+func validateDuration(v interface{}, k string) (ws []string, es []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		es = append(es, fmt.Errorf("%q: %s", k, err))
+	}
+	return ws, es
+}
+
+func validateAnnotationKeys(v interface{}, k string) (ws []string, es []error) {
+	for key := range v.(map[string]interface{}) {
+		for _, msg := range k8svalidation.IsQualifiedName(key) {
+			es = append(es, fmt.Errorf("%q: invalid key %q: %s", k, key, msg))
+		}
+	}
+	return ws, es
 }
 
 func readKubeconfigFile(s string) ([]byte, error) {
@@ -163,6 +1437,22 @@ func readKubeconfigFile(s string) ([]byte, error) {
 	return data, nil
 }
 
+func getMergedClientConfig(paths []string, context string) (*rest.Config, error) {
+	var precedence []string
+	for _, p := range paths {
+		ep, err := homedir.Expand(p)
+		if err != nil {
+			return nil, err
+		}
+		precedence = append(precedence, ep)
+	}
+
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: precedence}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
 func getClientConfig(data []byte, context string) (*rest.Config, error) {
 	if len(context) == 0 {
 		return clientcmd.RESTConfigFromKubeConfig(data)
@@ -0,0 +1,116 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// application ID of the AKS AAD server, the same for all clusters
+	aksDefaultServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+)
+
+// aksTokenSource returns Azure AD tokens for AKS clusters like
+// kubelogin, using a client secret, workload identity or managed identity
+type aksTokenSource struct {
+	tenantID           string
+	clientID           string
+	clientSecret       string
+	federatedTokenFile string
+	useManagedIdentity bool
+	serverID           string
+
+	credential azcore.TokenCredential
+}
+
+func getAKSTokenSource(a map[string]interface{}) (oauth2.TokenSource, error) {
+	// like the Azure SDKs, fall back to the environment
+	// e.g. set by the AKS workload identity webhook
+	ts := &aksTokenSource{
+		tenantID:           getStringOrEnv(a, "tenant_id", "AZURE_TENANT_ID"),
+		clientID:           getStringOrEnv(a, "client_id", "AZURE_CLIENT_ID"),
+		clientSecret:       getStringOrEnv(a, "client_secret", "AZURE_CLIENT_SECRET"),
+		federatedTokenFile: getStringOrEnv(a, "federated_token_file", "AZURE_FEDERATED_TOKEN_FILE"),
+		serverID:           getStringOrEnv(a, "server_id", ""),
+	}
+	ts.useManagedIdentity, _ = a["use_managed_identity"].(bool)
+
+	if ts.serverID == "" {
+		ts.serverID = aksDefaultServerID
+	}
+
+	if !ts.useManagedIdentity {
+		if ts.clientSecret == "" && ts.federatedTokenFile == "" {
+			return nil, fmt.Errorf("one of client_secret, federated_token_file or use_managed_identity is required")
+		}
+
+		if ts.tenantID == "" || ts.clientID == "" {
+			return nil, fmt.Errorf("tenant_id and client_id are required")
+		}
+	}
+
+	var err error
+	ts.credential, err = ts.newCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// newCredential returns the azidentity credential for the
+// configured authentication method, credentials cache their
+// tokens and refresh them before they expire
+func (ts *aksTokenSource) newCredential() (azcore.TokenCredential, error) {
+	switch {
+	case ts.useManagedIdentity:
+		opts := &azidentity.ManagedIdentityCredentialOptions{}
+		if ts.clientID != "" {
+			// user assigned identity
+			opts.ID = azidentity.ClientID(ts.clientID)
+		}
+
+		return azidentity.NewManagedIdentityCredential(opts)
+	case ts.clientSecret != "":
+		return azidentity.NewClientSecretCredential(ts.tenantID, ts.clientID, ts.clientSecret, nil)
+	default:
+		// the federated token is rotated, it is re-read for every new token
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			TenantID:      ts.tenantID,
+			ClientID:      ts.clientID,
+			TokenFilePath: ts.federatedTokenFile,
+		})
+	}
+}
+
+func getStringOrEnv(m map[string]interface{}, key string, env string) string {
+	if v, _ := m[key].(string); v != "" {
+		return v
+	}
+
+	if env == "" {
+		return ""
+	}
+
+	return os.Getenv(env)
+}
+
+func (ts *aksTokenSource) Token() (*oauth2.Token, error) {
+	token, err := ts.credential.GetToken(context.Background(), policy.TokenRequestOptions{
+		Scopes: []string{ts.serverID + "/.default"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aks: %s", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.Token,
+		Expiry:      token.ExpiresOn,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAKSTokenSource(t *testing.T) {
+	t.Setenv("AZURE_TENANT_ID", "")
+	t.Setenv("AZURE_CLIENT_ID", "")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+
+	_, err := getAKSTokenSource(map[string]interface{}{})
+	assert.NotEqual(t, nil, err, nil)
+
+	_, err = getAKSTokenSource(map[string]interface{}{"client_secret": "secret"})
+	assert.NotEqual(t, nil, err, nil)
+
+	t.Setenv("AZURE_TENANT_ID", "test-tenant")
+	t.Setenv("AZURE_CLIENT_ID", "test-client")
+
+	ts, err := getAKSTokenSource(map[string]interface{}{"client_secret": "secret"})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "test-tenant", ts.(*aksTokenSource).tenantID, nil)
+	assert.Equal(t, aksDefaultServerID, ts.(*aksTokenSource).serverID, nil)
+
+	_, err = getAKSTokenSource(map[string]interface{}{"use_managed_identity": true})
+	assert.Equal(t, nil, err, nil)
+}
+
+type testAzureCredential struct {
+	scopes []string
+}
+
+func (c *testAzureCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.scopes = opts.Scopes
+	return azcore.AccessToken{Token: "aks-token", ExpiresOn: time.Unix(1700000000, 0)}, nil
+}
+
+func TestAKSTokenSourceToken(t *testing.T) {
+	cred := &testAzureCredential{}
+	ts := &aksTokenSource{serverID: aksDefaultServerID, credential: cred}
+
+	token, err := ts.Token()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "aks-token", token.AccessToken, nil)
+	assert.Equal(t, time.Unix(1700000000, 0), token.Expiry, nil)
+	assert.Equal(t, []string{aksDefaultServerID + "/.default"}, cred.scopes, nil)
+}
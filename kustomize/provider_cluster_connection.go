@@ -0,0 +1,139 @@
+package kustomize
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const defaultExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+func getClusterConnectionSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"cluster_ca_certificate": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"client_certificate": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"client_key": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"token": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"insecure": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"exec": {
+				Type:       schema.TypeList,
+				Optional:   true,
+				MaxItems:   1,
+				ConfigMode: schema.SchemaConfigModeAttr,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"api_version": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"command": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"args": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"env": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// decodePEM returns PEM data, cluster resources of cloud providers
+// output certificates base64 encoded, others output them as is
+func decodePEM(in string) ([]byte, error) {
+	if in == "" || bytes.HasPrefix(bytes.TrimSpace([]byte(in)), []byte("-----BEGIN")) {
+		return []byte(in), nil
+	}
+
+	out, err := base64.StdEncoding.DecodeString(in)
+	if err != nil {
+		return nil, fmt.Errorf("neither PEM nor base64 encoded PEM: %s", err)
+	}
+
+	return out, nil
+}
+
+// getClusterConnectionConfig returns the rest config for a cluster_connection
+func getClusterConnectionConfig(c map[string]interface{}) (*rest.Config, error) {
+	config := &rest.Config{
+		Host:        c["host"].(string),
+		BearerToken: c["token"].(string),
+	}
+	config.Insecure = c["insecure"].(bool)
+
+	var err error
+	for k, v := range map[string]*[]byte{
+		"cluster_ca_certificate": &config.CAData,
+		"client_certificate":     &config.CertData,
+		"client_key":             &config.KeyData,
+	} {
+		*v, err = decodePEM(c[k].(string))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", k, err)
+		}
+	}
+
+	if exec := c["exec"].([]interface{}); len(exec) > 0 && exec[0] != nil {
+		e := exec[0].(map[string]interface{})
+
+		apiVersion := e["api_version"].(string)
+		if apiVersion == "" {
+			apiVersion = defaultExecAPIVersion
+		}
+
+		config.ExecProvider = &clientcmdapi.ExecConfig{
+			APIVersion:      apiVersion,
+			Command:         e["command"].(string),
+			Args:            convertListInterfaceToListString(e["args"].([]interface{})),
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		}
+		env := e["env"].(map[string]interface{})
+		var keys []string
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			config.ExecProvider.Env = append(config.ExecProvider.Env, clientcmdapi.ExecEnvVar{Name: k, Value: env[k].(string)})
+		}
+	}
+
+	return config, nil
+}
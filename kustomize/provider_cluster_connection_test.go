@@ -0,0 +1,114 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const testClusterCA = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+func testClusterConnection(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"host":                   "https://example.com",
+		"cluster_ca_certificate": "",
+		"client_certificate":     "",
+		"client_key":             "",
+		"token":                  "",
+		"insecure":               false,
+		"exec":                   []interface{}{},
+	}
+	for k, v := range overrides {
+		c[k] = v
+	}
+
+	return c
+}
+
+func TestGetClusterConnectionConfig(t *testing.T) {
+	// EKS and GKE output the CA base64 encoded
+	config, err := getClusterConnectionConfig(testClusterConnection(map[string]interface{}{
+		"cluster_ca_certificate": base64.StdEncoding.EncodeToString([]byte(testClusterCA)),
+		"token":                  "token",
+	}))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://example.com", config.Host, nil)
+	assert.Equal(t, testClusterCA, string(config.CAData), nil)
+	assert.Equal(t, "token", config.BearerToken, nil)
+	assert.Equal(t, (*clientcmdapi.ExecConfig)(nil), config.ExecProvider, nil)
+
+	config, err = getClusterConnectionConfig(testClusterConnection(map[string]interface{}{
+		"cluster_ca_certificate": testClusterCA,
+		"exec": []interface{}{map[string]interface{}{
+			"api_version": "",
+			"command":     "aws",
+			"args":        []interface{}{"eks", "get-token", "--cluster-name", "test"},
+			"env":         map[string]interface{}{"B": "2", "A": "1"},
+		}},
+	}))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testClusterCA, string(config.CAData), nil)
+	assert.Equal(t, defaultExecAPIVersion, config.ExecProvider.APIVersion, nil)
+	assert.Equal(t, "aws", config.ExecProvider.Command, nil)
+	assert.Equal(t, []string{"eks", "get-token", "--cluster-name", "test"}, config.ExecProvider.Args, nil)
+	assert.Equal(t, []clientcmdapi.ExecEnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}, config.ExecProvider.Env, nil)
+
+	_, err = getClusterConnectionConfig(testClusterConnection(map[string]interface{}{
+		"client_key": "not a key",
+	}))
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestConfigureClusterConnection(t *testing.T) {
+	t.Setenv("KUBECONFIG_PATH", "")
+	t.Setenv("KUBECONFIG", "")
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"cluster_connection": []interface{}{
+			map[string]interface{}{
+				"host":                   "https://example.com",
+				"cluster_ca_certificate": base64.StdEncoding.EncodeToString([]byte(testClusterCA)),
+				"token":                  "token",
+			},
+		},
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	kc, err := p.Meta().(*Config).getCluster("")
+	assert.Equal(t, nil, err, nil)
+	config, err := kc.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://example.com", config.Host, nil)
+	assert.Equal(t, "token", config.BearerToken, nil)
+	assert.Equal(t, testClusterCA, string(config.CAData), nil)
+}
+
+func TestConfigureClusterConnectionKubeconfigEnv(t *testing.T) {
+	// the env vars are ignored, instead of
+	// conflicting with the cluster_connection
+	t.Setenv("KUBECONFIG_PATH", "")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"cluster_connection": []interface{}{
+			map[string]interface{}{
+				"host":  "https://example.com",
+				"token": "token",
+			},
+		},
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	kc, err := p.Meta().(*Config).getCluster("")
+	assert.Equal(t, nil, err, nil)
+	config, err := kc.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://example.com", config.Host, nil)
+}
@@ -0,0 +1,74 @@
+package kustomize
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"golang.org/x/oauth2"
+)
+
+const (
+	eksClusterIDHeader = "x-k8s-aws-id"
+	eksTokenPrefix     = "k8s-aws-v1."
+
+	// EKS tokens are valid for 15 minutes,
+	// refresh them before they expire
+	eksTokenExpiry = 14 * time.Minute
+)
+
+// eksTokenSource generates EKS tokens like `aws eks get-token`,
+// using a presigned STS GetCallerIdentity request
+type eksTokenSource struct {
+	clusterName string
+	client      *sts.PresignClient
+}
+
+func getEKSTokenSource(e map[string]interface{}) (oauth2.TokenSource, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+
+	if region := e["region"].(string); region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+
+	if profile := e["profile"].(string); profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(profile))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleARN := e["role_arn"].(string); roleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN))
+	}
+
+	return &eksTokenSource{
+		clusterName: e["cluster_name"].(string),
+		client:      sts.NewPresignClient(sts.NewFromConfig(cfg)),
+	}, nil
+}
+
+func (ts *eksTokenSource) Token() (*oauth2.Token, error) {
+	req, err := ts.client.PresignGetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{}, func(po *sts.PresignOptions) {
+		po.ClientOptions = append(po.ClientOptions, sts.WithAPIOptions(
+			smithyhttp.AddHeaderValue(eksClusterIDHeader, ts.clusterName),
+			smithyhttp.AddHeaderValue("X-Amz-Expires", "60"),
+		))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eks: presigning token request failed: %s", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(req.URL)),
+		Expiry:      time.Now().Add(eksTokenExpiry),
+	}, nil
+}
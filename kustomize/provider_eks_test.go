@@ -0,0 +1,33 @@
+package kustomize
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEKSTokenSource(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	ts, err := getEKSTokenSource(map[string]interface{}{
+		"cluster_name": "test",
+		"region":       "eu-west-1",
+		"profile":      "",
+		"role_arn":     "",
+	})
+	assert.Equal(t, nil, err, nil)
+
+	token, err := ts.Token()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, true, strings.HasPrefix(token.AccessToken, eksTokenPrefix), nil)
+
+	u, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token.AccessToken, eksTokenPrefix))
+	assert.Equal(t, nil, err, nil)
+	assert.Contains(t, string(u), "https://sts.eu-west-1.amazonaws.com/?Action=GetCallerIdentity", nil)
+	assert.Contains(t, string(u), eksClusterIDHeader, nil)
+}
@@ -0,0 +1,156 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-mux/tf5to6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// ProviderServer returns the protocol version 6 server of the provider,
+// muxing the plugin SDK provider p, that implements the provider
+// configuration and the existing resources and data sources, with the
+// plugin framework provider, that new features are implemented in
+func ProviderServer(ctx context.Context, p *schema.Provider) (func() tfprotov6.ProviderServer, error) {
+	sdkServer, err := tf5to6server.UpgradeServer(ctx, p.GRPCProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	frameworkServer := &frameworkProviderServer{
+		ProviderServer: providerserver.NewProtocol6(NewFrameworkProvider(p))(),
+		sdk:            sdkServer,
+	}
+
+	mux, err := tf6muxserver.NewMuxServer(ctx,
+		func() tfprotov6.ProviderServer { return sdkServer },
+		func() tfprotov6.ProviderServer { return frameworkServer },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mux.ProviderServer, nil
+}
+
+// frameworkProviderServer serves the framework provider with the
+// provider schema of the plugin SDK provider
+//
+// The mux server requires the same provider schema from all servers.
+// Instead of declaring every connection setting twice, the provider
+// configuration is validated and applied by the plugin SDK provider
+// only, the framework provider uses the resulting Config.
+type frameworkProviderServer struct {
+	tfprotov6.ProviderServer
+
+	sdk tfprotov6.ProviderServer
+}
+
+func (s *frameworkProviderServer) GetProviderSchema(ctx context.Context, req *tfprotov6.GetProviderSchemaRequest) (*tfprotov6.GetProviderSchemaResponse, error) {
+	resp, err := s.ProviderServer.GetProviderSchema(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sdkResp, err := s.sdk.GetProviderSchema(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Provider = sdkResp.Provider
+	resp.ProviderMeta = sdkResp.ProviderMeta
+
+	return resp, nil
+}
+
+func (s *frameworkProviderServer) ValidateProviderConfig(ctx context.Context, req *tfprotov6.ValidateProviderConfigRequest) (*tfprotov6.ValidateProviderConfigResponse, error) {
+	return &tfprotov6.ValidateProviderConfigResponse{}, nil
+}
+
+// ConfigureProvider configures the framework provider with an empty
+// configuration, the configuration of the request is of the plugin SDK
+// provider's schema, that the framework provider does not declare
+func (s *frameworkProviderServer) ConfigureProvider(ctx context.Context, req *tfprotov6.ConfigureProviderRequest) (*tfprotov6.ConfigureProviderResponse, error) {
+	empty := tftypes.Object{AttributeTypes: map[string]tftypes.Type{}}
+
+	config, err := tfprotov6.NewDynamicValue(empty, tftypes.NewValue(empty, map[string]tftypes.Value{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ProviderServer.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: req.TerraformVersion,
+		Config:           &config,
+	})
+}
+
+// frameworkProvider implements the features that require the plugin
+// framework, resources and data sources migrate to it one at a time
+type frameworkProvider struct {
+	sdk *schema.Provider
+}
+
+// NewFrameworkProvider returns the plugin framework provider, using
+// the configuration of the plugin SDK provider p
+func NewFrameworkProvider(p *schema.Provider) fwprovider.Provider {
+	return &frameworkProvider{sdk: p}
+}
+
+// frameworkProviderData is the provider data of the framework
+// resources and data sources, it returns the Config of the
+// plugin SDK provider
+//
+// The mux server configures the servers one after another, the
+// Config is looked up when used, not when configuring.
+type frameworkProviderData func() (*Config, error)
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req fwprovider.MetadataRequest, resp *fwprovider.MetadataResponse) {
+	resp.TypeName = "kustomization"
+}
+
+func (p *frameworkProvider) Schema(ctx context.Context, req fwprovider.SchemaRequest, resp *fwprovider.SchemaResponse) {
+	// the provider schema is served from the plugin SDK provider
+}
+
+func (p *frameworkProvider) Configure(ctx context.Context, req fwprovider.ConfigureRequest, resp *fwprovider.ConfigureResponse) {
+	data := frameworkProviderData(func() (*Config, error) {
+		c, ok := p.sdk.Meta().(*Config)
+		if !ok || c == nil {
+			return nil, fmt.Errorf("provider kustomization: not configured")
+		}
+
+		return c, nil
+	})
+
+	resp.DataSourceData = data
+	resp.ResourceData = data
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return nil
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newObjectsDataSource,
+	}
+}
+
+func (p *frameworkProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		newParseIDFunction,
+		newConvertIDsFunction,
+		newManifestDecodeFunction,
+		newManifestEncodeFunction,
+		newBuildFunction,
+	}
+}
@@ -0,0 +1,66 @@
+package kustomize
+
+import (
+	"context"
+	"testing"
+
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderServer(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := ProviderServer(ctx, Provider())
+	assert.Equal(t, nil, err, nil)
+
+	// the mux server fails if the provider schemas differ
+	resp, err := server().GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(resp.Diagnostics), resp.Diagnostics)
+
+	var attrs []string
+	for _, a := range resp.Provider.Block.Attributes {
+		attrs = append(attrs, a.Name)
+	}
+	assert.Contains(t, attrs, "kubeconfig_path", nil)
+
+	_, ok := resp.ResourceSchemas["kustomization_resource"]
+	assert.Equal(t, true, ok, nil)
+
+	_, ok = resp.DataSourceSchemas["kustomization_build"]
+	assert.Equal(t, true, ok, nil)
+
+	_, ok = resp.DataSourceSchemas["kustomization_objects"]
+	assert.Equal(t, true, ok, nil)
+}
+
+func TestFrameworkProviderConfigure(t *testing.T) {
+	ctx := context.Background()
+
+	p := Provider()
+	server := &frameworkProviderServer{
+		ProviderServer: providerserver.NewProtocol6(NewFrameworkProvider(p))(),
+	}
+
+	// configured without the plugin SDK provider's configuration
+	resp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(resp.Diagnostics), resp.Diagnostics)
+
+	var configureResp fwprovider.ConfigureResponse
+	NewFrameworkProvider(p).Configure(ctx, fwprovider.ConfigureRequest{}, &configureResp)
+	data := configureResp.DataSourceData.(frameworkProviderData)
+
+	_, err = data()
+	assert.NotEqual(t, nil, err, nil)
+
+	config := &Config{}
+	p.SetMeta(config)
+
+	c, err := data()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, config, c, nil)
+}
@@ -0,0 +1,45 @@
+package kustomize
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+var gkeDefaultScopes = []string{
+	"https://www.googleapis.com/auth/cloud-platform",
+	"https://www.googleapis.com/auth/userinfo.email",
+}
+
+// getGKETokenSource returns access tokens for GKE clusters like the
+// gke-gcloud-auth-plugin, from the application default credentials
+// or the provided service account key
+func getGKETokenSource(g map[string]interface{}) (oauth2.TokenSource, error) {
+	scopes := gkeDefaultScopes
+	if s := convertListInterfaceToListString(g["scopes"].([]interface{})); len(s) > 0 {
+		scopes = s
+	}
+
+	if c := g["credentials"].(string); c != "" {
+		// either the key's JSON content or a path to the key file
+		data := []byte(c)
+		if !strings.HasPrefix(strings.TrimSpace(c), "{") {
+			var err error
+			data, err = readKubeconfigFile(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		creds, err := google.CredentialsFromJSON(context.Background(), data, scopes...)
+		if err != nil {
+			return nil, err
+		}
+
+		return creds.TokenSource, nil
+	}
+
+	return google.DefaultTokenSource(context.Background(), scopes...)
+}
@@ -0,0 +1,111 @@
+package kustomize
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testGKECredentials returns a service account key
+// that gets its tokens from tokenURL
+func testGKECredentials(t *testing.T, tokenURL string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, nil, err, nil)
+
+	pk := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	creds, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test",
+		"private_key_id": "test",
+		"private_key":    string(pk),
+		"client_email":   "test@test.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      tokenURL,
+	})
+	assert.Equal(t, nil, err, nil)
+
+	return string(creds)
+}
+
+func testGKETokenServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"gke-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGKETokenSource(t *testing.T) {
+	server := testGKETokenServer(t)
+	creds := testGKECredentials(t, server.URL)
+
+	ts, err := getGKETokenSource(map[string]interface{}{
+		"credentials": creds,
+		"scopes":      []interface{}{},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	token, err := ts.Token()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "gke-token", token.AccessToken, nil)
+}
+
+func TestGKETokenSourceCredentialsFile(t *testing.T) {
+	server := testGKETokenServer(t)
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	assert.Equal(t, nil, ioutil.WriteFile(path, []byte(testGKECredentials(t, server.URL)), 0600), nil)
+
+	ts, err := getGKETokenSource(map[string]interface{}{
+		"credentials": path,
+		"scopes":      []interface{}{"https://www.googleapis.com/auth/cloud-platform"},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	token, err := ts.Token()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "gke-token", token.AccessToken, nil)
+}
+
+func TestGKETokenSourceInvalidCredentials(t *testing.T) {
+	_, err := getGKETokenSource(map[string]interface{}{
+		"credentials": `{"type": "service_account", "private_key": "not a key"`,
+		"scopes":      []interface{}{},
+	})
+	assert.NotEqual(t, nil, err, nil)
+
+	_, err = getGKETokenSource(map[string]interface{}{
+		"credentials": filepath.Join(t.TempDir(), "missing.json"),
+		"scopes":      []interface{}{},
+	})
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestGKETokenSourceTokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	ts, err := getGKETokenSource(map[string]interface{}{
+		"credentials": testGKECredentials(t, server.URL),
+		"scopes":      []interface{}{},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	_, err = ts.Token()
+	assert.NotEqual(t, nil, err, nil)
+}
@@ -1,9 +1,15 @@
 package kustomize
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
 )
 
 var testAccProviders map[string]*schema.Provider
@@ -26,3 +32,241 @@ func TestProvider(t *testing.T) {
 func TestProvider_impl(t *testing.T) {
 	var _ schema.Provider = *Provider()
 }
+
+func testKubeconfig(name string, server string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+users:
+- name: %[1]s
+  user:
+    token: %[1]s-token
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+current-context: %[1]s
+`, name, server)
+}
+
+func TestGetMergedClientConfig(t *testing.T) {
+	tmp := t.TempDir()
+
+	pa := filepath.Join(tmp, "a")
+	pb := filepath.Join(tmp, "b")
+	assert.Equal(t, nil, ioutil.WriteFile(pa, []byte(testKubeconfig("a", "https://a.example.com")), 0600), nil)
+	assert.Equal(t, nil, ioutil.WriteFile(pb, []byte(testKubeconfig("b", "https://b.example.com")), 0600), nil)
+
+	// current-context of the first file wins
+	config, err := getMergedClientConfig([]string{pa, pb}, "")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://a.example.com", config.Host, nil)
+
+	// contexts from all files are available
+	config, err = getMergedClientConfig([]string{pa, pb}, "b")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://b.example.com", config.Host, nil)
+	assert.Equal(t, "b-token", config.BearerToken, nil)
+}
+
+func TestConfigureNamedClusters(t *testing.T) {
+	// ignored, because kubeconfig_raw is set
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig_raw": testKubeconfig("default", "https://default.example.com"),
+		"cluster": []interface{}{
+			map[string]interface{}{
+				"name":           "a",
+				"kubeconfig_raw": testKubeconfig("a", "https://a.example.com"),
+			},
+		},
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	kc, err := p.Meta().(*Config).getCluster("")
+	assert.Equal(t, nil, err, nil)
+	config, err := kc.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://default.example.com", config.Host, nil)
+
+	kc, err = p.Meta().(*Config).getCluster("a")
+	assert.Equal(t, nil, err, nil)
+	config, err = kc.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://a.example.com", config.Host, nil)
+
+	_, err = p.Meta().(*Config).getCluster("b")
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestConfigureNamedClusterConnection(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig_raw": testKubeconfig("default", "https://default.example.com"),
+		"cluster": []interface{}{
+			map[string]interface{}{
+				"name": "a",
+				"cluster_connection": []interface{}{
+					map[string]interface{}{
+						"host": "https://a.example.com",
+					},
+				},
+				"token_file": "/var/run/secrets/token",
+				"as":         "admin",
+				"as_groups":  []interface{}{"system:masters"},
+			},
+			map[string]interface{}{
+				"name":           "b",
+				"kubeconfig_raw": testKubeconfig("b", "https://b.example.com"),
+				"token_file":     "/var/run/secrets/token",
+				"username":       "user",
+				"password":       "password",
+			},
+			map[string]interface{}{
+				"name": "c",
+			},
+		},
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	kc, err := p.Meta().(*Config).getCluster("a")
+	assert.Equal(t, nil, err, nil)
+	config, err := kc.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://a.example.com", config.Host, nil)
+	assert.Equal(t, "/var/run/secrets/token", config.BearerTokenFile, nil)
+	assert.Equal(t, "admin", config.Impersonate.UserName, nil)
+	assert.Equal(t, []string{"system:masters"}, config.Impersonate.Groups, nil)
+
+	// conflicts are checked when connecting
+	kc, err = p.Meta().(*Config).getCluster("b")
+	assert.Equal(t, nil, err, nil)
+	_, err = kc.restConfig()
+	assert.NotEqual(t, nil, err, nil)
+
+	// named clusters do not fall back to KUBECONFIG
+	kc, err = p.Meta().(*Config).getCluster("c")
+	assert.Equal(t, nil, err, nil)
+	_, err = kc.restConfig()
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestConfigureKubeconfigFromEnv(t *testing.T) {
+	tmp := t.TempDir()
+
+	pa := filepath.Join(tmp, "a")
+	pb := filepath.Join(tmp, "b")
+	assert.Equal(t, nil, ioutil.WriteFile(pa, []byte(testKubeconfig("a", "https://a.example.com")), 0600), nil)
+	assert.Equal(t, nil, ioutil.WriteFile(pb, []byte(testKubeconfig("b", "https://b.example.com")), 0600), nil)
+
+	t.Setenv("KUBECONFIG_PATH", "")
+	t.Setenv("KUBECONFIG", pa)
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{}))
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	config, err := p.Meta().(*Config).clients.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://a.example.com", config.Host, nil)
+
+	// KUBECONFIG_PATH takes precedence
+	t.Setenv("KUBECONFIG_PATH", pb)
+
+	config, err = p.Meta().(*Config).clients.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://b.example.com", config.Host, nil)
+}
+
+func TestConfigureKubeconfigRawIgnoresEnv(t *testing.T) {
+	t.Setenv("KUBECONFIG_PATH", "")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig_raw": testKubeconfig("raw", "https://raw.example.com"),
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	config, err := p.Meta().(*Config).clients.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://raw.example.com", config.Host, nil)
+}
+
+func TestValidateAnnotationKeys(t *testing.T) {
+	_, es := validateAnnotationKeys(map[string]interface{}{
+		"example.com/workspace": "prod",
+		"commit":                "abc123",
+	}, "audit_annotations")
+	assert.Equal(t, 0, len(es), es)
+
+	_, es = validateAnnotationKeys(map[string]interface{}{"invalid key": ""}, "audit_annotations")
+	assert.Equal(t, 1, len(es), es)
+}
+
+func TestConfigureAuthPreference(t *testing.T) {
+	t.Setenv("KUBECONFIG_PATH", "")
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	// conflicting sources without auth_preference
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig_raw":       testKubeconfig("raw", "https://raw.example.com"),
+		"kubeconfig_incluster": true,
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+	_, err := p.Meta().(*Config).clients.restConfig()
+	assert.NotEqual(t, nil, err, nil)
+
+	// outside of a cluster, in-cluster config fails to load
+	p = Provider()
+	diags = p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig_raw":       testKubeconfig("raw", "https://raw.example.com"),
+		"kubeconfig_incluster": true,
+		"auth_preference":      []interface{}{"kubeconfig_incluster", "kubeconfig_raw"},
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+	config, err := p.Meta().(*Config).clients.restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "https://raw.example.com", config.Host, nil)
+
+	// sources set must be listed
+	p = Provider()
+	diags = p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"kubeconfig_raw":       testKubeconfig("raw", "https://raw.example.com"),
+		"kubeconfig_incluster": true,
+		"auth_preference":      []interface{}{"kubeconfig_incluster"},
+	}))
+	assert.Equal(t, false, diags.HasError(), diags)
+	_, err = p.Meta().(*Config).clients.restConfig()
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestLoadKubeconfigSourcesReason(t *testing.T) {
+	c := map[string]interface{}{
+		"kubeconfig_raw":       testKubeconfig("raw", "https://raw.example.com"),
+		"kubeconfig_path":      "",
+		"kubeconfig_incluster": false,
+		"context":              "",
+	}
+	get := func(k string) interface{} { return c[k] }
+
+	_, source, reason, err := loadKubeconfigSources(get, []string{"kubeconfig_raw"}, []string{"kubeconfig_path", "kubeconfig_raw"}, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "kubeconfig_raw", source, nil)
+	assert.Equal(t, "the first of auth_preference that is set and loads, skipped kubeconfig_path: not set", reason, nil)
+
+	_, source, reason, err = loadKubeconfigSources(get, []string{"kubeconfig_raw"}, nil, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "kubeconfig_raw", source, nil)
+	assert.Equal(t, "the only kubeconfig source set", reason, nil)
+}
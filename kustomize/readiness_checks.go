@@ -0,0 +1,161 @@
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// readinessCheck determines if objects of a kind are ready, like
+// kubectl wait, from a status condition or a JSONPath expression
+type readinessCheck struct {
+	condition       string
+	expression      string
+	value           string
+	failedCondition string
+}
+
+func getReadinessCheckSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"group": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kind": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"condition": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"jsonpath": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"value": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"failed_condition": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+// getReadinessChecks returns the readiness checks of the
+// readiness_check blocks by group and kind, e.g. "example.com/Database"
+func getReadinessChecks(in []interface{}) (map[string]waitRefreshFunction, error) {
+	checks := make(map[string]waitRefreshFunction)
+	for _, e := range in {
+		o := e.(map[string]interface{})
+		gk := fmt.Sprintf("%s/%s", o["group"].(string), o["kind"].(string))
+		if _, ok := checks[gk]; ok {
+			return nil, fmt.Errorf("duplicate check for %q", gk)
+		}
+
+		c := &readinessCheck{
+			condition:       o["condition"].(string),
+			expression:      o["jsonpath"].(string),
+			value:           o["value"].(string),
+			failedCondition: o["failed_condition"].(string),
+		}
+
+		if (c.condition == "") == (c.expression == "") {
+			return nil, fmt.Errorf("%q: exactly one of condition and jsonpath is required", gk)
+		}
+		if c.value != "" && c.expression == "" {
+			return nil, fmt.Errorf("%q: value requires jsonpath", gk)
+		}
+
+		if c.expression != "" {
+			if _, err := c.jsonPath(); err != nil {
+				return nil, fmt.Errorf("%q: jsonpath: %s", gk, err)
+			}
+		}
+
+		checks[gk] = waitRolloutRefresh(c.status)
+	}
+
+	return checks, nil
+}
+
+// status returns the readiness of u, objects are in progress until
+// the status of their current generation is observed, if reported
+func (c *readinessCheck) status(u *k8sunstructured.Unstructured) (rolloutStatus, error) {
+	if observed, ok, _ := k8sunstructured.NestedInt64(u.Object, "status", "observedGeneration"); ok && observed < u.GetGeneration() {
+		return waitingFor("waiting for spec update to be observed"), nil
+	}
+
+	if c.failedCondition != "" {
+		if status, msg := conditionStatus(u, c.failedCondition); status == "True" {
+			return rolloutStatus{failed: true, progress: fmt.Sprintf("condition %s is true: %s", c.failedCondition, msg)}, nil
+		}
+	}
+
+	if c.condition != "" {
+		status, _ := conditionStatus(u, c.condition)
+		if status == "True" {
+			return rolloutStatus{done: true}, nil
+		}
+		return waitingFor("waiting for condition %s", c.condition), nil
+	}
+
+	j, err := c.jsonPath()
+	if err != nil {
+		return rolloutStatus{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := j.Execute(&buf, u.Object); err != nil {
+		return rolloutStatus{}, fmt.Errorf("jsonpath %s: %s", c.expression, err)
+	}
+
+	got := strings.TrimSpace(buf.String())
+	if (c.value == "" && got != "") || (c.value != "" && got == c.value) {
+		return rolloutStatus{done: true}, nil
+	}
+
+	if c.value == "" {
+		return waitingFor("waiting for %s", c.expression), nil
+	}
+	return waitingFor("waiting for %s to be %q, got %q", c.expression, c.value, got), nil
+}
+
+// jsonPath parses the expression, parsed paths
+// are not safe to execute concurrently
+func (c *readinessCheck) jsonPath() (*jsonpath.JSONPath, error) {
+	j := jsonpath.New("readiness_check")
+	j.AllowMissingKeys(true)
+	if err := j.Parse(c.expression); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// conditionStatus returns the status and message of the
+// condition of type t in the status of u, empty if not set
+func conditionStatus(u *k8sunstructured.Unstructured, t string) (string, string) {
+	conditions, _, _ := k8sunstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, e := range conditions {
+		c, ok := e.(map[string]interface{})
+		if !ok || c["type"] != t {
+			continue
+		}
+
+		status, _ := c["status"].(string)
+		msg, _ := c["message"].(string)
+		return status, msg
+	}
+
+	return "", ""
+}
@@ -0,0 +1,97 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReadinessChecks(t *testing.T) {
+	checks, err := getReadinessChecks([]interface{}{
+		map[string]interface{}{"group": "example.com", "kind": "Database", "condition": "Ready", "jsonpath": "", "value": "", "failed_condition": ""},
+		map[string]interface{}{"group": "", "kind": "PersistentVolumeClaim", "condition": "", "jsonpath": "{.status.phase}", "value": "Bound", "failed_condition": ""},
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 2, len(checks), nil)
+	_, ok := checks["example.com/Database"]
+	assert.Equal(t, true, ok, nil)
+	_, ok = checks["/PersistentVolumeClaim"]
+	assert.Equal(t, true, ok, nil)
+
+	invalid := []map[string]interface{}{
+		{"group": "", "kind": "Test", "condition": "", "jsonpath": "", "value": "", "failed_condition": ""},
+		{"group": "", "kind": "Test", "condition": "Ready", "jsonpath": "{.status.phase}", "value": "", "failed_condition": ""},
+		{"group": "", "kind": "Test", "condition": "Ready", "jsonpath": "", "value": "Bound", "failed_condition": ""},
+		{"group": "", "kind": "Test", "condition": "", "jsonpath": "{.status.phase", "value": "", "failed_condition": ""},
+	}
+	for _, c := range invalid {
+		_, err := getReadinessChecks([]interface{}{c})
+		assert.NotEqual(t, nil, err, c)
+	}
+
+	c := map[string]interface{}{"group": "", "kind": "Test", "condition": "Ready", "jsonpath": "", "value": "", "failed_condition": ""}
+	_, err = getReadinessChecks([]interface{}{c, c})
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestReadinessCheckStatus(t *testing.T) {
+	condition := &readinessCheck{condition: "Ready", failedCondition: "Failed"}
+	phase := &readinessCheck{expression: "{.status.phase}", value: "Bound"}
+	endpoint := &readinessCheck{expression: "{.status.endpoint}"}
+
+	testCases := []struct {
+		name     string
+		check    *readinessCheck
+		manifest string
+		expected rolloutStatus
+	}{
+		{"not observed", condition, `
+metadata: {generation: 2}
+status:
+  observedGeneration: 1
+  conditions:
+  - {type: Ready, status: "True"}
+`, rolloutStatus{progress: "waiting for spec update to be observed"}},
+		{"condition false", condition, `
+metadata: {generation: 1}
+status:
+  observedGeneration: 1
+  conditions:
+  - {type: Ready, status: "False"}
+`, rolloutStatus{progress: "waiting for condition Ready"}},
+		{"condition missing", condition, `
+metadata: {generation: 1}
+`, rolloutStatus{progress: "waiting for condition Ready"}},
+		{"failed", condition, `
+metadata: {generation: 1}
+status:
+  conditions:
+  - {type: Failed, status: "True", message: quota exceeded}
+`, rolloutStatus{failed: true, progress: "condition Failed is true: quota exceeded"}},
+		{"condition true", condition, `
+metadata: {generation: 1}
+status:
+  observedGeneration: 1
+  conditions:
+  - {type: Ready, status: "True"}
+`, rolloutStatus{done: true}},
+		{"value differs", phase, `
+status: {phase: Pending}
+`, rolloutStatus{progress: `waiting for {.status.phase} to be "Bound", got "Pending"`}},
+		{"value equal", phase, `
+status: {phase: Bound}
+`, rolloutStatus{done: true}},
+		{"empty", endpoint, `
+status: {}
+`, rolloutStatus{progress: "waiting for {.status.endpoint}"}},
+		{"not empty", endpoint, `
+status: {endpoint: db.example.com}
+`, rolloutStatus{done: true}},
+	}
+
+	for _, tc := range testCases {
+		status, err := tc.check.status(testRolloutObject(t, tc.manifest))
+		assert.Equal(t, nil, err, tc.name)
+		assert.Equal(t, tc.expected, status, tc.name)
+	}
+}
@@ -0,0 +1,123 @@
+package kustomize
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// reauthTransport rebuilds the transport of a cluster connection
+// when the credentials were rejected, e.g. short-lived client
+// certificates or tokens that expired during a long apply
+//
+// The new transport uses the credentials the connection settings
+// return now, like a certificate rotated on disk or a new token. The
+// failed request is retried once, later requests use the new transport.
+type reauthTransport struct {
+	build func() (http.RoundTripper, error)
+
+	mu         sync.Mutex
+	rt         http.RoundTripper
+	generation int
+}
+
+func newReauthTransport(rt http.RoundTripper, build func() (http.RoundTripper, error)) *reauthTransport {
+	return &reauthTransport{build: build, rt: rt}
+}
+
+func (t *reauthTransport) current() (http.RoundTripper, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.rt, t.generation
+}
+
+// rebuild replaces the transport of generation, concurrent requests
+// failing with the same transport rebuild it only once
+func (t *reauthTransport) rebuild(generation int) (http.RoundTripper, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if generation != t.generation {
+		return t.rt, nil
+	}
+
+	rt, err := t.build()
+	if err != nil {
+		return nil, err
+	}
+
+	t.rt = rt
+	t.generation++
+
+	return rt, nil
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt, generation := t.current()
+
+	resp, err := rt.RoundTrip(req)
+	if !isCredentialsError(resp, err) || !isReplayable(req) {
+		return resp, err
+	}
+
+	log.Printf("[WARN] provider kustomization: credentials rejected, rebuilding client: %s", credentialsErrorReason(resp, err))
+
+	nrt, rerr := t.rebuild(generation)
+	if rerr != nil {
+		log.Printf("[WARN] provider kustomization: rebuilding client failed: %s", rerr)
+		return resp, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		retry.Body, rerr = req.GetBody()
+		if rerr != nil {
+			return resp, err
+		}
+	}
+
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	return nrt.RoundTrip(retry)
+}
+
+// isReplayable returns true if the body of req can be sent again
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// tlsCredentialsErrors are the TLS alerts servers send when the
+// client certificate is expired, revoked or otherwise rejected
+var tlsCredentialsErrors = []string{
+	"tls: expired certificate",
+	"tls: bad certificate",
+	"tls: revoked certificate",
+	"tls: certificate required",
+}
+
+// isCredentialsError returns true if the API server rejected the
+// credentials of the request, with a 401 or a TLS handshake alert
+func isCredentialsError(resp *http.Response, err error) bool {
+	if err != nil {
+		for _, e := range tlsCredentialsErrors {
+			if strings.Contains(err.Error(), e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return resp != nil && resp.StatusCode == http.StatusUnauthorized
+}
+
+func credentialsErrorReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return resp.Status
+}
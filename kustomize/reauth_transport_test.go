@@ -0,0 +1,56 @@
+package kustomize
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestReauthTransport(t *testing.T) {
+	expired := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("remote error: tls: expired certificate")
+	})
+
+	var body string
+	rotated := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		body = string(b)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	builds := 0
+	rt := newReauthTransport(expired, func() (http.RoundTripper, error) {
+		builds++
+		return rotated, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com", strings.NewReader("test"))
+	resp, err := rt.RoundTrip(req)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, nil)
+	assert.Equal(t, "test", body, nil)
+	assert.Equal(t, 1, builds, nil)
+
+	// a rebuilt transport is kept for later requests
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com", nil)
+	_, err = rt.RoundTrip(req)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 1, builds, nil)
+}
+
+func TestIsCredentialsError(t *testing.T) {
+	assert.Equal(t, true, isCredentialsError(&http.Response{StatusCode: http.StatusUnauthorized}, nil), nil)
+	assert.Equal(t, false, isCredentialsError(&http.Response{StatusCode: http.StatusForbidden}, nil), nil)
+	assert.Equal(t, true, isCredentialsError(nil, errors.New("remote error: tls: bad certificate")), nil)
+	assert.Equal(t, false, isCredentialsError(nil, errors.New("connection refused")), nil)
+}
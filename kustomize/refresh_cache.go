@@ -0,0 +1,120 @@
+package kustomize
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// refreshListLimit is the page size of refresh list requests
+const refreshListLimit = 500
+
+// refreshCache lists the objects matching the label selector once per
+// cluster, resource and namespace, to refresh many kustomization_resources
+// without a get request for each of them. Terraform starts the provider
+// for every plan and apply, so lists are cached for the provider's lifetime
+type refreshCache struct {
+	selector string
+
+	mu    sync.Mutex
+	lists map[string]*refreshList
+}
+
+type refreshList struct {
+	once    sync.Once
+	objects map[string]*k8sunstructured.Unstructured
+	err     error
+}
+
+func getRefreshCache(selector string) *refreshCache {
+	if selector == "" {
+		return nil
+	}
+
+	return &refreshCache{
+		selector: selector,
+		lists:    make(map[string]*refreshList),
+	}
+}
+
+// get returns the object of km from the list of its resource and
+// namespace, false if the object does not match the selector, does
+// not exist or the list failed, to fall back to a get request
+func (rc *refreshCache) get(cluster string, km *kManifest) (*k8sunstructured.Unstructured, bool) {
+	if rc == nil {
+		return nil, false
+	}
+
+	gvr, err := km.gvr()
+	if err != nil {
+		return nil, false
+	}
+
+	namespaced, err := km.isNamespaced()
+	if err != nil {
+		return nil, false
+	}
+
+	// without a namespace, the list would include all namespaces
+	if namespaced && km.namespace() == "" {
+		return nil, false
+	}
+
+	key := strings.Join([]string{cluster, gvr.String(), km.namespace()}, "|")
+
+	rc.mu.Lock()
+	l, ok := rc.lists[key]
+	if !ok {
+		l = &refreshList{}
+		rc.lists[key] = l
+	}
+	rc.mu.Unlock()
+
+	l.once.Do(func() {
+		l.objects, l.err = rc.list(km)
+		if l.err != nil {
+			log.Printf("[WARN] refresh_label_selector: listing %s: %s, falling back to get requests", key, l.err)
+		}
+	})
+	if l.err != nil {
+		return nil, false
+	}
+
+	u, ok := l.objects[km.name()]
+	return u, ok
+}
+
+// list returns the objects of the resource and namespace of km
+// matching the selector by name
+func (rc *refreshCache) list(km *kManifest) (map[string]*k8sunstructured.Unstructured, error) {
+	api, err := km.api()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]*k8sunstructured.Unstructured)
+	opts := k8smetav1.ListOptions{
+		LabelSelector: rc.selector,
+		Limit:         refreshListLimit,
+	}
+	for {
+		resp, err := api.List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range resp.Items {
+			u := &resp.Items[i]
+			objects[u.GetName()] = u
+		}
+
+		opts.Continue = resp.GetContinue()
+		if opts.Continue == "" {
+			return objects, nil
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRefreshCache(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "", "version": "v1", "kind": "Namespace", "resource": "namespaces", "namespaced": false},
+		map[string]interface{}{"group": "", "version": "v1", "kind": "ConfigMap", "resource": "configmaps", "namespaced": true},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[k8sschema.GroupVersionResource]string{
+			{Version: "v1", Resource: "namespaces"}: "NamespaceList",
+			{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+		},
+		testRolloutObject(t, "{apiVersion: v1, kind: ConfigMap, metadata: {name: labeled, namespace: test, labels: {app: test}}}"),
+		testRolloutObject(t, "{apiVersion: v1, kind: ConfigMap, metadata: {name: unlabeled, namespace: test}}"),
+		testRolloutObject(t, "{apiVersion: v1, kind: ConfigMap, metadata: {name: labeled, namespace: other, labels: {app: test}}}"),
+		testRolloutObject(t, "{apiVersion: v1, kind: Namespace, metadata: {name: test, labels: {app: test}}}"),
+	)
+	lists := 0
+	client.PrependReactor("list", "*", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		lists++
+		return false, nil, nil
+	})
+
+	load := func(body string) *kManifest {
+		km := newKManifest(mapper, client)
+		assert.Equal(t, nil, km.load([]byte(body)), nil)
+		return km
+	}
+
+	rc := getRefreshCache("app=test")
+
+	u, ok := rc.get("", load(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"labeled","namespace":"test"}}`))
+	assert.Equal(t, true, ok, nil)
+	assert.Equal(t, "test", u.GetNamespace(), nil)
+
+	_, ok = rc.get("", load(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"unlabeled","namespace":"test"}}`))
+	assert.Equal(t, false, ok, nil)
+
+	_, ok = rc.get("", load(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"missing"}}`))
+	assert.Equal(t, false, ok, nil)
+
+	u, ok = rc.get("", load(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test"}}`))
+	assert.Equal(t, true, ok, nil)
+	assert.Equal(t, "Namespace", u.GetKind(), nil)
+
+	// one list for configmaps in test and one for namespaces
+	assert.Equal(t, 2, lists, nil)
+
+	var nilCache *refreshCache
+	_, ok = nilCache.get("", load(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test"}}`))
+	assert.Equal(t, false, ok, nil)
+}
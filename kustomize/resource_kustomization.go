@@ -4,23 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	k8scorev1 "k8s.io/api/core/v1"
+	k8sequality "k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	k8smeta "k8s.io/apimachinery/pkg/api/meta"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8sdynamic "k8s.io/client-go/dynamic"
 )
 
 func kustomizationResource() *schema.Resource {
 	return &schema.Resource{
 		Create:        kustomizationResourceCreate,
-		Read:          kustomizationResourceRead,
+		Read:          kustomizationResourceRefresh,
 		Exists:        kustomizationResourceExists,
 		Update:        kustomizationResourceUpdate,
 		Delete:        kustomizationResourceDelete,
@@ -34,12 +39,169 @@ func kustomizationResource() *schema.Resource {
 			"manifest": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
+				// the hash of the manifest in state, if encrypted
+				DiffSuppressFunc: suppressEncryptedManifestDiff,
+			},
+			"manifest_encrypted": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
 			},
 			"wait": &schema.Schema{
 				Type:     schema.TypeBool,
 				Default:  false,
 				Optional: true,
 			},
+			"apply_method": &schema.Schema{
+				Type:     schema.TypeString,
+				Default:  "patch",
+				Optional: true,
+				ValidateFunc: validation.StringInSlice(
+					[]string{"patch", "replace"},
+					false,
+				),
+			},
+			"diff_mode": &schema.Schema{
+				Type:     schema.TypeString,
+				Default:  "structural",
+				Optional: true,
+				ValidateFunc: validation.StringInSlice(
+					[]string{"structural", "raw"},
+					false,
+				),
+			},
+			"cluster": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"server_side_apply": &schema.Schema{
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"field_manager": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"ignore_fields": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ignore_ca_bundles": &schema.Schema{
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"wait_for_deletion": waitForDeletionSchema(),
+			"min_server_version": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateServerVersion,
+			},
+			"replace_on_change": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"history_limit": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"history": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"rollback_to_previous": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"take_ownership_of": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateOwnershipPath,
+				},
+			},
+			"use_scale_subresource": &schema.Schema{
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"apply_status": &schema.Schema{
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"wait_load_balancer_cleanup": &schema.Schema{
+				Type:     schema.TypeBool,
+				Default:  false,
+				Optional: true,
+			},
+			"api_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"kind": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"load_balancer_ingress": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"hostname": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ip": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"generation": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"resource_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"secrets_hash": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"server_side_applied": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"audit_annotations": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"last_applied_at": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"apply_duration": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 
 		Timeouts: &schema.ResourceTimeout{
@@ -51,23 +213,44 @@ func kustomizationResource() *schema.Resource {
 }
 
 func kustomizationResourceCreate(d *schema.ResourceData, m interface{}) error {
-	mapper := m.(*Config).Mapper
-	client := m.(*Config).Client
+	start := time.Now()
+
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return logError(err)
+	}
 	km := newKManifest(mapper, client)
+	km.retry = m.(*Config).Retry
+
+	manifest, err := getManifest(d, m)
+	if err != nil {
+		return logError(err)
+	}
+
+	err = km.load([]byte(manifest))
+	if err != nil {
+		return logError(err)
+	}
+	removeIgnored(d, m, km)
+	km.secrets = getSecretResolver(m)
+	km.auditAnnotations = m.(*Config).AuditAnnotations
 
-	err := km.load([]byte(d.Get("manifest").(string)))
+	// not checked during plan if the cluster was unreachable
+	err = checkMinServerVersion(d, m, km)
 	if err != nil {
 		return logError(err)
 	}
 
+	timeout := getTimeout(d, m, schema.TimeoutCreate)
+
 	// required for CRDs
-	err = km.waitKind(d.Timeout(schema.TimeoutCreate))
+	err = km.waitKind(timeout)
 	if err != nil {
 		return logError(err)
 	}
 
 	// required for namespaced resources
-	err = km.waitNamespace(d.Timeout(schema.TimeoutCreate))
+	err = km.waitNamespace(timeout)
 	if err != nil {
 		return logError(err)
 	}
@@ -101,53 +284,336 @@ func kustomizationResourceCreate(d *schema.ResourceData, m interface{}) error {
 		}
 	}
 
-	gzipLastAppliedConfig := m.(*Config).GzipLastAppliedConfig
-	setLastAppliedConfig(km, gzipLastAppliedConfig)
+	// for objects replacing prior ones, e.g. migration jobs
+	err = km.waitPriorDeleted(getWaitForDeletion(d), timeout)
+	if err != nil {
+		return logError(err)
+	}
 
-	resp, err := km.apiCreate(k8smetav1.CreateOptions{})
+	gzipLastAppliedConfig := m.(*Config).GzipLastAppliedConfig
+	fm := getFieldManager(d, m)
+
+	applyStart := time.Now()
+	var resp *k8sunstructured.Unstructured
+	serverSideApplied := false
+	switch {
+	case getServerSideApply(d, m):
+		serverSideApplied = true
+		resp, err = km.apiApplyTakingOwnership(k8smetav1.PatchOptions{FieldManager: fm}, getTakeOwnershipOf(d))
+	case !setLastAppliedConfig(km, gzipLastAppliedConfig):
+		log.Printf("[WARN] %q: manifest exceeds the max annotation size even when compressed, falling back to server-side apply", km.id().string())
+		serverSideApplied = true
+		resp, err = km.apiApplyTakingOwnership(k8smetav1.PatchOptions{FieldManager: fm}, getTakeOwnershipOf(d))
+	default:
+		resp, err = km.apiCreate(k8smetav1.CreateOptions{FieldManager: fm})
+	}
 	if err != nil {
 		return logError(err)
 	}
 
-	if d.Get("wait").(bool) {
-		if err = km.waitCreatedOrUpdated(d.Timeout(schema.TimeoutCreate)); err != nil {
+	if d.Get("apply_status").(bool) && km.hasStatus() {
+		resp, err = km.apiPatchStatus(k8smetav1.PatchOptions{FieldManager: fm})
+		if err != nil {
+			return logError(km.fmtErr(err))
+		}
+	}
+
+	m.(*Config).Metrics.addApply(km.id().string(), time.Since(applyStart))
+
+	if getWait(d, m) {
+		km.podLogs = getPodLogs(d, m)
+		km.readinessChecks = m.(*Config).ReadinessChecks
+		km.skipUnavailableNodes = m.(*Config).SkipUnavailableNodes
+		waitStart := time.Now()
+		err = km.waitCreatedOrUpdated(timeout)
+		m.(*Config).Metrics.addWait(km.id().string(), time.Since(waitStart))
+		if err != nil {
 			return logError(err)
 		}
 	}
 
+	if g, ok := km.servedGroup(); ok {
+		// make the new kinds of the group discoverable
+		invalidateGroups(mapper, g)
+	}
+
 	id := string(resp.GetUID())
 	d.SetId(id)
 
-	d.Set("manifest", getLastAppliedConfig(resp, gzipLastAppliedConfig))
+	// only fully server-side applied objects are diffed by the
+	// fields owned by the field manager, see getAppliedManifest
+	d.Set("server_side_applied", serverSideApplied && len(km.leftFields) == 0)
+
+	stateManifest, err := getStateManifest(d, m, resp)
+	if err != nil {
+		return logError(err)
+	}
+	if err := setManifest(d, stateManifest, m); err != nil {
+		return logError(err)
+	}
+
+	if err := setSecretsHash(d, km, manifest); err != nil {
+		return logError(err)
+	}
+	d.Set("audit_annotations", km.auditAnnotations)
+
+	setApplyTiming(d, start)
 
 	return kustomizationResourceRead(d, m)
 }
 
 func kustomizationResourceRead(d *schema.ResourceData, m interface{}) error {
-	km := newKManifest(m.(*Config).Mapper, m.(*Config).Client)
+	return readKustomizationResource(d, m, false)
+}
+
+// kustomizationResourceRefresh reads the resource from the lists
+// of the refresh_label_selector, if set, and falls back to a get
+func kustomizationResourceRefresh(d *schema.ResourceData, m interface{}) error {
+	return readKustomizationResource(d, m, true)
+}
+
+func readKustomizationResource(d *schema.ResourceData, m interface{}, batched bool) error {
+	if skipUnreachableCluster(d, m) {
+		log.Printf("[WARN] %q: cluster unreachable, deferring read", d.Id())
+		return nil
+	}
 
-	err := km.load([]byte(d.Get("manifest").(string)))
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return logError(err)
+	}
+	km := newKManifest(mapper, client)
+	km.retry = m.(*Config).Retry
+
+	manifest, err := getManifest(d, m)
 	if err != nil {
 		return logError(err)
 	}
 
-	resp, err := km.apiGet(k8smetav1.GetOptions{})
+	err = km.load([]byte(manifest))
 	if err != nil {
 		return logError(err)
 	}
 
+	resp, ok := getRefreshed(d, m, km, batched)
+	if !ok {
+		resp, err = km.apiGet(k8smetav1.GetOptions{})
+		if err != nil {
+			return logError(err)
+		}
+	}
+
 	id := string(resp.GetUID())
 	d.SetId(id)
 
-	d.Set("manifest", getLastAppliedConfig(resp, m.(*Config).GzipLastAppliedConfig))
+	stateManifest, err := getStateManifest(d, m, resp)
+	if err != nil {
+		return logError(err)
+	}
+	if err := setManifest(d, stateManifest, m); err != nil {
+		return logError(err)
+	}
+
+	setIdentityAttributes(d, resp)
+	d.Set("load_balancer_ingress", flattenLoadBalancerIngress(resp))
+	d.Set("generation", resp.GetGeneration())
+	d.Set("resource_version", resp.GetResourceVersion())
 
 	return nil
 }
 
+func setIdentityAttributes(d *schema.ResourceData, u *k8sunstructured.Unstructured) {
+	d.Set("api_version", u.GetAPIVersion())
+	d.Set("kind", u.GetKind())
+	d.Set("name", u.GetName())
+	d.Set("namespace", u.GetNamespace())
+}
+
+// getStateManifest returns the manifest to store in the state
+// for the applied resource resp
+func getStateManifest(d *schema.ResourceData, m interface{}, resp *k8sunstructured.Unstructured) (string, error) {
+	manifest, err := getManifest(d, m)
+	if err != nil {
+		return "", err
+	}
+
+	applied := getAppliedManifest(resp, m.(*Config).GzipLastAppliedConfig, getFieldManager(d, m), d.Get("server_side_applied").(bool))
+
+	// objects server-side applied leaving fields to other
+	// field managers have no lastAppliedConfig
+	if applied == "" {
+		return manifest, nil
+	}
+
+	// keep the manifest including ignored fields and metadata, to not
+	// show a diff for what was removed before applying the manifest
+	remove := func(km *kManifest) { removeIgnored(d, m, km) }
+	if hasIgnored(d, m) && manifestsEqualIgnoring(manifest, applied, remove) {
+		return manifest, nil
+	}
+
+	// keep unknown fields of schemaless custom resources in the
+	// manifest, that the API server pruned from the object
+	if getRawDiff(d) && isPrunedManifest(manifest, applied) {
+		return manifest, nil
+	}
+
+	return applied, nil
+}
+
+// removeIgnored removes the ignored fields of the resource and the
+// annotations and labels ignored by the provider from the manifest
+func removeIgnored(d rawConfigGetter, m interface{}, km *kManifest) {
+	removeIgnoredFields(km, getIgnoreFields(d, m))
+	if d.Get("ignore_ca_bundles").(bool) {
+		removeCABundles(km)
+	}
+	removeIgnoredMetadata(km, m.(*Config).IgnoreAnnotations, m.(*Config).IgnoreLabels)
+}
+
+// getRawDiff returns true if the resource uses the raw diff_mode
+func getRawDiff(d changeGetter) bool {
+	return d.Get("diff_mode").(string) == "raw"
+}
+
+func hasIgnored(d rawConfigGetter, m interface{}) bool {
+	return len(getIgnoreFields(d, m)) > 0 || d.Get("ignore_ca_bundles").(bool) || len(m.(*Config).IgnoreAnnotations) > 0 || len(m.(*Config).IgnoreLabels) > 0
+}
+
+// getClients returns the clients for the cluster of the resource
+func getClients(d rawConfigGetter, m interface{}) (k8sdynamic.Interface, k8smeta.ResettableRESTMapper, error) {
+	kc, err := m.(*Config).getCluster(d.Get("cluster").(string))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return kc.get()
+}
+
+func getTakeOwnershipOf(d rawConfigGetter) []string {
+	return convertListInterfaceToListString(d.Get("take_ownership_of").([]interface{}))
+}
+
+// getPodLogs returns the function to read logs of failed
+// containers from the cluster of the resource, nil if unknown
+func getPodLogs(d rawConfigGetter, m interface{}) podLogsFunction {
+	kc, err := m.(*Config).getCluster(d.Get("cluster").(string))
+	if err != nil {
+		return nil
+	}
+
+	return kc.podLogs()
+}
+
+// getRefreshed returns the object of km from the lists of the
+// refresh_label_selector, false if not batched or not listed
+func getRefreshed(d rawConfigGetter, m interface{}, km *kManifest, batched bool) (*k8sunstructured.Unstructured, bool) {
+	if !batched {
+		return nil, false
+	}
+
+	return m.(*Config).RefreshCache.get(d.Get("cluster").(string), km)
+}
+
+// skipUnreachableCluster returns true if the cluster is unreachable
+// and the provider is configured to plan without it
+func skipUnreachableCluster(d rawConfigGetter, m interface{}) bool {
+	if !m.(*Config).AllowUnreachableCluster {
+		return false
+	}
+
+	kc, err := m.(*Config).getCluster(d.Get("cluster").(string))
+	if err != nil {
+		return false
+	}
+
+	return !kc.isReachable()
+}
+
+func setApplyTiming(d *schema.ResourceData, start time.Time) {
+	d.Set("last_applied_at", time.Now().UTC().Format(time.RFC3339))
+	d.Set("apply_duration", time.Since(start).Round(time.Millisecond).String())
+}
+
+func diffIdentityAttributes(d *schema.ResourceDiff, km *kManifest) {
+	attrs := map[string]string{
+		"api_version": km.resource.GetAPIVersion(),
+		"kind":        km.resource.GetKind(),
+		"name":        km.name(),
+		"namespace":   km.namespace(),
+	}
+
+	for k, v := range attrs {
+		if d.Get(k).(string) != v {
+			d.SetNew(k, v)
+		}
+	}
+}
+
+// identityChanges returns the identity attributes that differ between
+// the old and the new manifest and require a replacement, objects can
+// not be renamed, moved between namespaces or scopes, or change their
+// kind, version and group changes are API version upgrades
+func identityChanges(kmo *kManifest, kmm *kManifest) (changed []string) {
+	if kmo.resource.GetKind() != kmm.resource.GetKind() {
+		changed = append(changed, "kind")
+	}
+	if kmo.name() != kmm.name() {
+		changed = append(changed, "name")
+	}
+	if kmo.namespace() != kmm.namespace() {
+		changed = append(changed, "namespace")
+	}
+
+	return changed
+}
+
+// fieldChanges returns the field paths whose values differ
+// between the manifests, fields not set in either are unchanged
+func fieldChanges(kmo *kManifest, kmm *kManifest, paths []string) (changed []string) {
+	for _, p := range paths {
+		fields := parseFieldPath(p)
+		o, _, _ := k8sunstructured.NestedFieldNoCopy(kmo.resource.Object, fields...)
+		n, _, _ := k8sunstructured.NestedFieldNoCopy(kmm.resource.Object, fields...)
+		if !k8sequality.Semantic.DeepEqual(o, n) {
+			changed = append(changed, p)
+		}
+	}
+
+	return changed
+}
+
+// forceNewIdentity plans the replacement of the resource, marking the
+// changed identity attributes, to show the old and the new identity
+func forceNewIdentity(d *schema.ResourceDiff, changed []string) {
+	d.ForceNew("manifest")
+
+	for _, k := range changed {
+		// not set in the state of resources created by old versions
+		if o, _ := d.GetChange(k); o.(string) != "" {
+			d.ForceNew(k)
+		}
+	}
+}
+
 func kustomizationResourceExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	km := newKManifest(m.(*Config).Mapper, m.(*Config).Client)
+	if skipUnreachableCluster(d, m) {
+		return true, nil
+	}
 
-	err := km.load([]byte(d.Get("manifest").(string)))
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return false, logError(err)
+	}
+	km := newKManifest(mapper, client)
+	km.retry = m.(*Config).Retry
+
+	manifest, err := getManifest(d, m)
+	if err != nil {
+		return false, logError(err)
+	}
+
+	err = km.load([]byte(manifest))
 	if err != nil {
 		return false, logError(err)
 	}
@@ -162,6 +628,10 @@ func kustomizationResourceExists(d *schema.ResourceData, m interface{}) (bool, e
 		return false, logError(err)
 	}
 
+	if _, ok := getRefreshed(d, m, km, true); ok {
+		return true, nil
+	}
+
 	_, err = km.apiGet(k8smetav1.GetOptions{})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -174,22 +644,90 @@ func kustomizationResourceExists(d *schema.ResourceData, m interface{}) (bool, e
 }
 
 func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if err := diffEncryptedManifest(d, m); err != nil {
+		return logError(err)
+	}
+
+	// secrets are resolved during plan, to diff rotated
+	// secrets and to dry-run the resolved manifest
+	secrets := getSecretResolver(m)
+	if err := diffSecretsHash(d, secrets, m); err != nil {
+		return logError(err)
+	}
+
+	if err := diffManifestHistory(d); err != nil {
+		return logError(err)
+	}
+
 	if !d.HasChange("manifest") {
 		return nil
 	}
 
-	client := m.(*Config).Client
-	mapper := m.(*Config).Mapper
 	gzipLastAppliedConfig := m.(*Config).GzipLastAppliedConfig
 
-	do, dm := d.GetChange("manifest")
+	if !d.NewValueKnown("manifest") || skipUnreachableCluster(d, m) {
+		for _, k := range []string{"api_version", "kind", "name", "namespace", "load_balancer_ingress", "generation", "resource_version", "server_side_applied", "last_applied_at", "apply_duration"} {
+			d.SetNewComputed(k)
+		}
+		return nil
+	}
+
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return logError(err)
+	}
+
+	do, dm, err := getManifestChange(d, m)
+	if err != nil {
+		return logError(err)
+	}
 
 	kmm := newKManifest(mapper, client)
-	err := kmm.load([]byte(dm.(string)))
+	kmm.retry = m.(*Config).Retry
+	err = kmm.load([]byte(dm))
 	if err != nil {
 		return logError(err)
 	}
-	setLastAppliedConfig(kmm, gzipLastAppliedConfig)
+	diffIdentityAttributes(d, kmm)
+
+	if err := checkMinServerVersion(d, m, kmm); err != nil {
+		return logError(err)
+	}
+
+	// checked before the kind is known to the API, the
+	// new kind may be of a CRD that does not exist yet
+	if do != "" {
+		kmo := newKManifest(mapper, client)
+		if err := kmo.load([]byte(do)); err != nil {
+			return logError(err)
+		}
+
+		if changed := identityChanges(kmo, kmm); len(changed) > 0 {
+			forceNewIdentity(d, changed)
+			return nil
+		}
+
+		paths := convertListInterfaceToListString(d.Get("replace_on_change").([]interface{}))
+		if changed := fieldChanges(kmo, kmm, paths); len(changed) > 0 {
+			log.Printf("[INFO] %q: replacing, changed %s", kmm.id().string(), strings.Join(changed, ", "))
+			d.ForceNew("manifest")
+			return nil
+		}
+	}
+
+	removeIgnored(d, m, kmm)
+	kmm.secrets = secrets
+	for _, k := range []string{"generation", "resource_version", "server_side_applied", "last_applied_at", "apply_duration"} {
+		d.SetNewComputed(k)
+	}
+	if kmm.hasLoadBalancerStatus() {
+		d.SetNewComputed("load_balancer_ingress")
+	}
+	fm := getFieldManager(d, m)
+	serverSideApply := getServerSideApply(d, m)
+	if !serverSideApply {
+		serverSideApply = !setLastAppliedConfig(kmm, gzipLastAppliedConfig)
+	}
 
 	_, err = kmm.mappings()
 	if err != nil {
@@ -211,9 +749,13 @@ func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m in
 		return logError(err)
 	}
 
-	if do.(string) == "" {
+	if do == "" {
 		// diffing for create
-		_, err = kmm.apiCreate(k8smetav1.CreateOptions{DryRun: []string{k8smetav1.DryRunAll}})
+		if serverSideApply {
+			_, err = kmm.apiApplyTakingOwnership(k8smetav1.PatchOptions{DryRun: []string{k8smetav1.DryRunAll}, FieldManager: fm}, getTakeOwnershipOf(d))
+		} else {
+			_, err = kmm.apiCreate(k8smetav1.CreateOptions{DryRun: []string{k8smetav1.DryRunAll}, FieldManager: fm})
+		}
 		if err != nil {
 			if k8serrors.IsAlreadyExists(err) {
 				// this is an edge case during tests
@@ -229,6 +771,11 @@ func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m in
 				return nil
 			}
 
+			if isAdmissionDenial(err) {
+				warnAdmissionDenial(kmm, err)
+				return nil
+			}
+
 			return logError(kmm.fmtErr(err))
 		}
 
@@ -237,27 +784,39 @@ func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m in
 
 	// diffing for update
 	kmo := newKManifest(mapper, client)
-	err = kmo.load([]byte(do.(string)))
+	err = kmo.load([]byte(do))
 	if err != nil {
 		return logError(err)
 	}
+	removeIgnored(d, m, kmo)
 	setLastAppliedConfig(kmo, gzipLastAppliedConfig)
 
-	if kmo.name() != kmm.name() || kmo.namespace() != kmm.namespace() {
-		// if the resource name or namespace changes, we can't patch but have to destroy and re-create
-		d.ForceNew("manifest")
-		return nil
-	}
-
-	pt, p, err := kmm.apiPreparePatch(kmo, true)
-	if err != nil {
-		return logError(err)
-	}
+	dryRunPatch := k8smetav1.PatchOptions{DryRun: []string{k8smetav1.DryRunAll}, FieldManager: fm}
 
-	dryRunPatch := k8smetav1.PatchOptions{DryRun: []string{k8smetav1.DryRunAll}}
+	switch {
+	case serverSideApply:
+		_, err = kmm.apiApplyTakingOwnership(dryRunPatch, getTakeOwnershipOf(d))
+	case d.Get("apply_method").(string) == "replace":
+		_, err = kmm.apiReplace(k8smetav1.UpdateOptions{DryRun: []string{k8smetav1.DryRunAll}, FieldManager: fm})
+		if k8serrors.IsNotFound(err) {
+			// nothing to replace, the resource will be recreated
+			return nil
+		}
+	default:
+		kmm.rawDiff = getRawDiff(d)
+		pt, p, perr := kmm.apiPreparePatch(kmo, true)
+		if perr != nil {
+			return logError(perr)
+		}
 
-	_, err = kmm.apiPatch(pt, p, dryRunPatch)
+		_, err = kmm.apiPatch(pt, p, dryRunPatch)
+	}
 	if err != nil {
+		if isAdmissionDenial(err) {
+			warnAdmissionDenial(kmm, err)
+			return nil
+		}
+
 		// Handle specific invalid errors
 		if k8serrors.IsInvalid(err) {
 			as := err.(k8serrors.APIStatus).Status()
@@ -301,65 +860,204 @@ func kustomizationResourceDiff(ctx context.Context, d *schema.ResourceDiff, m in
 	return nil
 }
 
+// warnAdmissionDenial logs a webhook denying the dry-run of a plan,
+// webhooks may depend on objects created earlier in the same apply,
+// the apply fails with the same error if the webhook still denies it
+func warnAdmissionDenial(km *kManifest, err error) {
+	log.Printf("[WARN] %s, the apply will fail unless the webhook allows the request then", km.fmtErr(err))
+}
+
 func kustomizationResourceUpdate(d *schema.ResourceData, m interface{}) error {
-	client := m.(*Config).Client
-	mapper := m.(*Config).Mapper
+	start := time.Now()
+
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return logError(err)
+	}
 	gzipLastAppliedConfig := m.(*Config).GzipLastAppliedConfig
 
-	do, dm := d.GetChange("manifest")
+	do, dm, err := getManifestChange(d, m)
+	if err != nil {
+		return logError(err)
+	}
+
+	history, err := getManifestHistory(d, m)
+	if err != nil {
+		return logError(err)
+	}
+
+	// apply the previous manifest, the manifest of the
+	// configuration stays in state to be applied again
+	planned := dm
+	rollback := isRollback(d)
+	switch {
+	case rollback && len(history) > 0:
+		dm, history = history[0], history[1:]
+	case d.HasChange("manifest") && do != "":
+		history = append([]string{do}, history...)
+	}
 
 	kmo := newKManifest(mapper, client)
-	err := kmo.load([]byte(do.(string)))
+	err = kmo.load([]byte(do))
 	if err != nil {
 		return logError(err)
 	}
 
 	kmm := newKManifest(mapper, client)
-	err = kmm.load([]byte(dm.(string)))
+	kmm.retry = m.(*Config).Retry
+	err = kmm.load([]byte(dm))
 	if err != nil {
 		return logError(err)
 	}
 
-	if !d.HasChange("manifest") && !d.HasChange("wait") {
+	if !d.HasChanges("manifest", "secrets_hash", "wait", "use_scale_subresource", "apply_status", "apply_method", "wait_load_balancer_cleanup", "server_side_apply", "field_manager", "ignore_fields", "ignore_ca_bundles", "take_ownership_of", "replace_on_change", "history_limit", "rollback_to_previous", "diff_mode", "wait_for_deletion", "min_server_version") {
 		return logError(kmm.fmtErr(
 			errors.New("update called without diff"),
 		))
 	}
 
-	setLastAppliedConfig(kmo, gzipLastAppliedConfig)
-	setLastAppliedConfig(kmm, gzipLastAppliedConfig)
-
-	pt, p, err := kmm.apiPreparePatch(kmo, false)
+	// for objects replacing prior ones, e.g. migration jobs
+	err = kmm.waitPriorDeleted(getWaitForDeletion(d), getTimeout(d, m, schema.TimeoutUpdate))
 	if err != nil {
 		return logError(err)
 	}
 
-	resp, err := kmm.apiPatch(pt, p, k8smetav1.PatchOptions{})
-	if err != nil {
-		return logError(err)
+	if d.Get("use_scale_subresource").(bool) {
+		if replicas, ok := getReplicasOnlyChange(kmo.resource, kmm.resource); ok {
+			// scale first, the patch below then only updates the annotations
+			_, err = kmm.apiScale(replicas, k8smetav1.PatchOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return logError(kmm.fmtErr(err))
+			}
+		}
+	}
+
+	removeIgnored(d, m, kmo)
+	removeIgnored(d, m, kmm)
+	kmm.secrets = getSecretResolver(m)
+	kmm.auditAnnotations = m.(*Config).AuditAnnotations
+	// the audit annotations last applied, for the patch to
+	// remove annotations no longer set on the provider
+	kmo.auditAnnotations = convertMapStringInterfaceToMapStringString(d.Get("audit_annotations").(map[string]interface{}))
+
+	setLastAppliedConfig(kmo, gzipLastAppliedConfig)
+
+	fm := getFieldManager(d, m)
+
+	applyStart := time.Now()
+	var resp *k8sunstructured.Unstructured
+	serverSideApplied := false
+	switch {
+	case getServerSideApply(d, m):
+		serverSideApplied = true
+		resp, err = kmm.apiApplyTakingOwnership(k8smetav1.PatchOptions{FieldManager: fm}, getTakeOwnershipOf(d))
+		if err != nil {
+			return logError(err)
+		}
+	case !setLastAppliedConfig(kmm, gzipLastAppliedConfig):
+		log.Printf("[WARN] %q: manifest exceeds the max annotation size even when compressed, falling back to server-side apply", kmm.id().string())
+		serverSideApplied = true
+		resp, err = kmm.apiApplyTakingOwnership(k8smetav1.PatchOptions{FieldManager: fm}, getTakeOwnershipOf(d))
+		if err != nil {
+			return logError(err)
+		}
+	case d.Get("apply_method").(string) == "replace":
+		resp, err = kmm.apiReplace(k8smetav1.UpdateOptions{FieldManager: fm})
+		if err != nil {
+			return logError(err)
+		}
+	default:
+		kmm.rawDiff = getRawDiff(d)
+		pt, p, err := kmm.apiPreparePatch(kmo, false)
+		if err != nil {
+			return logError(err)
+		}
+
+		resp, err = kmm.apiPatch(pt, p, k8smetav1.PatchOptions{FieldManager: fm})
+		if err != nil {
+			return logError(err)
+		}
+	}
+
+	if d.Get("apply_status").(bool) && kmm.hasStatus() && !k8sequality.Semantic.DeepEqual(kmo.resource.Object["status"], kmm.resource.Object["status"]) {
+		resp, err = kmm.apiPatchStatus(k8smetav1.PatchOptions{FieldManager: fm})
+		if err != nil {
+			return logError(kmm.fmtErr(err))
+		}
 	}
 
-	if d.Get("wait").(bool) {
-		if err = kmm.waitCreatedOrUpdated(d.Timeout(schema.TimeoutUpdate)); err != nil {
+	m.(*Config).Metrics.addApply(kmm.id().string(), time.Since(applyStart))
+
+	if getWait(d, m) {
+		kmm.podLogs = getPodLogs(d, m)
+		kmm.readinessChecks = m.(*Config).ReadinessChecks
+		kmm.skipUnavailableNodes = m.(*Config).SkipUnavailableNodes
+		waitStart := time.Now()
+		err = kmm.waitCreatedOrUpdated(getTimeout(d, m, schema.TimeoutUpdate))
+		m.(*Config).Metrics.addWait(kmm.id().string(), time.Since(waitStart))
+		if err != nil {
 			return logError(err)
 		}
 	}
 
+	if g, ok := kmm.servedGroup(); ok {
+		// make added or removed versions of the group discoverable
+		invalidateGroups(mapper, g)
+	}
+
 	id := string(resp.GetUID())
 	d.SetId(id)
 
-	d.Set("manifest", getLastAppliedConfig(resp, gzipLastAppliedConfig))
+	// only fully server-side applied objects are diffed by the
+	// fields owned by the field manager, see getAppliedManifest
+	d.Set("server_side_applied", serverSideApplied && len(kmm.leftFields) == 0)
 
-	return kustomizationResourceRead(d, m)
+	stateManifest, err := getStateManifest(d, m, resp)
+	if err != nil {
+		return logError(err)
+	}
+	if err := setManifest(d, stateManifest, m); err != nil {
+		return logError(err)
+	}
+
+	if err := setSecretsHash(d, kmm, dm); err != nil {
+		return logError(err)
+	}
+	d.Set("audit_annotations", kmm.auditAnnotations)
+
+	if err := setManifestHistory(d, history, m); err != nil {
+		return logError(err)
+	}
+
+	setApplyTiming(d, start)
+
+	if err := kustomizationResourceRead(d, m); err != nil {
+		return err
+	}
+
+	if rollback {
+		log.Printf("[WARN] %q: rolled back to the previous manifest, the next apply applies the manifest of the configuration again", kmm.id().string())
+		return setManifest(d, planned, m)
+	}
+
+	return nil
 }
 
 func kustomizationResourceDelete(d *schema.ResourceData, m interface{}) error {
-	client := m.(*Config).Client
-	mapper := m.(*Config).Mapper
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return logError(err)
+	}
 
 	km := newKManifest(mapper, client)
+	km.retry = m.(*Config).Retry
 
-	err := parseResourceData(km, d.Get("manifest").(string))
+	manifest, err := getManifest(d, m)
+	if err != nil {
+		return logError(err)
+	}
+
+	err = parseResourceData(km, manifest)
 	if err != nil {
 		return logError(err)
 	}
@@ -387,19 +1085,37 @@ func kustomizationResourceDelete(d *schema.ResourceData, m interface{}) error {
 		return logError(err)
 	}
 
-	err = km.waitDeleted(d.Timeout(schema.TimeoutDelete))
+	timeout := getTimeout(d, m, schema.TimeoutDelete)
+
+	err = km.waitDeleted(timeout)
 	if err != nil {
 		return logError(err)
 	}
 
+	if d.Get("wait_load_balancer_cleanup").(bool) && km.isLoadBalancerService() {
+		// services without the load balancer cleanup finalizer
+		// are gone before the cloud load balancer is deleted
+		err = km.waitLoadBalancerDeleted(d.Id(), timeout)
+		if err != nil {
+			return logError(err)
+		}
+	}
+
+	if g, ok := km.servedGroup(); ok {
+		// stop mapping the removed kinds of the group
+		invalidateGroups(mapper, g)
+	}
+
 	d.SetId("")
 
 	return nil
 }
 
 func kustomizationResourceImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
-	client := m.(*Config).Client
-	mapper := m.(*Config).Mapper
+	client, mapper, err := getClients(d, m)
+	if err != nil {
+		return nil, logError(err)
+	}
 	gzipLastAppliedConfig := m.(*Config).GzipLastAppliedConfig
 
 	k, err := parseProviderId(d.Id())
@@ -438,8 +1154,14 @@ func kustomizationResourceImport(d *schema.ResourceData, m interface{}) ([]*sche
 		)
 	}
 
-	d.Set("manifest", lac)
+	if err := setManifest(d, lac, m); err != nil {
+		return nil, logError(err)
+	}
 	d.Set("wait", d.Get("wait"))
+	d.Set("use_scale_subresource", d.Get("use_scale_subresource"))
+	d.Set("apply_method", d.Get("apply_method"))
+	d.Set("wait_load_balancer_cleanup", d.Get("wait_load_balancer_cleanup"))
+	d.Set("server_side_apply", d.Get("server_side_apply"))
 
 	return []*schema.ResourceData{d}, nil
 }
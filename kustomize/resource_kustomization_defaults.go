@@ -0,0 +1,179 @@
+package kustomize
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// applyDefaults are provider level defaults for kustomization_resource
+// attributes, used if the attribute is not set on the resource
+type applyDefaults struct {
+	ServerSideApply bool
+	Wait            bool
+	FieldManager    string
+	IgnoreFields    []string
+	Timeouts        map[string]time.Duration
+}
+
+var resourceDefaultTimeouts = map[string]time.Duration{
+	schema.TimeoutCreate: 5 * time.Minute,
+	schema.TimeoutUpdate: 5 * time.Minute,
+	schema.TimeoutDelete: 10 * time.Minute,
+}
+
+func getApplyDefaultsSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"server_side_apply": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"wait": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"field_manager": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"ignore_fields": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"create_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDuration,
+			},
+			"update_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDuration,
+			},
+			"delete_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDuration,
+			},
+		},
+	}
+}
+
+func getApplyDefaults(in []interface{}) (ad applyDefaults, err error) {
+	ad.Timeouts = make(map[string]time.Duration)
+
+	if len(in) == 0 || in[0] == nil {
+		return ad, nil
+	}
+
+	o := in[0].(map[string]interface{})
+
+	ad.ServerSideApply = o["server_side_apply"].(bool)
+	ad.Wait = o["wait"].(bool)
+	ad.FieldManager = o["field_manager"].(string)
+	ad.IgnoreFields = convertListInterfaceToListString(o["ignore_fields"].([]interface{}))
+
+	for _, k := range []string{schema.TimeoutCreate, schema.TimeoutUpdate, schema.TimeoutDelete} {
+		v := o[k+"_timeout"].(string)
+		if v == "" {
+			continue
+		}
+
+		ad.Timeouts[k], err = time.ParseDuration(v)
+		if err != nil {
+			return ad, err
+		}
+	}
+
+	return ad, nil
+}
+
+// rawConfigGetter is implemented by both
+// schema.ResourceData and schema.ResourceDiff
+type rawConfigGetter interface {
+	Get(string) interface{}
+	GetRawConfig() cty.Value
+}
+
+// isConfigured returns true if key is set in the resource configuration,
+// as opposed to being unset and having the schema default value
+func isConfigured(d rawConfigGetter, key string) bool {
+	rc := d.GetRawConfig()
+	if rc.IsNull() || !rc.IsKnown() || !rc.Type().IsObjectType() || !rc.Type().HasAttribute(key) {
+		return false
+	}
+
+	return !rc.GetAttr(key).IsNull()
+}
+
+func getWait(d rawConfigGetter, m interface{}) bool {
+	if isConfigured(d, "wait") {
+		return d.Get("wait").(bool)
+	}
+
+	return m.(*Config).ApplyDefaults.Wait
+}
+
+func getServerSideApply(d rawConfigGetter, m interface{}) bool {
+	if isConfigured(d, "server_side_apply") {
+		return d.Get("server_side_apply").(bool)
+	}
+
+	return m.(*Config).ApplyDefaults.ServerSideApply
+}
+
+func getFieldManager(d rawConfigGetter, m interface{}) string {
+	if fm := d.Get("field_manager").(string); fm != "" {
+		return fm
+	}
+
+	if fm := m.(*Config).ApplyDefaults.FieldManager; fm != "" {
+		return fm
+	}
+
+	return fieldManager
+}
+
+func getIgnoreFields(d rawConfigGetter, m interface{}) []string {
+	if fields := convertListInterfaceToListString(d.Get("ignore_fields").([]interface{})); len(fields) > 0 {
+		return fields
+	}
+
+	return m.(*Config).ApplyDefaults.IgnoreFields
+}
+
+func getTimeout(d *schema.ResourceData, m interface{}, key string) time.Duration {
+	t := d.Timeout(key)
+
+	pt, ok := m.(*Config).ApplyDefaults.Timeouts[key]
+	if !ok {
+		return t
+	}
+
+	// timeouts set on the resource take precedence
+	if isTimeoutConfigured(d.GetRawConfig(), key) || t != resourceDefaultTimeouts[key] {
+		return t
+	}
+
+	return pt
+}
+
+func isTimeoutConfigured(rc cty.Value, key string) bool {
+	if rc.IsNull() || !rc.IsKnown() || !rc.Type().IsObjectType() || !rc.Type().HasAttribute("timeouts") {
+		return false
+	}
+
+	t := rc.GetAttr("timeouts")
+	if t.IsNull() || !t.IsKnown() {
+		return false
+	}
+
+	if t.Type().IsObjectType() && t.Type().HasAttribute(key) {
+		return !t.GetAttr(key).IsNull()
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func kustomizationOCIArtifact() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: kustomizationOCIArtifactPush,
+		ReadContext:   kustomizationOCIArtifactRead,
+		UpdateContext: kustomizationOCIArtifactPush,
+		DeleteContext: kustomizationOCIArtifactDelete,
+		CustomizeDiff: kustomizationOCIArtifactDiff,
+
+		Schema: map[string]*schema.Schema{
+			"repository": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"tag": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"manifests": {
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"source": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"revision": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"username": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"password": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"insecure": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"digest": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func getOCIArtifact(d rawConfigGetter) (*ociArtifact, error) {
+	files, err := renderFiles(d.Get("manifests").(map[string]interface{}), false)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make(map[string]string)
+	if s := d.Get("source").(string); s != "" {
+		annotations[ociSourceAnnotation] = s
+	}
+	if r := d.Get("revision").(string); r != "" {
+		annotations[ociRevisionAnnotation] = r
+	}
+
+	return newOCIArtifact(files, annotations)
+}
+
+func getOCIRegistry(d rawConfigGetter) (*ociRegistry, error) {
+	return newOCIRegistry(
+		d.Get("repository").(string),
+		d.Get("username").(string),
+		d.Get("password").(string),
+		d.Get("insecure").(bool),
+	)
+}
+
+func setOCIArtifactDigest(d *schema.ResourceData, digest string) {
+	d.Set("digest", digest)
+
+	url := ""
+	if digest != "" {
+		url = fmt.Sprintf("oci://%s@%s", d.Get("repository").(string), digest)
+	}
+	d.Set("url", url)
+}
+
+func kustomizationOCIArtifactPush(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	a, err := getOCIArtifact(d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationOCIArtifact: %s", err))
+	}
+
+	r, err := getOCIRegistry(d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationOCIArtifact: %s", err))
+	}
+
+	tag := d.Get("tag").(string)
+	digest, err := r.push(ctx, a, tag)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationOCIArtifact: pushing %s:%s failed: %s", d.Get("repository").(string), tag, err))
+	}
+
+	d.SetId(fmt.Sprintf("%s:%s", d.Get("repository").(string), tag))
+	setOCIArtifactDigest(d, digest)
+
+	return nil
+}
+
+func kustomizationOCIArtifactRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	r, err := getOCIRegistry(d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationOCIArtifact: %s", err))
+	}
+
+	// if the tag was moved or deleted, the digest
+	// differs from the planned one and is pushed again
+	digest, err := r.resolve(ctx, d.Get("tag").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationOCIArtifact: %s", err))
+	}
+
+	setOCIArtifactDigest(d, digest)
+
+	return nil
+}
+
+func kustomizationOCIArtifactDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	// many registries do not support deleting, keep the artifact
+	log.Printf("[INFO] %q: removing from state only, the artifact is kept in the registry", d.Id())
+
+	return nil
+}
+
+func kustomizationOCIArtifactDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	for _, k := range []string{"repository", "manifests", "source", "revision"} {
+		if !d.NewValueKnown(k) {
+			d.SetNewComputed("url")
+			return d.SetNewComputed("digest")
+		}
+	}
+
+	a, err := getOCIArtifact(d)
+	if err != nil {
+		return fmt.Errorf("kustomizationOCIArtifact: %s", err)
+	}
+
+	// the artifact is reproducible, the digest is known at plan time
+	digest := a.digest()
+	if d.Get("digest").(string) == digest {
+		return nil
+	}
+
+	err = d.SetNew("digest", digest)
+	if err != nil {
+		return err
+	}
+
+	return d.SetNew("url", fmt.Sprintf("oci://%s@%s", d.Get("repository").(string), digest))
+}
@@ -0,0 +1,285 @@
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	renderSingleFileName   = "manifests.yaml"
+	renderClusterScopedDir = "_cluster"
+)
+
+func kustomizationRender() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: kustomizationRenderCreate,
+		ReadContext:   kustomizationRenderRead,
+		UpdateContext: kustomizationRenderUpdate,
+		DeleteContext: kustomizationRenderDelete,
+		CustomizeDiff: kustomizationRenderDiff,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"manifests": {
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"single_file": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"files": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// renderFileName returns the path of the file for a manifest ID,
+// relative to the render directory, e.g. "example/deployment.apps-example.yaml"
+func renderFileName(id string) (string, error) {
+	k, err := parseProviderId(id)
+	if err != nil {
+		return "", err
+	}
+
+	dir := k.namespace
+	if dir == "" {
+		dir = renderClusterScopedDir
+	}
+
+	name := strings.ToLower(k.kind)
+	if k.group != "" {
+		name = name + "." + k.group
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.yaml", name, k.name)), nil
+}
+
+// renderFiles converts the JSON manifests to YAML and returns
+// the content of the files by path relative to the render directory
+func renderFiles(manifests map[string]interface{}, singleFile bool) (map[string][]byte, error) {
+	ids := make([]string, 0, len(manifests))
+	for id := range manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	files := make(map[string][]byte)
+	var single bytes.Buffer
+	for _, id := range ids {
+		y, err := yaml.JSONToYAML([]byte(manifests[id].(string)))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", id, err)
+		}
+
+		if singleFile {
+			single.WriteString("---\n")
+			single.Write(y)
+			continue
+		}
+
+		name, err := renderFileName(id)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = y
+	}
+
+	if singleFile && len(ids) > 0 {
+		files[renderSingleFileName] = single.Bytes()
+	}
+
+	return files, nil
+}
+
+func hashFileContent(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func hashFiles(files map[string][]byte) map[string]interface{} {
+	hashes := make(map[string]interface{})
+	for name, content := range files {
+		hashes[name] = hashFileContent(content)
+	}
+
+	return hashes
+}
+
+func writeRenderedFiles(path string, files map[string][]byte) error {
+	for name, content := range files {
+		p := filepath.Join(path, name)
+
+		err := os.MkdirAll(filepath.Dir(p), 0755)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(p, content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeRenderedFiles removes the files and their
+// directories, if the directories are empty afterwards
+func removeRenderedFiles(path string, names []string) error {
+	for _, name := range names {
+		p := filepath.Join(path, name)
+
+		err := os.Remove(p)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		// only removes empty directories
+		if dir := filepath.Dir(p); dir != filepath.Clean(path) {
+			os.Remove(dir)
+		}
+	}
+
+	return nil
+}
+
+func kustomizationRenderApply(d *schema.ResourceData) error {
+	path := d.Get("path").(string)
+
+	files, err := renderFiles(d.Get("manifests").(map[string]interface{}), d.Get("single_file").(bool))
+	if err != nil {
+		return err
+	}
+
+	err = writeRenderedFiles(path, files)
+	if err != nil {
+		return err
+	}
+
+	// clean up files of removed manifests
+	o, _ := d.GetChange("files")
+	var removed []string
+	for name := range o.(map[string]interface{}) {
+		if _, ok := files[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	err = removeRenderedFiles(path, removed)
+	if err != nil {
+		return err
+	}
+
+	d.Set("files", hashFiles(files))
+
+	return nil
+}
+
+func kustomizationRenderCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	err := kustomizationRenderApply(d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationRender: %s", err))
+	}
+
+	d.SetId(d.Get("path").(string))
+
+	return nil
+}
+
+func kustomizationRenderRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path := d.Get("path").(string)
+
+	// files that were changed or removed outside of Terraform
+	// get a different hash or are missing, and are rewritten
+	hashes := make(map[string]interface{})
+	for name := range d.Get("files").(map[string]interface{}) {
+		content, err := ioutil.ReadFile(filepath.Join(path, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("kustomizationRender: %s", err))
+		}
+
+		hashes[name] = hashFileContent(content)
+	}
+
+	d.Set("files", hashes)
+
+	return nil
+}
+
+func kustomizationRenderUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	err := kustomizationRenderApply(d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationRender: %s", err))
+	}
+
+	return nil
+}
+
+func kustomizationRenderDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var names []string
+	for name := range d.Get("files").(map[string]interface{}) {
+		names = append(names, name)
+	}
+
+	err := removeRenderedFiles(d.Get("path").(string), names)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("kustomizationRender: %s", err))
+	}
+
+	return nil
+}
+
+func kustomizationRenderDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("manifests") || !d.NewValueKnown("single_file") {
+		return d.SetNewComputed("files")
+	}
+
+	files, err := renderFiles(d.Get("manifests").(map[string]interface{}), d.Get("single_file").(bool))
+	if err != nil {
+		return fmt.Errorf("kustomizationRender: %s", err)
+	}
+
+	hashes := hashFiles(files)
+
+	o := d.Get("files").(map[string]interface{})
+	if len(o) == len(hashes) {
+		changed := false
+		for name, h := range hashes {
+			if o[name] != h {
+				changed = true
+				break
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+	}
+
+	return d.SetNew("files", hashes)
+}
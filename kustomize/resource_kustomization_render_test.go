@@ -0,0 +1,72 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRenderManifests = map[string]interface{}{
+	"_/Namespace/_/test-render":           `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test-render"}}`,
+	"apps/Deployment/test-render/test":    `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test-render"}}`,
+	"_/ConfigMap/test-render/test-config": `{"apiVersion":"v1","data":{"key":"value"},"kind":"ConfigMap","metadata":{"name":"test-config","namespace":"test-render"}}`,
+}
+
+func TestRenderFileName(t *testing.T) {
+	for id, expected := range map[string]string{
+		"_/Namespace/_/test-render":        "_cluster/namespace-test-render.yaml",
+		"apps/Deployment/test-render/test": "test-render/deployment.apps-test.yaml",
+	} {
+		name, err := renderFileName(id)
+		assert.Equal(t, nil, err, id)
+		assert.Equal(t, expected, name, id)
+	}
+
+	_, err := renderFileName("invalid")
+	assert.Error(t, err, nil)
+}
+
+func TestRenderFiles(t *testing.T) {
+	files, err := renderFiles(testRenderManifests, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 3, len(files), nil)
+	assert.Equal(t, "apiVersion: v1\ndata:\n  key: value\nkind: ConfigMap\nmetadata:\n  name: test-config\n  namespace: test-render\n", string(files["test-render/configmap-test-config.yaml"]), nil)
+
+	files, err = renderFiles(testRenderManifests, true)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 1, len(files), nil)
+	assert.Contains(t, string(files[renderSingleFileName]), "---\napiVersion: v1\nkind: Namespace\n", nil)
+
+	files, err = renderFiles(map[string]interface{}{}, true)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(files), nil)
+}
+
+func TestWriteAndRemoveRenderedFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "terraform-provider-kustomization-render-*")
+	assert.Equal(t, nil, err, nil)
+	defer os.RemoveAll(tmp)
+
+	files, err := renderFiles(testRenderManifests, false)
+	assert.Equal(t, nil, err, nil)
+
+	err = writeRenderedFiles(tmp, files)
+	assert.Equal(t, nil, err, nil)
+
+	content, err := ioutil.ReadFile(filepath.Join(tmp, "test-render/deployment.apps-test.yaml"))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, files["test-render/deployment.apps-test.yaml"], content, nil)
+
+	// directories are only removed once empty
+	err = removeRenderedFiles(tmp, []string{"_cluster/namespace-test-render.yaml", "test-render/deployment.apps-test.yaml"})
+	assert.Equal(t, nil, err, nil)
+
+	_, err = os.Stat(filepath.Join(tmp, "_cluster"))
+	assert.True(t, os.IsNotExist(err), nil)
+
+	_, err = os.Stat(filepath.Join(tmp, "test-render/configmap-test-config.yaml"))
+	assert.Equal(t, nil, err, nil)
+}
@@ -33,6 +33,11 @@ func TestAccResourceKustomization_basic(t *testing.T) {
 					resource.TestCheckResourceAttrSet("kustomization_resource.ns", "id"),
 					resource.TestCheckResourceAttrSet("kustomization_resource.svc", "id"),
 					resource.TestCheckResourceAttrSet("kustomization_resource.dep1", "id"),
+					resource.TestCheckResourceAttr("kustomization_resource.dep1", "api_version", "apps/v1"),
+					resource.TestCheckResourceAttr("kustomization_resource.dep1", "kind", "Deployment"),
+					resource.TestCheckResourceAttr("kustomization_resource.dep1", "name", "test"),
+					resource.TestCheckResourceAttr("kustomization_resource.dep1", "namespace", "test-basic"),
+					resource.TestCheckResourceAttr("kustomization_resource.ns", "namespace", ""),
 				),
 			},
 			//
@@ -64,10 +69,11 @@ func TestAccResourceKustomization_basic(t *testing.T) {
 			//
 			// Test state import
 			{
-				ResourceName:      "kustomization_resource.ns",
-				ImportStateId:     "_/Namespace/_/test-basic",
-				ImportState:       true,
-				ImportStateVerify: true,
+				ResourceName:            "kustomization_resource.ns",
+				ImportStateId:           "_/Namespace/_/test-basic",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_applied_at", "apply_duration"},
 			},
 		},
 	})
@@ -116,11 +122,12 @@ func TestAccResourceKustomization_importInvalidID(t *testing.T) {
 			//
 			// Test state import
 			{
-				ResourceName:      "kustomization_resource.ns",
-				ImportStateId:     "invalidID",
-				ImportState:       true,
-				ImportStateVerify: true,
-				ExpectError:       regexp.MustCompile("invalid ID: \"invalidID\", valid IDs look like: \"_/Namespace/_/example\""),
+				ResourceName:            "kustomization_resource.ns",
+				ImportStateId:           "invalidID",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_applied_at", "apply_duration"},
+				ExpectError:             regexp.MustCompile("invalid ID: \"invalidID\", valid IDs look like: \"_/Namespace/_/example\""),
 			},
 		},
 	})
@@ -176,10 +183,11 @@ func TestAccResourceKustomization_updateInplace(t *testing.T) {
 			//
 			// Test state import
 			{
-				ResourceName:      "kustomization_resource.ns",
-				ImportStateId:     "_/Namespace/_/test-update-inplace",
-				ImportState:       true,
-				ImportStateVerify: true,
+				ResourceName:            "kustomization_resource.ns",
+				ImportStateId:           "_/Namespace/_/test-update-inplace",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_applied_at", "apply_duration"},
 			},
 		},
 	})
@@ -201,6 +209,63 @@ resource "kustomization_resource" "dep1" {
 `
 }
 
+// Update_Replace Test
+func TestAccResourceKustomization_updateReplace(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		//PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			//
+			//
+			// Applying initial config with a deployment in a namespace
+			{
+				Config: testAccResourceKustomizationConfig_updateReplace("test_kustomizations/update_inplace/initial"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("kustomization_resource.ns", "id"),
+					resource.TestCheckResourceAttrSet("kustomization_resource.dep1", "id"),
+				),
+			},
+			//
+			//
+			// Applying modified config replacing the deployment
+			{
+				Config: testAccResourceKustomizationConfig_updateReplace("test_kustomizations/update_inplace/modified"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("kustomization_resource.ns", "id"),
+					resource.TestCheckResourceAttrSet("kustomization_resource.dep1", "id"),
+					testAccCheckManifestAnnotation("kustomization_resource.dep1", "test_annotation", "added"),
+				),
+			},
+			//
+			//
+			// Applying initial config again, ensure annotation is removed again
+			{
+				Config: testAccResourceKustomizationConfig_updateReplace("test_kustomizations/update_inplace/initial"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("kustomization_resource.ns", "id"),
+					resource.TestCheckResourceAttrSet("kustomization_resource.dep1", "id"),
+					testAccCheckManifestAnnotationAbsent("kustomization_resource.dep1", "test_annotation"),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceKustomizationConfig_updateReplace(path string) string {
+	return testAccDataSourceKustomizationConfig_basic(path) + `
+resource "kustomization_resource" "ns" {
+	manifest = data.kustomization_build.test.manifests["_/Namespace/_/test-update-inplace"]
+}
+
+resource "kustomization_resource" "dep1" {
+	manifest = data.kustomization_build.test.manifests["apps/Deployment/test-update-inplace/test"]
+
+	apply_method = "replace"
+}
+`
+}
+
 // Update_Recreate Test
 func TestAccResourceKustomization_updateRecreate(t *testing.T) {
 
@@ -247,10 +312,11 @@ func TestAccResourceKustomization_updateRecreate(t *testing.T) {
 			//
 			// Test state import
 			{
-				ResourceName:      "kustomization_resource.ns",
-				ImportStateId:     "_/Namespace/_/test-update-recreate",
-				ImportState:       true,
-				ImportStateVerify: true,
+				ResourceName:            "kustomization_resource.ns",
+				ImportStateId:           "_/Namespace/_/test-update-recreate",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_applied_at", "apply_duration"},
 			},
 		},
 	})
@@ -784,10 +850,11 @@ func TestAccResourceKustomization_crd(t *testing.T) {
 			//
 			// Test state import
 			{
-				ResourceName:      "kustomization_resource.clusteredcrd",
-				ImportStateId:     "apiextensions.k8s.io/CustomResourceDefinition/_/clusteredcrds.test.example.com",
-				ImportState:       true,
-				ImportStateVerify: true,
+				ResourceName:            "kustomization_resource.clusteredcrd",
+				ImportStateId:           "apiextensions.k8s.io/CustomResourceDefinition/_/clusteredcrds.test.example.com",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_applied_at", "apply_duration"},
 			},
 		},
 	})
@@ -925,10 +992,11 @@ func TestAccResourceKustomization_webhook(t *testing.T) {
 			//
 			// Test state import
 			{
-				ResourceName:      "kustomization_resource.webhook",
-				ImportStateId:     "admissionregistration.k8s.io/ValidatingWebhookConfiguration/_/pod-policy.example.com",
-				ImportState:       true,
-				ImportStateVerify: true,
+				ResourceName:            "kustomization_resource.webhook",
+				ImportStateId:           "admissionregistration.k8s.io/ValidatingWebhookConfiguration/_/pod-policy.example.com",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_applied_at", "apply_duration"},
 			},
 		},
 	})
@@ -1046,13 +1114,144 @@ resource "kustomization_resource" "dep1" {
 `
 }
 
+// Kind or scope change test
+func TestAccResourceKustomization_updateRecreateKindOrScopeChange(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			//
+			//
+			// Applying initial config with a namespaced configmap
+			{
+				Config: testAccResourceKustomizationConfig_updateRecreateKindOrScopeChange("ConfigMap", "test-kind-or-scope-change"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("kustomization_resource.test[\"_/ConfigMap/test-kind-or-scope-change/test\"]", "kind", "ConfigMap"),
+				),
+			},
+			//
+			//
+			// Applying modified config changing the kind, the moved
+			// block replaces the resource instead of destroying it
+			{
+				Config: testAccResourceKustomizationConfig_updateRecreateKindOrScopeChange("Secret", "test-kind-or-scope-change") + `
+moved {
+	from = kustomization_resource.test["_/ConfigMap/test-kind-or-scope-change/test"]
+	to   = kustomization_resource.test["_/Secret/test-kind-or-scope-change/test"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("kustomization_resource.test[\"_/Secret/test-kind-or-scope-change/test\"]", "kind", "Secret"),
+					resource.TestCheckResourceAttr("kustomization_resource.test[\"_/Secret/test-kind-or-scope-change/test\"]", "namespace", "test-kind-or-scope-change"),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceKustomizationConfig_updateRecreateKindOrScopeChange(kind string, namespace string) string {
+	return fmt.Sprintf(`
+resource "kustomization_resource" "ns" {
+	manifest = jsonencode({
+		apiVersion = "v1"
+		kind       = "Namespace"
+		metadata   = { name = %[2]q }
+	})
+}
+
+locals {
+	manifest = jsonencode({
+		apiVersion = "v1"
+		kind       = %[1]q
+		metadata   = { name = "test", namespace = %[2]q }
+		data       = {}
+	})
+}
+
+resource "kustomization_resource" "test" {
+	for_each = { "_/%[1]s/%[2]s/test" = local.manifest }
+
+	manifest = each.value
+
+	depends_on = [kustomization_resource.ns]
+}
+`, kind, namespace)
+}
+
+func TestIdentityChanges(t *testing.T) {
+	load := func(m string) *kManifest {
+		km := newKManifest(nil, nil)
+		if err := km.load([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+		return km
+	}
+
+	cm := load(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"}}`)
+
+	testCases := []struct {
+		manifest string
+		expected []string
+	}{
+		{`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"},"data":{"key":"value"}}`, nil},
+		{`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"test","namespace":"test"}}`, []string{"kind"}},
+		{`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"changed","namespace":"test"}}`, []string{"name"}},
+		{`{"apiVersion":"example.com/v1","kind":"Config","metadata":{"name":"test"}}`, []string{"kind", "namespace"}},
+	}
+
+	for _, tc := range testCases {
+		changed := identityChanges(cm, load(tc.manifest))
+		if strings.Join(changed, ",") != strings.Join(tc.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tc.manifest, tc.expected, changed)
+		}
+	}
+
+	// API version upgrades are updated in place
+	ing := load(`{"apiVersion":"extensions/v1beta1","kind":"Ingress","metadata":{"name":"test","namespace":"test"}}`)
+	if changed := identityChanges(ing, load(`{"apiVersion":"networking.k8s.io/v1","kind":"Ingress","metadata":{"name":"test","namespace":"test"}}`)); len(changed) > 0 {
+		t.Errorf("API version upgrade: expected no changes, got %v", changed)
+	}
+}
+
+func TestFieldChanges(t *testing.T) {
+	load := func(m string) *kManifest {
+		km := newKManifest(nil, nil)
+		if err := km.load([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+		return km
+	}
+
+	sts := load(`{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":1,"serviceName":"test","volumeClaimTemplates":[{"metadata":{"name":"data"}}]}}`)
+	paths := []string{"spec.volumeClaimTemplates", "spec.serviceName", "spec.selector"}
+
+	testCases := []struct {
+		manifest string
+		expected []string
+	}{
+		{`{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":2,"serviceName":"test","volumeClaimTemplates":[{"metadata":{"name":"data"}}]}}`, nil},
+		{`{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":1,"serviceName":"changed","volumeClaimTemplates":[{"metadata":{"name":"data"}}]}}`, []string{"spec.serviceName"}},
+		{`{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":1,"serviceName":"test"}}`, []string{"spec.volumeClaimTemplates"}},
+	}
+
+	for _, tc := range testCases {
+		changed := fieldChanges(sts, load(tc.manifest), paths)
+		if strings.Join(changed, ",") != strings.Join(tc.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tc.manifest, tc.expected, changed)
+		}
+	}
+}
+
 //
 //
 // Test check functions
 
 func testAccCheckDeploymentPurged(n string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(*Config).Client
+		client, _, err := testAccProvider.Meta().(*Config).getClients()
+		if err != nil {
+			return err
+		}
 
 		gvr := k8sschema.GroupVersionResource{
 			Group:    "apps",
@@ -1140,8 +1339,10 @@ func getResourceFromTestState(s *terraform.State, n string) (ur *k8sunstructured
 }
 
 func getResourceFromK8sAPI(u *k8sunstructured.Unstructured) (resp *k8sunstructured.Unstructured, err error) {
-	client := testAccProvider.Meta().(*Config).Client
-	mapper := testAccProvider.Meta().(*Config).Mapper
+	client, mapper, err := testAccProvider.Meta().(*Config).getClients()
+	if err != nil {
+		return nil, err
+	}
 
 	mapping, err := mapper.RESTMapping(u.GroupVersionKind().GroupKind(), u.GroupVersionKind().Version)
 	if err != nil {
@@ -0,0 +1,175 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// retryPolicy retries requests of kustomization_resources failing with
+// a retryable status code or an error matching any of the patterns,
+// backing off exponentially from initialBackoff up to maxBackoff
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	statusCodes    map[int32]bool
+	errorPatterns  []*regexp.Regexp
+
+	sleep func(d time.Duration)
+}
+
+func getRetrySchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"max_attempts": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      5,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"initial_backoff": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "1s",
+				ValidateFunc: validateDuration,
+			},
+			"max_backoff": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "30s",
+				ValidateFunc: validateDuration,
+			},
+			"retryable_status_codes": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeInt,
+					ValidateFunc: validation.IntBetween(400, 599),
+				},
+			},
+			"retryable_errors": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// defaultRetryableStatusCodes are retried if the retry
+// block does not set retryable_status_codes
+var defaultRetryableStatusCodes = []int{500, 502, 503, 504}
+
+func getRetryPolicy(in []interface{}) (*retryPolicy, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	// an empty retry block is a nil element
+	o := map[string]interface{}{
+		"max_attempts":           5,
+		"initial_backoff":        "1s",
+		"max_backoff":            "30s",
+		"retryable_status_codes": []interface{}{},
+		"retryable_errors":       []interface{}{},
+	}
+	if in[0] != nil {
+		o = in[0].(map[string]interface{})
+	}
+
+	p := &retryPolicy{
+		maxAttempts: o["max_attempts"].(int),
+		statusCodes: make(map[int32]bool),
+		sleep:       time.Sleep,
+	}
+
+	var err error
+	p.initialBackoff, err = time.ParseDuration(o["initial_backoff"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("initial_backoff: %s", err)
+	}
+
+	p.maxBackoff, err = time.ParseDuration(o["max_backoff"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("max_backoff: %s", err)
+	}
+
+	if p.maxBackoff < p.initialBackoff {
+		return nil, fmt.Errorf("max_backoff %s is less than initial_backoff %s", p.maxBackoff, p.initialBackoff)
+	}
+
+	codes := o["retryable_status_codes"].([]interface{})
+	if len(codes) == 0 {
+		for _, c := range defaultRetryableStatusCodes {
+			codes = append(codes, c)
+		}
+	}
+	for _, c := range codes {
+		if c.(int) == http.StatusTooManyRequests {
+			return nil, fmt.Errorf("retryable_status_codes: 429 is retried by the Kubernetes client already")
+		}
+		p.statusCodes[int32(c.(int))] = true
+	}
+
+	for _, e := range convertListInterfaceToListString(o["retryable_errors"].([]interface{})) {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("retryable_errors: %s", err)
+		}
+		p.errorPatterns = append(p.errorPatterns, re)
+	}
+
+	return p, nil
+}
+
+// retryable returns true if err has a retryable status
+// code or its message matches any of the error patterns,
+// except for throttled requests
+func (p *retryPolicy) retryable(err error) bool {
+	// throttled requests were retried by the client already,
+	// retrying them again would multiply the attempts
+	if k8serrors.IsTooManyRequests(err) {
+		return false
+	}
+
+	var status k8serrors.APIStatus
+	if errors.As(err, &status) && p.statusCodes[status.Status().Code] {
+		return true
+	}
+
+	return matchesAny(err.Error(), p.errorPatterns)
+}
+
+// do calls f until it succeeds, fails with an error that is not
+// retryable or the attempts are exhausted, f is called once if the
+// provider does not configure a retry policy
+func (p *retryPolicy) do(id string, f func() error) (err error) {
+	if p == nil {
+		return f()
+	}
+
+	backoff := p.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = f()
+		if err == nil || attempt >= p.maxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		log.Printf("[WARN] %q: attempt %d of %d failed, retrying in %s: %s", id, attempt, p.maxAttempts, backoff, err)
+		p.sleep(backoff)
+
+		backoff *= 2
+		if backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package kustomize
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGetRetryPolicy(t *testing.T) {
+	p, err := getRetryPolicy(nil)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, (*retryPolicy)(nil), p, nil)
+
+	// empty retry block
+	p, err = getRetryPolicy([]interface{}{nil})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 5, p.maxAttempts, nil)
+	assert.Equal(t, time.Second, p.initialBackoff, nil)
+	assert.Equal(t, 30*time.Second, p.maxBackoff, nil)
+	assert.Equal(t, map[int32]bool{500: true, 502: true, 503: true, 504: true}, p.statusCodes, nil)
+
+	p, err = getRetryPolicy([]interface{}{map[string]interface{}{
+		"max_attempts":           3,
+		"initial_backoff":        "2s",
+		"max_backoff":            "10s",
+		"retryable_status_codes": []interface{}{409},
+		"retryable_errors":       []interface{}{"failed calling webhook"},
+	}})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, map[int32]bool{409: true}, p.statusCodes, nil)
+	assert.Equal(t, 1, len(p.errorPatterns), nil)
+
+	_, err = getRetryPolicy([]interface{}{map[string]interface{}{
+		"max_attempts":           3,
+		"initial_backoff":        "20s",
+		"max_backoff":            "10s",
+		"retryable_status_codes": []interface{}{},
+		"retryable_errors":       []interface{}{},
+	}})
+	assert.NotEqual(t, nil, err, nil)
+
+	_, err = getRetryPolicy([]interface{}{map[string]interface{}{
+		"max_attempts":           3,
+		"initial_backoff":        "1s",
+		"max_backoff":            "10s",
+		"retryable_status_codes": []interface{}{},
+		"retryable_errors":       []interface{}{"("},
+	}})
+	assert.NotEqual(t, nil, err, nil)
+
+	// throttled requests are retried by the client
+	_, err = getRetryPolicy([]interface{}{map[string]interface{}{
+		"max_attempts":           3,
+		"initial_backoff":        "1s",
+		"max_backoff":            "10s",
+		"retryable_status_codes": []interface{}{429},
+		"retryable_errors":       []interface{}{},
+	}})
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var slept []time.Duration
+	p := &retryPolicy{
+		maxAttempts:    4,
+		initialBackoff: time.Second,
+		maxBackoff:     3 * time.Second,
+		statusCodes:    map[int32]bool{429: true, 503: true},
+		sleep:          func(d time.Duration) { slept = append(slept, d) },
+	}
+	p.errorPatterns, _ = compileIgnorePatterns([]string{".*failed calling webhook.*"})
+
+	gr := k8sschema.GroupResource{Resource: "configmaps"}
+	unavailable := k8serrors.NewServiceUnavailable("unavailable")
+	webhook := k8serrors.NewInternalError(fmt.Errorf(`failed calling webhook "example.com": context deadline exceeded`))
+	notFound := k8serrors.NewNotFound(gr, "test")
+	throttled := k8serrors.NewTooManyRequests("throttled", 1)
+
+	testCases := []struct {
+		errs     []error
+		calls    int
+		expected error
+		slept    []time.Duration
+	}{
+		{[]error{nil}, 1, nil, nil},
+		{[]error{unavailable, nil}, 2, nil, []time.Duration{time.Second}},
+		{[]error{webhook, webhook, nil}, 3, nil, []time.Duration{time.Second, 2 * time.Second}},
+		{[]error{notFound}, 1, notFound, nil},
+		{[]error{throttled}, 1, throttled, nil},
+		{[]error{unavailable, unavailable, unavailable, unavailable, nil}, 4, unavailable, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+	}
+
+	for i, tc := range testCases {
+		slept = nil
+		calls := 0
+		err := p.do("_/ConfigMap/test/test", func() error {
+			err := tc.errs[calls]
+			calls++
+			return err
+		})
+		assert.Equal(t, tc.expected, err, i)
+		assert.Equal(t, tc.calls, calls, i)
+		assert.Equal(t, tc.slept, slept, i)
+	}
+
+	// without a policy, f is called once
+	calls := 0
+	var nilPolicy *retryPolicy
+	err := nilPolicy.do("", func() error {
+		calls++
+		return unavailable
+	})
+	assert.Equal(t, error(unavailable), err, nil)
+	assert.Equal(t, 1, calls, nil)
+}
@@ -0,0 +1,184 @@
+package kustomize
+
+import (
+	"fmt"
+
+	k8sappsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// rolloutStatus of a workload, determined with the same
+// conditions as kubectl rollout status
+type rolloutStatus struct {
+	done bool
+
+	// failed rollouts do not progress anymore, e.g. because
+	// the progress deadline of a deployment was exceeded
+	failed bool
+
+	progress string
+}
+
+type rolloutStatusFunction func(u *k8sunstructured.Unstructured) (rolloutStatus, error)
+
+func waitingFor(format string, a ...interface{}) rolloutStatus {
+	return rolloutStatus{progress: fmt.Sprintf(format, a...)}
+}
+
+// deploymentRolloutStatus uses the available replicas, which
+// are only available after minReadySeconds
+func deploymentRolloutStatus(u *k8sunstructured.Unstructured) (rolloutStatus, error) {
+	var deployment k8sappsv1.Deployment
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &deployment); err != nil {
+		return rolloutStatus{}, err
+	}
+
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return waitingFor("waiting for deployment spec update to be observed"), nil
+	}
+
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == k8sappsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return rolloutStatus{failed: true, progress: fmt.Sprintf("deployment exceeded its progress deadline: %s", c.Message)}, nil
+		}
+	}
+
+	var replicas int32 = 1
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	s := deployment.Status
+	switch {
+	case s.UpdatedReplicas < replicas:
+		return waitingFor("%d out of %d new replicas have been updated", s.UpdatedReplicas, replicas), nil
+	case s.Replicas > s.UpdatedReplicas:
+		return waitingFor("%d old replicas are pending termination", s.Replicas-s.UpdatedReplicas), nil
+	case s.AvailableReplicas < s.UpdatedReplicas:
+		return waitingFor("%d of %d updated replicas are available", s.AvailableReplicas, s.UpdatedReplicas), nil
+	}
+
+	return rolloutStatus{done: true}, nil
+}
+
+// daemonSetRolloutStatus uses the available pods, which are only
+// available after minReadySeconds, the pace of the rollout is set
+// by maxUnavailable and maxSurge of the update strategy
+func daemonSetRolloutStatus(u *k8sunstructured.Unstructured) (rolloutStatus, error) {
+	return daemonSetRolloutStatusSkipping(u, 0)
+}
+
+// daemonSetRolloutStatusSkipping does not wait for skipped pods,
+// e.g. the pods on nodes that are cordoned or not ready
+func daemonSetRolloutStatusSkipping(u *k8sunstructured.Unstructured, skipped int32) (rolloutStatus, error) {
+	var daemonset k8sappsv1.DaemonSet
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &daemonset); err != nil {
+		return rolloutStatus{}, err
+	}
+
+	// pods of other strategies are only updated when deleted
+	if daemonset.Spec.UpdateStrategy.Type != k8sappsv1.RollingUpdateDaemonSetStrategyType {
+		return rolloutStatus{done: true}, nil
+	}
+
+	if daemonset.Generation > daemonset.Status.ObservedGeneration {
+		return waitingFor("waiting for daemon set spec update to be observed"), nil
+	}
+
+	s := daemonset.Status
+	desired := s.DesiredNumberScheduled - skipped
+	switch {
+	case s.UpdatedNumberScheduled < desired:
+		return waitingFor("%d out of %d new pods have been updated", s.UpdatedNumberScheduled, desired), nil
+	case s.NumberAvailable < desired:
+		return waitingFor("%d of %d updated pods are available", s.NumberAvailable, desired), nil
+	}
+
+	return rolloutStatus{done: true}, nil
+}
+
+// statefulSetRolloutStatus only waits for the pods above the partition
+// of partitioned rolling updates, with minReadySeconds the pods
+// have to be available instead of ready
+func statefulSetRolloutStatus(u *k8sunstructured.Unstructured) (rolloutStatus, error) {
+	var sts k8sappsv1.StatefulSet
+	if err := k8sruntime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &sts); err != nil {
+		return rolloutStatus{}, err
+	}
+
+	// pods of other strategies are only updated when deleted
+	if sts.Spec.UpdateStrategy.Type != k8sappsv1.RollingUpdateStatefulSetStrategyType {
+		return rolloutStatus{done: true}, nil
+	}
+
+	if sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration {
+		return waitingFor("waiting for statefulset spec update to be observed"), nil
+	}
+
+	var replicas int32 = 1
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	ready := sts.Status.ReadyReplicas
+	if sts.Spec.MinReadySeconds > 0 {
+		ready = sts.Status.AvailableReplicas
+	}
+	if ready < replicas {
+		return waitingFor("waiting for %d pods to be ready", replicas-ready), nil
+	}
+
+	if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		if updated := replicas - *ru.Partition; sts.Status.UpdatedReplicas < updated {
+			return waitingFor("waiting for partitioned roll out to finish: %d out of %d new pods have been updated", sts.Status.UpdatedReplicas, updated), nil
+		}
+		return rolloutStatus{done: true}, nil
+	}
+
+	if sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		return waitingFor("waiting for statefulset rolling update to complete %d pods at revision %s", sts.Status.UpdatedReplicas, sts.Status.UpdateRevision), nil
+	}
+
+	return rolloutStatus{done: true}, nil
+}
+
+func deploymentReady(u *k8sunstructured.Unstructured) (bool, error) {
+	status, err := deploymentRolloutStatus(u)
+	return status.done, err
+}
+
+// waitRolloutRefresh returns a refresh function that waits until the
+// rollout of the workload is done, and ends the wait if it failed
+func waitRolloutRefresh(status rolloutStatusFunction) waitRefreshFunction {
+	return func(km *kManifest) (interface{}, string, error) {
+		resp, err := km.apiGet(k8smetav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, "missing", nil
+			}
+			return nil, "error", err
+		}
+
+		s, err := status(resp)
+		if err != nil {
+			return nil, "error", err
+		}
+		if s.failed {
+			return nil, "error", fmt.Errorf("rollout failed: %s", s.progress)
+		}
+		if s.done {
+			return resp, "done", nil
+		}
+
+		// pods that can not start fail the wait early
+		if err := km.failedPod(resp); err != nil {
+			return nil, "error", fmt.Errorf("rollout failed: %s", err)
+		}
+
+		km.logProgress(s.progress)
+		return nil, "in progress", nil
+	}
+}
@@ -0,0 +1,148 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func testRolloutObject(t *testing.T, y string) *k8sunstructured.Unstructured {
+	u := &k8sunstructured.Unstructured{}
+	assert.Equal(t, nil, yaml.Unmarshal([]byte(y), &u.Object), nil)
+	return u
+}
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected rolloutStatus
+	}{
+		{"not observed", `
+metadata: {generation: 2}
+spec: {replicas: 2}
+status: {observedGeneration: 1}
+`, rolloutStatus{progress: "waiting for deployment spec update to be observed"}},
+		{"progress deadline exceeded", `
+metadata: {generation: 1}
+spec: {replicas: 2}
+status:
+  observedGeneration: 1
+  conditions:
+  - {type: Progressing, status: "False", reason: ProgressDeadlineExceeded, message: ReplicaSet "test" has timed out progressing.}
+`, rolloutStatus{failed: true, progress: `deployment exceeded its progress deadline: ReplicaSet "test" has timed out progressing.`}},
+		{"updating", `
+metadata: {generation: 1}
+spec: {replicas: 2}
+status: {observedGeneration: 1, replicas: 2, updatedReplicas: 1}
+`, rolloutStatus{progress: "1 out of 2 new replicas have been updated"}},
+		{"old replicas", `
+metadata: {generation: 1}
+spec: {replicas: 2}
+status: {observedGeneration: 1, replicas: 3, updatedReplicas: 2}
+`, rolloutStatus{progress: "1 old replicas are pending termination"}},
+		{"min ready seconds", `
+metadata: {generation: 1}
+spec: {replicas: 2, minReadySeconds: 30}
+status: {observedGeneration: 1, replicas: 2, updatedReplicas: 2, readyReplicas: 2, availableReplicas: 1}
+`, rolloutStatus{progress: "1 of 2 updated replicas are available"}},
+		{"done", `
+metadata: {generation: 1}
+spec: {replicas: 2}
+status: {observedGeneration: 1, replicas: 2, updatedReplicas: 2, availableReplicas: 2}
+`, rolloutStatus{done: true}},
+	}
+
+	for _, tc := range testCases {
+		status, err := deploymentRolloutStatus(testRolloutObject(t, tc.manifest))
+		assert.Equal(t, nil, err, tc.name)
+		assert.Equal(t, tc.expected, status, tc.name)
+	}
+}
+
+func TestDaemonSetRolloutStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected rolloutStatus
+	}{
+		{"on delete", `
+metadata: {generation: 2}
+spec: {updateStrategy: {type: OnDelete}}
+status: {observedGeneration: 1}
+`, rolloutStatus{done: true}},
+		{"updating", `
+metadata: {generation: 1}
+spec: {updateStrategy: {type: RollingUpdate, rollingUpdate: {maxUnavailable: 1}}}
+status: {observedGeneration: 1, desiredNumberScheduled: 3, updatedNumberScheduled: 2}
+`, rolloutStatus{progress: "2 out of 3 new pods have been updated"}},
+		{"available", `
+metadata: {generation: 1}
+spec: {updateStrategy: {type: RollingUpdate}}
+status: {observedGeneration: 1, desiredNumberScheduled: 3, updatedNumberScheduled: 3, numberReady: 3, numberAvailable: 2}
+`, rolloutStatus{progress: "2 of 3 updated pods are available"}},
+		{"done", `
+metadata: {generation: 1}
+spec: {updateStrategy: {type: RollingUpdate}}
+status: {observedGeneration: 1, desiredNumberScheduled: 3, updatedNumberScheduled: 3, numberAvailable: 3}
+`, rolloutStatus{done: true}},
+	}
+
+	for _, tc := range testCases {
+		status, err := daemonSetRolloutStatus(testRolloutObject(t, tc.manifest))
+		assert.Equal(t, nil, err, tc.name)
+		assert.Equal(t, tc.expected, status, tc.name)
+	}
+}
+
+func TestStatefulSetRolloutStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected rolloutStatus
+	}{
+		{"not observed", `
+metadata: {generation: 1}
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate}}
+status: {}
+`, rolloutStatus{progress: "waiting for statefulset spec update to be observed"}},
+		{"ready", `
+metadata: {generation: 1}
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate}}
+status: {observedGeneration: 1, readyReplicas: 2}
+`, rolloutStatus{progress: "waiting for 1 pods to be ready"}},
+		{"min ready seconds", `
+metadata: {generation: 1}
+spec: {replicas: 3, minReadySeconds: 10, updateStrategy: {type: RollingUpdate}}
+status: {observedGeneration: 1, readyReplicas: 3, availableReplicas: 1}
+`, rolloutStatus{progress: "waiting for 2 pods to be ready"}},
+		{"partitioned", `
+metadata: {generation: 1}
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate, rollingUpdate: {partition: 1}}}
+status: {observedGeneration: 1, readyReplicas: 3, updatedReplicas: 1, currentRevision: a, updateRevision: b}
+`, rolloutStatus{progress: "waiting for partitioned roll out to finish: 1 out of 2 new pods have been updated"}},
+		{"partitioned done", `
+metadata: {generation: 1}
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate, rollingUpdate: {partition: 1}}}
+status: {observedGeneration: 1, readyReplicas: 3, updatedReplicas: 2, currentRevision: a, updateRevision: b}
+`, rolloutStatus{done: true}},
+		{"revision", `
+metadata: {generation: 1}
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate}}
+status: {observedGeneration: 1, readyReplicas: 3, updatedReplicas: 2, currentRevision: a, updateRevision: b}
+`, rolloutStatus{progress: "waiting for statefulset rolling update to complete 2 pods at revision b"}},
+		{"done", `
+metadata: {generation: 1}
+spec: {replicas: 3, updateStrategy: {type: RollingUpdate}}
+status: {observedGeneration: 1, readyReplicas: 3, updatedReplicas: 3, currentRevision: b, updateRevision: b}
+`, rolloutStatus{done: true}},
+	}
+
+	for _, tc := range testCases {
+		status, err := statefulSetRolloutStatus(testRolloutObject(t, tc.manifest))
+		assert.Equal(t, nil, err, tc.name)
+		assert.Equal(t, tc.expected, status, tc.name)
+	}
+}
@@ -0,0 +1,255 @@
+package kustomize
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"k8s.io/client-go/rest"
+)
+
+// runMetricsTopN is the number of slowest builds, applies
+// and waits listed in the summary of the run metrics
+const runMetricsTopN = 5
+
+// phaseTiming is the accumulated duration of a phase, e.g. the
+// builds of a kustomization that is built multiple times per run
+type phaseTiming struct {
+	count int
+	total time.Duration
+}
+
+// runMetrics collects per-phase timings of the run, the builds of
+// kustomizations, the requests to the API and the applies and
+// waits of kustomization_resources
+type runMetrics struct {
+	mu       sync.Mutex
+	started  time.Time
+	builds   map[string]*phaseTiming
+	apiCalls map[string]int
+	applies  map[string]*phaseTiming
+	waits    map[string]*phaseTiming
+
+	// throttling of the requests to the API
+	throttling *throttleStats
+}
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{
+		started:  time.Now(),
+		builds:   make(map[string]*phaseTiming),
+		apiCalls: make(map[string]int),
+		applies:  make(map[string]*phaseTiming),
+		waits:    make(map[string]*phaseTiming),
+
+		throttling: &throttleStats{},
+	}
+}
+
+func (rm *runMetrics) add(timings map[string]*phaseTiming, key string, d time.Duration) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	t, ok := timings[key]
+	if !ok {
+		t = &phaseTiming{}
+		timings[key] = t
+	}
+	t.count++
+	t.total += d
+}
+
+func (rm *runMetrics) addBuild(path string, d time.Duration) {
+	if rm == nil {
+		return
+	}
+
+	rm.add(rm.builds, path, d)
+}
+
+func (rm *runMetrics) addApply(id string, d time.Duration) {
+	if rm == nil {
+		return
+	}
+
+	rm.add(rm.applies, id, d)
+}
+
+func (rm *runMetrics) addWait(id string, d time.Duration) {
+	if rm == nil {
+		return
+	}
+
+	rm.add(rm.waits, id, d)
+}
+
+func (rm *runMetrics) addAPICall(method string) {
+	if rm == nil {
+		return
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.apiCalls[method]++
+}
+
+// durations returns the total durations of timings
+// as strings, in the format of time.Duration
+func (rm *runMetrics) durations(timings map[string]*phaseTiming) map[string]interface{} {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	out := make(map[string]interface{}, len(timings))
+	for k, t := range timings {
+		out[k] = t.total.Round(time.Millisecond).String()
+	}
+
+	return out
+}
+
+func (rm *runMetrics) apiCallCounts() (counts map[string]interface{}, total int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	counts = make(map[string]interface{}, len(rm.apiCalls))
+	for k, c := range rm.apiCalls {
+		counts[k] = c
+		total += c
+	}
+
+	return counts, total
+}
+
+// slowest returns the n keys of timings with the longest
+// total duration, formatted for the summary
+func slowest(timings map[string]*phaseTiming, n int) []string {
+	keys := make([]string, 0, len(timings))
+	for k := range timings {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if timings[keys[i]].total != timings[keys[j]].total {
+			return timings[keys[i]].total > timings[keys[j]].total
+		}
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = fmt.Sprintf("%s %s", k, timings[k].total.Round(time.Millisecond))
+		if timings[k].count > 1 {
+			out[i] += fmt.Sprintf(" (%d times)", timings[k].count)
+		}
+	}
+
+	return out
+}
+
+func totalDuration(timings map[string]*phaseTiming) (total time.Duration) {
+	for _, t := range timings {
+		total += t.total
+	}
+
+	return total
+}
+
+// summary returns the metrics of the run so far
+func (rm *runMetrics) summary() string {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "provider kustomization: run metrics after %s", time.Since(rm.started).Round(time.Millisecond))
+
+	phases := []struct {
+		name    string
+		timings map[string]*phaseTiming
+	}{
+		{"builds", rm.builds},
+		{"applies", rm.applies},
+		{"waits", rm.waits},
+	}
+	for _, p := range phases {
+		fmt.Fprintf(&b, "\n  %s: %d in %s", p.name, len(p.timings), totalDuration(p.timings).Round(time.Millisecond))
+		for _, s := range slowest(p.timings, runMetricsTopN) {
+			fmt.Fprintf(&b, "\n    %s", s)
+		}
+	}
+
+	methods := make([]string, 0, len(rm.apiCalls))
+	total := 0
+	for m, c := range rm.apiCalls {
+		methods = append(methods, fmt.Sprintf("%s %d", m, c))
+		total += c
+	}
+	sort.Strings(methods)
+	fmt.Fprintf(&b, "\n  API calls: %d", total)
+	if len(methods) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(methods, ", "))
+	}
+
+	fmt.Fprintf(&b, "\n  %s", rm.throttling.summary())
+
+	return b.String()
+}
+
+// LogRunMetrics logs the summary of the run metrics of the
+// provider p, it is called when Terraform shuts the provider
+// down at the end of a plan or apply
+func LogRunMetrics(p *schema.Provider) {
+	c, ok := p.Meta().(*Config)
+	if !ok || c.Metrics == nil {
+		// the provider was never configured
+		return
+	}
+
+	log.Printf("[INFO] %s", c.Metrics.summary())
+}
+
+// instrument wraps the transport and rate limiter of the rest configs
+// returned by restConfig to count the requests to the API and record
+// their throttling, by the API server and client side
+func (rm *runMetrics) instrument(restConfig func() (*rest.Config, error)) func() (*rest.Config, error) {
+	return func() (*rest.Config, error) {
+		config, err := restConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		if config.RateLimiter != nil {
+			config.RateLimiter = newThrottleRateLimiter(config.RateLimiter, rm.throttling)
+		}
+
+		// requests throttled by the API server are retried by the
+		// client, the transport only records and logs them
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return newThrottleTransport(rt, rm.throttling)
+		})
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &metricsTransport{rt: rt, metrics: rm}
+		})
+
+		return config, nil
+	}
+}
+
+// metricsTransport counts the requests to the API by method
+type metricsTransport struct {
+	rt      http.RoundTripper
+	metrics *runMetrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.metrics.addAPICall(req.Method)
+	return t.rt.RoundTrip(req)
+}
@@ -0,0 +1,107 @@
+package kustomize
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+func TestRunMetricsSummary(t *testing.T) {
+	rm := newRunMetrics()
+
+	rm.addBuild("overlays/prod", 2*time.Second)
+	rm.addBuild("overlays/prod", time.Second)
+	rm.addBuild("overlays/dev", time.Second)
+	rm.addApply("apps/Deployment/default/app", 300*time.Millisecond)
+	rm.addWait("apps/Deployment/default/app", 20*time.Second)
+	rm.addAPICall(http.MethodGet)
+	rm.addAPICall(http.MethodGet)
+	rm.addAPICall(http.MethodPatch)
+
+	assert.Equal(t, map[string]interface{}{"overlays/prod": "3s", "overlays/dev": "1s"}, rm.durations(rm.builds), nil)
+	assert.Equal(t, map[string]interface{}{"apps/Deployment/default/app": "20s"}, rm.durations(rm.waits), nil)
+
+	counts, total := rm.apiCallCounts()
+	assert.Equal(t, map[string]interface{}{"GET": 2, "PATCH": 1}, counts, nil)
+	assert.Equal(t, 3, total, nil)
+
+	s := rm.summary()
+	assert.Equal(t, true, strings.Contains(s, "builds: 2 in 4s\n    overlays/prod 3s (2 times)\n    overlays/dev 1s"), s)
+	assert.Equal(t, true, strings.Contains(s, "applies: 1 in 300ms"), s)
+	assert.Equal(t, true, strings.Contains(s, "waits: 1 in 20s"), s)
+	assert.Equal(t, true, strings.Contains(s, "API calls: 3 (GET 2, PATCH 1)"), s)
+}
+
+func TestSlowest(t *testing.T) {
+	timings := map[string]*phaseTiming{
+		"a": {count: 1, total: time.Second},
+		"b": {count: 1, total: 3 * time.Second},
+		"c": {count: 1, total: 2 * time.Second},
+	}
+
+	assert.Equal(t, []string{"b 3s", "c 2s"}, slowest(timings, 2), nil)
+}
+
+func TestMetricsTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rm := newRunMetrics()
+	client := &http.Client{Transport: &metricsTransport{rt: http.DefaultTransport, metrics: rm}}
+
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, nil, err, nil)
+	resp.Body.Close()
+
+	counts, _ := rm.apiCallCounts()
+	assert.Equal(t, map[string]interface{}{"GET": 1}, counts, nil)
+}
+
+func TestRunMetricsInstrument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	rm := newRunMetrics()
+	other := newRunMetrics()
+	restConfig := rm.instrument(func() (*rest.Config, error) {
+		return &rest.Config{
+			Host:        server.URL,
+			RateLimiter: flowcontrol.NewTokenBucketRateLimiter(1, 1),
+		}, nil
+	})
+
+	config, err := restConfig()
+	assert.Equal(t, nil, err, nil)
+	assert.IsType(t, &throttleRateLimiter{}, config.RateLimiter, nil)
+
+	client := &http.Client{Transport: config.WrapTransport(http.DefaultTransport)}
+	resp, err := client.Get(server.URL)
+	assert.Equal(t, nil, err, nil)
+	resp.Body.Close()
+
+	// throttling is recorded per provider configuration
+	assert.Equal(t, 1, rm.throttling.serverCount, nil)
+	assert.Equal(t, 0, other.throttling.serverCount, nil)
+
+	counts, _ := rm.apiCallCounts()
+	assert.Equal(t, map[string]interface{}{"GET": 1}, counts, nil)
+}
+
+func TestRunMetricsNil(t *testing.T) {
+	var rm *runMetrics
+
+	// configs built outside of the provider have no metrics
+	rm.addBuild("overlays/prod", time.Second)
+	rm.addApply("apps/Deployment/default/app", time.Second)
+	rm.addWait("apps/Deployment/default/app", time.Second)
+	rm.addAPICall(http.MethodGet)
+}
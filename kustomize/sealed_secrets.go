@@ -0,0 +1,265 @@
+package kustomize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/crypto/hkdf"
+	"sigs.k8s.io/kustomize/api/resmap"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	sealedSecretsScopeStrict        = "strict"
+	sealedSecretsScopeNamespaceWide = "namespace-wide"
+	sealedSecretsScopeClusterWide   = "cluster-wide"
+
+	sealedSecretsAPIVersion = "bitnami.com/v1alpha1"
+
+	// session keys are only used once, the nonce can be constant
+	sealedSecretsSessionKeyBytes = 32
+)
+
+// sealedSecretsConfig transforms Secrets into Bitnami SealedSecrets,
+// encrypted for the public key of the sealed secrets controller
+type sealedSecretsConfig struct {
+	publicKey *rsa.PublicKey
+	scope     string
+}
+
+func getSealedSecretsConfig(in []interface{}) (*sealedSecretsConfig, error) {
+	if len(in) == 0 || in[0] == nil {
+		return nil, nil
+	}
+
+	s := in[0].(map[string]interface{})
+
+	pub, err := parseSealingKey(s["certificate"].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealedSecretsConfig{
+		publicKey: pub,
+		scope:     s["scope"].(string),
+	}, nil
+}
+
+// parseSealingKey parses the PEM encoded certificate, as returned by
+// `kubeseal --fetch-cert`, or the public key of the controller
+func parseSealingKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("sealed secrets: certificate is not PEM encoded")
+	}
+
+	var pub interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("sealed secrets: %s", err)
+		}
+		pub = cert.PublicKey
+	case "PUBLIC KEY":
+		var err error
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("sealed secrets: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("sealed secrets: unsupported PEM type %q", block.Type)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("sealed secrets: public key is not an RSA key")
+	}
+
+	return rsaPub, nil
+}
+
+// sealingLabel binds the encrypted values to the name and namespace
+// of the secret, depending on the scope, like kubeseal
+func sealingLabel(scope string, namespace string, name string) []byte {
+	switch scope {
+	case sealedSecretsScopeClusterWide:
+		return []byte{}
+	case sealedSecretsScopeNamespaceWide:
+		return []byte(namespace)
+	default:
+		return []byte(fmt.Sprintf("%s/%s", namespace, name))
+	}
+}
+
+// sealingRandom returns the randomness for encrypting plaintext, derived
+// from the key, label and plaintext so the same value encrypts to the
+// same ciphertext and plans do not show changes on every read
+func sealingRandom(pub *rsa.PublicKey, label []byte, plaintext []byte) io.Reader {
+	salt := sha256.New()
+	salt.Write(x509.MarshalPKCS1PublicKey(pub))
+	salt.Write(label)
+
+	return hkdf.New(sha256.New, plaintext, salt.Sum(nil), []byte("terraform-provider-kustomization sealed secrets"))
+}
+
+// hybridEncrypt encrypts plaintext in the format of the sealed secrets
+// controller: the length of the RSA-OAEP encrypted session key as uint16,
+// the encrypted session key and the AES-GCM encrypted plaintext
+func hybridEncrypt(rnd io.Reader, pub *rsa.PublicKey, plaintext []byte, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, sealedSecretsSessionKeyBytes)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aed, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rnd, pub, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+
+	zeroNonce := make([]byte, aed.NonceSize())
+	ciphertext = aed.Seal(ciphertext, zeroNonce, plaintext, nil)
+
+	return ciphertext, nil
+}
+
+func getSecretData(secret map[string]interface{}) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+
+	d, _ := secret["data"].(map[string]interface{})
+	for k, v := range d {
+		s, _ := v.(string)
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("data %q: %s", k, err)
+		}
+		data[k] = b
+	}
+
+	// stringData takes precedence, like in the API server
+	sd, _ := secret["stringData"].(map[string]interface{})
+	for k, v := range sd {
+		s, _ := v.(string)
+		data[k] = []byte(s)
+	}
+
+	return data, nil
+}
+
+func (sc *sealedSecretsConfig) seal(secret map[string]interface{}) (map[string]interface{}, error) {
+	metadata, _ := secret["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+
+	data, err := getSecretData(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	label := sealingLabel(sc.scope, namespace, name)
+	encryptedData := make(map[string]interface{})
+	for _, k := range keys {
+		ciphertext, err := hybridEncrypt(sealingRandom(sc.publicKey, label, data[k]), sc.publicKey, data[k], label)
+		if err != nil {
+			return nil, fmt.Errorf("data %q: %s", k, err)
+		}
+		encryptedData[k] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	templateMetadata := map[string]interface{}{"name": name}
+	sealedMetadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		templateMetadata["namespace"] = namespace
+		sealedMetadata["namespace"] = namespace
+	}
+	for _, k := range []string{"labels", "annotations"} {
+		if v, ok := metadata[k]; ok {
+			templateMetadata[k] = v
+			sealedMetadata[k] = v
+		}
+	}
+
+	if sc.scope != sealedSecretsScopeStrict {
+		annotations := make(map[string]interface{})
+		if a, ok := sealedMetadata["annotations"].(map[string]interface{}); ok {
+			for k, v := range a {
+				annotations[k] = v
+			}
+		}
+		annotations[fmt.Sprintf("sealedsecrets.bitnami.com/%s", sc.scope)] = "true"
+		sealedMetadata["annotations"] = annotations
+	}
+
+	template := map[string]interface{}{"metadata": templateMetadata}
+	for _, k := range []string{"type", "immutable"} {
+		if v, ok := secret[k]; ok {
+			template[k] = v
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": sealedSecretsAPIVersion,
+		"kind":       "SealedSecret",
+		"metadata":   sealedMetadata,
+		"spec": map[string]interface{}{
+			"encryptedData": encryptedData,
+			"template":      template,
+		},
+	}, nil
+}
+
+// sealSecrets replaces all Secrets in rm with SealedSecrets
+func sealSecrets(rm resmap.ResMap, sc *sealedSecretsConfig) error {
+	for _, r := range rm.Resources() {
+		if r.GetKind() != "Secret" || r.GetApiVersion() != "v1" {
+			continue
+		}
+
+		secret, err := r.Map()
+		if err != nil {
+			return err
+		}
+
+		sealed, err := sc.seal(secret)
+		if err != nil {
+			return fmt.Errorf("sealing secret %q failed: %s", r.CurId(), err)
+		}
+
+		node, err := kyaml.FromMap(sealed)
+		if err != nil {
+			return err
+		}
+		r.SetYNode(node.YNode())
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package kustomize
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hybridDecrypt decrypts like the sealed secrets controller
+func hybridDecrypt(priv *rsa.PrivateKey, ciphertext []byte, label []byte) ([]byte, error) {
+	l := int(binary.BigEndian.Uint16(ciphertext))
+	ciphertext = ciphertext[2:]
+
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, ciphertext[:l], label)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aed, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return aed.Open(nil, make([]byte, aed.NonceSize()), ciphertext[l:], nil)
+}
+
+func testSealingKey(t *testing.T) (*rsa.PrivateKey, string) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, nil, err, nil)
+
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.Equal(t, nil, err, nil)
+
+	return priv, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestSealSecret(t *testing.T) {
+	priv, pub := testSealingKey(t)
+
+	sc, err := getSealedSecretsConfig([]interface{}{
+		map[string]interface{}{
+			"certificate": pub,
+			"scope":       sealedSecretsScopeStrict,
+		},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	secret := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":      "test",
+			"namespace": "test-sealed",
+			"labels":    map[string]interface{}{"app": "test"},
+		},
+		"type":       "Opaque",
+		"data":       map[string]interface{}{"password": base64.StdEncoding.EncodeToString([]byte("secret"))},
+		"stringData": map[string]interface{}{"user": "admin"},
+	}
+
+	sealed, err := sc.seal(secret)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "SealedSecret", sealed["kind"], nil)
+
+	spec := sealed["spec"].(map[string]interface{})
+	template := spec["template"].(map[string]interface{})
+	assert.Equal(t, "Opaque", template["type"], nil)
+	assert.Equal(t, map[string]interface{}{"app": "test"}, template["metadata"].(map[string]interface{})["labels"], nil)
+
+	encryptedData := spec["encryptedData"].(map[string]interface{})
+	for k, expected := range map[string]string{"password": "secret", "user": "admin"} {
+		ciphertext, err := base64.StdEncoding.DecodeString(encryptedData[k].(string))
+		assert.Equal(t, nil, err, k)
+
+		plaintext, err := hybridDecrypt(priv, ciphertext, []byte("test-sealed/test"))
+		assert.Equal(t, nil, err, k)
+		assert.Equal(t, expected, string(plaintext), k)
+
+		// strict scope binds the value to the name and namespace
+		_, err = hybridDecrypt(priv, ciphertext, []byte("test-sealed"))
+		assert.Error(t, err, k)
+	}
+
+	// sealing is deterministic to prevent perpetual diffs
+	again, err := sc.seal(secret)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, sealed, again, nil)
+}
+
+func TestSealSecretClusterWide(t *testing.T) {
+	priv, pub := testSealingKey(t)
+
+	sc, err := getSealedSecretsConfig([]interface{}{
+		map[string]interface{}{
+			"certificate": pub,
+			"scope":       sealedSecretsScopeClusterWide,
+		},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	sealed, err := sc.seal(map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "test", "namespace": "test-sealed"},
+		"data":     map[string]interface{}{"key": base64.StdEncoding.EncodeToString([]byte("value"))},
+	})
+	assert.Equal(t, nil, err, nil)
+
+	metadata := sealed["metadata"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"sealedsecrets.bitnami.com/cluster-wide": "true"}, metadata["annotations"], nil)
+
+	ciphertext, _ := base64.StdEncoding.DecodeString(sealed["spec"].(map[string]interface{})["encryptedData"].(map[string]interface{})["key"].(string))
+	plaintext, err := hybridDecrypt(priv, ciphertext, []byte{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "value", string(plaintext), nil)
+}
+
+func TestSealSecrets(t *testing.T) {
+	_, pub := testSealingKey(t)
+	sc, _ := getSealedSecretsConfig([]interface{}{
+		map[string]interface{}{"certificate": pub, "scope": sealedSecretsScopeStrict},
+	})
+
+	rm, err := runKustomizeInMemory(context.TODO(), &Config{BuildLock: newBuildLock()}, []string{`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  namespace: test-sealed
+stringData:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: test-sealed
+`}, nil)
+	assert.Equal(t, nil, err, nil)
+
+	err = sealSecrets(rm, sc)
+	assert.Equal(t, nil, err, nil)
+
+	ids, _, err := flattenKustomizationIDs(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []string{"bitnami.com/SealedSecret/test-sealed/test", "_/ConfigMap/test-sealed/test"}, ids, nil)
+}
+
+func TestParseSealingKeyInvalid(t *testing.T) {
+	_, err := parseSealingKey("not PEM")
+	assert.Error(t, err, nil)
+
+	_, err = parseSealingKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{}})))
+	assert.Error(t, err, nil)
+}
@@ -0,0 +1,254 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// secret placeholders in manifests, e.g. "${secret:vault:secret/data/app#password}",
+// are only resolved in the objects sent to the Kubernetes API
+var secretPlaceholderRegexp = regexp.MustCompile(`\$\{secret:([a-z0-9]+):([^}]+)\}`)
+
+// secretResolver resolves the secret placeholders of manifests
+// from the provider's secret backends, reading every secret once
+type secretResolver struct {
+	vault   *vaultConfig
+	secrets map[string]map[string]interface{}
+}
+
+func newSecretResolver(vc *vaultConfig) *secretResolver {
+	return &secretResolver{
+		vault:   vc,
+		secrets: make(map[string]map[string]interface{}),
+	}
+}
+
+// getSecretResolver returns the resolver for a plan, create or
+// update, nil if placeholders are not resolved
+func getSecretResolver(m interface{}) *secretResolver {
+	if !m.(*Config).SecretPlaceholders {
+		return nil
+	}
+
+	return newSecretResolver(m.(*Config).Vault)
+}
+
+func (sr *secretResolver) resolve(backend string, ref string) (string, error) {
+	switch backend {
+	case "vault":
+		path, field, err := parseVaultReference(ref)
+		if err != nil {
+			return "", err
+		}
+
+		data, ok := sr.secrets[path]
+		if !ok {
+			data, err = sr.vault.read(path)
+			if err != nil {
+				return "", fmt.Errorf("vault: %s", err)
+			}
+			sr.secrets[path] = data
+		}
+
+		v, ok := data[field]
+		if !ok {
+			return "", fmt.Errorf("vault: field %q not found in %q", field, path)
+		}
+
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return "", fmt.Errorf("unknown secret backend %q of placeholder \"${secret:%s:%s}\"", backend, backend, ref)
+	}
+}
+
+// replace resolves all placeholders in s
+func (sr *secretResolver) replace(s string) (string, error) {
+	var err error
+	out := secretPlaceholderRegexp.ReplaceAllStringFunc(s, func(p string) string {
+		if err != nil {
+			return p
+		}
+
+		m := secretPlaceholderRegexp.FindStringSubmatch(p)
+
+		var v string
+		v, err = sr.resolve(m[1], m[2])
+		return v
+	})
+
+	return out, err
+}
+
+// resolveSecretPlaceholders returns a copy of u with all placeholders
+// resolved, except in the last applied configuration annotations, so
+// neither the state nor the annotation contain the secret values,
+// resolved values in the data of Secrets are base64 encoded
+func resolveSecretPlaceholders(u *k8sunstructured.Unstructured, sr *secretResolver) (*k8sunstructured.Unstructured, error) {
+	resolved := u.DeepCopy()
+	isSecret := u.GroupVersionKind().Group == "" && u.GetKind() == "Secret"
+
+	var walk func(v interface{}, path []string) (interface{}, error)
+	walk = func(v interface{}, path []string) (interface{}, error) {
+		switch o := v.(type) {
+		case string:
+			if !secretPlaceholderRegexp.MatchString(o) {
+				return o, nil
+			}
+
+			s, err := sr.replace(o)
+			if err != nil {
+				return nil, err
+			}
+
+			if isSecret && len(path) == 2 && path[0] == "data" {
+				s = base64.StdEncoding.EncodeToString([]byte(s))
+			}
+
+			return s, nil
+		case map[string]interface{}:
+			for k, c := range o {
+				if len(path) == 2 && path[0] == "metadata" && path[1] == "annotations" &&
+					(k == lastAppliedConfigAnnotation || k == gzipLastAppliedConfigAnnotation) {
+					continue
+				}
+
+				r, err := walk(c, append(append([]string{}, path...), k))
+				if err != nil {
+					return nil, err
+				}
+				o[k] = r
+			}
+			return o, nil
+		case []interface{}:
+			for i, c := range o {
+				r, err := walk(c, append(append([]string{}, path...), fmt.Sprint(i)))
+				if err != nil {
+					return nil, err
+				}
+				o[i] = r
+			}
+			return o, nil
+		default:
+			return v, nil
+		}
+	}
+
+	if _, err := walk(resolved.Object, nil); err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+// withSecrets returns the resource and its JSON with the secret
+// placeholders resolved, if the provider resolves placeholders
+func (km *kManifest) withSecrets() (*k8sunstructured.Unstructured, []byte, error) {
+	if km.secrets == nil {
+		return km.resource, km.json, nil
+	}
+
+	u, err := resolveSecretPlaceholders(km.resource, km.secrets)
+	if err != nil {
+		return nil, nil, km.fmtErr(fmt.Errorf("resolving secret placeholders failed: %s", err))
+	}
+
+	j, err := u.MarshalJSON()
+	if err != nil {
+		return nil, nil, km.fmtErr(err)
+	}
+
+	return u, j, nil
+}
+
+// secretsHash returns a hash of the resolved values of all secret
+// placeholders of the JSON manifest, empty without placeholders
+//
+// The hash is stored in the state instead of the values. Rotated
+// secrets change it, so they show as a diff and get applied.
+func secretsHash(manifest string, sr *secretResolver) (string, error) {
+	if sr == nil {
+		return "", nil
+	}
+
+	placeholders := make(map[string]bool)
+	for _, p := range secretPlaceholderRegexp.FindAllString(manifest, -1) {
+		placeholders[p] = true
+	}
+	if len(placeholders) == 0 {
+		return "", nil
+	}
+
+	sorted := make([]string, 0, len(placeholders))
+	for p := range placeholders {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, p := range sorted {
+		m := secretPlaceholderRegexp.FindStringSubmatch(p)
+		v, err := sr.resolve(m[1], m[2])
+		if err != nil {
+			return "", err
+		}
+
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// diffSecretsHash plans the secrets_hash of the manifest, if the
+// provider resolves placeholders, reading the secrets during plan
+func diffSecretsHash(d *schema.ResourceDiff, sr *secretResolver, m interface{}) error {
+	if sr == nil {
+		return nil
+	}
+
+	if !d.NewValueKnown("manifest") {
+		return d.SetNewComputed("secrets_hash")
+	}
+
+	manifest, err := getManifest(d, m)
+	if err != nil {
+		return err
+	}
+
+	h, err := secretsHash(manifest, sr)
+	if err != nil {
+		return fmt.Errorf("resolving secret placeholders failed: %s", err)
+	}
+
+	if h != d.Get("secrets_hash").(string) {
+		return d.SetNew("secrets_hash", h)
+	}
+
+	return nil
+}
+
+// setSecretsHash stores the secrets_hash of the applied manifest
+func setSecretsHash(d *schema.ResourceData, km *kManifest, manifest string) error {
+	h, err := secretsHash(manifest, km.secrets)
+	if err != nil {
+		return km.fmtErr(fmt.Errorf("resolving secret placeholders failed: %s", err))
+	}
+
+	d.Set("secrets_hash", h)
+	return nil
+}
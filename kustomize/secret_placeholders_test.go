@@ -0,0 +1,138 @@
+package kustomize
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPlaceholderSecret = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"stringData":{"url":"${secret:vault:secret/data/app#password}"}}'
+data:
+  password: ${secret:vault:secret/data/app#password}
+stringData:
+  url: postgres://app:${secret:vault:secret/data/app#password}@db:${secret:vault:secret/data/app#port}/app
+`
+
+func testSecretPlaceholdersVault(t *testing.T, reads *int) *vaultConfig {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*reads++
+		if r.URL.Path != "/v1/secret/data/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"data": {"password": "s3cret", "port": 5432}, "metadata": {"version": 1}}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	return getVaultConfig([]interface{}{
+		map[string]interface{}{
+			"address":   srv.URL,
+			"token":     "test-token",
+			"namespace": "",
+		},
+	})
+}
+
+func TestResolveSecretPlaceholders(t *testing.T) {
+	reads := 0
+	vc := testSecretPlaceholdersVault(t, &reads)
+	u := testRolloutObject(t, testPlaceholderSecret)
+
+	resolved, err := resolveSecretPlaceholders(u, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 1, reads, nil)
+
+	assert.Equal(t, "czNjcmV0", resolved.Object["data"].(map[string]interface{})["password"], nil)
+	assert.Equal(t, "postgres://app:s3cret@db:5432/app", resolved.Object["stringData"].(map[string]interface{})["url"], nil)
+
+	// the last applied configuration and the input keep the placeholders
+	assert.Contains(t, resolved.GetAnnotations()[lastAppliedConfigAnnotation], "${secret:vault:secret/data/app#password}", nil)
+	assert.Equal(t, "${secret:vault:secret/data/app#password}", u.Object["data"].(map[string]interface{})["password"], nil)
+}
+
+func TestSecretsHash(t *testing.T) {
+	reads := 0
+	vc := testSecretPlaceholdersVault(t, &reads)
+
+	manifest := `{"apiVersion":"v1","kind":"ConfigMap","data":{"a":"${secret:vault:secret/data/app#password}","b":"${secret:vault:secret/data/app#password}"}}`
+	h, err := secretsHash(manifest, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 1, reads, nil)
+	assert.Equal(t, 64, len(h), nil)
+	assert.NotContains(t, h, "s3cret", nil)
+
+	// stable for the same values
+	again, err := secretsHash(manifest, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, h, again, nil)
+
+	// other placeholders change the hash
+	port, err := secretsHash(`{"data":{"a":"${secret:vault:secret/data/app#port}"}}`, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.NotEqual(t, h, port, nil)
+
+	// no hash without placeholders or resolver
+	none, err := secretsHash(`{"data":{"a":"b"}}`, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "", none, nil)
+
+	none, err = secretsHash(manifest, nil)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "", none, nil)
+
+	_, err = secretsHash(`{"data":{"a":"${secret:vault:secret/data/app#missing}"}}`, newSecretResolver(vc))
+	assert.EqualError(t, err, `vault: field "missing" not found in "secret/data/app"`, nil)
+}
+
+func TestSecretsHashRotation(t *testing.T) {
+	password := "s3cret"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data": {"data": {"password": %q}, "metadata": {"version": 1}}}`, password)
+	}))
+	t.Cleanup(srv.Close)
+
+	vc := getVaultConfig([]interface{}{
+		map[string]interface{}{
+			"address":   srv.URL,
+			"token":     "test-token",
+			"namespace": "",
+		},
+	})
+
+	manifest := `{"data":{"a":"${secret:vault:secret/data/app#password}"}}`
+	before, err := secretsHash(manifest, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+
+	password = "rotated"
+	after, err := secretsHash(manifest, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.NotEqual(t, before, after, nil)
+}
+
+func TestResolveSecretPlaceholdersErrors(t *testing.T) {
+	reads := 0
+	vc := testSecretPlaceholdersVault(t, &reads)
+
+	u := testRolloutObject(t, "kind: ConfigMap\ndata:\n  key: ${secret:vault:secret/data/app#missing}\n")
+	_, err := resolveSecretPlaceholders(u, newSecretResolver(vc))
+	assert.EqualError(t, err, `vault: field "missing" not found in "secret/data/app"`, nil)
+
+	u = testRolloutObject(t, "kind: ConfigMap\ndata:\n  key: ${secret:aws:app#password}\n")
+	_, err = resolveSecretPlaceholders(u, newSecretResolver(vc))
+	assert.EqualError(t, err, `unknown secret backend "aws" of placeholder "${secret:aws:app#password}"`, nil)
+
+	// ConfigMap data is not base64 encoded
+	u = testRolloutObject(t, "kind: ConfigMap\ndata:\n  key: ${secret:vault:secret/data/app#password}\n")
+	resolved, err := resolveSecretPlaceholders(u, newSecretResolver(vc))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "s3cret", resolved.Object["data"].(map[string]interface{})["key"], nil)
+}
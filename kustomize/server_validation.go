@@ -0,0 +1,134 @@
+package kustomize
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+func validateModeSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "none",
+		ValidateFunc: validation.StringInSlice([]string{"none", "server"}, false),
+	}
+}
+
+// serverValidate runs the resources of rm through a server-side dry-run
+// if validate is set to server, and fails with the errors of all
+// invalid resources
+func serverValidate(d *schema.ResourceData, m interface{}, rm resmap.ResMap) error {
+	if d.Get("validate").(string) != "server" {
+		return nil
+	}
+
+	kc, err := m.(*Config).getCluster(d.Get("validate_cluster").(string))
+	if err != nil {
+		return err
+	}
+
+	if m.(*Config).AllowUnreachableCluster && !kc.isReachable() {
+		log.Printf("[WARN] validate: skipping server-side dry-run, cluster unreachable")
+		return nil
+	}
+
+	client, mapper, err := kc.get()
+	if err != nil {
+		return err
+	}
+
+	origins := expandOrigins(d.Get("origins").([]interface{}))
+
+	errs, err := dryRunResources(client, mapper, rm, m.(*Config).ApplyDefaults.FieldManager, origins)
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("server-side dry-run failed: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// dryRunResources server-side applies the resources of rm with dry-run
+// and returns the errors of all invalid resources, with their origin
+//
+// Custom resources of CRDs and resources in namespaces of the same
+// build can not be dry-run before these exist and are skipped.
+func dryRunResources(client k8sdynamic.Interface, mapper k8smeta.ResettableRESTMapper, rm resmap.ResMap, fm string, origins map[string]resourceOrigin) (errs []string, err error) {
+	var kms []*kManifest
+	crdKinds := make(map[k8sschema.GroupKind]bool)
+	namespaces := make(map[string]bool)
+	for _, r := range rm.Resources() {
+		body, err := r.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		km := newKManifest(mapper, client)
+		if err := km.load(body); err != nil {
+			return nil, err
+		}
+		kms = append(kms, km)
+
+		switch {
+		case km.isCRD():
+			group, _, _ := k8sunstructured.NestedString(km.resource.Object, "spec", "group")
+			kind, _, _ := k8sunstructured.NestedString(km.resource.Object, "spec", "names", "kind")
+			crdKinds[k8sschema.GroupKind{Group: group, Kind: kind}] = true
+		case km.gvk().GroupKind() == k8sschema.GroupKind{Kind: "Namespace"}:
+			namespaces[km.name()] = true
+		}
+	}
+
+	fail := func(km *kManifest, err error) {
+		msg := km.fmtErr(err).Error()
+		if o, ok := origins[km.id().string()]; ok {
+			msg = fmt.Sprintf("%s (%s)", msg, o)
+		}
+		errs = append(errs, msg)
+	}
+
+	opts := k8smetav1.PatchOptions{DryRun: []string{k8smetav1.DryRunAll}, FieldManager: fm}
+	for _, km := range kms {
+		mapping, err := km.mapping()
+		if err != nil {
+			if !crdKinds[km.gvk().GroupKind()] {
+				fail(km, fmt.Errorf("api error: %s", err))
+			}
+			continue
+		}
+
+		isNamespaced := mapping.Scope.Name() == k8smeta.RESTScopeNameNamespace
+		if isNamespaced && km.namespace() == "" {
+			fail(km, fmt.Errorf("is namespace scoped and must set metadata.namespace"))
+			continue
+		}
+		if !isNamespaced && km.namespace() != "" {
+			fail(km, fmt.Errorf("is not namespace scoped but has metadata.namespace set"))
+			continue
+		}
+
+		_, err = km.apiApply(opts)
+		if err != nil {
+			if k8serrors.IsNotFound(err) && namespaces[km.namespace()] {
+				continue
+			}
+			fail(km, err)
+		}
+	}
+
+	return errs, nil
+}
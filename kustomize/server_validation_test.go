@@ -0,0 +1,100 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const testServerValidationResources = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: created
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: in-created
+  namespace: created
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: invalid
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: valid
+  namespace: default
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: examples.example.com
+spec:
+  group: example.com
+  names:
+    kind: Example
+    plural: examples
+  scope: Namespaced
+---
+apiVersion: example.com/v1
+kind: Example
+metadata:
+  name: custom
+  namespace: default
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`
+
+func TestDryRunResources(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("kustomization.yaml", []byte("resources:\n- resources.yaml\n"))
+	fSys.WriteFile("resources.yaml", []byte(testServerValidationResources))
+
+	rm, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, ".")
+	assert.Equal(t, nil, err, nil)
+
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "", "version": "v1", "kind": "Namespace", "resource": "namespaces", "namespaced": false},
+		map[string]interface{}{"group": "", "version": "v1", "kind": "ConfigMap", "resource": "configmaps", "namespaced": true},
+		map[string]interface{}{"group": "apiextensions.k8s.io", "version": "v1", "kind": "CustomResourceDefinition", "resource": "customresourcedefinitions", "namespaced": false},
+	})
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	client := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	var dryRun []string
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pa := action.(k8stesting.PatchAction)
+		gr := k8sschema.GroupResource{Resource: pa.GetResource().Resource}
+		switch {
+		case pa.GetNamespace() == "created":
+			return true, nil, k8serrors.NewNotFound(k8sschema.GroupResource{Resource: "namespaces"}, "created")
+		case pa.GetName() == "invalid":
+			return true, nil, k8serrors.NewForbidden(gr, pa.GetName(), assert.AnError)
+		}
+		dryRun = append(dryRun, pa.GetName())
+		return true, nil, nil
+	})
+
+	origins := map[string]resourceOrigin{"_/ConfigMap/default/invalid": {file: "resources.yaml"}}
+	errs, err := dryRunResources(client, mapper, rm, "", origins)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 2, len(errs), nil)
+	assert.Contains(t, errs[0], `"_/ConfigMap/default/invalid"`, nil)
+	assert.Contains(t, errs[0], "(from resources.yaml)", nil)
+	assert.Contains(t, errs[1], `"example.com/Unknown/_/unknown"`, nil)
+	assert.ElementsMatch(t, []string{"created", "valid", "examples.example.com"}, dryRun, nil)
+}
@@ -0,0 +1,68 @@
+package kustomize
+
+import (
+	"fmt"
+
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func validateServerVersion(v interface{}, k string) (ws []string, es []error) {
+	if _, err := k8sversion.ParseGeneric(v.(string)); err != nil {
+		es = append(es, fmt.Errorf("%s: %s, valid versions look like: \"1.29\" or \"v1.29.2\"", k, err))
+	}
+
+	return ws, es
+}
+
+// serverVersion returns the version of the API server,
+// requested once for the lifetime of the provider
+func (kc *kubeClients) serverVersion() (*k8sversion.Version, error) {
+	kc.versionOnce.Do(func() {
+		dc, err := kc.discovery()
+		if err != nil {
+			kc.versionErr = err
+			return
+		}
+
+		info, err := dc.ServerVersion()
+		if err != nil {
+			kc.versionErr = err
+			return
+		}
+
+		kc.version, kc.versionErr = k8sversion.ParseGeneric(info.GitVersion)
+	})
+
+	return kc.version, kc.versionErr
+}
+
+// checkMinServerVersion returns an error if the cluster of the resource
+// is older than its min_server_version, suffixes of the server
+// version, e.g. of managed clusters like v1.29.2-eks-1234, are ignored
+func checkMinServerVersion(d changeGetter, m interface{}, km *kManifest) error {
+	minimum := d.Get("min_server_version").(string)
+	if minimum == "" {
+		return nil
+	}
+
+	minVersion, err := k8sversion.ParseGeneric(minimum)
+	if err != nil {
+		return km.fmtErr(fmt.Errorf("min_server_version: %s", err))
+	}
+
+	kc, err := m.(*Config).getCluster(d.Get("cluster").(string))
+	if err != nil {
+		return err
+	}
+
+	v, err := kc.serverVersion()
+	if err != nil {
+		return km.fmtErr(fmt.Errorf("min_server_version: requesting the server version failed: %s", err))
+	}
+
+	if v.LessThan(minVersion) {
+		return km.fmtErr(fmt.Errorf("requires Kubernetes %s or newer, the cluster runs %s", minimum, v))
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package kustomize
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func TestCheckMinServerVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"27+","gitVersion":"v1.27.3-eks-a5565ad"}`))
+	}))
+	defer server.Close()
+
+	m := &Config{clients: &kubeClients{restConfig: func() (*rest.Config, error) {
+		return &rest.Config{Host: server.URL}, nil
+	}}}
+
+	km := &kManifest{}
+	km.load([]byte(`{"apiVersion":"admissionregistration.k8s.io/v1","kind":"ValidatingAdmissionPolicy","metadata":{"name":"test"}}`))
+
+	for minimum, ok := range map[string]bool{
+		"":        true,
+		"1.26":    true,
+		"v1.27.3": true,
+		"1.28":    false,
+	} {
+		d := schema.TestResourceDataRaw(t, kustomizationResource().Schema, map[string]interface{}{
+			"min_server_version": minimum,
+		})
+
+		err := checkMinServerVersion(d, m, km)
+		assert.Equal(t, ok, err == nil, minimum)
+	}
+
+	d := schema.TestResourceDataRaw(t, kustomizationResource().Schema, map[string]interface{}{
+		"min_server_version": "1.30",
+	})
+	err := checkMinServerVersion(d, m, km)
+	assert.Contains(t, err.Error(), "requires Kubernetes 1.30 or newer, the cluster runs 1.27.3", nil)
+}
+
+func TestValidateServerVersion(t *testing.T) {
+	_, es := validateServerVersion("v1.29.2", "min_server_version")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateServerVersion("latest", "min_server_version")
+	assert.Equal(t, 1, len(es), nil)
+}
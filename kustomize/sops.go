@@ -0,0 +1,254 @@
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultSOPSPath = "sops"
+
+// sopsConfig configures decrypting SOPS encrypted
+// generator sources using the sops binary
+type sopsConfig struct {
+	Path string
+	Env  []string
+}
+
+// SOPS encrypted values, and the metadata of the YAML,
+// JSON, dotenv and INI formats
+var (
+	sopsEncryptedValueRegexp = regexp.MustCompile(`ENC\[AES256_GCM,data:`)
+	sopsMetadataRegexp       = regexp.MustCompile(`(?m)^(sops:|\s*"sops":|sops_mac=|\[sops\])`)
+)
+
+// sopsEnv maps sops settings to the environment variables of the
+// sops binary, for age identities and the cloud KMS credentials,
+// KMS settings not configured default to the ambient credentials
+var sopsEnv = map[string]string{
+	"age_key":          "SOPS_AGE_KEY",
+	"age_key_file":     "SOPS_AGE_KEY_FILE",
+	"age_ssh_key_file": "SOPS_AGE_SSH_PRIVATE_KEY_FILE",
+
+	"aws_profile": "AWS_PROFILE",
+	"aws_region":  "AWS_REGION",
+
+	"gcp_credentials_file": "GOOGLE_APPLICATION_CREDENTIALS",
+
+	"azure_tenant_id":     "AZURE_TENANT_ID",
+	"azure_client_id":     "AZURE_CLIENT_ID",
+	"azure_client_secret": "AZURE_CLIENT_SECRET",
+}
+
+func getSOPSConfig(in []interface{}) *sopsConfig {
+	sc := &sopsConfig{Path: defaultSOPSPath}
+
+	if len(in) == 0 || in[0] == nil {
+		return sc
+	}
+
+	s := in[0].(map[string]interface{})
+
+	if p := s["path"].(string); p != "" {
+		sc.Path = p
+	}
+
+	for k, env := range sopsEnv {
+		if v, _ := s[k].(string); v != "" {
+			sc.Env = append(sc.Env, fmt.Sprintf("%s=%s", env, v))
+		}
+	}
+	sort.Strings(sc.Env)
+
+	return sc
+}
+
+func isSOPSEncrypted(content []byte) bool {
+	return sopsEncryptedValueRegexp.Match(content) && sopsMetadataRegexp.Match(content)
+}
+
+// decrypt returns the plain text of the encrypted file,
+// the format is determined by sops from the file extension
+func (sc *sopsConfig) decrypt(path string) ([]byte, error) {
+	cmd := exec.Command(sc.Path, "--decrypt", path)
+	cmd.Env = append(os.Environ(), sc.Env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %q using %s failed: %s: %s", path, sc.Path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// getGeneratorSources returns the paths of all files
+// and envs of the config map and secret generators
+func getGeneratorSources(k types.Kustomization) (paths []string) {
+	var args []types.GeneratorArgs
+	for _, g := range k.ConfigMapGenerator {
+		args = append(args, g.GeneratorArgs)
+	}
+	for _, g := range k.SecretGenerator {
+		args = append(args, g.GeneratorArgs)
+	}
+
+	for _, a := range args {
+		paths = append(paths, a.EnvSources...)
+
+		// file sources optionally set the key, e.g. "key=path"
+		for _, f := range a.FileSources {
+			if i := strings.Index(f, "="); i >= 0 {
+				f = f[i+1:]
+			}
+			paths = append(paths, f)
+		}
+	}
+
+	return paths
+}
+
+// getKustomizationGeneratorSources returns the paths of the generator
+// sources of the kustomization at path and of its local bases and
+// components, relative to the directory of their kustomization
+func getKustomizationGeneratorSources(fSys filesys.FileSystem, path string) (paths []string) {
+	visited := make(map[string]bool)
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		if visited[dir] {
+			return
+		}
+		visited[dir] = true
+
+		for _, n := range konfig.RecognizedKustomizationFileNames() {
+			content, err := fSys.ReadFile(filepath.Join(dir, n))
+			if err != nil {
+				continue
+			}
+
+			var k types.Kustomization
+			if err := yaml.Unmarshal(content, &k); err != nil {
+				// left for kustomize to report
+				return
+			}
+
+			for _, s := range getGeneratorSources(k) {
+				paths = append(paths, resolveReference(dir, s))
+			}
+
+			bases := append(append(k.Resources, k.Components...), k.Bases...)
+			for _, b := range bases {
+				if p := resolveReference(dir, b); fSys.IsDir(p) {
+					walk(p)
+				}
+			}
+
+			return
+		}
+	}
+	walk(path)
+
+	return paths
+}
+
+// hasSOPSEncryptedSources returns true if any of the generator
+// sources, or of the files of source directories, is SOPS encrypted
+func hasSOPSEncryptedSources(fSys filesys.FileSystem, sources []string) bool {
+	for _, s := range sources {
+		files := []string{s}
+		if fSys.IsDir(s) {
+			names, err := fSys.ReadDir(s)
+			if err != nil {
+				continue
+			}
+
+			files = nil
+			for _, n := range names {
+				files = append(files, filepath.Join(s, n))
+			}
+		}
+
+		for _, f := range files {
+			if content, err := fSys.ReadFile(f); err == nil && isSOPSEncrypted(content) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// sopsFileSystem decrypts SOPS encrypted generator sources
+// when kustomize reads them, the plain text is only kept in memory
+type sopsFileSystem struct {
+	filesys.FileSystem
+	sources map[string]bool
+	sops    *sopsConfig
+}
+
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return abs
+	}
+
+	return resolved
+}
+
+func makeSOPSFS(fs filesys.FileSystem, sources []string, sc *sopsConfig) filesys.FileSystem {
+	if len(sources) == 0 {
+		return fs
+	}
+
+	if sc == nil {
+		sc = getSOPSConfig(nil)
+	}
+
+	sfs := sopsFileSystem{
+		FileSystem: fs,
+		sources:    make(map[string]bool),
+		sops:       sc,
+	}
+
+	for _, s := range sources {
+		sfs.sources[resolvePath(s)] = true
+	}
+
+	return sfs
+}
+
+func (sfs sopsFileSystem) ReadFile(name string) ([]byte, error) {
+	content, err := sfs.FileSystem.ReadFile(name)
+	if err != nil {
+		return content, err
+	}
+
+	// file sources can also be directories
+	resolved := resolvePath(name)
+	isSource := sfs.sources[resolved] || sfs.sources[filepath.Dir(resolved)]
+
+	if !isSource || !isSOPSEncrypted(content) {
+		return content, nil
+	}
+
+	return sfs.sops.decrypt(name)
+}
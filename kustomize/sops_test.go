@@ -0,0 +1,148 @@
+package kustomize
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const testSOPSEnv = `PASSWORD=ENC[AES256_GCM,data:Zm9v,iv:YmFy,tag:YmF6,type:str]
+sops_version=3.7.3
+sops_mac=ENC[AES256_GCM,data:Zm9v,iv:YmFy,tag:YmF6,type:str]
+`
+
+const testSOPSYAML = `password: ENC[AES256_GCM,data:Zm9v,iv:YmFy,tag:YmF6,type:str]
+sops:
+    mac: ENC[AES256_GCM,data:Zm9v,iv:YmFy,tag:YmF6,type:str]
+    version: 3.7.3
+`
+
+func TestIsSOPSEncrypted(t *testing.T) {
+	assert.True(t, isSOPSEncrypted([]byte(testSOPSEnv)), nil)
+	assert.True(t, isSOPSEncrypted([]byte(testSOPSYAML)), nil)
+	assert.True(t, isSOPSEncrypted([]byte(`{"data": "ENC[AES256_GCM,data:Zm9v,iv:YmFy,tag:YmF6,type:str]",
+	"sops": {"version": "3.7.3"}}`)), nil)
+
+	assert.False(t, isSOPSEncrypted([]byte("PASSWORD=plain\n")), nil)
+	assert.False(t, isSOPSEncrypted([]byte("sops:\n  version: 3.7.3\n")), nil)
+}
+
+func TestGetSOPSConfig(t *testing.T) {
+	sc := getSOPSConfig(nil)
+	assert.Equal(t, defaultSOPSPath, sc.Path, nil)
+	assert.Equal(t, 0, len(sc.Env), nil)
+
+	sc = getSOPSConfig([]interface{}{
+		map[string]interface{}{
+			"path":             "/usr/local/bin/sops",
+			"age_key":          "AGE-SECRET-KEY-1TEST",
+			"age_key_file":     "",
+			"age_ssh_key_file": "/home/test/.ssh/id_ed25519",
+			"aws_profile":      "test",
+		},
+	})
+	assert.Equal(t, "/usr/local/bin/sops", sc.Path, nil)
+	assert.Equal(t, []string{
+		"AWS_PROFILE=test",
+		"SOPS_AGE_KEY=AGE-SECRET-KEY-1TEST",
+		"SOPS_AGE_SSH_PRIVATE_KEY_FILE=/home/test/.ssh/id_ed25519",
+	}, sc.Env, nil)
+}
+
+func TestGetGeneratorSources(t *testing.T) {
+	k := types.Kustomization{}
+	k.ConfigMapGenerator = append(k.ConfigMapGenerator, types.ConfigMapArgs{})
+	k.ConfigMapGenerator[0].EnvSources = []string{"config.env"}
+	k.SecretGenerator = append(k.SecretGenerator, types.SecretArgs{})
+	k.SecretGenerator[0].FileSources = []string{"tls.key", "key=path/to/secret.yaml"}
+	k.SecretGenerator[0].LiteralSources = []string{"key=value"}
+
+	assert.Equal(t, []string{"config.env", "tls.key", "path/to/secret.yaml"}, getGeneratorSources(k), nil)
+}
+
+func TestSOPSFileSystem(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "terraform-provider-kustomization-sops-*")
+	assert.Equal(t, nil, err, nil)
+	defer os.RemoveAll(tmp)
+
+	// fake sops binary, printing the arguments and environment
+	sopsPath := filepath.Join(tmp, "sops")
+	err = ioutil.WriteFile(sopsPath, []byte("#!/bin/sh\necho \"decrypted $@ $SOPS_TEST\"\n"), 0755)
+	assert.Equal(t, nil, err, nil)
+
+	encrypted := filepath.Join(tmp, "secret.env")
+	err = ioutil.WriteFile(encrypted, []byte(testSOPSEnv), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	plain := filepath.Join(tmp, "plain.env")
+	err = ioutil.WriteFile(plain, []byte("PASSWORD=plain\n"), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	other := filepath.Join(tmp, "other.env")
+	err = ioutil.WriteFile(other, []byte(testSOPSEnv), 0644)
+	assert.Equal(t, nil, err, nil)
+
+	sc := &sopsConfig{Path: sopsPath, Env: []string{"SOPS_TEST=env"}}
+	fSys := makeSOPSFS(filesys.MakeFsOnDisk(), []string{encrypted, plain}, sc)
+
+	content, err := fSys.ReadFile(encrypted)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "decrypted --decrypt "+encrypted+" env\n", string(content), nil)
+
+	content, err = fSys.ReadFile(plain)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "PASSWORD=plain\n", string(content), nil)
+
+	// only generator sources are decrypted
+	content, err = fSys.ReadFile(other)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testSOPSEnv, string(content), nil)
+
+	sc.Path = filepath.Join(tmp, "does-not-exist")
+	_, err = fSys.ReadFile(encrypted)
+	assert.Error(t, err, nil)
+}
+
+func TestGetKustomizationGeneratorSources(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml":    "secretGenerator:\n- name: base\n  envs:\n  - secret.env\n",
+		"overlay/kustomization.yaml": "resources:\n- ../base\nconfigMapGenerator:\n- name: overlay\n  files:\n  - key=config.yaml\n",
+	})
+
+	sources := getKustomizationGeneratorSources(filesys.MakeFsOnDisk(), filepath.Join(dir, "overlay"))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "overlay", "config.yaml"),
+		filepath.Join(dir, "base", "secret.env"),
+	}, sources, nil)
+}
+
+func TestKustomizationBuildSOPS(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"base/kustomization.yaml": "secretGenerator:\n- name: test\n  envs:\n  - secret.env\n  options:\n    disableNameSuffixHash: true\n",
+		"base/secret.env":         testSOPSEnv,
+		"kustomization.yaml":      "resources:\n- base\n",
+	})
+
+	// fake sops binary
+	sopsPath := filepath.Join(dir, "sops")
+	err := ioutil.WriteFile(sopsPath, []byte("#!/bin/sh\necho PASSWORD=decrypted\n"), 0755)
+	assert.Equal(t, nil, err, nil)
+
+	d := schema.TestResourceDataRaw(t, dataSourceKustomization().Schema, map[string]interface{}{
+		"path": dir,
+	})
+	diags := kustomizationBuild(context.TODO(), d, &Config{BuildLock: newBuildLock(), Sops: &sopsConfig{Path: sopsPath}})
+	assert.Equal(t, false, diags.HasError(), diags)
+
+	// base64 encoded "decrypted"
+	assert.Contains(t, d.Get("manifests").(map[string]interface{})["_/Secret/_/test"], `"PASSWORD":"ZGVjcnlwdGVk"`, nil)
+}
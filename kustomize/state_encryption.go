@@ -0,0 +1,251 @@
+package kustomize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encrypted manifests in state start with this prefix,
+// manifests without it are plaintext
+const encryptedManifestPrefix = "kustomization-encrypted:v1:"
+
+const stateEncryptionSaltSize = 16
+
+// stateEncryption encrypts manifests stored in the state with AES-GCM,
+// using a key derived from the passphrase with scrypt
+//
+// Every provider configuration uses a new random salt, every manifest
+// a new random nonce, both are stored with the ciphertext. Keys for
+// the salts of existing ciphertexts are derived once and cached.
+type stateEncryption struct {
+	passphrase []byte
+	salt       []byte
+
+	mu   sync.Mutex
+	keys map[string]cipher.AEAD
+}
+
+func getStateEncryption(passphrase string) (*stateEncryption, error) {
+	if passphrase == "" {
+		return nil, nil
+	}
+
+	salt := make([]byte, stateEncryptionSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	se := &stateEncryption{
+		passphrase: []byte(passphrase),
+		salt:       salt,
+		keys:       make(map[string]cipher.AEAD),
+	}
+
+	// derive the key of the salt early, to fail configuring
+	if _, err := se.aead(salt); err != nil {
+		return nil, err
+	}
+
+	return se, nil
+}
+
+// aead returns the cipher of the key derived with salt
+func (se *stateEncryption) aead(salt []byte) (cipher.AEAD, error) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if aead, ok := se.keys[string(salt)]; ok {
+		return aead, nil
+	}
+
+	key, err := scrypt.Key(se.passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	se.keys[string(salt)] = aead
+
+	return aead, nil
+}
+
+func isEncryptedManifest(s string) bool {
+	return strings.HasPrefix(s, encryptedManifestPrefix)
+}
+
+func (se *stateEncryption) encrypt(plaintext string) (string, error) {
+	if se == nil || plaintext == "" || isEncryptedManifest(plaintext) {
+		return plaintext, nil
+	}
+
+	aead, err := se.aead(se.salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := append(append([]byte{}, se.salt...), nonce...)
+	sealed = aead.Seal(sealed, nonce, []byte(plaintext), nil)
+
+	return encryptedManifestPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (se *stateEncryption) decrypt(s string) (string, error) {
+	if !isEncryptedManifest(s) {
+		return s, nil
+	}
+
+	if se == nil {
+		return "", fmt.Errorf("manifest in state is encrypted, but the provider has no state_encryption_key")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedManifestPrefix))
+	if err != nil || len(sealed) < stateEncryptionSaltSize {
+		return "", fmt.Errorf("manifest in state is not valid encrypted data")
+	}
+	salt, sealed := sealed[:stateEncryptionSaltSize], sealed[stateEncryptionSaltSize:]
+
+	aead, err := se.aead(salt)
+	if err != nil {
+		return "", err
+	}
+
+	n := aead.NonceSize()
+	if len(sealed) < n {
+		return "", fmt.Errorf("manifest in state is not valid encrypted data")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting manifest in state failed, state_encryption_key changed?")
+	}
+
+	return string(plaintext), nil
+}
+
+// with encryption enabled, the state keeps the hash of the manifest,
+// to diff it with the configuration, and its ciphertext in
+// manifest_encrypted, to read the manifest in state
+const manifestHashPrefix = "sha256:"
+
+func hashManifest(manifest string) string {
+	h := sha256.Sum256([]byte(manifest))
+	return manifestHashPrefix + hex.EncodeToString(h[:])
+}
+
+// suppressEncryptedManifestDiff suppresses the diff between the hash
+// of the manifest in state and the unchanged manifest of the configuration
+func suppressEncryptedManifestDiff(k, old, new string, d *schema.ResourceData) bool {
+	return strings.HasPrefix(old, manifestHashPrefix) && old == hashManifest(new)
+}
+
+// decryptManifest returns the plaintext of the manifest value v,
+// decrypted from the ciphertext of manifest_encrypted if v is a hash
+func decryptManifest(v interface{}, encrypted interface{}, m interface{}) (string, error) {
+	manifest := v.(string)
+	if !strings.HasPrefix(manifest, manifestHashPrefix) {
+		return manifest, nil
+	}
+
+	plaintext, err := m.(*Config).StateEncryption.decrypt(encrypted.(string))
+	if err != nil {
+		return "", err
+	}
+
+	if hashManifest(plaintext) != manifest {
+		return "", fmt.Errorf("manifest in state does not match manifest_encrypted")
+	}
+
+	return plaintext, nil
+}
+
+// getManifest returns the plaintext of the manifest, of the
+// configuration or the state
+func getManifest(d changeGetter, m interface{}) (string, error) {
+	return decryptManifest(d.Get("manifest"), d.Get("manifest_encrypted"), m)
+}
+
+// manifestChangeGetter is implemented by ResourceData and ResourceDiff
+type manifestChangeGetter interface {
+	GetChange(string) (interface{}, interface{})
+}
+
+// getManifestChange returns the plaintext of the manifest in state
+// and of the planned manifest
+func getManifestChange(d manifestChangeGetter, m interface{}) (string, string, error) {
+	o, n := d.GetChange("manifest")
+	eo, en := d.GetChange("manifest_encrypted")
+
+	do, err := decryptManifest(o, eo, m)
+	if err != nil {
+		return "", "", err
+	}
+
+	dm, err := decryptManifest(n, en, m)
+	if err != nil {
+		return "", "", err
+	}
+
+	return do, dm, nil
+}
+
+// setManifest stores the manifest, or its hash and ciphertext if
+// encryption is enabled, keeping the ciphertext in the state if
+// the manifest did not change
+func setManifest(d *schema.ResourceData, manifest string, m interface{}) error {
+	se := m.(*Config).StateEncryption
+	if se == nil {
+		d.Set("manifest", manifest)
+		d.Set("manifest_encrypted", "")
+		return nil
+	}
+
+	encrypted := d.Get("manifest_encrypted").(string)
+	if plaintext, err := se.decrypt(encrypted); err != nil || encrypted == "" || plaintext != manifest {
+		if encrypted, err = se.encrypt(manifest); err != nil {
+			return fmt.Errorf("encrypting manifest failed: %s", err)
+		}
+	}
+
+	d.Set("manifest", hashManifest(manifest))
+	d.Set("manifest_encrypted", encrypted)
+	return nil
+}
+
+// diffEncryptedManifest plans the ciphertext of changed manifests,
+// unchanged manifests keep their hash and ciphertext in state
+func diffEncryptedManifest(d *schema.ResourceDiff, m interface{}) error {
+	if !d.HasChange("manifest") {
+		return nil
+	}
+
+	if m.(*Config).StateEncryption == nil && d.Get("manifest_encrypted").(string) == "" {
+		return nil
+	}
+
+	return d.SetNewComputed("manifest_encrypted")
+}
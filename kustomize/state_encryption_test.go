@@ -0,0 +1,123 @@
+package kustomize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+const testStateManifest = `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"test"},"stringData":{"key":"value"}}`
+
+func TestStateEncryption(t *testing.T) {
+	se, err := getStateEncryption("test-passphrase")
+	assert.Equal(t, nil, err, nil)
+
+	encrypted, err := se.encrypt(testStateManifest)
+	assert.Equal(t, nil, err, nil)
+	assert.True(t, strings.HasPrefix(encrypted, encryptedManifestPrefix), nil)
+	assert.NotContains(t, encrypted, "value", nil)
+
+	// every encryption uses a new nonce
+	again, _ := se.encrypt(testStateManifest)
+	assert.NotEqual(t, encrypted, again, nil)
+
+	// encrypting twice is a no-op
+	twice, _ := se.encrypt(encrypted)
+	assert.Equal(t, encrypted, twice, nil)
+
+	decrypted, err := se.decrypt(encrypted)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testStateManifest, decrypted, nil)
+
+	// plaintext state from before encryption was enabled
+	decrypted, err = se.decrypt(testStateManifest)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testStateManifest, decrypted, nil)
+}
+
+func TestStateEncryptionSalt(t *testing.T) {
+	se, _ := getStateEncryption("test-passphrase")
+	other, _ := getStateEncryption("test-passphrase")
+	assert.NotEqual(t, se.salt, other.salt, nil)
+
+	// ciphertexts of other provider configurations,
+	// with other salts, are decrypted
+	encrypted, _ := se.encrypt(testStateManifest)
+	decrypted, err := other.decrypt(encrypted)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testStateManifest, decrypted, nil)
+}
+
+func TestStateEncryptionKeys(t *testing.T) {
+	se, _ := getStateEncryption("test-passphrase")
+	other, _ := getStateEncryption("other-passphrase")
+	encrypted, _ := se.encrypt(testStateManifest)
+
+	_, err := other.decrypt(encrypted)
+	assert.EqualError(t, err, "decrypting manifest in state failed, state_encryption_key changed?", nil)
+
+	var disabled *stateEncryption
+	plaintext, err := disabled.encrypt(testStateManifest)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testStateManifest, plaintext, nil)
+
+	_, err = disabled.decrypt(encrypted)
+	assert.EqualError(t, err, "manifest in state is encrypted, but the provider has no state_encryption_key", nil)
+
+	_, err = se.decrypt(encryptedManifestPrefix + "AAAA")
+	assert.EqualError(t, err, "manifest in state is not valid encrypted data", nil)
+
+	none, err := getStateEncryption("")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, (*stateEncryption)(nil), none, nil)
+}
+
+func TestSetManifest(t *testing.T) {
+	se, _ := getStateEncryption("test-passphrase")
+	m := &Config{StateEncryption: se}
+
+	d := schema.TestResourceDataRaw(t, kustomizationResource().Schema, map[string]interface{}{
+		"manifest": testStateManifest,
+	})
+
+	assert.Equal(t, nil, setManifest(d, testStateManifest, m), nil)
+	assert.Equal(t, hashManifest(testStateManifest), d.Get("manifest"), nil)
+	encrypted := d.Get("manifest_encrypted").(string)
+	assert.True(t, strings.HasPrefix(encrypted, encryptedManifestPrefix), nil)
+
+	plaintext, err := getManifest(d, m)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, testStateManifest, plaintext, nil)
+
+	// unchanged manifests keep their ciphertext
+	assert.Equal(t, nil, setManifest(d, testStateManifest, m), nil)
+	assert.Equal(t, encrypted, d.Get("manifest_encrypted"), nil)
+
+	changed := strings.Replace(testStateManifest, "value", "changed", 1)
+	assert.Equal(t, nil, setManifest(d, changed, m), nil)
+	assert.NotEqual(t, encrypted, d.Get("manifest_encrypted"), nil)
+
+	plaintext, err = getManifest(d, m)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, changed, plaintext, nil)
+
+	// the hash in state has to match the ciphertext
+	d.Set("manifest_encrypted", encrypted)
+	_, err = getManifest(d, m)
+	assert.EqualError(t, err, "manifest in state does not match manifest_encrypted", nil)
+
+	// without encryption, the manifest is stored as is
+	assert.Equal(t, nil, setManifest(d, changed, &Config{}), nil)
+	assert.Equal(t, changed, d.Get("manifest"), nil)
+	assert.Equal(t, "", d.Get("manifest_encrypted"), nil)
+}
+
+func TestSuppressEncryptedManifestDiff(t *testing.T) {
+	changed := strings.Replace(testStateManifest, "value", "changed", 1)
+
+	assert.Equal(t, true, suppressEncryptedManifestDiff("manifest", hashManifest(testStateManifest), testStateManifest, nil), nil)
+	assert.Equal(t, false, suppressEncryptedManifestDiff("manifest", hashManifest(testStateManifest), changed, nil), nil)
+	assert.Equal(t, false, suppressEncryptedManifestDiff("manifest", testStateManifest, changed, nil), nil)
+}
@@ -0,0 +1,78 @@
+package kustomize
+
+import (
+	"strings"
+
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// staticRESTMapper looks up mappings in the static mappings first,
+// and only falls back to discovery for kinds that are not found,
+// for credentials that lack permissions for discovery
+type staticRESTMapper struct {
+	k8smeta.ResettableRESTMapper
+	static *k8smeta.DefaultRESTMapper
+}
+
+func newStaticRESTMapper(static *k8smeta.DefaultRESTMapper, discovery k8smeta.ResettableRESTMapper) k8smeta.ResettableRESTMapper {
+	if static == nil {
+		return discovery
+	}
+
+	return &staticRESTMapper{
+		ResettableRESTMapper: discovery,
+		static:               static,
+	}
+}
+
+func (m *staticRESTMapper) RESTMapping(gk k8sschema.GroupKind, versions ...string) (*k8smeta.RESTMapping, error) {
+	if mapping, err := m.static.RESTMapping(gk, versions...); err == nil {
+		return mapping, nil
+	}
+
+	return m.ResettableRESTMapper.RESTMapping(gk, versions...)
+}
+
+func (m *staticRESTMapper) RESTMappings(gk k8sschema.GroupKind, versions ...string) ([]*k8smeta.RESTMapping, error) {
+	if mappings, err := m.static.RESTMappings(gk, versions...); err == nil && len(mappings) > 0 {
+		return mappings, nil
+	}
+
+	return m.ResettableRESTMapper.RESTMappings(gk, versions...)
+}
+
+func (m *staticRESTMapper) invalidateGroups(groups ...string) {
+	invalidateGroups(m.ResettableRESTMapper, groups...)
+}
+
+// getStaticRESTMapper returns a mapper for the rest_mapping
+// provider configuration, or nil if there are no mappings
+func getStaticRESTMapper(in []interface{}) *k8smeta.DefaultRESTMapper {
+	if len(in) == 0 {
+		return nil
+	}
+
+	static := k8smeta.NewDefaultRESTMapper(nil)
+	for _, v := range in {
+		m := v.(map[string]interface{})
+
+		gvk := k8sschema.GroupVersionKind{
+			Group:   m["group"].(string),
+			Version: m["version"].(string),
+			Kind:    m["kind"].(string),
+		}
+
+		plural := gvk.GroupVersion().WithResource(m["resource"].(string))
+		singular := gvk.GroupVersion().WithResource(strings.ToLower(gvk.Kind))
+
+		scope := k8smeta.RESTScopeRoot
+		if m["namespaced"].(bool) {
+			scope = k8smeta.RESTScopeNamespace
+		}
+
+		static.AddSpecific(gvk, plural, singular, scope)
+	}
+
+	return static
+}
@@ -0,0 +1,46 @@
+package kustomize
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8smeta "k8s.io/apimachinery/pkg/api/meta"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type failingRESTMapper struct {
+	k8smeta.ResettableRESTMapper
+}
+
+func (m failingRESTMapper) RESTMapping(gk k8sschema.GroupKind, versions ...string) (*k8smeta.RESTMapping, error) {
+	return nil, fmt.Errorf("discovery forbidden")
+}
+
+func TestStaticRESTMapper(t *testing.T) {
+	static := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{
+			"group":      "apps",
+			"version":    "v1",
+			"kind":       "Deployment",
+			"resource":   "deployments",
+			"namespaced": true,
+		},
+	})
+
+	mapper := newStaticRESTMapper(static, failingRESTMapper{})
+
+	mapping, err := mapper.RESTMapping(k8sschema.GroupKind{Group: "apps", Kind: "Deployment"}, "v1")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "deployments", mapping.Resource.Resource, nil)
+	assert.Equal(t, k8smeta.RESTScopeNameNamespace, mapping.Scope.Name(), nil)
+
+	_, err = mapper.RESTMapping(k8sschema.GroupKind{Kind: "ConfigMap"}, "v1")
+	assert.EqualError(t, err, "discovery forbidden", nil)
+}
+
+func TestStaticRESTMapperNone(t *testing.T) {
+	discovery := failingRESTMapper{}
+
+	assert.Equal(t, discovery, newStaticRESTMapper(getStaticRESTMapper(nil), discovery), nil)
+}
@@ -0,0 +1,124 @@
+package kustomize
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/kustomize/api/resmap"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// pod templates of workloads, their metadata is stripped too
+var podTemplatePaths = [][]string{
+	{"spec", "template"},
+	{"spec", "jobTemplate", "spec", "template"},
+}
+
+func stripPatternsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validateStripPattern,
+		},
+	}
+}
+
+func validateStripPattern(v interface{}, k string) (ws []string, es []error) {
+	if _, err := compileIgnorePatterns([]string{v.(string)}); err != nil {
+		es = append(es, fmt.Errorf("invalid %s: %s", k, err))
+	}
+
+	return ws, es
+}
+
+// stripMetadata removes the annotations and labels matching any of the
+// patterns from all resources of rm and their pod templates, labels of
+// pod templates used by the selector of the resource are kept
+func stripMetadata(rm resmap.ResMap, annotations []*regexp.Regexp, labels []*regexp.Regexp) error {
+	if len(annotations) == 0 && len(labels) == 0 {
+		return nil
+	}
+
+	for _, r := range rm.Resources() {
+		node := &r.RNode
+
+		selector, err := node.Pipe(kyaml.Lookup("spec", "selector", "matchLabels"))
+		if err != nil {
+			return err
+		}
+		keep := make(map[string]bool)
+		if selector != nil {
+			fields, err := selector.Fields()
+			if err != nil {
+				return err
+			}
+			for _, f := range fields {
+				keep[f] = true
+			}
+		}
+
+		if err := stripKeys(node, []string{"metadata", "annotations"}, annotations, nil); err != nil {
+			return err
+		}
+		if err := stripKeys(node, []string{"metadata", "labels"}, labels, nil); err != nil {
+			return err
+		}
+
+		for _, p := range podTemplatePaths {
+			metadata := append(append([]string{}, p...), "metadata")
+			if err := stripKeys(node, append(metadata, "annotations"), annotations, nil); err != nil {
+				return err
+			}
+			if err := stripKeys(node, append(metadata, "labels"), labels, keep); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// stripKeys removes the keys matching any of the patterns, except
+// keys to keep, from the map at path, and the map if it is empty
+func stripKeys(node *kyaml.RNode, path []string, patterns []*regexp.Regexp, keep map[string]bool) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	m, err := node.Pipe(kyaml.Lookup(path...))
+	if err != nil || m == nil {
+		return err
+	}
+
+	keys, err := m.Fields()
+	if err != nil {
+		return err
+	}
+
+	remaining := len(keys)
+	for _, k := range keys {
+		if keep[k] || !matchesAny(k, patterns) {
+			continue
+		}
+
+		if _, err := m.Pipe(kyaml.Clear(k)); err != nil {
+			return err
+		}
+		remaining--
+	}
+
+	if remaining == 0 {
+		parent, err := node.Pipe(kyaml.Lookup(path[:len(path)-1]...))
+		if err != nil {
+			return err
+		}
+		if _, err := parent.Pipe(kyaml.Clear(path[len(path)-1])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+func TestStripMetadata(t *testing.T) {
+	rf := provider.NewDefaultDepProvider().GetResourceFactory()
+	rm, err := resmap.NewFactory(rf).NewResMapFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  annotations:
+    checksum/config: abc
+  labels:
+    app: test
+    helm.sh/chart: test-1.0.0
+spec:
+  selector:
+    matchLabels:
+      app: test
+      helm.sh/chart: test-1.0.0
+  template:
+    metadata:
+      annotations:
+        checksum/config: abc
+        checksum/secret: def
+      labels:
+        app: test
+        helm.sh/chart: test-1.0.0
+        app.kubernetes.io/managed-by: Helm
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+spec:
+  jobTemplate:
+    spec:
+      template:
+        metadata:
+          labels:
+            app.kubernetes.io/managed-by: Helm
+`))
+	assert.Equal(t, nil, err, nil)
+
+	annotations, _ := compileIgnorePatterns([]string{"checksum/.*"})
+	labels, _ := compileIgnorePatterns([]string{"helm.sh/chart", "app.kubernetes.io/managed-by"})
+
+	err = stripMetadata(rm, annotations, labels)
+	assert.Equal(t, nil, err, nil)
+
+	res, _, err := flattenBuild(rm, buildLimits{})
+	assert.Equal(t, nil, err, nil)
+
+	// selector labels of the pod template are kept
+	assert.Equal(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"labels":{"app":"test"},"name":"test"},"spec":{"selector":{"matchLabels":{"app":"test","helm.sh/chart":"test-1.0.0"}},"template":{"metadata":{"labels":{"app":"test","helm.sh/chart":"test-1.0.0"}}}}}`, res["apps/Deployment/_/test"], nil)
+	assert.Equal(t, `{"apiVersion":"batch/v1","kind":"CronJob","metadata":{"name":"test"},"spec":{"jobTemplate":{"spec":{"template":{"metadata":{}}}}}}`, res["batch/CronJob/_/test"], nil)
+}
+
+func TestStripMetadataNone(t *testing.T) {
+	rf := provider.NewDefaultDepProvider().GetResourceFactory()
+	rm, err := resmap.NewFactory(rf).NewResMapFromBytes([]byte(testCacheConfigMap))
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, nil, stripMetadata(rm, nil, nil), nil)
+
+	res, _, err := flattenBuild(rm, buildLimits{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `{"apiVersion":"v1","data":{"key":"value"},"kind":"ConfigMap","metadata":{"name":"test"}}`, res["_/ConfigMap/_/test"], nil)
+}
+
+func TestValidateStripPattern(t *testing.T) {
+	_, es := validateStripPattern("checksum/.*", "strip_annotations")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateStripPattern("checksum/(", "strip_annotations")
+	assert.Equal(t, 1, len(es), nil)
+}
@@ -0,0 +1,55 @@
+package kustomize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+func validateStructuredData(v interface{}, k string) (ws []string, es []error) {
+	for key, value := range v.(map[string]interface{}) {
+		if !json.Valid([]byte(value.(string))) {
+			es = append(es, fmt.Errorf("%s: value of %q is not valid JSON, use jsonencode()", k, key))
+		}
+	}
+
+	return ws, es
+}
+
+// getStructuredDataLiterals serializes the JSON encoded values of data
+// as JSON or YAML documents, returned as key=document literal sources
+// sorted by key
+//
+// Documents end with a newline, so kustomize never strips quotes
+// from JSON strings.
+func getStructuredDataLiterals(data map[string]interface{}, asYAML bool) (literals []string, err error) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		in := []byte(data[k].(string))
+
+		var out []byte
+		if asYAML {
+			out, err = yaml.JSONToYAML(in)
+		} else {
+			var b bytes.Buffer
+			err = json.Indent(&b, in, "", "  ")
+			b.WriteString("\n")
+			out = b.Bytes()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %s", k, err)
+		}
+
+		literals = append(literals, fmt.Sprintf("%s=%s", k, out))
+	}
+
+	return literals, nil
+}
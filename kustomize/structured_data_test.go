@@ -0,0 +1,36 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStructuredDataLiterals(t *testing.T) {
+	data := map[string]interface{}{
+		"config.yaml": `{"name":"example","ports":[80,443]}`,
+		"app.json":    `"quoted"`,
+	}
+
+	literals, err := getStructuredDataLiterals(data, true)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{
+		"app.json=quoted\n",
+		"config.yaml=name: example\nports:\n- 80\n- 443\n",
+	}, literals, nil)
+
+	literals, err = getStructuredDataLiterals(data, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{
+		"app.json=\"quoted\"\n",
+		"config.yaml={\n  \"name\": \"example\",\n  \"ports\": [\n    80,\n    443\n  ]\n}\n",
+	}, literals, nil)
+}
+
+func TestValidateStructuredData(t *testing.T) {
+	_, es := validateStructuredData(map[string]interface{}{"config.yaml": `{"key":"value"}`}, "data_yaml")
+	assert.Equal(t, 0, len(es), nil)
+
+	_, es = validateStructuredData(map[string]interface{}{"config.yaml": "key: value"}, "data_yaml")
+	assert.Equal(t, 1, len(es), nil)
+}
@@ -1,42 +1,70 @@
 package kustomize
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/kbst/terraform-provider-kustomize/manifest"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/api/hasher"
+	"sigs.k8s.io/kustomize/api/provider"
 	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/yaml"
 )
 
 func flattenKustomizationIDs(rm resmap.ResMap) (ids []string, idsPrio [][]string, err error) {
-	p0 := []string{}
-	p1 := []string{}
-	p2 := []string{}
+	var mIds []manifest.ID
 	for _, id := range rm.AllIds() {
-		kr := &kManifestId{
-			group:     id.Group,
-			kind:      id.Kind,
-			namespace: id.Namespace,
-			name:      id.Name,
+		mId := manifest.ID{
+			Group:     id.Group,
+			Kind:      id.Kind,
+			Namespace: id.Namespace,
+			Name:      id.Name,
 		}
 
-		ids = append(ids, kr.string())
+		ids = append(ids, mId.String())
+		mIds = append(mIds, mId)
+	}
 
-		p := determinePrefix(kr)
-		if p < 5 {
-			p0 = append(p0, kr.string())
-		} else if p == 9 {
-			p2 = append(p2, kr.string())
-		} else {
-			p1 = append(p1, kr.string())
-		}
+	return ids, manifest.GroupByPriority(mIds), nil
+}
+
+// generatedNameSuffix matches names with the content hash
+// suffix of generators, e.g. test-5g2kf9tt7b
+var generatedNameSuffix = regexp.MustCompile(`^(.+)-([a-z0-9]{10})$`)
+
+// isGenerated returns true for ConfigMaps and Secrets with the content
+// hash suffix of generators, the hash is computed again to not mistake
+// static names ending with ten characters for generated ones
+func isGenerated(r *resource.Resource) bool {
+	if k := r.GetKind(); k != "ConfigMap" && k != "Secret" {
+		return false
 	}
 
-	idsPrio = append(idsPrio, p0)
-	idsPrio = append(idsPrio, p1)
-	idsPrio = append(idsPrio, p2)
+	m := generatedNameSuffix.FindStringSubmatch(r.GetName())
+	if m == nil {
+		return false
+	}
+
+	c := r.DeepCopy()
+	if err := c.SetName(m[1]); err != nil {
+		return false
+	}
 
-	return ids, idsPrio, nil
+	h, err := c.Hash(&hasher.Hasher{})
+	return err == nil && h == m[2]
 }
 
-func flattenKustomizationResources(rm resmap.ResMap) (res map[string]string, err error) {
-	res = make(map[string]string)
+// flattenGeneratedIDs returns the IDs of the resources of rm
+// split into generated resources with a hash suffix and static ones
+func flattenGeneratedIDs(rm resmap.ResMap) (generated []string, static []string) {
+	generated, static = []string{}, []string{}
 	for _, r := range rm.Resources() {
 		kr := &kManifestId{
 			group:     r.CurId().Group,
@@ -45,11 +73,280 @@ func flattenKustomizationResources(rm resmap.ResMap) (res map[string]string, err
 			name:      r.GetName(),
 		}
 
+		if isGenerated(r) {
+			generated = append(generated, kr.string())
+			continue
+		}
+		static = append(static, kr.string())
+	}
+
+	return generated, static
+}
+
+// flattenBuild returns the JSON manifests of rm by ID and the ID of the
+// build, the hash of its YAML, serializing one resource at a time to not
+// hold the build in memory once more, and fails early if the build
+// exceeds the limits
+func flattenBuild(rm resmap.ResMap, limits buildLimits) (res map[string]string, id string, err error) {
+	if err := limits.checkResources(rm.Size()); err != nil {
+		return nil, "", err
+	}
+
+	h := sha512.New()
+	size := 0
+	res = make(map[string]string, rm.Size())
+	for i, r := range rm.Resources() {
+		kr := &kManifestId{
+			group:     r.CurId().Group,
+			kind:      r.CurId().Kind,
+			namespace: r.GetNamespace(),
+			name:      r.GetName(),
+		}
+
 		json, err := r.MarshalJSON()
 		if err != nil {
-			return nil, err
+			return nil, "", err
+		}
+
+		size += len(json)
+		if err := limits.checkManifestBytes(size); err != nil {
+			return nil, "", err
 		}
+
+		// same as hashing rm.AsYaml
+		y, err := yaml.JSONToYAML(json)
+		if err != nil {
+			return nil, "", err
+		}
+		if i > 0 {
+			h.Write([]byte(resourcesYAMLSeparator))
+		}
+		h.Write(y)
+
 		res[kr.string()] = string(json)
 	}
-	return res, nil
+
+	return res, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// groupKustomizationResources groups the ids, and their manifests if
+// not nil, by the key of the ID, e.g. the kind, sorted by key
+func groupKustomizationResources(ids []string, manifests map[string]string, attr string, key func(manifest.ID) string) ([]interface{}, error) {
+	groups := make(map[string]map[string]interface{})
+	var keys []string
+	for _, s := range ids {
+		id, err := manifest.ParseID(s)
+		if err != nil {
+			return nil, err
+		}
+
+		k := key(id)
+		g, ok := groups[k]
+		if !ok {
+			g = map[string]interface{}{
+				attr:        k,
+				"ids":       []string{},
+				"manifests": map[string]string{},
+			}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+
+		g["ids"] = append(g["ids"].([]string), s)
+		if m, ok := manifests[s]; ok {
+			g["manifests"].(map[string]string)[s] = m
+		}
+	}
+	sort.Strings(keys)
+
+	out := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, groups[k])
+	}
+
+	return out, nil
+}
+
+func idsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeSet,
+		Computed: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+		Set:      idSetHash,
+	}
+}
+
+func namespaceFilterSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+}
+
+func groupedResourcesSchema(attr string) *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				attr: &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"ids": &schema.Schema{
+					Type:     schema.TypeSet,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+					Set:      idSetHash,
+				},
+				"manifests": &schema.Schema{
+					Type:     schema.TypeMap,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+// namespaces that exist in every cluster
+var builtinNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// getReferencedNamespaces returns the namespaces of the
+// namespaced resources of rm, sorted
+func getReferencedNamespaces(rm resmap.ResMap) []string {
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, r := range rm.Resources() {
+		ns := r.GetNamespace()
+		if ns == "" || seen[ns] {
+			continue
+		}
+
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+// addMissingNamespaces appends a Namespace to rm for every namespace
+// referenced but not defined by its resources, except the namespaces
+// that exist in every cluster
+func addMissingNamespaces(rm resmap.ResMap) error {
+	defined := make(map[string]bool)
+	for _, r := range rm.Resources() {
+		if r.CurId().Group == "" && r.CurId().Kind == "Namespace" {
+			defined[r.GetName()] = true
+		}
+	}
+
+	rf := provider.NewDefaultDepProvider().GetResourceFactory()
+	for _, ns := range getReferencedNamespaces(rm) {
+		if defined[ns] || builtinNamespaces[ns] {
+			continue
+		}
+
+		r := rf.FromMap(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]interface{}{
+				"name": ns,
+			},
+		})
+		if err := rm.Append(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filterNamespaces removes all resources from rm that are not in one
+// of the namespaces, except the Namespaces named in namespaces, the
+// resources without a namespace, e.g. cluster scoped ones, are removed
+func filterNamespaces(rm resmap.ResMap, namespaces []string) error {
+	keep := make(map[string]bool)
+	for _, ns := range namespaces {
+		keep[ns] = true
+	}
+
+	for _, r := range rm.Resources() {
+		ns := r.GetNamespace()
+		if r.CurId().Group == "" && r.CurId().Kind == "Namespace" {
+			ns = r.GetName()
+		}
+
+		if keep[ns] {
+			continue
+		}
+
+		if err := rm.Remove(r.CurId()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hashManifests returns the SHA256 hashes of the manifests by ID
+func hashManifests(manifests map[string]string) map[string]string {
+	hashes := make(map[string]string, len(manifests))
+	for id, m := range manifests {
+		h := sha256.Sum256([]byte(m))
+		hashes[id] = hex.EncodeToString(h[:])
+	}
+
+	return hashes
+}
+
+// resourcesYAMLSeparator separates resources in YAML streams
+const resourcesYAMLSeparator = "---\n"
+
+// writeResourcesYAML writes the resources of rm to w like rm.AsYaml,
+// one resource at a time instead of the whole stream at once
+func writeResourcesYAML(w io.Writer, rm resmap.ResMap) error {
+	for i, r := range rm.Resources() {
+		y, err := r.AsYAML()
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, resourcesYAMLSeparator); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(y); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func flattenLoadBalancerIngress(u *k8sunstructured.Unstructured) (lbi []interface{}) {
+	ingress, _, _ := k8sunstructured.NestedSlice(u.UnstructuredContent(), "status", "loadBalancer", "ingress")
+	for _, i := range ingress {
+		im, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		hostname, _, _ := k8sunstructured.NestedString(im, "hostname")
+		ip, _, _ := k8sunstructured.NestedString(im, "ip")
+
+		lbi = append(lbi, map[string]interface{}{
+			"hostname": hostname,
+			"ip":       ip,
+		})
+	}
+	return lbi
 }
@@ -1,10 +1,17 @@
 package kustomize
 
 import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
 	"testing"
 
+	"github.com/kbst/terraform-provider-kustomize/manifest"
 	"github.com/stretchr/testify/assert"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resmap"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
 )
 
@@ -35,3 +42,236 @@ func TestConvertKustomizationIDs(t *testing.T) {
 	expP3 := []string{}
 	assert.ElementsMatch(t, expP3, idsPrio[2], nil)
 }
+
+func TestFlattenGeneratedIDs(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	fSys.WriteFile("kustomization.yaml", []byte(`
+resources:
+- static.yaml
+configMapGenerator:
+- name: generated
+  literals: [key=value]
+- name: unhashed
+  literals: [key=value]
+  options:
+    disableNameSuffixHash: true
+secretGenerator:
+- name: generated
+  literals: [key=value]
+`))
+	fSys.WriteFile("static.yaml", []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: static-abcdefghij
+data:
+  key: value
+`))
+
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	rm, err := k.Run(fSys, ".")
+	assert.Equal(t, nil, err, nil)
+
+	generated, static := flattenGeneratedIDs(rm)
+	assert.Equal(t, 2, len(generated), nil)
+	for _, id := range generated {
+		assert.Regexp(t, `^_/(ConfigMap|Secret)/_/generated-[a-z0-9]{10}$`, id, nil)
+	}
+	assert.ElementsMatch(t, []string{"_/ConfigMap/_/static-abcdefghij", "_/ConfigMap/_/unhashed"}, static, nil)
+}
+
+func TestFlattenLoadBalancerIngress(t *testing.T) {
+	u := &k8sunstructured.Unstructured{}
+	u.SetUnstructuredContent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"status": map[string]interface{}{
+			"loadBalancer": map[string]interface{}{
+				"ingress": []interface{}{
+					map[string]interface{}{"ip": "192.0.2.1"},
+					map[string]interface{}{"hostname": "lb.example.com"},
+				},
+			},
+		},
+	})
+
+	lbi := flattenLoadBalancerIngress(u)
+
+	exp := []interface{}{
+		map[string]interface{}{"hostname": "", "ip": "192.0.2.1"},
+		map[string]interface{}{"hostname": "lb.example.com", "ip": ""},
+	}
+	assert.Equal(t, exp, lbi, nil)
+}
+
+func TestFlattenBuild(t *testing.T) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	rm, err := k.Run(filesys.MakeFsOnDisk(), "test_kustomizations/basic/initial")
+	assert.Equal(t, nil, err, nil)
+
+	res, id, err := flattenBuild(rm, buildLimits{})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 4, len(res), nil)
+	assert.Contains(t, res["_/Namespace/_/test-basic"], `"kind":"Namespace"`, nil)
+
+	// the ID is the hash of the YAML of the build
+	yaml, err := rm.AsYaml()
+	assert.Equal(t, nil, err, nil)
+	h := sha512.Sum512(yaml)
+	assert.Equal(t, hex.EncodeToString(h[:]), id, nil)
+
+	var b bytes.Buffer
+	assert.Equal(t, nil, writeResourcesYAML(&b, rm), nil)
+	assert.Equal(t, string(yaml), b.String(), nil)
+}
+
+func TestFlattenBuildLimits(t *testing.T) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	rm, err := k.Run(filesys.MakeFsOnDisk(), "test_kustomizations/basic/initial")
+	assert.Equal(t, nil, err, nil)
+
+	_, _, err = flattenBuild(rm, getBuildLimits(4, 0))
+	assert.Equal(t, nil, err, nil)
+
+	_, _, err = flattenBuild(rm, getBuildLimits(3, 0))
+	assert.EqualError(t, err, "build has 4 resources, more than max_build_resources of 3, split the kustomization or raise the limit", nil)
+
+	_, _, err = flattenBuild(rm, getBuildLimits(0, 100))
+	assert.EqualError(t, err, "manifests of the build exceed max_build_manifest_bytes of 100 bytes, split the kustomization or raise the limit", nil)
+}
+
+func TestGroupKustomizationResources(t *testing.T) {
+	ids := []string{
+		"_/Namespace/_/test",
+		"apps/Deployment/test/a",
+		"apps/Deployment/test/b",
+		"_/Service/test/a",
+	}
+	manifests := map[string]string{
+		"_/Namespace/_/test":     "ns",
+		"apps/Deployment/test/a": "a",
+		"apps/Deployment/test/b": "b",
+		"_/Service/test/a":       "svc",
+	}
+
+	byKind, err := groupKustomizationResources(ids, manifests, "kind", func(id manifest.ID) string {
+		return id.Kind
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"kind":      "Deployment",
+			"ids":       []string{"apps/Deployment/test/a", "apps/Deployment/test/b"},
+			"manifests": map[string]string{"apps/Deployment/test/a": "a", "apps/Deployment/test/b": "b"},
+		},
+		map[string]interface{}{
+			"kind":      "Namespace",
+			"ids":       []string{"_/Namespace/_/test"},
+			"manifests": map[string]string{"_/Namespace/_/test": "ns"},
+		},
+		map[string]interface{}{
+			"kind":      "Service",
+			"ids":       []string{"_/Service/test/a"},
+			"manifests": map[string]string{"_/Service/test/a": "svc"},
+		},
+	}, byKind, nil)
+
+	byNamespace, err := groupKustomizationResources(ids, nil, "namespace", func(id manifest.ID) string {
+		return id.Namespace
+	})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 2, len(byNamespace), nil)
+	assert.Equal(t, "", byNamespace[0].(map[string]interface{})["namespace"], nil)
+	assert.Equal(t, []string{"apps/Deployment/test/a", "apps/Deployment/test/b", "_/Service/test/a"}, byNamespace[1].(map[string]interface{})["ids"], nil)
+	assert.Equal(t, map[string]string{}, byNamespace[1].(map[string]interface{})["manifests"], nil)
+
+	_, err = groupKustomizationResources([]string{"invalid"}, nil, "kind", func(id manifest.ID) string {
+		return id.Kind
+	})
+	assert.NotEqual(t, nil, err, nil)
+}
+
+func TestAddMissingNamespaces(t *testing.T) {
+	rf := provider.NewDefaultDepProvider().GetResourceFactory()
+	rm, err := resmap.NewFactory(rf).NewResMapFromBytes([]byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: defined
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  namespace: defined
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+  namespace: generated
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: c
+  namespace: kube-system
+`))
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, []string{"defined", "generated", "kube-system"}, getReferencedNamespaces(rm), nil)
+
+	assert.Equal(t, nil, addMissingNamespaces(rm), nil)
+
+	ids, _, err := flattenKustomizationIDs(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []string{
+		"_/Namespace/_/defined",
+		"_/ConfigMap/defined/a",
+		"_/ConfigMap/generated/b",
+		"_/ConfigMap/kube-system/c",
+		"_/Namespace/_/generated",
+	}, ids, nil)
+}
+
+func TestFilterNamespaces(t *testing.T) {
+	rf := provider.NewDefaultDepProvider().GetResourceFactory()
+	rm, err := resmap.NewFactory(rf).NewResMapFromBytes([]byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant-a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant-b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  namespace: tenant-a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+  namespace: tenant-b
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: shared
+`))
+	assert.Equal(t, nil, err, nil)
+
+	assert.Equal(t, nil, filterNamespaces(rm, []string{"tenant-a"}), nil)
+
+	ids, _, err := flattenKustomizationIDs(rm)
+	assert.Equal(t, nil, err, nil)
+	assert.ElementsMatch(t, []string{
+		"_/Namespace/_/tenant-a",
+		"_/ConfigMap/tenant-a/a",
+	}, ids, nil)
+}
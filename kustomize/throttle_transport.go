@@ -0,0 +1,124 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// throttleClientWarnDelay is the client side rate limiting
+	// delay after which a warning is logged, like kubectl does
+	throttleClientWarnDelay = time.Second
+
+	// priorityLevelHeader names the API priority and fairness
+	// priority level a request was classified as
+	priorityLevelHeader = "X-Kubernetes-PF-PriorityLevel-UID"
+)
+
+// throttleStats summarizes the throttling encountered
+// during the run, by the API server and client side
+type throttleStats struct {
+	mu           sync.Mutex
+	serverCount  int
+	serverWaited time.Duration
+	clientCount  int
+	clientWaited time.Duration
+}
+
+func (s *throttleStats) addServer(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.serverCount++
+	s.serverWaited += d
+}
+
+func (s *throttleStats) addClient(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clientCount++
+	s.clientWaited += d
+}
+
+// summary returns the throttling encountered so far
+func (s *throttleStats) summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fmt.Sprintf(
+		"throttled %d times for %s by the API server and %d times for %s by client side rate limiting so far",
+		s.serverCount, s.serverWaited.Round(time.Millisecond),
+		s.clientCount, s.clientWaited.Round(time.Millisecond),
+	)
+}
+
+// throttleTransport records requests the API server responded
+// 429 Too Many Requests
+//
+// The requests are retried by the client after the Retry-After delay,
+// the transport only logs them, so throttling is retried by one layer.
+type throttleTransport struct {
+	rt    http.RoundTripper
+	stats *throttleStats
+}
+
+func newThrottleTransport(rt http.RoundTripper, stats *throttleStats) http.RoundTripper {
+	return &throttleTransport{rt: rt, stats: stats}
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	delay := throttleDelay(resp.Header.Get("Retry-After"))
+	t.stats.addServer(delay)
+	log.Printf("[WARN] provider kustomization: %s %s: API server throttled the request, priority level %q, retry after %s, %s", req.Method, req.URL.Path, resp.Header.Get(priorityLevelHeader), delay, t.stats.summary())
+
+	return resp, nil
+}
+
+// throttleDelay returns the delay of the Retry-After header, the
+// client only retries requests with a delay in seconds
+func throttleDelay(retryAfter string) time.Duration {
+	s, err := strconv.Atoi(retryAfter)
+	if err != nil || s < 0 {
+		return 0
+	}
+
+	return time.Duration(s) * time.Second
+}
+
+// throttleRateLimiter records client side rate limiting delays
+type throttleRateLimiter struct {
+	flowcontrol.RateLimiter
+	stats *throttleStats
+}
+
+func newThrottleRateLimiter(rl flowcontrol.RateLimiter, stats *throttleStats) flowcontrol.RateLimiter {
+	return &throttleRateLimiter{
+		RateLimiter: rl,
+		stats:       stats,
+	}
+}
+
+func (l *throttleRateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+
+	if waited := time.Since(start); waited > throttleClientWarnDelay {
+		l.stats.addClient(waited)
+		log.Printf("[WARN] provider kustomization: request delayed %s by client side rate limiting, increase client_qps and client_burst to reduce it, %s", waited.Round(time.Millisecond), l.stats.summary())
+	}
+
+	return err
+}
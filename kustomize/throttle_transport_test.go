@@ -0,0 +1,65 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type throttlingTransport struct {
+	throttled int
+	requests  int
+	bodies    []string
+}
+
+func (t *throttlingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	if req.Body != nil {
+		b, _ := ioutil.ReadAll(req.Body)
+		t.bodies = append(t.bodies, string(b))
+	}
+
+	status := http.StatusOK
+	if t.requests <= t.throttled {
+		status = http.StatusTooManyRequests
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+func TestThrottleTransport(t *testing.T) {
+	ft := &throttlingTransport{throttled: 2}
+	tt := &throttleTransport{rt: ft, stats: &throttleStats{}}
+
+	// throttled requests are returned to the client to retry them
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/api/v1/configmaps", strings.NewReader("{}"))
+	resp, err := tt.RoundTrip(req)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, nil)
+	assert.Equal(t, 1, ft.requests, nil)
+
+	resp, _ = tt.RoundTrip(req)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, nil)
+
+	resp, _ = tt.RoundTrip(req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, nil)
+	assert.Equal(t, 3, ft.requests, nil)
+
+	assert.Equal(t, 2, tt.stats.serverCount, nil)
+	assert.Equal(t, 2*time.Second, tt.stats.serverWaited, nil)
+	assert.Contains(t, tt.stats.summary(), "throttled 2 times", nil)
+}
+
+func TestThrottleDelay(t *testing.T) {
+	assert.Equal(t, 3*time.Second, throttleDelay("3"), nil)
+	assert.Equal(t, time.Duration(0), throttleDelay(""), nil)
+	assert.Equal(t, time.Duration(0), throttleDelay("invalid"), nil)
+}
@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"regexp"
 	"runtime"
 	"strings"
 
+	"github.com/kbst/terraform-provider-kustomize/manifest"
 	k8scorev1 "k8s.io/api/core/v1"
+	k8sequality "k8s.io/apimachinery/pkg/api/equality"
 	k8svalidation "k8s.io/apimachinery/pkg/api/validation"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -25,8 +30,12 @@ import (
 
 const lastAppliedConfigAnnotation = k8scorev1.LastAppliedConfigAnnotation
 const gzipLastAppliedConfigAnnotation = "kustomization.kubestack.com/last-applied-config-gzip"
+const fieldManager = "terraform-provider-kustomization"
 
-func setLastAppliedConfig(km *kManifest, gzipLastAppliedConfig bool) {
+// setLastAppliedConfig sets the lastAppliedConfig annotation on the manifest.
+// It returns false, leaving the manifest unmodified, if the manifest does not
+// fit the annotation even when compressed.
+func setLastAppliedConfig(km *kManifest, gzipLastAppliedConfig bool) bool {
 	annotations := km.resource.GetAnnotations()
 	if len(annotations) == 0 {
 		annotations = make(map[string]string)
@@ -34,6 +43,10 @@ func setLastAppliedConfig(km *kManifest, gzipLastAppliedConfig bool) {
 
 	annotations[lastAppliedConfigAnnotation] = string(km.json)
 
+	if !gzipLastAppliedConfig && k8svalidation.ValidateAnnotationsSize(annotations) != nil {
+		return false
+	}
+
 	if gzipLastAppliedConfig {
 		needsGzip := false
 		sErr := k8svalidation.ValidateAnnotationsSize(annotations)
@@ -52,12 +65,18 @@ func setLastAppliedConfig(km *kManifest, gzipLastAppliedConfig bool) {
 			if err1 == nil && err2 == nil {
 				annotations[gzipLastAppliedConfigAnnotation] = base64.StdEncoding.EncodeToString(buf.Bytes())
 				delete(annotations, lastAppliedConfigAnnotation)
+
+				if k8svalidation.ValidateAnnotationsSize(annotations) != nil {
+					return false
+				}
 			}
 		}
 	}
 
 	km.resource.SetAnnotations(annotations)
 	km.json, _ = km.resource.MarshalJSON()
+
+	return true
 }
 
 func getLastAppliedConfig(u *k8sunstructured.Unstructured, gzipLastAppliedConfig bool) (lac string) {
@@ -65,6 +84,12 @@ func getLastAppliedConfig(u *k8sunstructured.Unstructured, gzipLastAppliedConfig
 
 	lac = u.GetAnnotations()[lastAppliedConfigAnnotation]
 
+	// kubectl apply ends the annotation with a newline, the
+	// provider's annotations, and the compressed one, do not
+	if strings.HasSuffix(lac, "\n") {
+		lac = fromKubectlLastAppliedConfig(lac)
+	}
+
 	if gzipLastAppliedConfig {
 		// read the compressed lac if available
 		if gzEnc, ok := annotations[gzipLastAppliedConfigAnnotation]; ok {
@@ -93,21 +118,413 @@ func getLastAppliedConfig(u *k8sunstructured.Unstructured, gzipLastAppliedConfig
 	return strings.TrimRight(lac, "\r\n")
 }
 
-func getPatch(gvk k8sschema.GroupVersionKind, original []byte, modified []byte, current []byte) (pt k8stypes.PatchType, p []byte, err error) {
-	versionedObject, err := scheme.Scheme.New(gvk)
+// fromKubectlLastAppliedConfig returns the lastAppliedConfig written by
+// kubectl apply encoded like the manifests of the provider, for resources
+// previously managed by kubectl to not show a diff when adopted
+//
+// kubectl keeps the emptied annotations of the object in the annotation,
+// the next apply by the provider replaces it with its own.
+func fromKubectlLastAppliedConfig(lac string) string {
+	u, err := manifest.Parse([]byte(lac))
+	if err != nil {
+		return lac
+	}
+
+	if annotations, ok, _ := k8sunstructured.NestedMap(u.Object, "metadata", "annotations"); ok && len(annotations) == 0 {
+		k8sunstructured.RemoveNestedField(u.Object, "metadata", "annotations")
+	}
+
+	body, err := u.MarshalJSON()
+	if err != nil {
+		return lac
+	}
+
+	return string(body)
+}
+
+// getAppliedManifest returns the lastAppliedConfig of u, or the fields
+// of u owned by manager if u was fully server-side applied
+//
+// Apply managed fields of manager also exist after applies that left
+// fields to other field managers, e.g. with take_ownership_of, these
+// are only a part of the manifest and are not used.
+func getAppliedManifest(u *k8sunstructured.Unstructured, gzipLastAppliedConfig bool, manager string, serverSideApplied bool) string {
+	if !serverSideApplied {
+		return getLastAppliedConfig(u, gzipLastAppliedConfig)
+	}
+
+	if owned, ok := getOwnedManifest(u, manager); ok {
+		return owned
+	}
+
+	return getLastAppliedConfig(u, gzipLastAppliedConfig)
+}
+
+// getApplyManagedFields returns the fields of u that manager
+// owns from server-side applies to the main resource
+func getApplyManagedFields(u *k8sunstructured.Unstructured, manager string) (map[string]interface{}, bool) {
+	for _, mf := range u.GetManagedFields() {
+		if mf.Manager != manager || mf.Operation != k8smetav1.ManagedFieldsOperationApply || mf.Subresource != "" {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		if mf.FieldsV1 != nil {
+			if err := json.Unmarshal(mf.FieldsV1.Raw, &fields); err != nil {
+				log.Printf("[WARN] %q: invalid managed fields of %q: %s", u.GetName(), manager, err)
+				return nil, false
+			}
+		}
+
+		return fields, true
+	}
+
+	return nil, false
+}
+
+// getOwnedManifest returns the live fields of u owned by manager,
+// so that changes to them made by others show as a diff, false
+// if u was not server-side applied by manager
+func getOwnedManifest(u *k8sunstructured.Unstructured, manager string) (string, bool) {
+	fields, ok := getApplyManagedFields(u, manager)
+	if !ok {
+		return "", false
+	}
+
+	owned, _ := extractOwnedFields(u.Object, fields).(map[string]interface{})
+	if owned == nil {
+		owned = make(map[string]interface{})
+	}
+
+	// identifying fields are never part of the managed fields
+	owned["apiVersion"] = u.GetAPIVersion()
+	owned["kind"] = u.GetKind()
+	metadata, _ := owned["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["name"] = u.GetName()
+	if ns := u.GetNamespace(); ns != "" {
+		metadata["namespace"] = ns
+	}
+	owned["metadata"] = metadata
+
+	j, err := json.Marshal(owned)
+	if err != nil {
+		return "", false
+	}
+
+	return string(j), true
+}
+
+// extractOwnedFields returns the parts of v listed in fields, the
+// FieldsV1 format of managed fields, see
+// https://kubernetes.io/docs/reference/using-api/server-side-apply/#field-management
+func extractOwnedFields(v interface{}, fields map[string]interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, sub := range fields {
+			if !strings.HasPrefix(k, "f:") {
+				continue
+			}
+
+			name := strings.TrimPrefix(k, "f:")
+			child, ok := val[name]
+			if !ok {
+				continue
+			}
+
+			out[name] = extractOwnedChild(child, sub)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0)
+		for i, item := range val {
+			for k, sub := range fields {
+				if matchesListItem(i, item, k) {
+					out = append(out, extractOwnedChild(item, sub))
+					break
+				}
+			}
+		}
+
+		return out
+	}
+
+	return v
+}
+
+// extractOwnedChild returns child if it is owned as a whole,
+// otherwise its parts listed in sub
+func extractOwnedChild(child interface{}, sub interface{}) interface{} {
+	subFields, _ := sub.(map[string]interface{})
+	for k := range subFields {
+		if k != "." {
+			return extractOwnedFields(child, subFields)
+		}
+	}
+
+	return child
+}
+
+// matchesListItem returns true if item at index i is the list
+// element identified by the managed fields key k
+func matchesListItem(i int, item interface{}, k string) bool {
 	switch {
-	case k8sruntime.IsNotRegisteredError(err):
-		pt = k8stypes.MergePatchType
+	case strings.HasPrefix(k, "k:"):
+		var keys map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(k, "k:")), &keys); err != nil {
+			return false
+		}
+
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for name, want := range keys {
+			if !jsonEqual(m[name], want) {
+				return false
+			}
+		}
+
+		return true
+	case strings.HasPrefix(k, "v:"):
+		var want interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(k, "v:")), &want); err != nil {
+			return false
+		}
+
+		return jsonEqual(item, want)
+	case strings.HasPrefix(k, "i:"):
+		return k == fmt.Sprintf("i:%d", i)
+	}
+
+	return false
+}
+
+// jsonEqual compares a and b by their JSON encoding, to not
+// distinguish the integer types of unstructured objects from
+// the float64 of decoded JSON
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// parseFieldPath splits a path like 'metadata.annotations["example.com/key"]'
+// into its segments, keys containing dots have to be quoted in brackets
+func parseFieldPath(path string) (fields []string) {
+	var current strings.Builder
+	inBrackets := false
+
+	for _, c := range path {
+		switch {
+		case c == '[' && !inBrackets:
+			inBrackets = true
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		case c == ']' && inBrackets:
+			inBrackets = false
+			fields = append(fields, strings.Trim(current.String(), `"'`))
+			current.Reset()
+		case c == '.' && !inBrackets:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(c)
+		}
+	}
+
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
 
-		preconditions := []mergepatch.PreconditionFunc{
-			mergepatch.RequireKeyUnchanged("kind"),
-			mergepatch.RequireMetadataKeyUnchanged("name"),
+	return fields
+}
+
+// removeIgnoredFields removes fields from the manifest
+// so they are neither applied nor diffed
+func removeIgnoredFields(km *kManifest, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	for _, f := range fields {
+		k8sunstructured.RemoveNestedField(km.resource.Object, parseFieldPath(f)...)
+	}
+
+	km.json, _ = km.resource.MarshalJSON()
+}
+
+// removeCABundles removes the CA bundles of webhook client configs
+// from the manifest, that controllers like cert-manager's cainjector
+// inject into webhook configurations, CRD conversion webhooks and
+// API services
+func removeCABundles(km *kManifest) {
+	obj := km.resource.Object
+
+	switch km.gvk().GroupKind().String() {
+	case "ValidatingWebhookConfiguration.admissionregistration.k8s.io", "MutatingWebhookConfiguration.admissionregistration.k8s.io":
+		webhooks, ok, _ := k8sunstructured.NestedSlice(obj, "webhooks")
+		if !ok {
+			return
+		}
+
+		for _, w := range webhooks {
+			if w, ok := w.(map[string]interface{}); ok {
+				k8sunstructured.RemoveNestedField(w, "clientConfig", "caBundle")
+			}
 		}
+		k8sunstructured.SetNestedSlice(obj, webhooks, "webhooks")
+	case "CustomResourceDefinition.apiextensions.k8s.io":
+		k8sunstructured.RemoveNestedField(obj, "spec", "conversion", "webhook", "clientConfig", "caBundle")
+		k8sunstructured.RemoveNestedField(obj, "spec", "conversion", "webhookClientConfig", "caBundle")
+	case "APIService.apiregistration.k8s.io":
+		k8sunstructured.RemoveNestedField(obj, "spec", "caBundle")
+	default:
+		return
+	}
 
-		p, err = jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current, preconditions...)
+	km.json, _ = km.resource.MarshalJSON()
+}
+
+// removeIgnoredMetadata removes annotations and labels matching
+// any of the patterns from the manifest, e.g. to not remove
+// annotations and labels set by mutating controllers
+func removeIgnoredMetadata(km *kManifest, annotations []*regexp.Regexp, labels []*regexp.Regexp) {
+	if len(annotations) == 0 && len(labels) == 0 {
+		return
+	}
+
+	km.resource.SetAnnotations(filterIgnoredKeys(km.resource.GetAnnotations(), annotations))
+	km.resource.SetLabels(filterIgnoredKeys(km.resource.GetLabels(), labels))
+
+	km.json, _ = km.resource.MarshalJSON()
+}
+
+func filterIgnoredKeys(in map[string]string, patterns []*regexp.Regexp) map[string]string {
+	if len(in) == 0 {
+		return in
+	}
+
+	var out map[string]string
+	for k, v := range in {
+		if matchesAny(k, patterns) {
+			continue
+		}
+
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileIgnorePatterns compiles exact names or regular expressions,
+// patterns have to match the entire name
+func compileIgnorePatterns(in []string) (out []*regexp.Regexp, err error) {
+	for _, p := range in {
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", p))
 		if err != nil {
-			return pt, p, fmt.Errorf("getPatch failed: %s", err)
+			return out, err
 		}
+		out = append(out, re)
+	}
+
+	return out, nil
+}
+
+// manifestsEqualIgnoring returns true if the manifests
+// are equal, after calling remove on both
+func manifestsEqualIgnoring(a string, b string, remove func(km *kManifest)) bool {
+	kma := &kManifest{}
+	kmb := &kManifest{}
+	if kma.load([]byte(a)) != nil || kmb.load([]byte(b)) != nil {
+		return false
+	}
+
+	remove(kma)
+	remove(kmb)
+
+	return k8sequality.Semantic.DeepEqual(kma.resource.Object, kmb.resource.Object)
+}
+
+// isPrunedManifest returns true if the applied manifest equals the
+// desired manifest, except for fields of desired missing in applied,
+// e.g. unknown fields the API server pruned from custom resources
+func isPrunedManifest(desired string, applied string) bool {
+	kmd := &kManifest{}
+	kma := &kManifest{}
+	if kmd.load([]byte(desired)) != nil || kma.load([]byte(applied)) != nil {
+		return false
+	}
+
+	return isPrunedValue(kmd.resource.Object, kma.resource.Object)
+}
+
+func isPrunedValue(desired interface{}, applied interface{}) bool {
+	dm, dok := desired.(map[string]interface{})
+	am, aok := applied.(map[string]interface{})
+	if !dok || !aok {
+		return k8sequality.Semantic.DeepEqual(desired, applied)
+	}
+
+	for k, av := range am {
+		dv, ok := dm[k]
+		if !ok || !isPrunedValue(dv, av) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getReplicasOnlyChange returns the modified replica count if
+// spec.replicas is the only difference between original and modified
+func getReplicasOnlyChange(original *k8sunstructured.Unstructured, modified *k8sunstructured.Unstructured) (replicas int64, ok bool) {
+	or, _, _ := k8sunstructured.NestedInt64(original.UnstructuredContent(), "spec", "replicas")
+	mr, found, _ := k8sunstructured.NestedInt64(modified.UnstructuredContent(), "spec", "replicas")
+	if !found || or == mr {
+		return replicas, false
+	}
+
+	o := original.DeepCopy()
+	m := modified.DeepCopy()
+	k8sunstructured.RemoveNestedField(o.Object, "spec", "replicas")
+	k8sunstructured.RemoveNestedField(m.Object, "spec", "replicas")
+
+	if !k8sequality.Semantic.DeepEqual(o.Object, m.Object) {
+		return replicas, false
+	}
+
+	return mr, true
+}
+
+func getPatch(gvk k8sschema.GroupVersionKind, original []byte, modified []byte, current []byte) (pt k8stypes.PatchType, p []byte, err error) {
+	versionedObject, err := scheme.Scheme.New(gvk)
+	switch {
+	case k8sruntime.IsNotRegisteredError(err):
+		return getMergePatch(original, modified, current)
 	case err != nil:
 		return pt, p, fmt.Errorf("getPatch failed: %s", err)
 	case err == nil:
@@ -127,6 +544,24 @@ func getPatch(gvk k8sschema.GroupVersionKind, original []byte, modified []byte,
 	return pt, p, nil
 }
 
+// getMergePatch returns a three-way JSON merge patch, without
+// strategic merge assumptions, lists are replaced as a whole
+func getMergePatch(original []byte, modified []byte, current []byte) (pt k8stypes.PatchType, p []byte, err error) {
+	pt = k8stypes.MergePatchType
+
+	preconditions := []mergepatch.PreconditionFunc{
+		mergepatch.RequireKeyUnchanged("kind"),
+		mergepatch.RequireMetadataKeyUnchanged("name"),
+	}
+
+	p, err = jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current, preconditions...)
+	if err != nil {
+		return pt, p, fmt.Errorf("getPatch failed: %s", err)
+	}
+
+	return pt, p, nil
+}
+
 // log error including caller name
 func logError(m error) error {
 	pc, _, _, _ := runtime.Caller(1)
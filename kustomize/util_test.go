@@ -3,6 +3,7 @@ package kustomize
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,6 +31,27 @@ func TestLastAppliedConfig(t *testing.T) {
 	}
 }
 
+func TestLastAppliedConfigKubectl(t *testing.T) {
+	km := &kManifest{}
+	err := km.load([]byte(`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "test-unit"}}`))
+	assert.Equal(t, nil, err, nil)
+
+	// as written by kubectl apply
+	km.resource.SetAnnotations(map[string]string{
+		lastAppliedConfigAnnotation: "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"annotations\":{},\"name\":\"test-unit\"}}\n",
+	})
+
+	lac := getLastAppliedConfig(km.resource, true)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"test-unit"}}`, lac, nil)
+
+	km.resource.SetAnnotations(map[string]string{
+		lastAppliedConfigAnnotation: "{\"apiVersion\":\"v1\",\"kind\":\"Namespace\",\"metadata\":{\"annotations\":{\"test\":\"true\"},\"name\":\"test-unit\"}}\n",
+	})
+
+	lac = getLastAppliedConfig(km.resource, false)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"Namespace","metadata":{"annotations":{"test":"true"},"name":"test-unit"}}`, lac, nil)
+}
+
 func randomDataHelper(n int) string {
 	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	b := make([]byte, n)
@@ -40,7 +62,8 @@ func randomDataHelper(n int) string {
 }
 
 func TestLastAppliedConfigCompressed(t *testing.T) {
-	filler := randomDataHelper(256 * (1 << 10))
+	// compressible filler, that exceeds the max annotation size uncompressed
+	filler := strings.Repeat(randomDataHelper(1<<10), 256)
 	srcJSON := fmt.Sprintf("{\"apiVersion\": \"v1\", \"kind\": \"ConfigMap\", \"metadata\": {\"name\": \"test-unit\", \"namespace\": \"test-unit\"}, \"data\": {\"payload\": %q}}", filler)
 
 	km := &kManifest{}
@@ -147,3 +170,136 @@ func TestGetPatchMergePatch(t *testing.T) {
 	assert.Equal(t, `{"metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"test.example.com/v1alpha1\",\"kind\":\"Namespacedcrd\",\"metadata\":{\"name\":\"namespacedco\",\"namespace\":\"test-crd\"},\"spec\":{\"test-key\":\"test-value\"}}\n"}},"spec":{"test-key":"test-value"}}`, string(p), nil)
 	assert.Equal(t, types.MergePatchType, pt, nil)
 }
+
+func TestLastAppliedConfigOversized(t *testing.T) {
+	filler := randomDataHelper(512 * (1 << 10))
+	srcJSON := fmt.Sprintf("{\"apiVersion\": \"v1\", \"kind\": \"ConfigMap\", \"metadata\": {\"name\": \"test-unit\", \"namespace\": \"test-unit\"}, \"data\": {\"payload\": %q}}", filler)
+
+	km := &kManifest{}
+	err := km.load([]byte(srcJSON))
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	ok := setLastAppliedConfig(km, true)
+	assert.Equal(t, false, ok, "TestLastAppliedConfigOversized: expected manifest to not fit the annotation")
+
+	annotations := km.resource.GetAnnotations()
+	assert.Equal(t, 0, len(annotations), "TestLastAppliedConfigOversized: expected no annotations")
+	assert.Equal(t, srcJSON, string(km.json), "TestLastAppliedConfigOversized: expected manifest to be unmodified")
+}
+
+func TestLastAppliedConfigOversizedUncompressed(t *testing.T) {
+	filler := randomDataHelper(300 * (1 << 10))
+	srcJSON := fmt.Sprintf("{\"apiVersion\": \"v1\", \"kind\": \"ConfigMap\", \"metadata\": {\"name\": \"test-unit\", \"namespace\": \"test-unit\"}, \"data\": {\"payload\": %q}}", filler)
+
+	km := &kManifest{}
+	err := km.load([]byte(srcJSON))
+	if err != nil {
+		t.Errorf("Error: %s", err)
+	}
+	ok := setLastAppliedConfig(km, false)
+	assert.Equal(t, false, ok, "TestLastAppliedConfigOversizedUncompressed: expected manifest to not fit the annotation")
+	assert.Equal(t, srcJSON, string(km.json), "TestLastAppliedConfigOversizedUncompressed: expected manifest to be unmodified")
+}
+
+func TestGetAppliedManifestServerSideApplied(t *testing.T) {
+	km := kManifest{}
+	km.load([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test","labels":{"app":"test","team":"other"},"managedFields":[{"manager":"terraform-provider-kustomization","operation":"Apply","apiVersion":"apps/v1","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:labels":{"f:app":{}}},"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"nginx\"}":{".":{},"f:image":{},"f:name":{}}}}}}}},{"manager":"kubectl","operation":"Update","apiVersion":"apps/v1","fieldsType":"FieldsV1","fieldsV1":{"f:metadata":{"f:labels":{"f:team":{}}},"f:spec":{"f:replicas":{}}}}]},"spec":{"replicas":3,"template":{"spec":{"containers":[{"image":"nginx:edited","imagePullPolicy":"Always","name":"nginx"}]}}}}`))
+
+	applied := getAppliedManifest(km.resource, true, fieldManager, true)
+	assert.JSONEq(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test","labels":{"app":"test"}},"spec":{"template":{"spec":{"containers":[{"image":"nginx:edited","name":"nginx"}]}}}}`, applied, nil)
+
+	// managed fields of partial applies, e.g. leaving fields to
+	// other field managers, are not used
+	assert.Equal(t, "", getAppliedManifest(km.resource, true, fieldManager, false), nil)
+}
+
+func TestGetAppliedManifestClientSideApplied(t *testing.T) {
+	km := kManifest{}
+	km.load([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"v1\",\"kind\":\"ConfigMap\",\"metadata\":{\"name\":\"test\",\"namespace\":\"test\"}}\n"},"managedFields":[{"manager":"terraform-provider-kustomization","operation":"Update","apiVersion":"v1","fieldsType":"FieldsV1","fieldsV1":{"f:data":{}}}]},"data":{"key":"value"}}`))
+
+	applied := getAppliedManifest(km.resource, true, fieldManager, false)
+	assert.Equal(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"test","namespace":"test"}}`, applied, nil)
+}
+
+func TestGetReplicasOnlyChange(t *testing.T) {
+	kmo := kManifest{}
+	kmm := kManifest{}
+	kmo.load([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":1,"template":{"spec":{"containers":[{"image":"nginx","name":"nginx"}]}}}}`))
+	kmm.load([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":3,"template":{"spec":{"containers":[{"image":"nginx","name":"nginx"}]}}}}`))
+
+	replicas, ok := getReplicasOnlyChange(kmo.resource, kmm.resource)
+	assert.Equal(t, true, ok, nil)
+	assert.Equal(t, int64(3), replicas, nil)
+
+	kmm.load([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":3,"template":{"spec":{"containers":[{"image":"nginx:latest","name":"nginx"}]}}}}`))
+
+	_, ok = getReplicasOnlyChange(kmo.resource, kmm.resource)
+	assert.Equal(t, false, ok, nil)
+}
+
+func TestParseFieldPath(t *testing.T) {
+	assert.Equal(t, []string{"spec", "replicas"}, parseFieldPath("spec.replicas"), nil)
+	assert.Equal(t, []string{"metadata", "annotations", "example.com/key"}, parseFieldPath(`metadata.annotations["example.com/key"]`), nil)
+	assert.Equal(t, []string{"metadata", "labels", "app.kubernetes.io/name"}, parseFieldPath(`metadata.labels['app.kubernetes.io/name']`), nil)
+}
+
+func TestRemoveIgnoredFields(t *testing.T) {
+	km := kManifest{}
+	km.load([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"annotations":{"example.com/key":"value"},"name":"test","namespace":"test"},"spec":{"replicas":1}}`))
+
+	removeIgnoredFields(&km, []string{"spec.replicas", `metadata.annotations["example.com/key"]`})
+	assert.JSONEq(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"annotations":{},"name":"test","namespace":"test"},"spec":{}}`, string(km.json), nil)
+
+	a := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":1}}`
+	b := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test"},"spec":{"replicas":3}}`
+	assert.Equal(t, true, manifestsEqualIgnoring(a, b, func(km *kManifest) { removeIgnoredFields(km, []string{"spec.replicas"}) }), nil)
+	assert.Equal(t, false, manifestsEqualIgnoring(a, b, func(km *kManifest) { removeIgnoredFields(km, []string{"metadata.labels"}) }), nil)
+}
+
+func TestRemoveCABundles(t *testing.T) {
+	km := kManifest{}
+	km.load([]byte(`{"apiVersion":"admissionregistration.k8s.io/v1","kind":"ValidatingWebhookConfiguration","metadata":{"name":"test"},"webhooks":[{"name":"a.example.com","clientConfig":{"caBundle":"Q0E=","service":{"name":"a","namespace":"test"}}},{"name":"b.example.com","clientConfig":{"url":"https://b.example.com"}}]}`))
+
+	removeCABundles(&km)
+	assert.JSONEq(t, `{"apiVersion":"admissionregistration.k8s.io/v1","kind":"ValidatingWebhookConfiguration","metadata":{"name":"test"},"webhooks":[{"name":"a.example.com","clientConfig":{"service":{"name":"a","namespace":"test"}}},{"name":"b.example.com","clientConfig":{"url":"https://b.example.com"}}]}`, string(km.json), nil)
+
+	km = kManifest{}
+	km.load([]byte(`{"apiVersion":"apiextensions.k8s.io/v1","kind":"CustomResourceDefinition","metadata":{"name":"tests.example.com"},"spec":{"conversion":{"strategy":"Webhook","webhook":{"clientConfig":{"caBundle":"Q0E="},"conversionReviewVersions":["v1"]}}}}`))
+
+	removeCABundles(&km)
+	assert.JSONEq(t, `{"apiVersion":"apiextensions.k8s.io/v1","kind":"CustomResourceDefinition","metadata":{"name":"tests.example.com"},"spec":{"conversion":{"strategy":"Webhook","webhook":{"clientConfig":{},"conversionReviewVersions":["v1"]}}}}`, string(km.json), nil)
+
+	// other kinds keep fields named caBundle
+	km = kManifest{}
+	km.load([]byte(`{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"caBundle":"Q0E="}}`))
+
+	removeCABundles(&km)
+	assert.JSONEq(t, `{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"caBundle":"Q0E="}}`, string(km.json), nil)
+}
+
+func TestIsPrunedManifest(t *testing.T) {
+	desired := `{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"known":1,"unknown":{"key":"value"},"list":[{"a":1}]}}`
+
+	assert.Equal(t, true, isPrunedManifest(desired, desired), nil)
+	assert.Equal(t, true, isPrunedManifest(desired, `{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"known":1,"list":[{"a":1}]}}`), nil)
+	assert.Equal(t, false, isPrunedManifest(desired, `{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"known":2}}`), nil)
+	assert.Equal(t, false, isPrunedManifest(desired, `{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"known":1,"list":[]}}`), nil)
+	assert.Equal(t, false, isPrunedManifest(desired, `{"apiVersion":"example.com/v1","kind":"Test","metadata":{"name":"test"},"spec":{"known":1,"added":true}}`), nil)
+}
+
+func TestRemoveIgnoredMetadata(t *testing.T) {
+	km := kManifest{}
+	km.load([]byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"annotations":{"sidecar.istio.io/status":"{}","example.com/keep":"true"},"labels":{"security.istio.io/tlsMode":"istio"},"name":"test","namespace":"test"}}`))
+
+	annotations, err := compileIgnorePatterns([]string{"sidecar.istio.io/.*"})
+	assert.Equal(t, nil, err, nil)
+	labels, err := compileIgnorePatterns([]string{"security.istio.io/tlsMode"})
+	assert.Equal(t, nil, err, nil)
+
+	removeIgnoredMetadata(&km, annotations, labels)
+	assert.JSONEq(t, `{"apiVersion":"v1","kind":"Pod","metadata":{"annotations":{"example.com/keep":"true"},"name":"test","namespace":"test"}}`, string(km.json), nil)
+
+	_, err = compileIgnorePatterns([]string{"("})
+	assert.NotEqual(t, nil, err, nil)
+}
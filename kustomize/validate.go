@@ -0,0 +1,147 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	openapi_v2 "github.com/google/gnostic/openapiv2"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/kustomize/kyaml/openapi/kubernetesapi"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+)
+
+// builtinSchemaVersion converts e.g. "v1.21.2" to the name
+// of the schema built into kustomize, "v1212"
+func builtinSchemaVersion(version string) string {
+	return strings.ReplaceAll(version, ".", "")
+}
+
+// builtinSchemaVersions returns the Kubernetes versions
+// of the schemas built into kustomize, e.g. "v1.21.2"
+func builtinSchemaVersions() []string {
+	var versions []string
+	for v := range kubernetesapi.OpenAPIMustAsset {
+		if len(v) == 5 {
+			v = fmt.Sprintf("%s.%s.%s", v[:2], v[2:4], v[4:])
+		}
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+var (
+	builtinSchemasMu sync.Mutex
+	builtinSchemas   = make(map[string][]byte)
+)
+
+// getBuiltinSchema returns the schema of the Kubernetes version built
+// into kustomize as JSON, it is parsed once and cached
+func getBuiltinSchema(version string) ([]byte, error) {
+	builtinSchemasMu.Lock()
+	defer builtinSchemasMu.Unlock()
+
+	v := builtinSchemaVersion(version)
+	if s, ok := builtinSchemas[v]; ok {
+		return s, nil
+	}
+
+	asset, ok := kubernetesapi.OpenAPIMustAsset[v]
+	if !ok {
+		return nil, fmt.Errorf("no schema for Kubernetes version %q, available versions: %s", version, strings.Join(builtinSchemaVersions(), ", "))
+	}
+
+	doc := &openapi_v2.Document{}
+	if err := proto.Unmarshal(asset(filepath.Join("kubernetesapi", v, "swagger.pb")), doc); err != nil {
+		return nil, err
+	}
+
+	var swagger spec.Swagger
+	if _, err := swagger.FromGnostic(doc); err != nil {
+		return nil, err
+	}
+
+	s, err := json.Marshal(swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	builtinSchemas[v] = s
+
+	return s, nil
+}
+
+// manifestSchemas are the schemas to validate manifests against,
+// by the group, version and kind of the manifests
+type manifestSchemas struct {
+	definitions map[string]interface{}
+	schemas     map[resid.Gvk]map[string]interface{}
+}
+
+// makeManifestSchemas indexes the definitions of the OpenAPI v2 schema s
+// by their group, version and kind, the schemas of crds take precedence
+func makeManifestSchemas(s []byte, crds []crdSchema) (*manifestSchemas, error) {
+	var swagger struct {
+		Definitions map[string]interface{} `json:"definitions"`
+	}
+	if err := json.Unmarshal(s, &swagger); err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI schema failed: %s", err)
+	}
+
+	ms := &manifestSchemas{
+		definitions: swagger.Definitions,
+		schemas:     make(map[resid.Gvk]map[string]interface{}),
+	}
+
+	for _, d := range swagger.Definitions {
+		def, _ := d.(map[string]interface{})
+		gvks, _ := def["x-kubernetes-group-version-kind"].([]interface{})
+		for _, g := range gvks {
+			gvk, _ := g.(map[string]interface{})
+			group, _ := gvk["group"].(string)
+			version, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+			ms.schemas[resid.NewGvk(group, version, kind)] = def
+		}
+	}
+
+	for _, c := range crds {
+		ms.schemas[c.gvk] = c.schema
+	}
+
+	return ms, nil
+}
+
+// validate returns the violations of the schema of the manifest
+func (ms *manifestSchemas) validate(manifest string, ignoreMissingSchemas bool) ([]string, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(manifest), &obj); err != nil {
+		return nil, err
+	}
+
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	gvk := resid.NewGvk("", apiVersion, kind)
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		gvk = resid.NewGvk(apiVersion[:i], apiVersion[i+1:], kind)
+	}
+
+	s, ok := ms.schemas[gvk]
+	if !ok {
+		if ignoreMissingSchemas {
+			return nil, nil
+		}
+		return []string{fmt.Sprintf("no schema for %s %s", apiVersion, kind)}, nil
+	}
+
+	errs := validateSchema(kind, obj, s, ms.definitions)
+	sort.Strings(errs)
+
+	return errs, nil
+}
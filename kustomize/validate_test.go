@@ -0,0 +1,55 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinSchemaVersions(t *testing.T) {
+	assert.Equal(t, []string{"v1.21.2"}, builtinSchemaVersions(), nil)
+	assert.Equal(t, "v1212", builtinSchemaVersion("v1.21.2"), nil)
+}
+
+func TestGetBuiltinSchema(t *testing.T) {
+	_, err := getBuiltinSchema("v1.0.0")
+	assert.EqualError(t, err, "no schema for Kubernetes version \"v1.0.0\", available versions: v1.21.2", nil)
+
+	s, err := getBuiltinSchema("v1.21.2")
+	assert.Equal(t, nil, err, nil)
+	assert.Contains(t, string(s), "io.k8s.api.apps.v1.Deployment", nil)
+}
+
+func TestManifestSchemasValidate(t *testing.T) {
+	s, err := getBuiltinSchema("v1.21.2")
+	assert.Equal(t, nil, err, nil)
+
+	crds, err := parseCRDs([]byte(testBackendCRD))
+	assert.Equal(t, nil, err, nil)
+
+	ms, err := makeManifestSchemas(s, crds)
+	assert.Equal(t, nil, err, nil)
+
+	errs, err := ms.validate(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test"},"spec":{"replicas":1,"selector":{"matchLabels":{"app":"test"}},"template":{"metadata":{"labels":{"app":"test"}},"spec":{"containers":[{"name":"test","image":"test","ports":[{"containerPort":80}],"resources":{"limits":{"cpu":1,"memory":"1Gi"}}}]}}}}`, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string(nil), errs, nil)
+
+	errs, err = ms.validate(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test"},"spec":{"replicas":"1","selector":{"matchLabels":{"app":"test"}},"template":{"spec":{"containers":[{"image":"test"}]}}}}`, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{
+		"Deployment.spec.replicas: must be an integer",
+		"Deployment.spec.template.spec.containers[0].name: is required",
+	}, errs, nil)
+
+	errs, err = ms.validate(`{"apiVersion":"example.com/v1","kind":"Backend","metadata":{"name":"test"},"spec":{}}`, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{"Backend.spec.routes: is required"}, errs, nil)
+
+	errs, err = ms.validate(`{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"test"}}`, false)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{"no schema for example.com/v1 Unknown"}, errs, nil)
+
+	errs, err = ms.validate(`{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"test"}}`, true)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string(nil), errs, nil)
+}
@@ -0,0 +1,148 @@
+package kustomize
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+// secret generator literal values with this prefix
+// reference a Vault secret, e.g. "vault:secret/data/app#password"
+const vaultLiteralPrefix = "vault:"
+
+// vaultConfig is the connection to read secret generator values from
+// HashiCorp Vault, settings default to the Vault CLI environment variables
+type vaultConfig struct {
+	Address   string
+	Token     string
+	Namespace string
+
+	client *http.Client
+}
+
+func getVaultConfig(in []interface{}) *vaultConfig {
+	v := make(map[string]interface{})
+	if len(in) > 0 && in[0] != nil {
+		v = in[0].(map[string]interface{})
+	}
+
+	return &vaultConfig{
+		Address:   strings.TrimSuffix(getStringOrEnv(v, "address", "VAULT_ADDR"), "/"),
+		Token:     getStringOrEnv(v, "token", "VAULT_TOKEN"),
+		Namespace: getStringOrEnv(v, "namespace", "VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// parseVaultReference splits e.g. "secret/data/app#password"
+// into the path and the field of the secret
+func parseVaultReference(ref string) (path string, field string, err error) {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts) != 2 || strings.Trim(parts[0], "/") == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid Vault reference: %q, valid references look like: \"vault:secret/data/app#password\"", vaultLiteralPrefix+ref)
+	}
+
+	return strings.Trim(parts[0], "/"), parts[1], nil
+}
+
+// read returns the data of the secret at path, for KV version 2
+// secret engines the data is nested inside the response data
+func (vc *vaultConfig) read(path string) (map[string]interface{}, error) {
+	if vc.Address == "" {
+		return nil, fmt.Errorf("Vault address not configured, set the provider vault address or VAULT_ADDR")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s", vc.Address, path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", vc.Token)
+	if vc.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vc.Namespace)
+	}
+
+	resp, err := vc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("reading %q failed: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("reading %q failed: %s", path, err)
+	}
+
+	data, isKV2 := secret.Data["data"].(map[string]interface{})
+	if _, hasMetadata := secret.Data["metadata"]; isKV2 && hasMetadata {
+		return data, nil
+	}
+
+	return secret.Data, nil
+}
+
+// resolveVaultLiterals replaces secret generator literals referencing
+// Vault secrets with the values read from Vault
+func resolveVaultLiterals(k *types.Kustomization, vc *vaultConfig) error {
+	if vc == nil {
+		vc = getVaultConfig(nil)
+	}
+
+	secrets := make(map[string]map[string]interface{})
+
+	for i := range k.SecretGenerator {
+		literals := k.SecretGenerator[i].LiteralSources
+		for j, l := range literals {
+			kv := strings.SplitN(l, "=", 2)
+			if len(kv) != 2 || !strings.HasPrefix(kv[1], vaultLiteralPrefix) {
+				continue
+			}
+
+			path, field, err := parseVaultReference(strings.TrimPrefix(kv[1], vaultLiteralPrefix))
+			if err != nil {
+				return err
+			}
+
+			// read every secret only once
+			data, ok := secrets[path]
+			if !ok {
+				data, err = vc.read(path)
+				if err != nil {
+					return fmt.Errorf("vault: %s", err)
+				}
+				secrets[path] = data
+			}
+
+			v, ok := data[field]
+			if !ok {
+				return fmt.Errorf("vault: field %q not found in %q", field, path)
+			}
+
+			s, ok := v.(string)
+			if !ok {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return fmt.Errorf("vault: %s", err)
+				}
+				s = string(b)
+			}
+
+			literals[j] = fmt.Sprintf("%s=%s", kv[0], s)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package kustomize
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+func TestParseVaultReference(t *testing.T) {
+	path, field, err := parseVaultReference("secret/data/app#password")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, "secret/data/app", path, nil)
+	assert.Equal(t, "password", field, nil)
+
+	for _, ref := range []string{"secret/data/app", "#password", "secret/data/app#"} {
+		_, _, err = parseVaultReference(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestResolveVaultLiterals(t *testing.T) {
+	reads := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" || r.Header.Get("X-Vault-Namespace") != "test" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		reads[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			fmt.Fprint(w, `{"data": {"data": {"password": "kv2-secret", "port": 5432}, "metadata": {"version": 1}}}`)
+		case "/v1/kv/app":
+			fmt.Fprint(w, `{"data": {"password": "kv1-secret"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	vc := getVaultConfig([]interface{}{
+		map[string]interface{}{
+			"address":   srv.URL + "/",
+			"token":     "test-token",
+			"namespace": "test",
+		},
+	})
+
+	k := types.Kustomization{}
+	k.SecretGenerator = append(k.SecretGenerator, types.SecretArgs{})
+	k.SecretGenerator[0].LiteralSources = []string{
+		"PASSWORD=vault:secret/data/app#password",
+		"PORT=vault:secret/data/app#port",
+		"KV1=vault:kv/app#password",
+		"PLAIN=value",
+	}
+
+	err := resolveVaultLiterals(&k, vc)
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, []string{
+		"PASSWORD=kv2-secret",
+		"PORT=5432",
+		"KV1=kv1-secret",
+		"PLAIN=value",
+	}, k.SecretGenerator[0].LiteralSources, nil)
+	assert.Equal(t, 1, reads["/v1/secret/data/app"], nil)
+
+	k.SecretGenerator[0].LiteralSources = []string{"MISSING=vault:secret/data/app#missing"}
+	err = resolveVaultLiterals(&k, vc)
+	assert.EqualError(t, err, `vault: field "missing" not found in "secret/data/app"`, nil)
+
+	k.SecretGenerator[0].LiteralSources = []string{"NOTFOUND=vault:secret/data/other#password"}
+	err = resolveVaultLiterals(&k, vc)
+	assert.Error(t, err, nil)
+}
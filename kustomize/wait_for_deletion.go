@@ -0,0 +1,157 @@
+package kustomize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/kbst/terraform-provider-kustomize/manifest"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func waitForDeletionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"kinds": &schema.Schema{
+					Type:     schema.TypeList,
+					Required: true,
+					MinItems: 1,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validateAPIVersionKind,
+					},
+				},
+				"label_selector": &schema.Schema{
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"namespace": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+			},
+		},
+	}
+}
+
+// deletionSelector selects prior objects that have to be deleted
+// before the resource is applied, e.g. jobs of an earlier migration
+type deletionSelector struct {
+	kinds         []string
+	labelSelector string
+	namespace     string
+}
+
+func getWaitForDeletion(d changeGetter) []deletionSelector {
+	var selectors []deletionSelector
+	for _, v := range d.Get("wait_for_deletion").([]interface{}) {
+		s := v.(map[string]interface{})
+		selectors = append(selectors, deletionSelector{
+			kinds:         convertListInterfaceToListString(s["kinds"].([]interface{})),
+			labelSelector: s["label_selector"].(string),
+			namespace:     s["namespace"].(string),
+		})
+	}
+
+	return selectors
+}
+
+// remainingPrior returns the objects matching the selectors, except
+// the object of km itself, which is updated instead
+func (km *kManifest) remainingPrior(selectors []deletionSelector) ([]k8sunstructured.Unstructured, error) {
+	var remaining []k8sunstructured.Unstructured
+	for _, s := range selectors {
+		resources, err := getKindResources(km.mapper, s.kinds)
+		if err != nil {
+			return nil, err
+		}
+
+		ns := s.namespace
+		if ns == "" {
+			ns = km.namespace()
+		}
+
+		for _, r := range resources {
+			api := km.client.Resource(r.gvr)
+			var list *k8sunstructured.UnstructuredList
+			if r.namespaced {
+				list, err = api.Namespace(ns).List(context.TODO(), k8smetav1.ListOptions{LabelSelector: s.labelSelector})
+			} else {
+				list, err = api.List(context.TODO(), k8smetav1.ListOptions{LabelSelector: s.labelSelector})
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			for _, u := range list.Items {
+				if u.GetKind() == km.gvk().Kind && u.GetNamespace() == km.namespace() && u.GetName() == km.name() {
+					continue
+				}
+				remaining = append(remaining, u)
+			}
+		}
+	}
+
+	return remaining, nil
+}
+
+// describePrior returns the ids of the remaining objects, with the
+// finalizers blocking their deletion, if any
+func describePrior(remaining []k8sunstructured.Unstructured) string {
+	var out []string
+	for i := range remaining {
+		s := fmt.Sprintf("%q", manifest.IDFromObject(&remaining[i]).String())
+		if f := describeFinalizers(&remaining[i]); f != "" {
+			s = fmt.Sprintf("%s (%s)", s, f)
+		}
+		out = append(out, s)
+	}
+
+	return strings.Join(out, ", ")
+}
+
+// waitPriorDeleted waits until all objects matching the selectors
+// are deleted, to not conflict with immutable fields or reused names
+func (km *kManifest) waitPriorDeleted(selectors []deletionSelector, t time.Duration) error {
+	if len(selectors) == 0 {
+		return nil
+	}
+
+	var remaining []k8sunstructured.Unstructured
+	stateConf := &resource.StateChangeConf{
+		Target:  []string{"deleted"},
+		Pending: []string{"deleting"},
+		Timeout: t,
+		Refresh: func() (interface{}, string, error) {
+			var err error
+			remaining, err = km.remainingPrior(selectors)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if len(remaining) == 0 {
+				return remaining, "deleted", nil
+			}
+
+			km.logProgress(fmt.Sprintf("%d prior objects not deleted yet: %s", len(remaining), describePrior(remaining)))
+			return remaining, "deleting", nil
+		},
+	}
+
+	_, err := stateConf.WaitForState()
+	if err != nil {
+		if len(remaining) > 0 {
+			err = fmt.Errorf("%s, remaining: %s", err, describePrior(remaining))
+		}
+		return km.fmtErr(fmt.Errorf("timed out waiting for deletion of prior objects: %s", err))
+	}
+
+	return nil
+}
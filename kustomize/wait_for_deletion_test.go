@@ -0,0 +1,58 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func testMigrationJob(name string, app string) string {
+	return `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name + `
+  namespace: test-ns
+  labels: {app: ` + app + `}
+  finalizers: [batch.kubernetes.io/job-tracking]
+`
+}
+
+func TestRemainingPrior(t *testing.T) {
+	jobsGVR := k8sschema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+
+	var objs []k8sruntime.Object
+	for _, o := range []string{
+		testMigrationJob("migrate-1", "migrate"),
+		testMigrationJob("migrate-2", "migrate"),
+		testMigrationJob("other", "other"),
+	} {
+		objs = append(objs, testRolloutObject(t, o))
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[k8sschema.GroupVersionResource]string{
+			jobsGVR: "JobList",
+		},
+		objs...,
+	)
+
+	mapper := getStaticRESTMapper([]interface{}{
+		map[string]interface{}{"group": "batch", "version": "v1", "kind": "Job", "resource": "jobs", "namespaced": true},
+	})
+
+	km := newKManifest(staticOnlyRESTMapper{mapper}, client)
+	assert.Equal(t, nil, km.load([]byte(`{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"migrate-2","namespace":"test-ns"}}`)), nil)
+
+	remaining, err := km.remainingPrior([]deletionSelector{{kinds: []string{"batch/v1/Job"}, labelSelector: "app=migrate"}})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, `"batch/Job/test-ns/migrate-1" (finalizers remaining: batch.kubernetes.io/job-tracking)`, describePrior(remaining), nil)
+
+	remaining, err = km.remainingPrior([]deletionSelector{{kinds: []string{"batch/v1/Job"}, labelSelector: "app=none"}})
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, 0, len(remaining), nil)
+}
@@ -5,8 +5,7 @@ import (
 	"flag"
 	"log"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
 	"github.com/kbst/terraform-provider-kustomize/kustomize"
 )
 
@@ -16,19 +15,24 @@ func main() {
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
-	opts := &plugin.ServeOpts{
-		ProviderFunc: func() *schema.Provider {
-			return kustomize.Provider()
-		},
+	provider := kustomize.Provider()
+	server, err := kustomize.ProviderServer(context.Background(), provider)
+	if err != nil {
+		log.Fatal(err.Error())
 	}
 
+	var opts []tf6server.ServeOpt
 	if debugMode {
-		err := plugin.Debug(context.Background(), "registry.terraform.io/kbst/kustomization", opts)
-		if err != nil {
-			log.Fatal(err.Error())
-		}
-		return
+		opts = append(opts, tf6server.WithManagedDebug())
 	}
 
-	plugin.Serve(opts)
+	err = tf6server.Serve("registry.terraform.io/kbst/kustomization", server, opts...)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	// Terraform shuts the provider down at the end of the run
+	if !debugMode {
+		kustomize.LogRunMetrics(provider)
+	}
 }
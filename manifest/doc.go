@@ -0,0 +1,8 @@
+// Package manifest handles the resource IDs and manifests in the format
+// of the Terraform provider for Kustomize, for tools that read or write
+// the provider's state, e.g. operators or test harnesses.
+//
+// IDs have the format group/kind/namespace/name, with "_" for the core
+// group and for cluster scoped resources, e.g. "apps/Deployment/example/example"
+// or "_/Namespace/_/example". Manifests are JSON encoded.
+package manifest
@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ID identifies a resource, like the ids of the provider's
+// data sources and the IDs of the kustomization_resource
+type ID struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ParseID parses an ID, e.g. "apps/Deployment/example/example"
+func ParseID(s string) (ID, error) {
+	parts := strings.Split(s, "/")
+
+	if len(parts) != 4 {
+		return ID{}, fmt.Errorf("invalid ID: %q, valid IDs look like: \"_/Namespace/_/example\"", s)
+	}
+
+	return ID{
+		Group:     underscoreToEmpty(parts[0]),
+		Kind:      parts[1],
+		Namespace: underscoreToEmpty(parts[2]),
+		Name:      parts[3],
+	}, nil
+}
+
+// IDFromObject returns the ID of a Kubernetes object
+func IDFromObject(u *k8sunstructured.Unstructured) ID {
+	return ID{
+		Group:     u.GroupVersionKind().Group,
+		Kind:      u.GetKind(),
+		Namespace: u.GetNamespace(),
+		Name:      u.GetName(),
+	}
+}
+
+// String returns the ID in the format of the provider
+func (id ID) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", emptyToUnderscore(id.Group), id.Kind, emptyToUnderscore(id.Namespace), id.Name)
+}
+
+func underscoreToEmpty(value string) string {
+	if value == "_" {
+		return ""
+	}
+	return value
+}
+
+func emptyToUnderscore(value string) string {
+	if value == "" {
+		return "_"
+	}
+	return value
+}
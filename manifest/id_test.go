@@ -0,0 +1,33 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseID(t *testing.T) {
+	id, err := ParseID("apps/Deployment/test-ns/test")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, ID{Group: "apps", Kind: "Deployment", Namespace: "test-ns", Name: "test"}, id, nil)
+	assert.Equal(t, "apps/Deployment/test-ns/test", id.String(), nil)
+
+	id, err = ParseID("_/Namespace/_/test-ns")
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, ID{Kind: "Namespace", Name: "test-ns"}, id, nil)
+	assert.Equal(t, "_/Namespace/_/test-ns", id.String(), nil)
+
+	_, err = ParseID("apps_v1_Deployment|test-ns|test")
+	assert.EqualError(t, err, "invalid ID: \"apps_v1_Deployment|test-ns|test\", valid IDs look like: \"_/Namespace/_/example\"", nil)
+}
+
+func TestIDFromObject(t *testing.T) {
+	u := &k8sunstructured.Unstructured{}
+	u.SetAPIVersion("apps/v1")
+	u.SetKind("Deployment")
+	u.SetNamespace("test-ns")
+	u.SetName("test")
+
+	assert.Equal(t, "apps/Deployment/test-ns/test", IDFromObject(u).String(), nil)
+}
@@ -0,0 +1,55 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	k8sunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// Parse decodes a JSON or YAML manifest of a single Kubernetes object
+func Parse(manifest []byte) (*k8sunstructured.Unstructured, error) {
+	body, err := yaml.YAMLToJSON(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("yaml error: %s", err)
+	}
+
+	return Decode(body)
+}
+
+// Decode decodes a JSON manifest of a single Kubernetes object, lists,
+// e.g. v1 Lists of vendored install.yaml files, are an error
+func Decode(body []byte) (*k8sunstructured.Unstructured, error) {
+	obj, err := k8sruntime.Decode(k8sunstructured.UnstructuredJSONScheme, body)
+	if err != nil {
+		return nil, fmt.Errorf("json error: %s", err)
+	}
+
+	switch o := obj.(type) {
+	case *k8sunstructured.Unstructured:
+		return o, nil
+	case *k8sunstructured.UnstructuredList:
+		return nil, fmt.Errorf("manifest is a %s of %d items, not a single object, split it into its items, e.g. using the kustomization_manifests data source", o.GetKind(), len(o.Items))
+	}
+
+	return nil, fmt.Errorf("json error: unexpected object %T", obj)
+}
+
+// Normalize returns a JSON or YAML manifest JSON encoded like the
+// manifests of the provider, compact and with sorted keys, so
+// manifests can be compared as strings
+func Normalize(manifest []byte) (string, error) {
+	u, err := Parse(manifest)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := u.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(body), "\n"), nil
+}
@@ -0,0 +1,43 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	expected := `{"apiVersion":"v1","data":{"a":"1","b":"2"},"kind":"ConfigMap","metadata":{"name":"test"}}`
+
+	s, err := Normalize([]byte(`
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: test
+data:
+  b: "2"
+  a: "1"
+`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, expected, s, nil)
+
+	s, err = Normalize([]byte(`{"kind": "ConfigMap", "apiVersion": "v1", "metadata": {"name": "test"}, "data": {"b": "2", "a": "1"}}`))
+	assert.Equal(t, nil, err, nil)
+	assert.Equal(t, expected, s, nil)
+
+	_, err = Normalize([]byte(`{"metadata": {"name": "test"}}`))
+	assert.Error(t, err, nil)
+}
+
+func TestParseList(t *testing.T) {
+	_, err := Parse([]byte(`
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: test
+`))
+	assert.Error(t, err, nil)
+}
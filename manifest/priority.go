@@ -0,0 +1,60 @@
+package manifest
+
+import (
+	"strings"
+)
+
+// Priority is the group of an ID in the ids_prio attribute of the
+// provider's data sources, resources are applied in this order
+type Priority int
+
+const (
+	// PriorityFirst are Namespaces and CustomResourceDefinitions,
+	// other resources may depend on them
+	PriorityFirst Priority = iota
+	// PriorityDefault are all resources not in another group
+	PriorityDefault
+	// PriorityLast are admission webhook configurations,
+	// the webhooks may not be running before
+	PriorityLast
+)
+
+// KindPriority returns the priority of resources of kind
+func KindPriority(kind string) Priority {
+	for _, k := range []string{
+		"Namespace",
+		"CustomResourceDefinition",
+	} {
+		if strings.HasPrefix(kind, k) {
+			return PriorityFirst
+		}
+	}
+
+	for _, k := range []string{
+		"MutatingWebhookConfiguration",
+		"ValidatingWebhookConfiguration",
+	} {
+		if strings.HasPrefix(kind, k) {
+			return PriorityLast
+		}
+	}
+
+	return PriorityDefault
+}
+
+// Priority returns the priority of the resource
+func (id ID) Priority() Priority {
+	return KindPriority(id.Kind)
+}
+
+// GroupByPriority groups ids by their priority, like the ids_prio
+// attribute, the index of each group is its priority
+func GroupByPriority(ids []ID) [][]string {
+	groups := [][]string{{}, {}, {}}
+	for _, id := range ids {
+		p := id.Priority()
+		groups[p] = append(groups[p], id.String())
+	}
+
+	return groups
+}
@@ -0,0 +1,31 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindPriority(t *testing.T) {
+	assert.Equal(t, PriorityFirst, KindPriority("Namespace"), nil)
+	assert.Equal(t, PriorityFirst, KindPriority("CustomResourceDefinition"), nil)
+	assert.Equal(t, PriorityDefault, KindPriority("Deployment"), nil)
+	assert.Equal(t, PriorityLast, KindPriority("ValidatingWebhookConfiguration"), nil)
+	assert.Equal(t, PriorityLast, KindPriority("MutatingWebhookConfiguration"), nil)
+}
+
+func TestGroupByPriority(t *testing.T) {
+	groups := GroupByPriority([]ID{
+		{Group: "apps", Kind: "Deployment", Namespace: "test", Name: "test"},
+		{Kind: "Namespace", Name: "test"},
+		{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration", Name: "test"},
+	})
+
+	assert.Equal(t, [][]string{
+		{"_/Namespace/_/test"},
+		{"apps/Deployment/test/test"},
+		{"admissionregistration.k8s.io/ValidatingWebhookConfiguration/_/test"},
+	}, groups, nil)
+
+	assert.Equal(t, [][]string{{}, {}, {}}, GroupByPriority(nil), nil)
+}